@@ -0,0 +1,249 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "sync"
+    "time"
+)
+
+var ErrBinaryNotAllowed = errors.New("binary not allowed by sandbox")
+
+// sandboxWaitDelay is how long Run waits for output once the command has
+// exited or been killed.
+const sandboxWaitDelay = time.Second
+
+// Action is a command the agent asks the client to run locally. Either
+// Command (argv form) or Script is set.
+type Action struct {
+    ID string `json:"id"`
+    Command []string `json:"command,omitempty"`
+    Script string `json:"script,omitempty"`
+    Env map[string]string `json:"env,omitempty"`
+}
+
+// ActionResult is the outcome of one Action. Duration is sent as
+// duration_ms, in milliseconds, like ExecutionResult's.
+type ActionResult struct {
+    ID string `json:"id"`
+    ExitCode int `json:"exit_code"`
+    Stdout string `json:"stdout"`
+    Stderr string `json:"stderr"`
+    Truncated bool `json:"truncated,omitempty"`
+    TimedOut bool `json:"timed_out,omitempty"`
+    Error string `json:"error,omitempty"`
+    Duration time.Duration `json:"-"`
+}
+
+func (r ActionResult) MarshalJSON() ([]byte, error) {
+    type plain ActionResult
+    return json.Marshal(struct {
+        plain
+        DurationMS float64 `json:"duration_ms,omitempty"`
+    }{plain(r), float64(r.Duration) / float64(time.Millisecond)})
+}
+
+func (r *ActionResult) UnmarshalJSON(data []byte) error {
+    type plain ActionResult
+    var wire struct {
+        plain
+        DurationMS float64 `json:"duration_ms"`
+    }
+    if err := json.Unmarshal(data, &wire); err != nil {
+        return err
+    }
+    *r = ActionResult(wire.plain)
+    r.Duration = time.Duration(wire.DurationMS * float64(time.Millisecond))
+    return nil
+}
+
+// ActionsFromResponse extracts the actions listed under Data["actions"].
+func ActionsFromResponse(resp *ChatResponse) ([]Action, error) {
+    raw, ok := resp.Data["actions"]
+    if !ok {
+        return nil, nil
+    }
+    encoded, err := json.Marshal(raw)
+    if err != nil {
+        return nil, err
+    }
+    var actions []Action
+    if err := json.Unmarshal(encoded, &actions); err != nil {
+        return nil, fmt.Errorf("decode actions: %w", err)
+    }
+    return actions, nil
+}
+
+// Sandbox runs agent-returned actions under strict limits. Nothing runs
+// unless its binary (or ScriptShell for scripts) is listed in
+// AllowedBinaries, by name or absolute path. The child environment contains
+// only the variables named in PassEnv; action-supplied variables are dropped
+// unless they are also named there. OnOutput, when set, sees each chunk of
+// output as it is written, before MaxOutputBytes applies.
+type Sandbox struct {
+    AllowedBinaries []string
+    PassEnv []string
+    Dir string
+    Timeout time.Duration
+    MaxOutputBytes int
+    ScriptShell string
+    OnOutput func(stream string, chunk []byte)
+}
+
+func (s *Sandbox) Run(ctx context.Context, action Action) ActionResult {
+    result := ActionResult{ID: action.ID, ExitCode: -1}
+    argv := action.Command
+    if action.Script != "" {
+        shell := s.ScriptShell
+        if shell == "" {
+            shell = "/bin/sh"
+        }
+        argv = []string{shell, "-c", action.Script}
+    }
+    if len(argv) == 0 {
+        result.Error = "action has no command or script"
+        return result
+    }
+    binary, err := s.resolveBinary(argv[0])
+    if err != nil {
+        result.Error = err.Error()
+        return result
+    }
+
+    timeout := s.Timeout
+    if timeout <= 0 {
+        timeout = 30 * time.Second
+    }
+    ctx, cancel := context.WithTimeout(ctx, timeout)
+    defer cancel()
+
+    limit := s.MaxOutputBytes
+    if limit <= 0 {
+        limit = 64 << 10
+    }
+    stdout := &limitedBuffer{limit: limit}
+    stderr := &limitedBuffer{limit: limit}
+    if s.OnOutput != nil {
+        stdout.emit = func(p []byte) { s.OnOutput("stdout", p) }
+        stderr.emit = func(p []byte) { s.OnOutput("stderr", p) }
+    }
+    cmd := exec.CommandContext(ctx, binary, argv[1:]...)
+    cmd.Dir = s.Dir
+    cmd.Env = s.environment(action.Env)
+    cmd.Stdout = stdout
+    cmd.Stderr = stderr
+    // Children a script leaves behind share its output pipes and would
+    // hold Wait open past the timeout; the whole group is killed instead.
+    setProcessGroup(cmd)
+    cmd.Cancel = func() error { return killProcessGroup(cmd) }
+    cmd.WaitDelay = sandboxWaitDelay
+
+    start := time.Now()
+    err = cmd.Run()
+    if errors.Is(err, exec.ErrWaitDelay) {
+        killProcessGroup(cmd)
+    }
+    result.Duration = time.Since(start)
+    result.Stdout = stdout.String()
+    result.Stderr = stderr.String()
+    result.Truncated = stdout.truncated || stderr.truncated
+    if cmd.ProcessState != nil {
+        result.ExitCode = cmd.ProcessState.ExitCode()
+    }
+    if ctx.Err() == context.DeadlineExceeded {
+        result.TimedOut = true
+    }
+    if err != nil {
+        result.Error = err.Error()
+    }
+    return result
+}
+
+func (s *Sandbox) RunAll(ctx context.Context, actions []Action) []ActionResult {
+    results := make([]ActionResult, 0, len(actions))
+    for _, action := range actions {
+        results = append(results, s.Run(ctx, action))
+    }
+    return results
+}
+
+func (s *Sandbox) resolveBinary(name string) (string, error) {
+    resolved, err := exec.LookPath(name)
+    if err != nil {
+        return "", err
+    }
+    if abs, err := filepath.Abs(resolved); err == nil {
+        resolved = abs
+    }
+    for _, allowed := range s.AllowedBinaries {
+        if allowed == name && !filepath.IsAbs(allowed) && filepath.Base(name) == name {
+            return resolved, nil
+        }
+        if filepath.IsAbs(allowed) && filepath.Clean(allowed) == resolved {
+            return resolved, nil
+        }
+    }
+    return "", fmt.Errorf("%w: %s", ErrBinaryNotAllowed, name)
+}
+
+func (s *Sandbox) environment(actionEnv map[string]string) []string {
+    env := []string{}
+    for _, name := range s.PassEnv {
+        if value, ok := os.LookupEnv(name); ok {
+            env = append(env, name+"="+value)
+        }
+        if value, ok := actionEnv[name]; ok {
+            env = append(env, name+"="+value)
+        }
+    }
+    return env
+}
+
+// ContinuationRequest builds the follow-up ChatRequest that hands action
+// results back to the agent so it can continue the turn that requested them.
+func ContinuationRequest(resp *ChatResponse, results []ActionResult) ChatRequest {
+    return ChatRequest{
+        Message: "action results",
+        Inputs: map[string]any{
+            "continuation_of": resp.Function,
+            "action_results": results,
+        },
+    }
+}
+
+type limitedBuffer struct {
+    mu sync.Mutex
+    buf []byte
+    limit int
+    truncated bool
+    emit func([]byte)
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+    if b.emit != nil {
+        b.emit(p)
+    }
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    room := b.limit - len(b.buf)
+    if room < len(p) {
+        b.truncated = true
+        if room > 0 {
+            b.buf = append(b.buf, p[:room]...)
+        }
+        return len(p), nil
+    }
+    b.buf = append(b.buf, p...)
+    return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    return string(b.buf)
+}
@@ -0,0 +1,56 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "net/http"
+    "strings"
+)
+
+const (
+    HeaderActorID = "X-Echo-Actor-ID"
+    HeaderActorRoles = "X-Echo-Actor-Roles"
+    HeaderTenant = "X-Echo-Tenant"
+)
+
+// Actor identifies the end user an agent call is made on behalf of.
+type Actor struct {
+    ID string `json:"id"`
+    Roles []string `json:"roles,omitempty"`
+    Tenant string `json:"tenant,omitempty"`
+}
+
+type actorKey struct{}
+
+// WithActor attributes every call made with ctx to actor: the identity is
+// sent as X-Echo-Actor-* headers, its roles feed policy evaluation, and it
+// is stamped on audit records.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+    return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func ActorFromContext(ctx context.Context) (Actor, bool) {
+    actor, ok := ctx.Value(actorKey{}).(Actor)
+    return actor, ok
+}
+
+func (c *Client) decorate(ctx context.Context, req *http.Request) {
+    c.setVersionHeaders(ctx, req)
+    c.setLanguageHeader(ctx, req)
+    for k, v := range c.defaultHeaders {
+        req.Header.Set(k, v)
+    }
+    if actor, ok := ActorFromContext(ctx); ok {
+        if actor.ID != "" {
+            req.Header.Set(HeaderActorID, actor.ID)
+        }
+        if len(actor.Roles) > 0 {
+            req.Header.Set(HeaderActorRoles, strings.Join(actor.Roles, ","))
+        }
+        if actor.Tenant != "" {
+            req.Header.Set(HeaderTenant, actor.Tenant)
+        }
+    }
+    if key, ok := ctx.Value(idempotencyKey{}).(string); ok {
+        req.Header.Set(HeaderIdempotencyKey, key)
+    }
+}
@@ -0,0 +1,314 @@
+// Package agentdiff replays a corpus of chat requests against two agents,
+// typically the deployed version and an upgrade candidate, and reports
+// where their routing, response shapes, and latency diverge.
+//
+// Requests are replayed as dry runs unless Harness.Execute is set, so a
+// corpus recorded from production traffic is safe to run by default.
+package agentdiff
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "os"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    client "echo_computer_agent_client"
+)
+
+// Case is one replayed request. Name labels it in reports and defaults to
+// its position in the corpus.
+type Case struct {
+    Name string `json:"name,omitempty"`
+    Message string `json:"message"`
+    Inputs map[string]any `json:"inputs,omitempty"`
+}
+
+// LoadCorpus reads JSON lines of the form {"message": ..., "inputs": ...},
+// which audit logs from client.JSONLinesAuditSink already are. Blank
+// lines are skipped.
+func LoadCorpus(path string) ([]Case, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+    return ReadCorpus(f)
+}
+
+func ReadCorpus(r io.Reader) ([]Case, error) {
+    var cases []Case
+    scanner := bufio.NewScanner(r)
+    scanner.Buffer(make([]byte, 64*1024), 16<<20)
+    for n := 1; scanner.Scan(); n++ {
+        line := strings.TrimSpace(scanner.Text())
+        if line == "" {
+            continue
+        }
+        var c Case
+        if err := json.Unmarshal([]byte(line), &c); err != nil {
+            return nil, fmt.Errorf("agentdiff: corpus line %d: %w", n, err)
+        }
+        if c.Name == "" {
+            c.Name = fmt.Sprintf("case-%d", len(cases)+1)
+        }
+        cases = append(cases, c)
+    }
+    return cases, scanner.Err()
+}
+
+// Harness compares Baseline with Candidate. When Functions is non-empty
+// only cases the baseline routes to one of them are diffed; the rest are
+// counted as skipped.
+type Harness struct {
+    Baseline *client.Client
+    Candidate *client.Client
+    Functions []string
+    Execute bool
+    Concurrency int
+    Timeout time.Duration
+}
+
+// Outcome is one agent's answer to a case.
+type Outcome struct {
+    Function string `json:"function,omitempty"`
+    Shape map[string]string `json:"shape,omitempty"`
+    Latency time.Duration `json:"latency_ns"`
+    Error string `json:"error,omitempty"`
+}
+
+// CaseResult pairs both outcomes with what differed: "function", "shape",
+// or "error".
+type CaseResult struct {
+    Case Case `json:"case"`
+    Baseline Outcome `json:"baseline"`
+    Candidate Outcome `json:"candidate"`
+    Differences []string `json:"differences,omitempty"`
+    ShapeDiff []string `json:"shape_diff,omitempty"`
+    Skipped bool `json:"skipped,omitempty"`
+}
+
+type LatencySummary struct {
+    Mean time.Duration `json:"mean_ns"`
+    P50 time.Duration `json:"p50_ns"`
+    P95 time.Duration `json:"p95_ns"`
+    P99 time.Duration `json:"p99_ns"`
+    Max time.Duration `json:"max_ns"`
+}
+
+type Summary struct {
+    Total int `json:"total"`
+    Compared int `json:"compared"`
+    Skipped int `json:"skipped"`
+    Matched int `json:"matched"`
+    FunctionMismatches int `json:"function_mismatches"`
+    ShapeMismatches int `json:"shape_mismatches"`
+    ErrorMismatches int `json:"error_mismatches"`
+    BaselineLatency LatencySummary `json:"baseline_latency"`
+    CandidateLatency LatencySummary `json:"candidate_latency"`
+}
+
+type Report struct {
+    Started time.Time `json:"started"`
+    Summary Summary `json:"summary"`
+    Cases []CaseResult `json:"cases"`
+}
+
+// Run replays every case against both agents. It only fails if ctx ends;
+// per-case errors are part of the report.
+func (h *Harness) Run(ctx context.Context, cases []Case) (*Report, error) {
+    report := &Report{Started: time.Now().UTC(), Cases: make([]CaseResult, len(cases))}
+    workers := h.Concurrency
+    if workers < 1 {
+        workers = 1
+    }
+    next := make(chan int)
+    var wg sync.WaitGroup
+    for w := 0; w < workers; w++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for i := range next {
+                report.Cases[i] = h.compare(ctx, cases[i])
+            }
+        }()
+    }
+feed:
+    for i := range cases {
+        select {
+        case next <- i:
+        case <-ctx.Done():
+            break feed
+        }
+    }
+    close(next)
+    wg.Wait()
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+    report.Summary = summarize(report.Cases)
+    return report, nil
+}
+
+func (h *Harness) compare(ctx context.Context, c Case) CaseResult {
+    result := CaseResult{Case: c}
+    // Both agents see the case concurrently so neither is penalized by
+    // warming the other's caches.
+    var wg sync.WaitGroup
+    wg.Add(2)
+    go func() { defer wg.Done(); result.Baseline = h.call(ctx, h.Baseline, c) }()
+    go func() { defer wg.Done(); result.Candidate = h.call(ctx, h.Candidate, c) }()
+    wg.Wait()
+
+    if len(h.Functions) > 0 && !contains(h.Functions, result.Baseline.Function) {
+        result.Skipped = true
+        return result
+    }
+    if (result.Baseline.Error == "") != (result.Candidate.Error == "") {
+        result.Differences = append(result.Differences, "error")
+    }
+    if result.Baseline.Function != result.Candidate.Function {
+        result.Differences = append(result.Differences, "function")
+    }
+    if result.ShapeDiff = diffShapes(result.Baseline.Shape, result.Candidate.Shape); len(result.ShapeDiff) > 0 {
+        result.Differences = append(result.Differences, "shape")
+    }
+    return result
+}
+
+func (h *Harness) call(ctx context.Context, agent *client.Client, c Case) Outcome {
+    if h.Timeout > 0 {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+        defer cancel()
+    }
+    request := client.ChatRequest{Message: c.Message, Inputs: c.Inputs}
+    if h.Execute {
+        request = request.AutoExecute()
+    } else {
+        request = request.DryRun()
+    }
+    start := time.Now()
+    resp, err := agent.Chat(ctx, request)
+    outcome := Outcome{Latency: time.Since(start)}
+    if err != nil {
+        outcome.Error = err.Error()
+        return outcome
+    }
+    outcome.Function = resp.Function
+    outcome.Shape = map[string]string{}
+    shape("", resp.Data, outcome.Shape)
+    return outcome
+}
+
+// shape flattens a decoded JSON value into dotted paths and their JSON
+// types. Array elements share the path "[]".
+func shape(path string, value any, out map[string]string) {
+    switch v := value.(type) {
+    case map[string]any:
+        if path != "" {
+            out[path] = "object"
+        }
+        for key, item := range v {
+            child := key
+            if path != "" {
+                child = path + "." + key
+            }
+            shape(child, item, out)
+        }
+    case []any:
+        out[path] = "array"
+        for _, item := range v {
+            shape(path+"[]", item, out)
+        }
+    case string:
+        out[path] = "string"
+    case float64:
+        out[path] = "number"
+    case bool:
+        out[path] = "boolean"
+    case nil:
+        if path != "" {
+            out[path] = "null"
+        }
+    default:
+        out[path] = fmt.Sprintf("%T", v)
+    }
+}
+
+func diffShapes(baseline, candidate map[string]string) []string {
+    var diffs []string
+    for path, kind := range baseline {
+        other, ok := candidate[path]
+        switch {
+        case !ok:
+            diffs = append(diffs, "- "+path+" ("+kind+")")
+        case other != kind:
+            diffs = append(diffs, "~ "+path+" ("+kind+" -> "+other+")")
+        }
+    }
+    for path, kind := range candidate {
+        if _, ok := baseline[path]; !ok {
+            diffs = append(diffs, "+ "+path+" ("+kind+")")
+        }
+    }
+    sort.Slice(diffs, func(i, j int) bool { return diffs[i][2:] < diffs[j][2:] })
+    return diffs
+}
+
+func summarize(cases []CaseResult) Summary {
+    summary := Summary{Total: len(cases)}
+    var baseline, candidate []time.Duration
+    for _, c := range cases {
+        if c.Skipped {
+            summary.Skipped++
+            continue
+        }
+        summary.Compared++
+        baseline = append(baseline, c.Baseline.Latency)
+        candidate = append(candidate, c.Candidate.Latency)
+        if len(c.Differences) == 0 {
+            summary.Matched++
+        }
+        for _, difference := range c.Differences {
+            switch difference {
+            case "function":
+                summary.FunctionMismatches++
+            case "shape":
+                summary.ShapeMismatches++
+            case "error":
+                summary.ErrorMismatches++
+            }
+        }
+    }
+    summary.BaselineLatency, summary.CandidateLatency = latencySummary(baseline), latencySummary(candidate)
+    return summary
+}
+
+func latencySummary(samples []time.Duration) LatencySummary {
+    if len(samples) == 0 {
+        return LatencySummary{}
+    }
+    sorted := append([]time.Duration(nil), samples...)
+    sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+    var total time.Duration
+    for _, d := range sorted {
+        total += d
+    }
+    at := func(q float64) time.Duration { return sorted[int(q*float64(len(sorted)-1))] }
+    return LatencySummary{Mean: total / time.Duration(len(sorted)), P50: at(0.5), P95: at(0.95), P99: at(0.99), Max: sorted[len(sorted)-1]}
+}
+
+func contains(list []string, s string) bool {
+    for _, item := range list {
+        if item == s {
+            return true
+        }
+    }
+    return false
+}
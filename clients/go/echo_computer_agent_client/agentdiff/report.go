@@ -0,0 +1,64 @@
+package agentdiff
+
+import (
+    "encoding/json"
+    "html/template"
+    "io"
+    "time"
+)
+
+func (r *Report) WriteJSON(w io.Writer) error {
+    enc := json.NewEncoder(w)
+    enc.SetIndent("", "  ")
+    return enc.Encode(r)
+}
+
+// Differing returns the compared cases where the agents disagreed.
+func (r *Report) Differing() []CaseResult {
+    var cases []CaseResult
+    for _, c := range r.Cases {
+        if !c.Skipped && len(c.Differences) > 0 {
+            cases = append(cases, c)
+        }
+    }
+    return cases
+}
+
+var page = template.Must(template.New("report").Funcs(template.FuncMap{
+    "ms": func(d time.Duration) string { return d.Round(time.Millisecond).String() },
+}).Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Agent diff {{.Started.Format "2006-01-02 15:04"}}</title>
+<style>
+body { font: 14px system-ui, sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+.diff { background: #fdecea; }
+pre { margin: 0; }
+</style></head><body>
+<h1>Agent diff</h1>
+{{with .Summary}}
+<p>{{.Compared}} of {{.Total}} cases compared ({{.Skipped}} skipped), {{.Matched}} matched.
+{{.FunctionMismatches}} routed differently, {{.ShapeMismatches}} changed data shape, {{.ErrorMismatches}} differed in failing.</p>
+<table>
+<tr><th></th><th>mean</th><th>p50</th><th>p95</th><th>p99</th><th>max</th></tr>
+{{with .BaselineLatency}}<tr><th>baseline</th><td>{{ms .Mean}}</td><td>{{ms .P50}}</td><td>{{ms .P95}}</td><td>{{ms .P99}}</td><td>{{ms .Max}}</td></tr>{{end}}
+{{with .CandidateLatency}}<tr><th>candidate</th><td>{{ms .Mean}}</td><td>{{ms .P50}}</td><td>{{ms .P95}}</td><td>{{ms .P99}}</td><td>{{ms .Max}}</td></tr>{{end}}
+</table>
+{{end}}
+<h2>Differences</h2>
+<table>
+<tr><th>case</th><th>message</th><th>baseline</th><th>candidate</th><th>shape</th></tr>
+{{range .Differing}}<tr class="diff">
+<td>{{.Case.Name}}</td><td>{{.Case.Message}}</td>
+<td>{{.Baseline.Function}}{{with .Baseline.Error}}<br>error: {{.}}{{end}}<br>{{ms .Baseline.Latency}}</td>
+<td>{{.Candidate.Function}}{{with .Candidate.Error}}<br>error: {{.}}{{end}}<br>{{ms .Candidate.Latency}}</td>
+<td><pre>{{range .ShapeDiff}}{{.}}
+{{end}}</pre></td>
+</tr>{{else}}<tr><td colspan="5">None.</td></tr>{{end}}
+</table>
+</body></html>
+`))
+
+func (r *Report) WriteHTML(w io.Writer) error {
+    return page.Execute(w, r)
+}
@@ -0,0 +1,193 @@
+// Package agenthttp stands up thin HTTP APIs whose routes are backed by
+// agent function invocations.
+package agenthttp
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "sort"
+    "strings"
+
+    client "echo_computer_agent_client"
+    "echo_computer_agent_client/internal/reftemplate"
+)
+
+// Route maps one HTTP endpoint onto a function. Inputs values are templates:
+// a value that is exactly one reference such as "{path.id}" or
+// "{body.spec.replicas}" keeps the referenced value's JSON type, while
+// references embedded in longer strings are interpolated as text. Sources
+// are path, query, header, and body. Render selects a Data field to return
+// as the response body; the whole Data object is returned when it is empty.
+type Route struct {
+    Function string
+    Inputs map[string]string
+    Render string
+    Status int
+}
+
+// RouteMap keys are "METHOD /path/{param}" patterns.
+type RouteMap map[string]Route
+
+type compiledRoute struct {
+    pattern string
+    method string
+    segments []string
+    route Route
+}
+
+type handler struct {
+    client *client.Client
+    routes []compiledRoute
+}
+
+func Handler(c *client.Client, routes RouteMap) http.Handler {
+    h := &handler{client: c}
+    for pattern, route := range routes {
+        method, routePath, ok := strings.Cut(pattern, " ")
+        if !ok {
+            method, routePath = "", pattern
+        }
+        h.routes = append(h.routes, compiledRoute{
+            pattern: pattern,
+            method: strings.ToUpper(method),
+            segments: splitPath(routePath),
+            route: route,
+        })
+    }
+    // Prefer literal segments over parameters so "/jobs/latest" wins over
+    // "/jobs/{id}" regardless of map order.
+    sort.Slice(h.routes, func(i, j int) bool { return h.routes[i].before(h.routes[j]) })
+    return h
+}
+
+// before orders routes most literal first: fewer parameters, then a literal
+// at the first position where one route has a parameter and the other does
+// not, then routes naming a method; the pattern breaks remaining ties.
+func (a compiledRoute) before(b compiledRoute) bool {
+    if pa, pb := paramCount(a.segments), paramCount(b.segments); pa != pb {
+        return pa < pb
+    }
+    for i := 0; i < len(a.segments) && i < len(b.segments); i++ {
+        if la, lb := !isParam(a.segments[i]), !isParam(b.segments[i]); la != lb {
+            return la
+        }
+    }
+    if (a.method == "") != (b.method == "") {
+        return a.method != ""
+    }
+    return a.pattern < b.pattern
+}
+
+func isParam(segment string) bool {
+    return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+func paramCount(segments []string) int {
+    n := 0
+    for _, segment := range segments {
+        if isParam(segment) {
+            n++
+        }
+    }
+    return n
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    route, params, ok := h.match(r)
+    if !ok {
+        writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+        return
+    }
+    var body map[string]any
+    if r.Body != nil && r.ContentLength != 0 {
+        if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<20)).Decode(&body); err != nil {
+            writeJSON(w, http.StatusBadRequest, map[string]string{"error": "request body must be a JSON object"})
+            return
+        }
+    }
+    sources := map[string]reftemplate.Source{
+        "path": func(key string) (any, bool) { v, ok := params[key]; return v, ok },
+        "query": func(key string) (any, bool) { v := r.URL.Query(); return v.Get(key), v.Has(key) },
+        "header": func(key string) (any, bool) { v := r.Header.Get(key); return v, v != "" },
+        "body": reftemplate.Map(body),
+    }
+    inputs := make(map[string]any, len(route.Inputs))
+    for name, template := range route.Inputs {
+        value, err := reftemplate.Render(template, sources)
+        if err != nil {
+            writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("input %s: %v", name, err)})
+            return
+        }
+        inputs[name] = value
+    }
+    // Callers of the API are not trusted with the agent's secrets.
+    inputs = h.client.StripSecretRefs(inputs)
+
+    ctx := r.Context()
+    if lang := r.Header.Get("Accept-Language"); lang != "" {
+        ctx = client.WithLanguage(ctx, lang)
+    }
+    resp, err := h.client.InvokeFunction(ctx, route.Function, inputs)
+    if err != nil {
+        status := http.StatusBadGateway
+        if errors.Is(err, client.ErrFunctionNotAllowed) || errors.Is(err, client.ErrPolicyDenied) || errors.Is(err, client.ErrConfirmationRequired) {
+            status = http.StatusForbidden
+        }
+        writeJSON(w, status, map[string]string{"error": err.Error()})
+        return
+    }
+    status := route.Status
+    if status == 0 {
+        status = http.StatusOK
+    }
+    if route.Render == "" {
+        writeJSON(w, status, resp.Data)
+        return
+    }
+    value, ok := reftemplate.Lookup(resp.Data, route.Render)
+    if !ok {
+        writeJSON(w, http.StatusBadGateway, map[string]string{"error": "agent response has no " + route.Render + " field"})
+        return
+    }
+    writeJSON(w, status, value)
+}
+
+func (h *handler) match(r *http.Request) (Route, map[string]string, bool) {
+    segments := splitPath(r.URL.Path)
+    for _, candidate := range h.routes {
+        if candidate.method != "" && candidate.method != r.Method {
+            continue
+        }
+        if len(candidate.segments) != len(segments) {
+            continue
+        }
+        params := map[string]string{}
+        matched := true
+        for i, segment := range candidate.segments {
+            if isParam(segment) {
+                params[segment[1:len(segment)-1]] = segments[i]
+                continue
+            }
+            if segment != segments[i] {
+                matched = false
+                break
+            }
+        }
+        if matched {
+            return candidate.route, params, true
+        }
+    }
+    return Route{}, nil, false
+}
+
+func splitPath(p string) []string {
+    return strings.Split(strings.Trim(p, "/"), "/")
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload any) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(payload)
+}
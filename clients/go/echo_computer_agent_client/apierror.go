@@ -0,0 +1,137 @@
+package echo_computer_agent_client
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+    "time"
+)
+
+// Error codes the agent reports for failures callers commonly branch on.
+const (
+    ErrorCodeFunctionNotFound = "function_not_found"
+    ErrorCodeRateLimited = "rate_limited"
+)
+
+// maxErrorBody bounds how much of an error response APIError keeps.
+const maxErrorBody = 64 << 10
+
+// APIError is a response the agent answered with an HTTP error status.
+// Code and Message come from the body when it is a JSON error document,
+// either {"code", "message"}, {"error": ...}, or FastAPI's {"detail": ...};
+// Body holds the raw response, truncated to 64 KiB.
+type APIError struct {
+    Status int
+    Code string
+    Message string
+    Body []byte
+
+    retryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+    text := fmt.Sprintf("request failed with status %d", e.Status)
+    if e.Code != "" {
+        text += " (" + e.Code + ")"
+    }
+    if e.Message != "" {
+        text += ": " + e.Message
+    }
+    return text
+}
+
+func newAPIError(resp *http.Response) *APIError {
+    body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+    e := &APIError{Status: resp.StatusCode, Body: body, retryAfter: retryAfter(resp.Header.Get("Retry-After"), time.Now())}
+    var document struct {
+        Code any `json:"code"`
+        Message string `json:"message"`
+        Error json.RawMessage `json:"error"`
+        Detail json.RawMessage `json:"detail"`
+    }
+    if json.Unmarshal(body, &document) != nil {
+        return e
+    }
+    if document.Code != nil {
+        e.Code = fmt.Sprint(document.Code)
+    }
+    e.Message = document.Message
+    for _, nested := range []json.RawMessage{document.Error, document.Detail} {
+        if len(nested) > 0 && e.Message == "" {
+            e.parseNested(nested)
+        }
+    }
+    return e
+}
+
+// parseNested reads an "error" or "detail" member, which is a string, an
+// object with a code and message, or FastAPI's list of validation errors.
+func (e *APIError) parseNested(raw json.RawMessage) {
+    var text string
+    if json.Unmarshal(raw, &text) == nil {
+        e.Message = text
+        return
+    }
+    var object struct {
+        Code any `json:"code"`
+        Type string `json:"type"`
+        Message string `json:"message"`
+        Msg string `json:"msg"`
+    }
+    var list []json.RawMessage
+    if json.Unmarshal(raw, &list) == nil && len(list) > 0 {
+        messages := make([]string, 0, len(list))
+        for _, item := range list {
+            if json.Unmarshal(item, &object) == nil && object.Msg != "" {
+                messages = append(messages, object.Msg)
+            }
+        }
+        e.Message = strings.Join(messages, "; ")
+        return
+    }
+    if json.Unmarshal(raw, &object) != nil {
+        return
+    }
+    if e.Code == "" {
+        if object.Code != nil {
+            e.Code = fmt.Sprint(object.Code)
+        } else {
+            e.Code = object.Type
+        }
+    }
+    e.Message = object.Message
+    if e.Message == "" {
+        e.Message = object.Msg
+    }
+}
+
+// IsNotFound reports whether err is an APIError with status 404.
+func IsNotFound(err error) bool {
+    var apiErr *APIError
+    return errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound
+}
+
+// IsRateLimited reports whether the agent rejected the call for exceeding
+// its rate limit, including after the client gave up waiting it out.
+func IsRateLimited(err error) bool {
+    if errors.Is(err, ErrRateLimited) {
+        return true
+    }
+    var apiErr *APIError
+    return errors.As(err, &apiErr) && (apiErr.Status == http.StatusTooManyRequests || apiErr.Code == ErrorCodeRateLimited)
+}
+
+// IsFunctionNotFound reports whether the agent does not know the function
+// a call named, whether it said so in an error response or in-band on a
+// stream.
+func IsFunctionNotFound(err error) bool {
+    var apiErr *APIError
+    if errors.As(err, &apiErr) {
+        return apiErr.Code == ErrorCodeFunctionNotFound
+    }
+    var event *ErrorEvent
+    return errors.As(err, &event) && event.Code == ErrorCodeFunctionNotFound
+}
@@ -0,0 +1,248 @@
+package echo_computer_agent_client
+
+import (
+    "bytes"
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "sync"
+    "time"
+)
+
+// AuditRecord describes one execute=true call, whether it reached the agent
+// or was refused client-side.
+type AuditRecord struct {
+    Time time.Time `json:"time"`
+    Function string `json:"function,omitempty"`
+    Message string `json:"message"`
+    Inputs map[string]any `json:"inputs,omitempty"`
+    Actor *Actor `json:"actor,omitempty"`
+    Outcome string `json:"outcome"`
+    Error string `json:"error,omitempty"`
+    PrevHash string `json:"prev_hash,omitempty"`
+    Hash string `json:"hash,omitempty"`
+
+    // raw is the document the record was decoded from, which VerifyChain
+    // hashes: decoding and re-encoding would round large integers in
+    // Inputs and need not reproduce the bytes that were hashed.
+    raw json.RawMessage
+}
+
+func (r *AuditRecord) UnmarshalJSON(data []byte) error {
+    type plain AuditRecord
+    if err := json.Unmarshal(data, (*plain)(r)); err != nil {
+        return err
+    }
+    r.raw = append(json.RawMessage(nil), data...)
+    return nil
+}
+
+type AuditSink interface {
+    Record(ctx context.Context, record AuditRecord) error
+}
+
+// SetAuditSink records every executing Chat call to sink. Sink errors are
+// not surfaced to callers; sinks that must not lose records should buffer or
+// fail loudly themselves.
+func (c *Client) SetAuditSink(sink AuditSink) {
+    c.auditSink = sink
+}
+
+func (c *Client) audit(ctx context.Context, function string, request ChatRequest, resp *ChatResponse, err error) {
+    if c.auditSink == nil {
+        return
+    }
+    record := AuditRecord{
+        Time: time.Now().UTC(),
+        Function: function,
+        Message: request.Message,
+        Inputs: request.Inputs,
+        Outcome: "executed",
+    }
+    if actor, ok := ActorFromContext(ctx); ok {
+        record.Actor = &actor
+    }
+    if resp != nil && resp.Function != "" {
+        record.Function = resp.Function
+    }
+    if err != nil {
+        record.Outcome = "failed"
+        if errors.Is(err, ErrFunctionNotAllowed) || errors.Is(err, ErrPolicyDenied) || errors.Is(err, ErrConfirmationRequired) {
+            record.Outcome = "refused"
+        }
+        record.Error = err.Error()
+    }
+    _ = c.auditSink.Record(ctx, record)
+}
+
+// JSONLinesAuditSink writes one JSON document per record.
+type JSONLinesAuditSink struct {
+    mu sync.Mutex
+    w io.Writer
+}
+
+func NewJSONLinesAuditSink(w io.Writer) *JSONLinesAuditSink {
+    return &JSONLinesAuditSink{w: w}
+}
+
+func (s *JSONLinesAuditSink) Record(ctx context.Context, record AuditRecord) error {
+    line, err := json.Marshal(record)
+    if err != nil {
+        return err
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    _, err = s.w.Write(append(line, '\n'))
+    return err
+}
+
+// ChainHead identifies the latest record of a hash chain.
+type ChainHead struct {
+    Hash string `json:"hash"`
+    Count int `json:"count"`
+    Time time.Time `json:"time"`
+}
+
+// Anchorer publishes a chain head somewhere the audit log's writer cannot
+// rewrite, such as a transparency log or a separate ledger.
+type Anchorer interface {
+    Anchor(ctx context.Context, head ChainHead) error
+}
+
+// HashChainSink links records before handing them to the wrapped sink: each
+// record carries the previous record's digest and its own, so removing or
+// editing any record breaks every digest after it.
+type HashChainSink struct {
+    mu sync.Mutex
+    next AuditSink
+    head string
+    count int
+}
+
+// NewHashChainSink starts a chain after prevHash, which is empty for a new
+// log or the last recorded Hash when appending to an existing one.
+func NewHashChainSink(next AuditSink, prevHash string) *HashChainSink {
+    return &HashChainSink{next: next, head: prevHash}
+}
+
+func (s *HashChainSink) Record(ctx context.Context, record AuditRecord) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    record.PrevHash = s.head
+    hash, err := recordDigest(record)
+    if err != nil {
+        return err
+    }
+    record.Hash = hash
+    if err := s.next.Record(ctx, record); err != nil {
+        return err
+    }
+    s.head = hash
+    s.count++
+    return nil
+}
+
+func (s *HashChainSink) Head() ChainHead {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return ChainHead{Hash: s.head, Count: s.count, Time: time.Now().UTC()}
+}
+
+// RunAnchoring anchors the chain head every interval until ctx is done,
+// skipping ticks where no new records were written.
+func (s *HashChainSink) RunAnchoring(ctx context.Context, anchorer Anchorer, interval time.Duration) error {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    anchored := ""
+    for {
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-ticker.C:
+            head := s.Head()
+            if head.Hash == "" || head.Hash == anchored {
+                continue
+            }
+            if err := anchorer.Anchor(ctx, head); err != nil {
+                return err
+            }
+            anchored = head.Hash
+        }
+    }
+}
+
+// VerifyChain recomputes every digest in records, which must be in write
+// order, and reports the first record that does not link or hash correctly.
+// Records decoded from JSON are checked against the exact document read.
+func VerifyChain(records []AuditRecord, prevHash string) error {
+    for i, record := range records {
+        if record.PrevHash != prevHash {
+            return fmt.Errorf("audit chain broken at record %d: previous hash mismatch", i)
+        }
+        want := record.Hash
+        hash, err := recordDigest(record)
+        if record.raw != nil {
+            hash, err = rawDigest(record.raw)
+        }
+        if err != nil {
+            return err
+        }
+        if hash != want {
+            return fmt.Errorf("audit chain broken at record %d: digest mismatch", i)
+        }
+        prevHash = hash
+    }
+    return nil
+}
+
+func recordDigest(record AuditRecord) (string, error) {
+    record.Hash = ""
+    encoded, err := json.Marshal(record)
+    if err != nil {
+        return "", err
+    }
+    sum := sha256.Sum256(encoded)
+    return hex.EncodeToString(sum[:]), nil
+}
+
+// rawDigest hashes a serialized record as recordDigest hashed it when it was
+// written: the document with its "hash" member removed, keeping the order
+// and bytes of every other member.
+func rawDigest(raw json.RawMessage) (string, error) {
+    dec := json.NewDecoder(bytes.NewReader(raw))
+    if token, err := dec.Token(); err != nil || token != json.Delim('{') {
+        return "", fmt.Errorf("audit record is not a JSON object")
+    }
+    var buf bytes.Buffer
+    buf.WriteByte('{')
+    for dec.More() {
+        token, err := dec.Token()
+        if err != nil {
+            return "", err
+        }
+        var value json.RawMessage
+        if err := dec.Decode(&value); err != nil {
+            return "", err
+        }
+        key, _ := token.(string)
+        if key == "hash" {
+            continue
+        }
+        if buf.Len() > 1 {
+            buf.WriteByte(',')
+        }
+        name, _ := json.Marshal(key)
+        buf.Write(name)
+        buf.WriteByte(':')
+        if err := json.Compact(&buf, value); err != nil {
+            return "", err
+        }
+    }
+    buf.WriteByte('}')
+    sum := sha256.Sum256(buf.Bytes())
+    return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,186 @@
+package echo_computer_agent_client
+
+import (
+    "errors"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+)
+
+// tokenExpiryDelta is how long before its expiry a token is refreshed, so
+// it does not lapse in flight.
+const tokenExpiryDelta = 30 * time.Second
+
+// Token is a credential from a TokenSource. A zero Expiry never expires;
+// TokenType defaults to "Bearer".
+type Token struct {
+    AccessToken string
+    TokenType string
+    Expiry time.Time
+}
+
+func (t *Token) valid(now time.Time) bool {
+    return t != nil && t.AccessToken != "" && (t.Expiry.IsZero() || now.Add(tokenExpiryDelta).Before(t.Expiry))
+}
+
+// TokenSource supplies tokens, fetching a new one each call. It has the
+// shape of golang.org/x/oauth2's, which adapts with a TokenSourceFunc:
+//
+//	client.TokenSourceFunc(func() (*client.Token, error) {
+//	    t, err := src.Token()
+//	    if err != nil {
+//	        return nil, err
+//	    }
+//	    return &client.Token{AccessToken: t.AccessToken, TokenType: t.Type(), Expiry: t.Expiry}, nil
+//	})
+type TokenSource interface {
+    Token() (*Token, error)
+}
+
+type TokenSourceFunc func() (*Token, error)
+
+func (f TokenSourceFunc) Token() (*Token, error) { return f() }
+
+// authState is how the client authenticates: a static header, or a token
+// source whose token is cached until shortly before it expires.
+type authState struct {
+    mu sync.Mutex
+    header string
+    value string
+    source TokenSource
+    token *Token
+}
+
+// SetBearerToken authenticates every request with a fixed bearer token.
+func (c *Client) SetBearerToken(token string) {
+    c.setAuth("Authorization", "Bearer "+token, nil)
+}
+
+// SetAPIKey sends value in header on every request, e.g. "X-API-Key".
+func (c *Client) SetAPIKey(header, value string) {
+    c.setAuth(header, value, nil)
+}
+
+// SetTokenSource authenticates requests with bearer tokens from source,
+// fetching a new one shortly before the current one expires, or when the
+// agent rejects it with a 401, after which the request is sent once more.
+func (c *Client) SetTokenSource(source TokenSource) {
+    c.setAuth("Authorization", "", source)
+}
+
+func WithBearerToken(token string) Option {
+    return func(c *Client) { c.SetBearerToken(token) }
+}
+
+func WithAPIKey(header, value string) Option {
+    return func(c *Client) { c.SetAPIKey(header, value) }
+}
+
+func WithTokenSource(source TokenSource) Option {
+    return func(c *Client) { c.SetTokenSource(source) }
+}
+
+func (c *Client) setAuth(header, value string, source TokenSource) {
+    c.auth.mu.Lock()
+    defer c.auth.mu.Unlock()
+    c.auth.header, c.auth.value, c.auth.source, c.auth.token = http.CanonicalHeaderKey(header), value, source, nil
+}
+
+// authenticate sets req's credentials.
+func (c *Client) authenticate(req *http.Request) error {
+    a := &c.auth
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    if a.header == "" {
+        return nil
+    }
+    if a.source == nil {
+        req.Header.Set(a.header, a.value)
+        return nil
+    }
+    if !a.token.valid(time.Now()) {
+        token, err := a.source.Token()
+        if err != nil {
+            return &AuthError{Err: err}
+        }
+        if token == nil || token.AccessToken == "" {
+            return &AuthError{Err: errors.New("token source returned no token")}
+        }
+        a.token = token
+    }
+    req.Header.Set(a.header, a.token.header())
+    return nil
+}
+
+func (t *Token) header() string {
+    kind := t.TokenType
+    if kind == "" || strings.EqualFold(kind, "bearer") {
+        kind = "Bearer"
+    }
+    return kind + " " + t.AccessToken
+}
+
+// rejected drops a token the agent refused, reporting whether the request
+// is worth sending again with a fresh one.
+func (c *Client) rejected(req *http.Request, resp *http.Response) bool {
+    if resp.StatusCode != http.StatusUnauthorized || (req.Body != nil && req.GetBody == nil) {
+        return false
+    }
+    a := &c.auth
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    if a.source == nil {
+        return false
+    }
+    // Unless another request already replaced it.
+    if a.token != nil && req.Header.Get(a.header) == a.token.header() {
+        a.token = nil
+    }
+    return true
+}
+
+// credentials authenticates and signs req.
+func (c *Client) credentials(req *http.Request) error {
+    if err := c.authenticate(req); err != nil {
+        return err
+    }
+    return c.sign(req)
+}
+
+// sendAuthenticated sends req with the client's credentials, resending it
+// once after refreshing a rejected token or correcting for clock skew.
+func (c *Client) sendAuthenticated(req *http.Request) (*http.Response, error) {
+    if err := c.credentials(req); err != nil {
+        return nil, err
+    }
+    resp, err := c.roundTrip(req)
+    if err != nil {
+        return resp, err
+    }
+    rejected := c.rejected(req, resp)
+    if !c.skewed(req, resp) && !rejected {
+        return resp, err
+    }
+    retry := req.Clone(req.Context())
+    if req.GetBody != nil {
+        body, err := req.GetBody()
+        if err != nil {
+            return resp, nil
+        }
+        retry.Body = body
+    }
+    if err := c.credentials(retry); err != nil {
+        return resp, nil
+    }
+    resp.Body.Close()
+    return c.roundTrip(retry)
+}
+
+// AuthError is a failure to obtain credentials for a request.
+type AuthError struct {
+    Err error
+}
+
+func (e *AuthError) Error() string { return "authenticate: " + e.Err.Error() }
+func (e *AuthError) Unwrap() error { return e.Err }
@@ -0,0 +1,154 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "sync"
+    "time"
+)
+
+// ErrCircuitOpen is returned without contacting the agent while the
+// client's circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+const (
+    DefaultFailureThreshold = 5
+    DefaultProbeInterval = 30 * time.Second
+)
+
+type CircuitState int
+
+const (
+    CircuitClosed CircuitState = iota
+    CircuitOpen
+    CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+    switch s {
+    case CircuitOpen:
+        return "open"
+    case CircuitHalfOpen:
+        return "half-open"
+    }
+    return "closed"
+}
+
+// CircuitBreaker configures the client's breaker. After FailureThreshold
+// consecutive failures, connection errors, timeouts and 5xx responses, it
+// opens and every request fails fast with ErrCircuitOpen. Once
+// ProbeInterval has passed it lets a single request through, half-open:
+// success closes the circuit, failure opens it for another interval.
+// OnStateChange runs with the breaker locked, so it must not call back
+// into it.
+type CircuitBreaker struct {
+    FailureThreshold int
+    ProbeInterval time.Duration
+    OnStateChange func(from, to CircuitState)
+}
+
+type breakerState struct {
+    sync.Mutex
+    config *CircuitBreaker
+    state CircuitState
+    failures int
+    opened time.Time
+    probing bool
+}
+
+// SetCircuitBreaker guards the client's requests with a breaker; nil
+// removes it. Zero fields take the defaults.
+func (c *Client) SetCircuitBreaker(config *CircuitBreaker) {
+    c.breaker.Lock()
+    defer c.breaker.Unlock()
+    c.breaker.config, c.breaker.state, c.breaker.failures, c.breaker.probing = nil, CircuitClosed, 0, false
+    if config != nil {
+        configured := *config
+        if configured.FailureThreshold <= 0 {
+            configured.FailureThreshold = DefaultFailureThreshold
+        }
+        if configured.ProbeInterval <= 0 {
+            configured.ProbeInterval = DefaultProbeInterval
+        }
+        c.breaker.config = &configured
+    }
+}
+
+func WithCircuitBreaker(config CircuitBreaker) Option {
+    return func(c *Client) { c.SetCircuitBreaker(&config) }
+}
+
+func (c *Client) CircuitState() CircuitState {
+    c.breaker.Lock()
+    defer c.breaker.Unlock()
+    return c.breaker.state
+}
+
+// allowRequest admits a request, or fails it while the circuit is open or
+// another request is probing it.
+func (c *Client) allowRequest() error {
+    b := &c.breaker
+    b.Lock()
+    defer b.Unlock()
+    if b.config == nil {
+        return nil
+    }
+    switch b.state {
+    case CircuitOpen:
+        wait := b.config.ProbeInterval - time.Since(b.opened)
+        if wait > 0 {
+            return fmt.Errorf("%w: probing again in %s", ErrCircuitOpen, wait.Round(time.Millisecond))
+        }
+        c.setCircuit(CircuitHalfOpen)
+        b.probing = true
+    case CircuitHalfOpen:
+        if b.probing {
+            return fmt.Errorf("%w: probe in flight", ErrCircuitOpen)
+        }
+        b.probing = true
+    }
+    return nil
+}
+
+// recordOutcome feeds a request's outcome to the breaker. Calls the
+// caller cancelled say nothing about the agent and are not counted.
+func (c *Client) recordOutcome(status int, err error) {
+    b := &c.breaker
+    b.Lock()
+    defer b.Unlock()
+    if b.config == nil || errors.Is(err, context.Canceled) {
+        if b.state == CircuitHalfOpen {
+            b.probing = false
+        }
+        return
+    }
+    failed := err != nil || status >= 500 && status != http.StatusNotImplemented
+    switch {
+    case b.state == CircuitHalfOpen && failed:
+        b.probing = false
+        b.opened = time.Now()
+        c.setCircuit(CircuitOpen)
+    case b.state == CircuitHalfOpen:
+        b.probing, b.failures = false, 0
+        c.setCircuit(CircuitClosed)
+    case failed:
+        b.failures++
+        if b.state == CircuitClosed && b.failures >= b.config.FailureThreshold {
+            b.opened = time.Now()
+            c.setCircuit(CircuitOpen)
+        }
+    default:
+        b.failures = 0
+    }
+}
+
+// setCircuit is called with c.breaker held.
+func (c *Client) setCircuit(state CircuitState) {
+    from := c.breaker.state
+    c.breaker.state = state
+    if hook := c.breaker.config.OnStateChange; hook != nil && from != state {
+        hook(from, state)
+    }
+}
@@ -0,0 +1,182 @@
+// Package bridge lets the agent drive approved host executables: a
+// declarative config maps function names to binaries and argument
+// templates, and the resulting handlers serve tool calls pushed over a
+// reverse connection.
+package bridge
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "regexp"
+    "strings"
+    "time"
+
+    client "echo_computer_agent_client"
+)
+
+// Config is the bridge file format:
+//
+//    {"tools": {"disk_usage": {"path": "/usr/bin/du", "args": ["-sh", "{inputs.dir}"], "timeout": "10s"}}}
+type Config struct {
+    Tools map[string]Command `json:"tools"`
+}
+
+// Command runs Path directly, never through a shell. Args may reference
+// inputs as "{inputs.name}"; an argument that is exactly one reference to a
+// list input expands to one argument per element, up to MaxListItems (32
+// by default). Input values may not begin with "-", so the agent cannot
+// pass options the config does not spell out, unless the reference is
+// marked as a flag: "{inputs.name|flag}". A "--" is inserted before the
+// first argument taken whole from an input unless Args has one already
+// or NoSeparator is set, for tools that do not take "--" to end options.
+// Only variables named in PassEnv reach the child process.
+type Command struct {
+    Path string `json:"path"`
+    Args []string `json:"args,omitempty"`
+    Dir string `json:"dir,omitempty"`
+    PassEnv []string `json:"pass_env,omitempty"`
+    Timeout string `json:"timeout,omitempty"`
+    MaxOutputBytes int `json:"max_output_bytes,omitempty"`
+    MaxListItems int `json:"max_list_items,omitempty"`
+    NoSeparator bool `json:"no_separator,omitempty"`
+}
+
+func LoadConfig(name string) (*Config, error) {
+    raw, err := os.ReadFile(name)
+    if err != nil {
+        return nil, err
+    }
+    var cfg Config
+    if err := json.Unmarshal(raw, &cfg); err != nil {
+        return nil, fmt.Errorf("parse bridge config %s: %w", name, err)
+    }
+    for fn, cmd := range cfg.Tools {
+        if !strings.HasPrefix(cmd.Path, "/") {
+            return nil, fmt.Errorf("bridge tool %s: path must be absolute", fn)
+        }
+        if cmd.Timeout != "" {
+            if _, err := time.ParseDuration(cmd.Timeout); err != nil {
+                return nil, fmt.Errorf("bridge tool %s: %w", fn, err)
+            }
+        }
+    }
+    return &cfg, nil
+}
+
+// Handlers returns one tool handler per configured function, ready for
+// client.ReverseOptions.Tools.
+func (cfg *Config) Handlers() map[string]client.ToolHandler {
+    handlers := make(map[string]client.ToolHandler, len(cfg.Tools))
+    for name, cmd := range cfg.Tools {
+        cmd := cmd
+        handlers[name] = func(ctx context.Context, call client.ToolCall) (map[string]any, error) {
+            return cmd.Run(ctx, call.Inputs)
+        }
+    }
+    return handlers
+}
+
+var inputRef = regexp.MustCompile(`\{inputs\.([A-Za-z0-9_]+)(\|flag)?\}`)
+
+func (c Command) Argv(inputs map[string]any) ([]string, error) {
+    maxItems := c.MaxListItems
+    if maxItems <= 0 {
+        maxItems = 32
+    }
+    separated := c.NoSeparator
+    for _, arg := range c.Args {
+        separated = separated || arg == "--"
+    }
+    argv := []string{}
+    for _, arg := range c.Args {
+        if m := inputRef.FindStringSubmatch(arg); m != nil && m[0] == arg {
+            value, ok := inputs[m[1]]
+            if !ok {
+                return nil, fmt.Errorf("missing input %s", m[1])
+            }
+            values := []any{value}
+            if list, ok := value.([]any); ok {
+                if len(list) > maxItems {
+                    return nil, fmt.Errorf("input %s: %d items, at most %d allowed", m[1], len(list), maxItems)
+                }
+                values = list
+            }
+            flag := m[2] != ""
+            if !flag && !separated {
+                argv = append(argv, "--")
+                separated = true
+            }
+            for _, item := range values {
+                expanded := fmt.Sprint(item)
+                if !flag && strings.HasPrefix(expanded, "-") {
+                    return nil, fmt.Errorf("input %s: value %q may not begin with -", m[1], expanded)
+                }
+                argv = append(argv, expanded)
+            }
+            continue
+        }
+        var missing error
+        flag := false
+        expanded := inputRef.ReplaceAllStringFunc(arg, func(ref string) string {
+            m := inputRef.FindStringSubmatch(ref)
+            value, ok := inputs[m[1]]
+            if !ok {
+                missing = fmt.Errorf("missing input %s", m[1])
+            }
+            flag = flag || m[2] != ""
+            return fmt.Sprint(value)
+        })
+        if missing != nil {
+            return nil, missing
+        }
+        if !flag && !strings.HasPrefix(arg, "-") && strings.HasPrefix(expanded, "-") {
+            return nil, fmt.Errorf("argument %q may not begin with -", expanded)
+        }
+        argv = append(argv, expanded)
+    }
+    return argv, nil
+}
+
+// Run executes the command in a client.Sandbox allowing only Path,
+// streaming output chunks with client.EmitToolOutput as they arrive and
+// returning the exit code plus the captured (size-limited) output.
+func (c Command) Run(ctx context.Context, inputs map[string]any) (map[string]any, error) {
+    argv, err := c.Argv(inputs)
+    if err != nil {
+        return nil, err
+    }
+    timeout := 30 * time.Second
+    if c.Timeout != "" {
+        timeout, _ = time.ParseDuration(c.Timeout)
+    }
+    limit := c.MaxOutputBytes
+    if limit <= 0 {
+        limit = 256 << 10
+    }
+    sandbox := &client.Sandbox{
+        AllowedBinaries: []string{c.Path},
+        PassEnv: c.PassEnv,
+        Dir: c.Dir,
+        Timeout: timeout,
+        MaxOutputBytes: limit,
+        OnOutput: func(stream string, chunk []byte) { client.EmitToolOutput(ctx, stream, chunk) },
+    }
+    run := sandbox.Run(ctx, client.Action{Command: append([]string{c.Path}, argv...)})
+    result := map[string]any{
+        "exit_code": run.ExitCode,
+        "stdout": run.Stdout,
+        "stderr": run.Stderr,
+        "truncated": run.Truncated,
+        "duration_ms": run.Duration.Milliseconds(),
+    }
+    if run.TimedOut {
+        return result, fmt.Errorf("%s timed out after %s", c.Path, timeout)
+    }
+    if run.ExitCode < 0 && run.Error != "" {
+        return result, errors.New(run.Error)
+    }
+    return result, nil
+}
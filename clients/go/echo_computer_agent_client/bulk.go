@@ -0,0 +1,190 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "sync"
+)
+
+// BulkResult is the outcome of one BulkInvoke item. Error mirrors Err for
+// serialised output.
+type BulkResult struct {
+    Index int `json:"index"`
+    Function string `json:"function"`
+    Inputs map[string]any `json:"inputs,omitempty"`
+    Response *ChatResponse `json:"response,omitempty"`
+    Err error `json:"-"`
+    Error string `json:"error,omitempty"`
+}
+
+// BulkInvoke runs InvokeFunction once per input set with at most
+// concurrency calls in flight, or sends a single batch request when the
+// agent supports FeatureBatch. Results are returned in input order and a
+// failed item never aborts the others.
+func (c *Client) BulkInvoke(ctx context.Context, name string, inputs []map[string]any, concurrency int) []BulkResult {
+    if len(inputs) > 1 && c.Supports(ctx, FeatureBatch) {
+        return c.batchInvoke(ctx, name, inputs)
+    }
+    if concurrency <= 0 {
+        concurrency = 4
+    }
+    results := make([]BulkResult, len(inputs))
+    slots := make(chan struct{}, concurrency)
+    var wg sync.WaitGroup
+    for i, in := range inputs {
+        results[i] = BulkResult{Index: i, Function: name, Inputs: in}
+        wg.Add(1)
+        go func(result *BulkResult) {
+            defer wg.Done()
+            select {
+            case slots <- struct{}{}:
+            case <-ctx.Done():
+                result.Err = ctx.Err()
+                result.Error = result.Err.Error()
+                return
+            }
+            defer func() { <-slots }()
+            result.Response, result.Err = c.InvokeFunction(ctx, name, result.Inputs)
+            if result.Err != nil {
+                result.Error = result.Err.Error()
+            }
+        }(&results[i])
+    }
+    wg.Wait()
+    return results
+}
+
+// ChatResult is the outcome of one ChatBatch item. Error mirrors Err for
+// serialised output.
+type ChatResult struct {
+    Index int `json:"index"`
+    Request ChatRequest `json:"request"`
+    Response *ChatResponse `json:"response,omitempty"`
+    Err error `json:"-"`
+    Error string `json:"error,omitempty"`
+}
+
+// BatchOptions configure ChatBatch. Concurrency bounds the calls in
+// flight and defaults to 4. OnResult, when set, is called with each
+// result as it completes, one call at a time.
+type BatchOptions struct {
+    Concurrency int
+    OnResult func(ChatResult)
+}
+
+// ChatBatch sends each request with Chat, from a pool of at most
+// opts.Concurrency workers, and returns the results in request order. A
+// failed item never aborts the others; once ctx is done, items not yet
+// started fail with its error.
+func (c *Client) ChatBatch(ctx context.Context, requests []ChatRequest, opts BatchOptions) []ChatResult {
+    workers := opts.Concurrency
+    if workers <= 0 {
+        workers = 4
+    }
+    workers = min(workers, len(requests))
+    results := make([]ChatResult, len(requests))
+    next := make(chan int)
+    var report sync.Mutex
+    var wg sync.WaitGroup
+    for w := 0; w < workers; w++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for i := range next {
+                result := &results[i]
+                if result.Err = ctx.Err(); result.Err == nil {
+                    result.Response, result.Err = c.Chat(ctx, result.Request)
+                }
+                if result.Err != nil {
+                    result.Error = result.Err.Error()
+                }
+                if opts.OnResult != nil {
+                    report.Lock()
+                    opts.OnResult(*result)
+                    report.Unlock()
+                }
+            }
+        }()
+    }
+    for i, request := range requests {
+        results[i] = ChatResult{Index: i, Request: request}
+        next <- i
+    }
+    close(next)
+    wg.Wait()
+    return results
+}
+
+type batchRequest struct {
+    Inputs []map[string]any `json:"inputs"`
+}
+
+type batchItem struct {
+    ChatResponse
+    Error string `json:"error,omitempty"`
+}
+
+type batchResponse struct {
+    Results []batchItem `json:"results"`
+}
+
+// batchInvoke posts every input set to /functions/{name}/batch in one
+// request, applying the same guard, policy, secrets, filters, and audit
+// per item as InvokeFunction.
+func (c *Client) batchInvoke(ctx context.Context, name string, inputs []map[string]any) []BulkResult {
+    results := make([]BulkResult, len(inputs))
+    fail := func(i int, err error) {
+        results[i].Err, results[i].Error = err, err.Error()
+        c.audit(ctx, name, ChatRequest{Message: name, Inputs: inputs[i]}, nil, err)
+    }
+    var wire batchRequest
+    var sent []int
+    for i, in := range inputs {
+        results[i] = BulkResult{Index: i, Function: name, Inputs: in}
+        request := ChatRequest{Message: name, Inputs: in}
+        if err := c.checkInputs(ctx, name, in, false); err != nil {
+            fail(i, err)
+            continue
+        }
+        if err := c.authorize(ctx, name, request); err != nil {
+            fail(i, err)
+            continue
+        }
+        if err := c.prepare(ctx, &request); err != nil {
+            fail(i, err)
+            continue
+        }
+        wire.Inputs = append(wire.Inputs, request.Inputs)
+        sent = append(sent, i)
+    }
+    if len(sent) == 0 {
+        return results
+    }
+    var payload batchResponse
+    err := c.doJSON(ctx, http.MethodPost, "/functions/"+url.PathEscape(name)+"/batch", wire, &payload)
+    if err == nil && len(payload.Results) != len(sent) {
+        err = fmt.Errorf("batch returned %d results for %d inputs", len(payload.Results), len(sent))
+    }
+    for n, i := range sent {
+        if err != nil {
+            fail(i, err)
+            continue
+        }
+        item := payload.Results[n]
+        if item.Error != "" {
+            fail(i, errors.New(item.Error))
+            continue
+        }
+        resp := item.ChatResponse
+        if ferr := c.filterResponse(ctx, &resp); ferr != nil {
+            fail(i, ferr)
+            continue
+        }
+        results[i].Response = &resp
+        c.audit(ctx, name, ChatRequest{Message: name, Inputs: inputs[i]}, &resp, nil)
+    }
+    return results
+}
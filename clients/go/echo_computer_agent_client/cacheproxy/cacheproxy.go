@@ -0,0 +1,330 @@
+// Package cacheproxy is a caching, rate-limiting reverse proxy for the
+// agent's HTTP API, meant to sit between many small internal clients and
+// one agent.
+//
+// GET /functions and dry-run POST /chat requests (execute absent or false)
+// are cached for their TTLs, unless too large, and identical requests in
+// flight are coalesced into one upstream call. Everything else, including every executing
+// request, is forwarded untouched. Cache keys include the caller's
+// Authorization and actor headers so one caller never sees another's
+// responses, and its API version and Accept headers, on which the shape
+// of a response depends. Cached responses are replayed with the agent's
+// headers, so version negotiation and deprecation notices still reach
+// callers.
+package cacheproxy
+
+import (
+    "bytes"
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "io"
+    "math"
+    "net"
+    "net/http"
+    "net/http/httputil"
+    "net/url"
+    "strconv"
+    "sync"
+    "time"
+
+    client "echo_computer_agent_client"
+)
+
+// Proxy serves the agent API. Rate is the sustained requests per second
+// allowed per caller (keyed by Authorization header, falling back to the
+// remote address) with bursts up to Burst; zero disables rate limiting.
+// Responses larger than MaxResponseBytes are passed through uncached.
+type Proxy struct {
+    CatalogTTL time.Duration
+    ResponseTTL time.Duration
+    MaxEntries int
+    MaxBodyBytes int64
+    MaxResponseBytes int64
+    Rate float64
+    Burst int
+
+    upstream *httputil.ReverseProxy
+    transport http.RoundTripper
+    target *url.URL
+
+    mu sync.Mutex
+    entries map[string]*entry
+    inflight map[string]*call
+    buckets map[string]*bucket
+}
+
+type entry struct {
+    status int
+    header http.Header
+    body []byte
+    expires time.Time
+    // rest is the unread remainder of a body over MaxResponseBytes. Such
+    // entries go only to the request that fetched them.
+    rest io.ReadCloser
+}
+
+type call struct {
+    done chan struct{}
+    result *entry
+}
+
+type bucket struct {
+    tokens float64
+    updated time.Time
+}
+
+func New(target string) (*Proxy, error) {
+    u, err := url.Parse(target)
+    if err != nil {
+        return nil, err
+    }
+    return &Proxy{
+        CatalogTTL: time.Minute,
+        ResponseTTL: 30 * time.Second,
+        MaxEntries: 1024,
+        MaxBodyBytes: 1 << 20,
+        MaxResponseBytes: 8 << 20,
+        upstream: httputil.NewSingleHostReverseProxy(u),
+        transport: http.DefaultTransport,
+        target: u,
+        entries: map[string]*entry{},
+        inflight: map[string]*call{},
+        buckets: map[string]*bucket{},
+    }, nil
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    if wait, ok := p.allow(r); !ok {
+        w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+        writeError(w, http.StatusTooManyRequests, "rate limit exceeded")
+        return
+    }
+    ttl := time.Duration(0)
+    var body []byte
+    switch {
+    case r.Method == http.MethodGet && r.URL.Path == "/functions":
+        ttl = p.CatalogTTL
+    case r.Method == http.MethodPost && r.URL.Path == "/chat":
+        raw, err := io.ReadAll(http.MaxBytesReader(w, r.Body, p.MaxBodyBytes))
+        if err != nil {
+            writeError(w, http.StatusRequestEntityTooLarge, "request body too large")
+            return
+        }
+        body = raw
+        r.Body = io.NopCloser(bytes.NewReader(raw))
+        r.ContentLength = int64(len(raw))
+        var request client.ChatRequest
+        if json.Unmarshal(raw, &request) == nil && (request.Execute == nil || !*request.Execute) {
+            ttl = p.ResponseTTL
+            body = canonicalJSON(raw)
+        }
+    }
+    if ttl <= 0 {
+        p.upstream.ServeHTTP(w, r)
+        return
+    }
+
+    key := cacheKey(r, body)
+    result, source := p.lookup(key, r, ttl)
+    if result == nil {
+        // The request this one joined got a response too large to share.
+        w.Header().Set("X-Cache", source)
+        p.upstream.ServeHTTP(w, r)
+        return
+    }
+    for name, values := range result.header {
+        w.Header()[name] = values
+    }
+    w.Header().Set("X-Cache", source)
+    w.WriteHeader(result.status)
+    w.Write(result.body)
+    if result.rest != nil {
+        io.Copy(w, result.rest)
+        result.rest.Close()
+    }
+}
+
+// lookup serves key from the cache, joins an identical in-flight request,
+// or performs the upstream call itself.
+func (p *Proxy) lookup(key string, r *http.Request, ttl time.Duration) (*entry, string) {
+    p.mu.Lock()
+    if cached, ok := p.entries[key]; ok && time.Now().Before(cached.expires) {
+        p.mu.Unlock()
+        return cached, "HIT"
+    }
+    if pending, ok := p.inflight[key]; ok {
+        p.mu.Unlock()
+        <-pending.done
+        if pending.result == nil {
+            return nil, "BYPASS"
+        }
+        return pending.result, "COALESCED"
+    }
+    pending := &call{done: make(chan struct{})}
+    p.inflight[key] = pending
+    p.mu.Unlock()
+
+    result := p.fetch(r)
+    source := "BYPASS"
+    if result.rest == nil {
+        result.expires = time.Now().Add(ttl)
+        pending.result, source = result, "MISS"
+    }
+
+    p.mu.Lock()
+    delete(p.inflight, key)
+    if pending.result != nil && pending.result.status == http.StatusOK {
+        p.store(key, pending.result)
+    }
+    p.mu.Unlock()
+    close(pending.done)
+    return result, source
+}
+
+// fetch runs the upstream request detached from the caller's cancellation,
+// since coalesced followers depend on its result. At most MaxResponseBytes
+// of the body are buffered; a longer body is left open in rest.
+func (p *Proxy) fetch(r *http.Request) *entry {
+    out := r.Clone(context.WithoutCancel(r.Context()))
+    out.URL.Scheme, out.URL.Host = p.target.Scheme, p.target.Host
+    out.URL.Path = singleJoin(p.target.Path, r.URL.Path)
+    out.Host = p.target.Host
+    out.RequestURI = ""
+    // Let the transport negotiate and strip compression so cached bodies
+    // are always plain.
+    out.Header.Del("Accept-Encoding")
+    resp, err := p.transport.RoundTrip(out)
+    if err != nil {
+        return &entry{status: http.StatusBadGateway, header: http.Header{"Content-Type": {"application/json"}}, body: []byte(`{"error":"agent unavailable"}`)}
+    }
+    body, err := io.ReadAll(io.LimitReader(resp.Body, p.MaxResponseBytes+1))
+    if err != nil {
+        resp.Body.Close()
+        return &entry{status: http.StatusBadGateway, header: http.Header{"Content-Type": {"application/json"}}, body: []byte(`{"error":"agent response truncated"}`)}
+    }
+    header := resp.Header.Clone()
+    for _, name := range uncachedHeaders {
+        header.Del(name)
+    }
+    if int64(len(body)) > p.MaxResponseBytes {
+        return &entry{status: resp.StatusCode, header: header, body: body, rest: resp.Body}
+    }
+    resp.Body.Close()
+    return &entry{status: resp.StatusCode, header: header, body: body}
+}
+
+// uncachedHeaders are response headers that describe one connection or
+// one caller, not the response, and so are never replayed.
+var uncachedHeaders = []string{
+    "Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Connection", "Te", "Trailer", "Transfer-Encoding", "Upgrade",
+    "Content-Length", "Content-Encoding", "Date", "Set-Cookie",
+}
+
+// store adds e, first dropping expired entries and, if still full, an
+// arbitrary one.
+func (p *Proxy) store(key string, e *entry) {
+    if p.MaxEntries > 0 && len(p.entries) >= p.MaxEntries {
+        now := time.Now()
+        for k, cached := range p.entries {
+            if now.After(cached.expires) {
+                delete(p.entries, k)
+            }
+        }
+        for k := range p.entries {
+            if len(p.entries) < p.MaxEntries {
+                break
+            }
+            delete(p.entries, k)
+        }
+    }
+    p.entries[key] = e
+}
+
+// Purge empties the response cache, e.g. after the catalog changes.
+func (p *Proxy) Purge() {
+    p.mu.Lock()
+    p.entries = map[string]*entry{}
+    p.mu.Unlock()
+}
+
+func (p *Proxy) allow(r *http.Request) (time.Duration, bool) {
+    if p.Rate <= 0 {
+        return 0, true
+    }
+    burst := float64(p.Burst)
+    if burst < 1 {
+        burst = 1
+    }
+    key := r.Header.Get("Authorization")
+    if key == "" {
+        key = r.RemoteAddr
+        if host, _, err := net.SplitHostPort(key); err == nil {
+            key = host
+        }
+    }
+    now := time.Now()
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    b, ok := p.buckets[key]
+    if !ok {
+        if len(p.buckets) > 10000 {
+            p.buckets = map[string]*bucket{}
+        }
+        b = &bucket{tokens: burst, updated: now}
+        p.buckets[key] = b
+    }
+    b.tokens = math.Min(burst, b.tokens+now.Sub(b.updated).Seconds()*p.Rate)
+    b.updated = now
+    if b.tokens < 1 {
+        return time.Duration((1 - b.tokens) / p.Rate * float64(time.Second)), false
+    }
+    b.tokens--
+    return 0, true
+}
+
+func cacheKey(r *http.Request, body []byte) string {
+    h := sha256.New()
+    for _, part := range []string{r.Method, r.URL.Path, r.URL.RawQuery, r.Header.Get("Authorization"), r.Header.Get("Accept"), r.Header.Get("Accept-Language"), r.Header.Get(client.HeaderAPIVersion), r.Header.Get(client.HeaderActorID), r.Header.Get(client.HeaderActorRoles), r.Header.Get(client.HeaderTenant)} {
+        h.Write([]byte(part))
+        h.Write([]byte{0})
+    }
+    h.Write(body)
+    return hex.EncodeToString(h.Sum(nil))
+}
+
+// canonicalJSON re-encodes raw with sorted keys and no insignificant
+// whitespace, keeping every field, so equal requests share a key and
+// different ones never do. Numbers are kept as written.
+func canonicalJSON(raw []byte) []byte {
+    decoder := json.NewDecoder(bytes.NewReader(raw))
+    decoder.UseNumber()
+    var value any
+    if decoder.Decode(&value) != nil {
+        return raw
+    }
+    canonical, err := json.Marshal(value)
+    if err != nil {
+        return raw
+    }
+    return canonical
+}
+
+func singleJoin(a, b string) string {
+    switch {
+    case a == "" || a == "/":
+        return b
+    case a[len(a)-1] == '/' && len(b) > 0 && b[0] == '/':
+        return a + b[1:]
+    case a[len(a)-1] != '/' && (len(b) == 0 || b[0] != '/'):
+        return a + "/" + b
+    }
+    return a + b
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,154 @@
+// Package callbacks provides an embeddable server that receives async job
+// completion callbacks from the agent and wakes the matching waiters.
+package callbacks
+
+import (
+    "context"
+    "errors"
+    "net"
+    "net/http"
+    "sync"
+    "time"
+
+    client "echo_computer_agent_client"
+    "echo_computer_agent_client/webhooks"
+)
+
+// Server registers itself as the callback target of the jobs it submits.
+// WaitForJob returns as soon as a verified job.completed callback arrives
+// and otherwise falls back to polling every PollInterval, so a lost or
+// blocked callback only costs latency.
+type Server struct {
+    Client *client.Client
+    CallbackURL string
+    PollInterval time.Duration
+
+    webhook *webhooks.Handler
+    mu sync.Mutex
+    waiters map[string][]chan struct{}
+    httpServer *http.Server
+}
+
+// NewServer creates a server whose callbacks are reachable by the agent at
+// callbackURL and signed with secret. Mount Handler yourself or call
+// ListenAndServe.
+func NewServer(c *client.Client, callbackURL, secret string) *Server {
+    s := &Server{
+        Client: c,
+        CallbackURL: callbackURL,
+        PollInterval: 15 * time.Second,
+        waiters: map[string][]chan struct{}{},
+    }
+    s.webhook = webhooks.NewHandler(secret, s.onEvent)
+    return s
+}
+
+func (s *Server) Handler() http.Handler {
+    return s.webhook
+}
+
+func (s *Server) ListenAndServe(addr string) error {
+    listener, err := net.Listen("tcp", addr)
+    if err != nil {
+        return err
+    }
+    return s.Serve(listener)
+}
+
+func (s *Server) Serve(listener net.Listener) error {
+    s.mu.Lock()
+    s.httpServer = &http.Server{Handler: s.webhook, ReadHeaderTimeout: 10 * time.Second}
+    srv := s.httpServer
+    s.mu.Unlock()
+    err := srv.Serve(listener)
+    if errors.Is(err, http.ErrServerClosed) {
+        return nil
+    }
+    return err
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+    s.mu.Lock()
+    srv := s.httpServer
+    s.mu.Unlock()
+    if srv == nil {
+        return nil
+    }
+    return srv.Shutdown(ctx)
+}
+
+// Submit queues request with the server as its callback target. Agents
+// that run jobs synchronously get no callback; the job has finished by the
+// time it returns.
+func (s *Server) Submit(ctx context.Context, request client.ChatRequest) (*client.Job, error) {
+    job, err := s.Client.SubmitJob(ctx, request, s.CallbackURL)
+    if errors.Is(err, client.ErrCallbackUnsupported) {
+        return s.Client.SubmitJob(ctx, request, "")
+    }
+    return job, err
+}
+
+func (s *Server) WaitForJob(ctx context.Context, id string) (*client.Job, error) {
+    wake := make(chan struct{}, 1)
+    s.mu.Lock()
+    s.waiters[id] = append(s.waiters[id], wake)
+    s.mu.Unlock()
+    defer s.forget(id, wake)
+
+    interval := s.PollInterval
+    if interval <= 0 {
+        interval = 15 * time.Second
+    }
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    for {
+        job, err := s.Client.GetJob(ctx, id)
+        if err != nil {
+            return nil, err
+        }
+        if job.Done() {
+            return job, nil
+        }
+        select {
+        case <-ctx.Done():
+            return nil, ctx.Err()
+        case <-wake:
+        case <-ticker.C:
+        }
+    }
+}
+
+func (s *Server) onEvent(event webhooks.Event) error {
+    completed, ok := event.Payload.(*webhooks.JobCompleted)
+    if !ok {
+        return nil
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    // A callback that beats WaitForJob needs no bookkeeping: waiters always
+    // check the job once before blocking.
+    for _, wake := range s.waiters[completed.JobID] {
+        select {
+        case wake <- struct{}{}:
+        default:
+        }
+    }
+    return nil
+}
+
+func (s *Server) forget(id string, wake chan struct{}) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    waiters := s.waiters[id]
+    for i, w := range waiters {
+        if w == wake {
+            waiters = append(waiters[:i], waiters[i+1:]...)
+            break
+        }
+    }
+    if len(waiters) == 0 {
+        delete(s.waiters, id)
+    } else {
+        s.waiters[id] = waiters
+    }
+}
@@ -0,0 +1,119 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "net/http"
+    "sync"
+    "time"
+)
+
+// Features the agent may advertise on /capabilities.
+const (
+    FeatureStreaming = "streaming"
+    FeatureBatch = "batch"
+    FeatureAsyncJobs = "async_jobs"
+    FeatureMsgpack = "msgpack"
+    FeatureEmbeddings = "embeddings"
+)
+
+// Capabilities is the agent's self-description. Known is false when the
+// agent has no /capabilities endpoint, in which case nothing is assumed
+// either way.
+type Capabilities struct {
+    Version string `json:"version,omitempty"`
+    Features []string `json:"features"`
+    Known bool `json:"-"`
+}
+
+func (c *Capabilities) Has(feature string) bool {
+    for _, f := range c.Features {
+        if f == feature {
+            return true
+        }
+    }
+    return false
+}
+
+// lacks reports whether the agent is known not to offer feature.
+func (c *Capabilities) lacks(feature string) bool {
+    return c.Known && !c.Has(feature)
+}
+
+const capabilitiesTTL = 5 * time.Minute
+
+type capabilityCache struct {
+    sync.Mutex
+    value *Capabilities
+    fetched time.Time
+    pending *capabilityFetch
+}
+
+// capabilityFetch is a fetch in progress; done is closed when it finishes.
+type capabilityFetch struct {
+    done chan struct{}
+    value *Capabilities
+    err error
+    // cancelled is set when the fetch failed because its caller's context
+    // ended, so waiters fetch again rather than share that error.
+    cancelled bool
+}
+
+// Capabilities fetches /capabilities, caching the answer for five minutes.
+// Agents without the endpoint yield an empty, unknown set rather than an
+// error. Concurrent callers share a single fetch.
+func (c *Client) Capabilities(ctx context.Context) (*Capabilities, error) {
+    cache := &c.capabilities
+    for {
+        cache.Lock()
+        if cache.value != nil && time.Since(cache.fetched) < capabilitiesTTL {
+            value := cache.value
+            cache.Unlock()
+            return value, nil
+        }
+        if fetch := cache.pending; fetch != nil {
+            cache.Unlock()
+            select {
+            case <-ctx.Done():
+                return nil, ctx.Err()
+            case <-fetch.done:
+            }
+            if fetch.cancelled {
+                continue
+            }
+            return fetch.value, fetch.err
+        }
+        fetch := &capabilityFetch{done: make(chan struct{})}
+        cache.pending = fetch
+        cache.Unlock()
+
+        fetch.value, fetch.err = c.fetchCapabilities(ctx)
+        fetch.cancelled = fetch.err != nil && ctx.Err() != nil
+        cache.Lock()
+        if fetch.err == nil {
+            cache.value, cache.fetched = fetch.value, time.Now()
+        }
+        cache.pending = nil
+        cache.Unlock()
+        close(fetch.done)
+        return fetch.value, fetch.err
+    }
+}
+
+func (c *Client) fetchCapabilities(ctx context.Context) (*Capabilities, error) {
+    var caps Capabilities
+    if err := c.doJSON(ctx, http.MethodGet, "/capabilities", nil, &caps); err != nil {
+        if !IsNotFound(err) {
+            return nil, err
+        }
+        return &Capabilities{}, nil
+    }
+    caps.Known = true
+    return &caps, nil
+}
+
+// Supports reports whether the agent advertises feature. It is false when
+// the agent cannot be asked.
+func (c *Client) Supports(ctx context.Context, feature string) bool {
+    caps, err := c.Capabilities(ctx)
+    return err == nil && caps.Has(feature)
+}
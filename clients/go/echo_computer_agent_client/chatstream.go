@@ -0,0 +1,217 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "strings"
+    "time"
+)
+
+// ChatChunk is one event of a streamed reply. Text is the fragment a
+// TextDelta adds and empty for other events; Event is the typed event.
+type ChatChunk struct {
+    Index int
+    Text string
+    Event StreamEvent
+}
+
+// chatStreamReconnects bounds how often ChatStream resumes a dropped
+// stream before giving up.
+const chatStreamReconnects = 5
+
+// ErrNotResumable is returned by ResumeStream for a stream the agent gave
+// no event IDs to resume from.
+var ErrNotResumable = errors.New("stream cannot be resumed")
+
+// StreamInterruptedError reports a stream that died mid-response once its
+// reconnects were used up. Partial is the reply assembled so far; pass
+// Token to ResumeStream to continue the turn from the last event received.
+// Token is empty when the agent sent no event IDs.
+type StreamInterruptedError struct {
+    Partial *ChatResponse
+    Token string
+    Err error
+}
+
+func (e *StreamInterruptedError) Error() string {
+    return "chat stream interrupted: " + e.Err.Error()
+}
+
+func (e *StreamInterruptedError) Unwrap() error { return e.Err }
+
+// streamState is a streamed reply as far as it got, and what a resume
+// token carries. Request is kept as the caller sent it, so secrets are
+// resolved again on resume rather than stored in the token.
+type streamState struct {
+    Request ChatRequest `json:"request"`
+    Function string `json:"function,omitempty"`
+    LastEventID string `json:"last_event_id"`
+    Index int `json:"index"`
+    Text string `json:"text,omitempty"`
+    Selected string `json:"selected,omitempty"`
+    Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+func (s *streamState) token() string {
+    if s.LastEventID == "" {
+        return ""
+    }
+    encoded, err := json.Marshal(s)
+    if err != nil {
+        return ""
+    }
+    return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// ChatStream sends request to /chat/stream and calls handle with each
+// chunk as it arrives, returning the assembled response once the agent
+// sends its done event. Dropped connections are resumed from the last
+// event received; cancel ctx to stop mid-stream. A handler error stops the
+// stream and is returned as is. Progress events also go to the channel set
+// by WithProgress. A stream that cannot be recovered fails with a
+// *StreamInterruptedError carrying the partial reply.
+//
+// Executing requests are checked and audited like Chat. Response filters
+// run on the assembled response, after its chunks were delivered, so a
+// blocked reply fails the call but cannot be unsent. Agents known not to
+// stream get a plain Chat, delivered as one text chunk.
+func (c *Client) ChatStream(ctx context.Context, request ChatRequest, handle func(ChatChunk) error) (*ChatResponse, error) {
+    request = c.withDefaultExecute(request)
+    if caps, err := c.Capabilities(ctx); err == nil && caps.lacks(FeatureStreaming) {
+        resp, err := c.Chat(ctx, request)
+        if err != nil {
+            return nil, err
+        }
+        if err := handle(ChatChunk{Text: resp.Message, Event: &TextDelta{Text: resp.Message}}); err != nil {
+            return resp, err
+        }
+        return resp, handle(ChatChunk{Index: 1, Event: &Done{Response: resp}})
+    }
+
+    state := &streamState{Request: request}
+    if executes(request) {
+        var err error
+        if state.Function, err = c.preflight(ctx, request); err != nil {
+            return nil, err
+        }
+    }
+    return c.runChatStream(ctx, state, handle)
+}
+
+// ResumeStream continues the turn an interrupted ChatStream's token names,
+// calling handle with the chunks after those already delivered, and
+// returns the reply assembled across both streams. Preflight passed when
+// the stream began, so the request is not authorized again.
+func (c *Client) ResumeStream(ctx context.Context, token string, handle func(ChatChunk) error) (*ChatResponse, error) {
+    if token == "" {
+        return nil, ErrNotResumable
+    }
+    raw, err := base64.RawURLEncoding.DecodeString(token)
+    if err != nil {
+        return nil, fmt.Errorf("resume token: %w", err)
+    }
+    var state streamState
+    if err := json.Unmarshal(raw, &state); err != nil {
+        return nil, fmt.Errorf("resume token: %w", err)
+    }
+    if state.LastEventID == "" {
+        return nil, ErrNotResumable
+    }
+    return c.runChatStream(ctx, &state, handle)
+}
+
+func (c *Client) runChatStream(ctx context.Context, state *streamState, handle func(ChatChunk) error) (*ChatResponse, error) {
+    request := state.Request
+    wire := request
+    if err := c.prepare(ctx, &wire); err != nil {
+        return nil, err
+    }
+    start := time.Now()
+    resp, err := c.streamChat(ctx, wire, state, handle)
+    if err == nil {
+        err = c.filterResponse(ctx, resp)
+    }
+    // An interrupted turn is audited once it is resumed to its end.
+    var interrupted *StreamInterruptedError
+    if executes(request) && !errors.As(err, &interrupted) {
+        if err == nil {
+            c.observeLatency(resp.Function, time.Since(start))
+        }
+        c.audit(ctx, state.Function, request, resp, err)
+    }
+    if err != nil {
+        return resp, err
+    }
+    c.checkDeprecatedFunction(resp.Function)
+    return resp, nil
+}
+
+func (c *Client) streamChat(ctx context.Context, request ChatRequest, state *streamState, handle func(ChatChunk) error) (*ChatResponse, error) {
+    var text strings.Builder
+    text.WriteString(state.Text)
+    var final *ChatResponse
+    var stopped error
+    opts := StreamOptions{MaxReconnects: chatStreamReconnects, LastEventID: state.LastEventID}
+    err := c.StreamEvents(ctx, http.MethodPost, "/chat/stream", request, opts, func(frame ServerSentEvent) error {
+        if frame.ID != "" {
+            state.LastEventID = frame.ID
+        }
+        event, err := ParseStreamEvent(frame)
+        if err != nil || event == nil {
+            stopped = err
+            return err
+        }
+        chunk := ChatChunk{Index: state.Index, Event: event}
+        state.Index++
+        switch e := event.(type) {
+        case *TextDelta:
+            chunk.Text = e.Text
+            text.WriteString(e.Text)
+        case *FunctionSelected:
+            state.Selected = e.Function
+        case *UsageReport:
+            state.Metadata = map[string]any{"usage": map[string]any{
+                "prompt_tokens": e.PromptTokens,
+                "completion_tokens": e.CompletionTokens,
+                "total_tokens": e.TotalTokens,
+            }}
+        case *Progress:
+            reportProgress(ctx, *e)
+        case *ErrorEvent:
+            stopped = e
+            return e
+        case *Done:
+            final = e.Response
+        }
+        if err := handle(chunk); err != nil {
+            stopped = err
+            return err
+        }
+        if _, done := event.(*Done); done {
+            return ErrStreamDone
+        }
+        return nil
+    })
+    state.Text = text.String()
+    if final == nil {
+        final = &ChatResponse{Function: state.Selected, Message: state.Text, Metadata: state.Metadata}
+    }
+    if final.Function == "" {
+        final.Function = state.Selected
+    }
+    if final.Message == "" {
+        final.Message = state.Text
+    }
+    // Only a stream that died mid-response is interrupted: not one the
+    // handler or the agent ended, one ctx cancelled, or one refused.
+    var apiErr *APIError
+    refused := errors.As(err, &apiErr) && apiErr.Status < 500 && apiErr.Status != http.StatusTooManyRequests
+    if err != nil && stopped == nil && ctx.Err() == nil && !refused && state.Index > 0 {
+        return final, &StreamInterruptedError{Partial: final, Token: state.token(), Err: err}
+    }
+    return final, err
+}
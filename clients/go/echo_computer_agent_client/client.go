@@ -3,10 +3,13 @@ package echo_computer_agent_client
 import (
     "bytes"
     "context"
-    "encoding/json"
-    "fmt"
+    "errors"
+    "io"
+    "log/slog"
     "net/http"
     "strings"
+    "sync"
+    "time"
 )
 
 type ChatRequest struct {
@@ -35,8 +38,55 @@ type FunctionListResponse struct {
 
 type Client struct {
     baseURL string
-    httpClient *http.Client
+    transport Transport
+    interceptors []Interceptor
     defaultHeaders map[string]string
+    guard *FunctionGuard
+    policy *Policy
+    confirm Confirmer
+    auditSink AuditSink
+    secrets map[string]SecretResolver
+    requestFilters []RequestFilter
+    responseFilters []ResponseFilter
+    responseProcessors []ResponseProcessor
+    apiVersion string
+    versionMu sync.Mutex
+    negotiatedVersion string
+    deprecations deprecationState
+    capabilities capabilityCache
+    localJobs localJobStore
+    memory memoryState
+    catalog catalogCache
+    language string
+    defaultExecute *bool
+    responseCache ResponseCache
+    responseTTL time.Duration
+    templates *TemplateRegistry
+    throttle throttleState
+    retry RetryPolicy
+    experiments []*Experiment
+    timeout time.Duration
+    baseCtx context.Context
+    debug debugState
+    metrics Metrics
+    logger *slog.Logger
+    redactor *Redactor
+    resolvedSecrets loggedSecrets
+    auth authState
+    signing signingState
+    rateLimit rateLimitState
+    inlineLimit *int64
+    breaker breakerState
+    validateInputs bool
+    clock clockState
+    embeddings embeddingCache
+    listing listingCache
+    usage usageState
+    conversationsOnce sync.Once
+    conversations *ConversationTree
+    budget *Budget
+    latency latencyStats
+    drain drainState
 }
 
 func NewClient(baseURL string, httpClient *http.Client) *Client {
@@ -46,7 +96,7 @@ func NewClient(baseURL string, httpClient *http.Client) *Client {
     }
     return &Client{
         baseURL: trimmed,
-        httpClient: httpClient,
+        transport: httpClient,
         defaultHeaders: map[string]string{},
     }
 }
@@ -55,53 +105,201 @@ func (c *Client) SetDefaultHeader(key, value string) {
     c.defaultHeaders[key] = value
 }
 
+// ListFunctions lists the agent's catalog. The last listing is kept with
+// its ETag and Last-Modified, and sent back as If-None-Match and
+// If-Modified-Since, so an unchanged catalog costs a 304 and is answered
+// from the copy.
 func (c *Client) ListFunctions(ctx context.Context) (*FunctionListResponse, error) {
-    req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/functions", nil)
+    c.listing.Lock()
+    since, held := c.listing.validators, c.listing.payload
+    c.listing.Unlock()
+    if held == nil {
+        since = validators{}
+    }
+    payload, received, unchanged, err := c.listFunctionsSince(ctx, since)
     if err != nil {
         return nil, err
     }
-    for k, v := range c.defaultHeaders {
-        req.Header.Set(k, v)
+    if unchanged {
+        return &FunctionListResponse{Functions: append([]FunctionDescription(nil), held.Functions...)}, nil
+    }
+    c.listing.Lock()
+    if received.empty() {
+        c.listing.payload, c.listing.validators = nil, validators{}
+    } else {
+        c.listing.payload = &FunctionListResponse{Functions: append([]FunctionDescription(nil), payload.Functions...)}
+        c.listing.validators = received
     }
-    resp, err := c.httpClient.Do(req)
+    c.listing.Unlock()
+    return payload, nil
+}
+
+func (c *Client) Chat(ctx context.Context, request ChatRequest) (*ChatResponse, error) {
+    request = c.withDefaultExecute(request)
+    if len(c.experiments) > 0 {
+        return c.experimentChat(ctx, request)
+    }
+    return c.chat(ctx, request)
+}
+
+func (c *Client) chat(ctx context.Context, request ChatRequest) (*ChatResponse, error) {
+    if !executes(request) {
+        return c.dryRun(ctx, request)
+    }
+    function, err := c.preflight(ctx, request)
     if err != nil {
         return nil, err
     }
-    defer resp.Body.Close()
-    if resp.StatusCode >= 400 {
-        return nil, fmt.Errorf("request failed with status %d", resp.StatusCode)
+    start := time.Now()
+    callCtx := ctx
+    if c.idempotentFunction(ctx, function) {
+        callCtx = withIdempotent(ctx)
     }
-    var payload FunctionListResponse
-    if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
-        return nil, err
+    resp, err := c.postChat(callCtx, request)
+    if err == nil {
+        c.observeLatency(resp.Function, time.Since(start))
     }
-    return &payload, nil
+    c.audit(ctx, function, request, resp, err)
+    return resp, err
 }
 
-func (c *Client) Chat(ctx context.Context, request ChatRequest) (*ChatResponse, error) {
-    body, err := json.Marshal(request)
+// Plan sends request as a dry run so the agent reports which function it
+// would route to without executing it.
+func (c *Client) Plan(ctx context.Context, request ChatRequest) (*ChatResponse, error) {
+    return c.dryRun(ctx, request.DryRun())
+}
+
+func (c *Client) dryRun(ctx context.Context, request ChatRequest) (*ChatResponse, error) {
+    return c.cachedCall(ctx, dryRunFunction, responseKey(ctx, request.Message, request.Inputs), func() (*ChatResponse, error) {
+        return c.postChat(withIdempotent(ctx), request)
+    })
+}
+
+// preflight resolves the function an executing request would run and checks
+// it against the guard and policy, auditing refusals.
+func (c *Client) preflight(ctx context.Context, request ChatRequest) (string, error) {
+    if c.guard == nil && c.policy == nil && c.budget == nil && !(c.validateInputs && len(request.Inputs) > 0) {
+        return "", nil
+    }
+    // Routing is resolved fresh rather than from the response cache.
+    plan, err := c.postChat(withIdempotent(ctx), request.DryRun())
     if err != nil {
-        return nil, err
+        return "", err
     }
-    req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat", bytes.NewReader(body))
+    if err := c.checkInputs(ctx, plan.Function, request.Inputs, true); err != nil {
+        c.audit(ctx, plan.Function, request, nil, err)
+        return "", err
+    }
+    if err := c.authorize(ctx, plan.Function, request); err != nil {
+        c.audit(ctx, plan.Function, request, nil, err)
+        return "", err
+    }
+    return plan.Function, nil
+}
+
+// prepare applies request filters and resolves secrets on the wire copy.
+func (c *Client) prepare(ctx context.Context, request *ChatRequest) error {
+    if err := c.filterRequest(ctx, request); err != nil {
+        return err
+    }
+    inputs, err := c.resolveSecrets(ctx, request.Inputs)
     if err != nil {
+        return err
+    }
+    request.Inputs = inputs
+    return nil
+}
+
+func (c *Client) postChat(ctx context.Context, request ChatRequest) (*ChatResponse, error) {
+    if err := c.prepare(ctx, &request); err != nil {
         return nil, err
     }
-    req.Header.Set("Content-Type", "application/json")
-    for k, v := range c.defaultHeaders {
-        req.Header.Set(k, v)
+    var payload ChatResponse
+    if err := c.doJSON(ctx, http.MethodPost, "/chat", request, &payload); err != nil {
+        return nil, err
     }
-    resp, err := c.httpClient.Do(req)
-    if err != nil {
+    if err := c.filterResponse(ctx, &payload); err != nil {
         return nil, err
     }
+    c.checkDeprecatedFunction(payload.Function)
+    return &payload, nil
+}
+
+// doJSON sends in as JSON and decodes the reply into out. A 429 parks the
+// call until the agent's Retry-After horizon and then sends it again; see
+// SetThrottleWait. Other transient failures of idempotent calls are
+// retried under the client's RetryPolicy.
+func (c *Client) doJSON(ctx context.Context, method, path string, in, out any) error {
+    var encoded []byte
+    if in != nil {
+        var err error
+        if encoded, err = c.encodeVersioned(path, in); err != nil {
+            return err
+        }
+    }
+    retryable := c.retryable(ctx, method)
+    var waited time.Duration
+    for attempt := 1; ; {
+        parked, err := c.awaitThrottle(ctx, waited)
+        if err != nil {
+            return err
+        }
+        waited += parked
+        err = c.sendJSON(ctx, method, path, encoded, out)
+        var apiErr *APIError
+        if errors.As(err, &apiErr) && apiErr.Status == http.StatusTooManyRequests && c.throttleWait() > 0 {
+            c.logThrottled(ctx, method, path)
+            continue
+        }
+        if err == nil || !retryable || attempt >= c.retry.MaxAttempts || !transient(err) {
+            return err
+        }
+        c.logRetry(ctx, method, path, attempt, err)
+        if err := c.backoff(ctx, attempt, err); err != nil {
+            return err
+        }
+        attempt++
+    }
+}
+
+func (c *Client) sendJSON(ctx context.Context, method, path string, encoded []byte, out any) error {
+    ctx, cancel := c.callContext(ctx, c.timeout)
+    defer cancel()
+    var body io.Reader
+    if encoded != nil {
+        body = bytes.NewReader(encoded)
+    }
+    req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+    if err != nil {
+        return err
+    }
+    if encoded != nil {
+        req.Header.Set("Content-Type", "application/json")
+    }
+    c.decorate(ctx, req)
+    cond := conditionalFrom(ctx)
+    if cond != nil {
+        cond.decorate(req)
+    }
+    resp, err := c.do(req)
+    if err != nil {
+        return err
+    }
     defer resp.Body.Close()
+    version := c.observeVersion(resp)
+    c.observeDeprecation(path, resp.Header)
+    if cond != nil && cond.observe(resp) {
+        return nil
+    }
+    if resp.StatusCode == http.StatusTooManyRequests {
+        c.noteThrottled(resp.Header)
+    }
     if resp.StatusCode >= 400 {
-        return nil, fmt.Errorf("request failed with status %d", resp.StatusCode)
+        return c.apiError(req, resp)
     }
-    var payload ChatResponse
-    if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
-        return nil, err
+    if out == nil {
+        return nil
     }
-    return &payload, nil
+    return decodeVersioned(resp.Body, version, path, out)
 }
+
@@ -0,0 +1,121 @@
+package echo_computer_agent_client
+
+import (
+    "errors"
+    "fmt"
+    "log/slog"
+    "net/http"
+    "sync"
+    "time"
+)
+
+// ErrClockSkew is matched by a *ClockSkewError.
+var ErrClockSkew = errors.New("local clock is too far from the agent's")
+
+const (
+    // DefaultClockSkewThreshold is how far the clocks may drift before
+    // the client warns. Date headers have a resolution of a second.
+    DefaultClockSkewThreshold = 30 * time.Second
+
+    // clockSampleTTL is how long a skew measurement stands; a strict
+    // client lets requests through again once it has aged out, so they
+    // can measure afresh.
+    clockSampleTTL = 5 * time.Minute
+)
+
+// ClockSkewPolicy decides what the client does when the agent's Date
+// headers show the local clock has drifted more than Threshold from the
+// agent's. It always logs a warning, when a logger is set, and calls
+// OnSkew as the skew first crosses the threshold. Strict also fails
+// requests with a *ClockSkewError, without sending them, while the last
+// measurement is over the threshold, as signed requests and idempotency
+// windows would otherwise break without saying why.
+type ClockSkewPolicy struct {
+    Threshold time.Duration
+    Strict bool
+    OnSkew func(skew time.Duration)
+}
+
+// ClockSkewError reports a request a strict client refused to send.
+// Skew is how far the agent's clock is ahead of the local one.
+type ClockSkewError struct {
+    Skew time.Duration
+    Threshold time.Duration
+}
+
+func (e *ClockSkewError) Error() string {
+    return fmt.Sprintf("%v: agent clock is %s off, threshold %s", ErrClockSkew, e.Skew.Round(time.Second), e.Threshold)
+}
+
+func (e *ClockSkewError) Unwrap() error { return ErrClockSkew }
+
+type clockState struct {
+    sync.Mutex
+    policy ClockSkewPolicy
+    skew time.Duration
+    measured time.Time
+    over bool
+}
+
+func (c *Client) SetClockSkewPolicy(policy ClockSkewPolicy) {
+    c.clock.Lock()
+    c.clock.policy = policy
+    c.clock.Unlock()
+}
+
+func WithClockSkewPolicy(policy ClockSkewPolicy) Option {
+    return func(c *Client) { c.SetClockSkewPolicy(policy) }
+}
+
+// ClockSkew returns how far the agent's clock was ahead of the local one
+// at the last response with a Date header, negative when it is behind,
+// and when that was measured; measured is zero before any response.
+func (c *Client) ClockSkew() (skew time.Duration, measured time.Time) {
+    c.clock.Lock()
+    defer c.clock.Unlock()
+    return c.clock.skew, c.clock.measured
+}
+
+func (p ClockSkewPolicy) threshold() time.Duration {
+    if p.Threshold <= 0 {
+        return DefaultClockSkewThreshold
+    }
+    return p.Threshold
+}
+
+// checkClock fails a request from a strict client whose last measurement
+// is over the threshold.
+func (c *Client) checkClock() error {
+    c.clock.Lock()
+    defer c.clock.Unlock()
+    if !c.clock.policy.Strict || !c.clock.over || time.Since(c.clock.measured) > clockSampleTTL {
+        return nil
+    }
+    return &ClockSkewError{Skew: c.clock.skew, Threshold: c.clock.policy.threshold()}
+}
+
+// observeClock measures skew from resp's Date header, taking the agent
+// to have stamped it halfway between sending the request and receiving
+// the response.
+func (c *Client) observeClock(req *http.Request, resp *http.Response, sent, received time.Time) {
+    agent, err := http.ParseTime(resp.Header.Get("Date"))
+    if err != nil {
+        return
+    }
+    skew := agent.Sub(sent.Add(received.Sub(sent) / 2))
+    c.clock.Lock()
+    policy := c.clock.policy
+    over := skew > policy.threshold() || skew < -policy.threshold()
+    crossed := over && !c.clock.over
+    c.clock.skew, c.clock.measured, c.clock.over = skew, received, over
+    c.clock.Unlock()
+    if !crossed {
+        return
+    }
+    if c.logEnabled(req.Context(), slog.LevelWarn) {
+        c.logger.LogAttrs(req.Context(), slog.LevelWarn, "local clock differs from the agent's", slog.Duration("skew", skew), slog.Duration("threshold", policy.threshold()))
+    }
+    if policy.OnSkew != nil {
+        policy.OnSkew(skew)
+    }
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+    "bytes"
+    "context"
+    "flag"
+    "log"
+    "net/http"
+    "os"
+    "sort"
+    "sync"
+    "time"
+
+    client "echo_computer_agent_client"
+    "echo_computer_agent_client/daemon"
+    "echo_computer_agent_client/internal/promtext"
+    "echo_computer_agent_client/prommetrics"
+    "echo_computer_agent_client/systemd"
+)
+
+type exporter struct {
+    client *client.Client
+    timeout time.Duration
+
+    mu sync.Mutex
+    page []byte
+    scrapeErrors map[string]float64
+}
+
+func main() {
+    baseURL := flag.String("base-url", "http://127.0.0.1:8000", "Echo Computer Agent base URL")
+    listen := flag.String("listen", ":9465", "Address to serve /metrics on")
+    interval := flag.Duration("interval", 30*time.Second, "How often to poll the agent")
+    timeout := flag.Duration("timeout", 10*time.Second, "Timeout for each agent request")
+    flag.Parse()
+
+    agent := client.NewClient(*baseURL, nil)
+    clientMetrics := prommetrics.New()
+    agent.SetMetrics(clientMetrics)
+    agent.SetDeprecationHandler(client.WriteDeprecations(os.Stderr))
+    e := &exporter{client: agent, timeout: *timeout, scrapeErrors: map[string]float64{}}
+    prober := agent.NewHealthProber(*interval)
+    prober.Timeout = *timeout
+    e.poll()
+
+    mux := http.NewServeMux()
+    mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+        e.mu.Lock()
+        page := e.page
+        e.mu.Unlock()
+        w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+        w.Write(page)
+        // The exporter's own requests to the agent.
+        clientMetrics.WriteTo(w)
+    })
+    d := daemon.New("agent-exporter")
+    serve := d.Serve(&http.Server{Addr: *listen, Handler: mux})
+    d.Drain(agent)
+    log.Printf("serving metrics for %s on %s", *baseURL, *listen)
+    err := d.Run(context.Background(), func(ctx context.Context) error {
+        go prober.Run(ctx)
+        go systemd.Supervise(ctx, prober)
+        go func() {
+            ticker := time.NewTicker(*interval)
+            defer ticker.Stop()
+            for {
+                select {
+                case <-ctx.Done():
+                    return
+                case <-ticker.C:
+                    e.poll()
+                }
+            }
+        }()
+        return serve(ctx)
+    })
+    if err != nil {
+        log.Fatal(err)
+    }
+}
+
+// poll scrapes the agent once and renders the complete metrics page, so the
+// /metrics handler never blocks on the agent.
+func (e *exporter) poll() {
+    ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+    defer cancel()
+    var buf bytes.Buffer
+    m := promtext.NewWriter(&buf)
+
+    up := 1.0
+    health, err := e.client.Health(ctx)
+    if err != nil {
+        up = 0
+        e.failed("health", err)
+    } else {
+        healthy := 0.0
+        if health.OK() {
+            healthy = 1
+        }
+        m.Sample("echo_agent_healthy", "gauge", "Whether the agent reports itself healthy.", nil, healthy)
+        m.Sample("echo_agent_health_latency_seconds", "gauge", "Latency of the last health check.", nil, health.Latency.Seconds())
+        if health.Version != "" {
+            m.Sample("echo_agent_info", "gauge", "Agent build information.", map[string]string{"version": health.Version}, 1)
+        }
+        if health.Uptime > 0 {
+            m.Sample("echo_agent_uptime_seconds", "gauge", "Agent uptime reported by /health.", nil, health.Uptime)
+        }
+        for _, check := range sortedKeys(health.Checks) {
+            ok := 0.0
+            if status := health.Checks[check]; status == "ok" || status == "pass" || status == "healthy" {
+                ok = 1
+            }
+            m.Sample("echo_agent_check_ok", "gauge", "Per-dependency health check status.", map[string]string{"check": check}, ok)
+        }
+    }
+    m.Sample("echo_agent_up", "gauge", "Whether the agent health endpoint answered.", nil, up)
+
+    if usage, err := e.client.AgentUsage(ctx); err != nil {
+        e.failed("usage", err)
+    } else {
+        for _, name := range sortedKeys(usage.Counters) {
+            m.Sample("echo_agent_usage_"+promtext.MetricName(name)+"_total", "counter", "Agent-reported usage counter "+name+".", nil, usage.Counters[name])
+        }
+        for _, fn := range sortedKeys(usage.ByFunction) {
+            m.Sample("echo_agent_function_requests_total", "counter", "Agent-reported requests per function.", map[string]string{"function": fn}, usage.ByFunction[fn])
+        }
+    }
+
+    if catalog, err := e.client.ListFunctions(ctx); err != nil {
+        e.failed("functions", err)
+    } else {
+        m.Sample("echo_agent_functions", "gauge", "Number of functions in the agent catalog.", nil, float64(len(catalog.Functions)))
+        for _, fn := range catalog.Functions {
+            m.Sample("echo_agent_function_info", "gauge", "Functions advertised by the agent.", map[string]string{"function": fn.Name}, 1)
+        }
+    }
+
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    for _, endpoint := range sortedKeys(e.scrapeErrors) {
+        m.Sample("echo_agent_exporter_scrape_errors_total", "counter", "Failed agent requests by endpoint.", map[string]string{"endpoint": endpoint}, e.scrapeErrors[endpoint])
+    }
+    m.Sample("echo_agent_exporter_last_scrape_timestamp_seconds", "gauge", "Unix time of the last poll.", nil, float64(time.Now().Unix()))
+    e.page = buf.Bytes()
+}
+
+func (e *exporter) failed(endpoint string, err error) {
+    log.Printf("%s: %v", endpoint, err)
+    e.mu.Lock()
+    e.scrapeErrors[endpoint]++
+    e.mu.Unlock()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+    keys := make([]string, 0, len(m))
+    for k := range m {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+    return keys
+}
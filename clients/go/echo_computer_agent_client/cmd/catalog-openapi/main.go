@@ -0,0 +1,42 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "flag"
+    "log"
+    "os"
+    "time"
+
+    client "echo_computer_agent_client"
+)
+
+func main() {
+    baseURL := flag.String("base-url", "http://127.0.0.1:8000", "Echo Computer Agent base URL")
+    title := flag.String("title", "Echo Computer Agent Functions", "OpenAPI info.title")
+    version := flag.String("version", "1.0.0", "OpenAPI info.version")
+    out := flag.String("out", "", "Write the document to this file instead of stdout")
+    flag.Parse()
+
+    ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+
+    agent := client.NewClient(*baseURL, nil)
+    agent.SetDeprecationHandler(client.WriteDeprecations(os.Stderr))
+    document, err := agent.CatalogOpenAPI(ctx, *title, *version)
+    if err != nil {
+        log.Fatal(err)
+    }
+    encoded, err := json.MarshalIndent(document, "", "  ")
+    if err != nil {
+        log.Fatal(err)
+    }
+    encoded = append(encoded, '\n')
+    if *out == "" {
+        os.Stdout.Write(encoded)
+        return
+    }
+    if err := os.WriteFile(*out, encoded, 0o644); err != nil {
+        log.Fatal(err)
+    }
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "flag"
+    "fmt"
+    "log"
+    "os"
+    "os/signal"
+    "strings"
+    "time"
+
+    client "echo_computer_agent_client"
+    "echo_computer_agent_client/replay"
+    "echo_computer_agent_client/workflow"
+)
+
+const usage = `usage:
+  echo-agent record [flags] script.yaml   record the messages read from stdin
+  echo-agent replay [flags] script.yaml   re-run a recorded script
+  echo-agent run [flags] workflow.yaml    run a workflow
+  echo-agent config effective [flags]     print the client configuration the flags resolve to
+
+Requests are authenticated with the bearer token in $ECHO_AGENT_TOKEN, when
+set. Snapshots are signed and verified with the key in $ECHO_SNAPSHOT_KEY.`
+
+func main() {
+    log.SetFlags(0)
+    if len(os.Args) < 2 {
+        log.Fatal(usage)
+    }
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+    defer stop()
+    switch os.Args[1] {
+    case "record":
+        record(ctx, os.Args[2:])
+    case "replay":
+        replayScript(ctx, os.Args[2:])
+    case "run":
+        runWorkflow(ctx, os.Args[2:])
+    case "config":
+        if len(os.Args) < 3 || os.Args[2] != "effective" {
+            log.Fatal(usage)
+        }
+        effectiveConfig(os.Args[3:])
+    default:
+        log.Fatal(usage)
+    }
+}
+
+// clientFlags are the flags every subcommand builds its client from.
+type clientFlags struct {
+    baseURL *string
+    timeout *time.Duration
+    retries *int
+    apiVersion *string
+}
+
+func addClientFlags(flags *flag.FlagSet) *clientFlags {
+    return &clientFlags{
+        baseURL: flags.String("base-url", "http://127.0.0.1:8000", "Echo Computer Agent base URL"),
+        timeout: flags.Duration("timeout", 0, "Timeout for each request (default none)"),
+        retries: flags.Int("retries", 1, "Attempts for requests that are safe to repeat"),
+        apiVersion: flags.String("api-version", "", "API version to request"),
+    }
+}
+
+func (f *clientFlags) client() *client.Client {
+    opts := []client.Option{client.WithTimeout(*f.timeout)}
+    if *f.retries > 1 {
+        policy := client.DefaultRetryPolicy
+        policy.MaxAttempts = *f.retries
+        opts = append(opts, client.WithRetryPolicy(policy))
+    }
+    c := client.NewClientWithOptions(*f.baseURL, opts...)
+    if *f.apiVersion != "" {
+        c.SetAPIVersion(*f.apiVersion)
+    }
+    if token := os.Getenv("ECHO_AGENT_TOKEN"); token != "" {
+        c.SetBearerToken(token)
+    }
+    return c
+}
+
+// pairs collects repeated key=value flags.
+type pairs map[string]string
+
+func (p pairs) String() string { return "" }
+
+func (p pairs) Set(value string) error {
+    key, val, ok := strings.Cut(value, "=")
+    if !ok || key == "" {
+        return fmt.Errorf("want key=value, got %q", value)
+    }
+    p[key] = val
+    return nil
+}
+
+func record(ctx context.Context, args []string) {
+    flags := flag.NewFlagSet("record", flag.ExitOnError)
+    agent := addClientFlags(flags)
+    name := flags.String("name", "", "Script name")
+    execute := flags.Bool("execute", false, "Execute each request instead of planning it")
+    snapshotPath := flags.String("snapshot", "", "Save a snapshot of the agent environment to this file")
+    placeholders := pairs{}
+    flags.Var(placeholders, "placeholder", "Record value as a script variable, as value=name (repeatable)")
+    flags.Parse(args)
+    if flags.NArg() != 1 {
+        log.Fatal(usage)
+    }
+    path := flags.Arg(0)
+
+    c := agent.client()
+    if *snapshotPath != "" {
+        snapshot, err := c.SnapshotEnvironment(ctx)
+        if err != nil {
+            log.Fatal(err)
+        }
+        if key := snapshotKey(); key != nil {
+            snapshot.Sign(key)
+        }
+        if err := snapshot.Save(*snapshotPath); err != nil {
+            log.Fatal(err)
+        }
+    }
+    recorder := replay.NewRecorder(*name)
+    recorder.Placeholders = placeholders
+    scanner := bufio.NewScanner(os.Stdin)
+    for {
+        fmt.Fprint(os.Stderr, "> ")
+        if !scanner.Scan() {
+            break
+        }
+        message := strings.TrimSpace(scanner.Text())
+        if message == "" {
+            continue
+        }
+        resp, err := recorder.Chat(ctx, c, client.ChatRequest{Message: message, Execute: execute})
+        if err != nil {
+            if ctx.Err() != nil {
+                break
+            }
+            fmt.Fprintln(os.Stderr, "error:", err)
+        } else {
+            fmt.Printf("[%s] %s\n", resp.Function, resp.Message)
+        }
+        // Saving after every step keeps the script when the session is
+        // interrupted.
+        if err := recorder.Script().Save(path); err != nil {
+            log.Fatal(err)
+        }
+    }
+    fmt.Fprintln(os.Stderr)
+    if err := scanner.Err(); err != nil {
+        log.Fatal(err)
+    }
+    log.Printf("recorded %d steps to %s", len(recorder.Script().Steps), path)
+}
+
+func replayScript(ctx context.Context, args []string) {
+    flags := flag.NewFlagSet("replay", flag.ExitOnError)
+    agent := addClientFlags(flags)
+    dryRun := flags.Bool("dry-run", false, "Send every step as a dry run")
+    jsonOut := flags.Bool("json", false, "Print the result as JSON")
+    snapshotPath := flags.String("snapshot", "", "Refuse to run unless the agent matches this environment snapshot")
+    vars := pairs{}
+    flags.Var(vars, "var", "Override a script variable, as name=value (repeatable)")
+    flags.Parse(args)
+    if flags.NArg() != 1 {
+        log.Fatal(usage)
+    }
+    script, err := replay.Load(flags.Arg(0))
+    if err != nil {
+        log.Fatal(err)
+    }
+
+    opts := replay.Options{DryRun: *dryRun, Vars: map[string]any{}}
+    if *snapshotPath != "" {
+        if opts.Environment, err = client.LoadSnapshot(*snapshotPath, snapshotKey()); err != nil {
+            log.Fatal(err)
+        }
+    }
+    for name, value := range vars {
+        opts.Vars[name] = value
+    }
+    if !*jsonOut {
+        opts.OnStep = func(step replay.StepResult) {
+            status := "ok"
+            switch {
+            case step.Error != "":
+                status = "error: " + step.Error
+            case !step.Passed():
+                status = fmt.Sprintf("routed to %s, expected %s", step.Function, step.Expected)
+            }
+            fmt.Printf("%3d  %-40s %s\n", step.Index, step.Message, status)
+        }
+    }
+    result, err := replay.Replay(ctx, agent.client(), script, opts)
+    if err != nil {
+        log.Fatal(err)
+    }
+    if *jsonOut {
+        encoder := json.NewEncoder(os.Stdout)
+        encoder.SetIndent("", "  ")
+        encoder.Encode(result)
+    }
+    if !result.Passed() {
+        os.Exit(1)
+    }
+}
+
+func runWorkflow(ctx context.Context, args []string) {
+    flags := flag.NewFlagSet("run", flag.ExitOnError)
+    agent := addClientFlags(flags)
+    dryRun := flags.Bool("dry-run", false, "Plan chat steps and skip function steps")
+    jsonOut := flags.Bool("json", false, "Print the result as JSON")
+    vars := pairs{}
+    flags.Var(vars, "var", "Override a workflow variable, as name=value (repeatable)")
+    flags.Parse(args)
+    if flags.NArg() != 1 {
+        log.Fatal(usage)
+    }
+    wf, err := workflow.Load(flags.Arg(0))
+    if err != nil {
+        log.Fatal(err)
+    }
+
+    opts := workflow.Options{DryRun: *dryRun, Vars: map[string]any{}}
+    for name, value := range vars {
+        opts.Vars[name] = value
+    }
+    if !*jsonOut {
+        opts.OnStep = func(step workflow.StepResult) {
+            status := "ok"
+            switch {
+            case step.Skipped:
+                status = "skipped"
+            case step.Error != "":
+                status = "error: " + step.Error
+            }
+            if step.Attempts > 1 {
+                status += fmt.Sprintf(" (%d attempts)", step.Attempts)
+            }
+            fmt.Printf("%-24s %-24s %s\n", step.ID, step.Function, status)
+        }
+    }
+    result, runErr := workflow.Run(ctx, agent.client(), wf, opts)
+    if *jsonOut && result != nil {
+        encoder := json.NewEncoder(os.Stdout)
+        encoder.SetIndent("", "  ")
+        encoder.Encode(result)
+    }
+    if runErr != nil {
+        log.Fatal(runErr)
+    }
+    if !result.Passed() {
+        os.Exit(1)
+    }
+}
+
+func effectiveConfig(args []string) {
+    flags := flag.NewFlagSet("config effective", flag.ExitOnError)
+    agent := addClientFlags(flags)
+    flags.Parse(args)
+    if flags.NArg() != 0 {
+        log.Fatal(usage)
+    }
+    encoder := json.NewEncoder(os.Stdout)
+    encoder.SetIndent("", "  ")
+    if err := encoder.Encode(agent.client().EffectiveConfig()); err != nil {
+        log.Fatal(err)
+    }
+}
+
+// snapshotKey is the snapshot signing key, or nil when none is set.
+func snapshotKey() []byte {
+    if key := os.Getenv("ECHO_SNAPSHOT_KEY"); key != "" {
+        return []byte(key)
+    }
+    return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "log"
+    "net/http"
+    "time"
+
+    client "echo_computer_agent_client"
+    "echo_computer_agent_client/cacheproxy"
+    "echo_computer_agent_client/daemon"
+    "echo_computer_agent_client/systemd"
+)
+
+func main() {
+    baseURL := flag.String("base-url", "http://127.0.0.1:8000", "Echo Computer Agent base URL")
+    listen := flag.String("listen", ":9475", "Address to serve the proxied API on")
+    catalogTTL := flag.Duration("catalog-ttl", time.Minute, "How long to cache GET /functions")
+    responseTTL := flag.Duration("response-ttl", 30*time.Second, "How long to cache dry-run /chat responses")
+    rate := flag.Float64("rate", 0, "Requests per second allowed per caller (0 disables)")
+    burst := flag.Int("burst", 10, "Burst size for -rate")
+    maxResponse := flag.Int64("max-response-bytes", 8<<20, "Largest response to cache; larger ones are passed through")
+    drain := flag.Duration("drain-timeout", daemon.DefaultDrainTimeout, "How long to wait for requests in flight on shutdown")
+    flag.Parse()
+
+    proxy, err := cacheproxy.New(*baseURL)
+    if err != nil {
+        log.Fatal(err)
+    }
+    proxy.CatalogTTL, proxy.ResponseTTL = *catalogTTL, *responseTTL
+    proxy.Rate, proxy.Burst = *rate, *burst
+    proxy.MaxResponseBytes = *maxResponse
+
+    prober := client.NewClient(*baseURL, nil).NewHealthProber(15 * time.Second)
+
+    // The proxy has no config file; SIGHUP purges the cache instead.
+    d := daemon.New("echo-cache-proxy")
+    d.DrainTimeout = *drain
+    serve := d.Serve(&http.Server{Addr: *listen, Handler: proxy})
+    d.OnReload(func() error {
+        proxy.Purge()
+        return nil
+    })
+    log.Printf("caching proxy for %s on %s", *baseURL, *listen)
+    err = d.Run(context.Background(), func(ctx context.Context) error {
+        go prober.Run(ctx)
+        go systemd.Supervise(ctx, prober)
+        return serve(ctx)
+    })
+    if err != nil {
+        log.Fatal(err)
+    }
+}
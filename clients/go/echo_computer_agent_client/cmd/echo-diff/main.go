@@ -0,0 +1,83 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "fmt"
+    "io"
+    "log"
+    "os"
+    "os/signal"
+    "strings"
+    "time"
+
+    client "echo_computer_agent_client"
+    "echo_computer_agent_client/agentdiff"
+)
+
+func main() {
+    baseline := flag.String("baseline", "", "Base URL of the current agent")
+    candidate := flag.String("candidate", "", "Base URL of the agent being qualified")
+    corpus := flag.String("corpus", "", "JSON lines file of requests to replay")
+    functions := flag.String("functions", "", "Comma-separated functions to compare (default all)")
+    execute := flag.Bool("execute", false, "Replay requests with execute=true instead of as dry runs")
+    concurrency := flag.Int("concurrency", 4, "Cases replayed at once")
+    timeout := flag.Duration("timeout", 30*time.Second, "Timeout for each request")
+    jsonOut := flag.String("json", "", "Write the JSON report to this file")
+    htmlOut := flag.String("html", "", "Write the HTML report to this file")
+    flag.Parse()
+    if *baseline == "" || *candidate == "" || *corpus == "" {
+        log.Fatal("-baseline, -candidate, and -corpus are required")
+    }
+
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+    defer stop()
+
+    cases, err := agentdiff.LoadCorpus(*corpus)
+    if err != nil {
+        log.Fatal(err)
+    }
+    harness := &agentdiff.Harness{
+        Baseline: client.NewClient(*baseline, nil),
+        Candidate: client.NewClient(*candidate, nil),
+        Execute: *execute,
+        Concurrency: *concurrency,
+        Timeout: *timeout,
+    }
+    harness.Baseline.SetDeprecationHandler(client.WriteDeprecations(os.Stderr))
+    harness.Candidate.SetDeprecationHandler(client.WriteDeprecations(os.Stderr))
+    if *functions != "" {
+        harness.Functions = strings.Split(*functions, ",")
+    }
+    report, err := harness.Run(ctx, cases)
+    if err != nil {
+        log.Fatal(err)
+    }
+    if *jsonOut != "" {
+        writeFile(*jsonOut, report.WriteJSON)
+    }
+    if *htmlOut != "" {
+        writeFile(*htmlOut, report.WriteHTML)
+    }
+
+    s := report.Summary
+    fmt.Printf("%d/%d compared cases matched (%d skipped): %d routing, %d shape, %d error differences\n",
+        s.Matched, s.Compared, s.Skipped, s.FunctionMismatches, s.ShapeMismatches, s.ErrorMismatches)
+    fmt.Printf("p95 latency: baseline %s, candidate %s\n", s.BaselineLatency.P95.Round(time.Millisecond), s.CandidateLatency.P95.Round(time.Millisecond))
+    if s.Matched != s.Compared {
+        os.Exit(1)
+    }
+}
+
+func writeFile(path string, write func(w io.Writer) error) {
+    f, err := os.Create(path)
+    if err != nil {
+        log.Fatal(err)
+    }
+    if err := write(f); err != nil {
+        log.Fatal(err)
+    }
+    if err := f.Close(); err != nil {
+        log.Fatal(err)
+    }
+}
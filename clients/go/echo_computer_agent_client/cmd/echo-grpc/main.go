@@ -0,0 +1,60 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "flag"
+    "log"
+    "net/http"
+    "os"
+    "time"
+
+    client "echo_computer_agent_client"
+    "echo_computer_agent_client/daemon"
+    "echo_computer_agent_client/grpcgw"
+    "echo_computer_agent_client/systemd"
+)
+
+func main() {
+    baseURL := flag.String("base-url", "http://127.0.0.1:8000", "Echo Computer Agent base URL")
+    listen := flag.String("listen", "127.0.0.1:9480", "Address to serve gRPC on")
+    certFile := flag.String("tls-cert", "", "TLS certificate (gRPC requires HTTP/2, which net/http serves over TLS)")
+    keyFile := flag.String("tls-key", "", "TLS private key")
+    actorsPath := flag.String("actors", "", "JSON file mapping bearer tokens to the actor each is issued to, as {\"token\": {\"id\": ..., \"roles\": [...]}}")
+    trustHeaders := flag.Bool("trust-actor-headers", false, "Take the actor from x-echo-actor-* metadata; only behind a proxy that sets them")
+    drain := flag.Duration("drain-timeout", daemon.DefaultDrainTimeout, "How long to wait for calls in flight on shutdown")
+    flag.Parse()
+    if *certFile == "" || *keyFile == "" {
+        log.Fatal("-tls-cert and -tls-key are required")
+    }
+
+    agent := client.NewClient(*baseURL, nil)
+    agent.SetDeprecationHandler(client.WriteDeprecations(os.Stderr))
+    prober := agent.NewHealthProber(15 * time.Second)
+
+    gateway := grpcgw.New(agent, os.Getenv("ECHO_GRPC_TOKEN"))
+    gateway.TrustActorHeaders = *trustHeaders
+    if *actorsPath != "" {
+        raw, err := os.ReadFile(*actorsPath)
+        if err != nil {
+            log.Fatal(err)
+        }
+        if err := json.Unmarshal(raw, &gateway.Actors); err != nil {
+            log.Fatalf("%s: %v", *actorsPath, err)
+        }
+    }
+
+    d := daemon.New("echo-grpc")
+    d.DrainTimeout = *drain
+    serve := d.ServeTLS(&http.Server{Addr: *listen, Handler: gateway}, *certFile, *keyFile)
+    d.Drain(agent)
+    log.Printf("serving echo.agent.v1.EchoAgent for %s on %s", *baseURL, *listen)
+    err := d.Run(context.Background(), func(ctx context.Context) error {
+        go prober.Run(ctx)
+        go systemd.Supervise(ctx, prober)
+        return serve(ctx)
+    })
+    if err != nil {
+        log.Fatal(err)
+    }
+}
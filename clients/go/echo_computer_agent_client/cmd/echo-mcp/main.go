@@ -0,0 +1,34 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "log"
+    "os"
+    "os/signal"
+    "time"
+
+    client "echo_computer_agent_client"
+    "echo_computer_agent_client/mcp"
+    "echo_computer_agent_client/systemd"
+)
+
+func main() {
+    baseURL := flag.String("base-url", "http://127.0.0.1:8000", "Echo Computer Agent base URL")
+    flag.Parse()
+
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+    defer stop()
+
+    // stdout carries the protocol, so diagnostics go to stderr.
+    log.SetOutput(os.Stderr)
+    agent := client.NewClient(*baseURL, nil)
+    agent.SetDeprecationHandler(client.WriteDeprecations(os.Stderr))
+    prober := agent.NewHealthProber(15 * time.Second)
+    go prober.Run(ctx)
+    go systemd.Supervise(ctx, prober)
+    server := mcp.NewServer(agent)
+    if err := server.Serve(ctx, os.Stdin, os.Stdout); err != nil {
+        log.Fatal(err)
+    }
+}
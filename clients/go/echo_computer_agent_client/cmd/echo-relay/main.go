@@ -0,0 +1,58 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "log"
+    "net/http"
+    "os"
+    "time"
+
+    client "echo_computer_agent_client"
+    "echo_computer_agent_client/daemon"
+    "echo_computer_agent_client/relay"
+    "echo_computer_agent_client/systemd"
+)
+
+func main() {
+    baseURL := flag.String("base-url", "http://127.0.0.1:8000", "Echo Computer Agent base URL")
+    listen := flag.String("listen", ":9470", "Address to accept webhooks on")
+    configPath := flag.String("config", "relays.json", "Relay configuration file, re-read on SIGHUP")
+    drain := flag.Duration("drain-timeout", daemon.DefaultDrainTimeout, "How long to wait for deliveries in flight on shutdown")
+    flag.Parse()
+
+    config, err := relay.LoadConfig(*configPath)
+    if err != nil {
+        log.Fatal(err)
+    }
+    agent := client.NewClient(*baseURL, nil)
+    agent.SetDeprecationHandler(client.WriteDeprecations(os.Stderr))
+    prober := agent.NewHealthProber(15 * time.Second)
+    relays := relay.NewServer(agent, config)
+
+    // SIGHUP re-reads the config file; SIGTERM stops taking webhooks and
+    // waits for accepted deliveries, async ones included.
+    d := daemon.New("echo-relay")
+    d.DrainTimeout = *drain
+    serve := d.Serve(&http.Server{Addr: *listen, Handler: relays})
+    d.OnShutdown(relays.Drain)
+    d.Drain(agent)
+    d.OnReload(func() error {
+        config, err := relay.LoadConfig(*configPath)
+        if err != nil {
+            return err
+        }
+        relays.SetConfig(config)
+        log.Printf("loaded %d webhook endpoints from %s", len(config.Relays), *configPath)
+        return nil
+    })
+    log.Printf("relaying %d webhook endpoints to %s on %s", len(config.Relays), *baseURL, *listen)
+    err = d.Run(context.Background(), func(ctx context.Context) error {
+        go prober.Run(ctx)
+        go systemd.Supervise(ctx, prober)
+        return serve(ctx)
+    })
+    if err != nil {
+        log.Fatal(err)
+    }
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "fmt"
+    "log"
+    "os"
+    "os/signal"
+    "strings"
+    "time"
+
+    client "echo_computer_agent_client"
+    "echo_computer_agent_client/progressbar"
+)
+
+func main() {
+    baseURL := flag.String("base-url", "http://127.0.0.1:8000", "Echo Computer Agent base URL")
+    execute := flag.Bool("execute", false, "Execute the selected function instead of planning it")
+    async := flag.Bool("async", false, "Submit the request as a job and poll it until it finishes")
+    interval := flag.Duration("interval", 2*time.Second, "Polling interval for -async")
+    flag.Parse()
+    message := strings.Join(flag.Args(), " ")
+    if message == "" {
+        log.Fatal("usage: echo-run [flags] message")
+    }
+
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+    defer stop()
+
+    progress := make(chan client.Progress, 16)
+    bar := progressbar.New(os.Stderr)
+    drawn := make(chan struct{})
+    go func() {
+        bar.Run(progress)
+        close(drawn)
+    }()
+    ctx = client.WithProgress(ctx, progress)
+
+    c := client.NewClient(*baseURL, nil)
+    request := client.ChatRequest{Message: message, Execute: execute}
+    var resp *client.ChatResponse
+    var err error
+    if *async {
+        resp, err = runJob(ctx, c, request, *interval)
+    } else {
+        resp, err = c.ChatStream(ctx, request, func(client.ChatChunk) error { return nil })
+    }
+    close(progress)
+    <-drawn
+    if err != nil {
+        log.Fatal(err)
+    }
+    fmt.Printf("%s: %s\n", resp.Function, resp.Message)
+}
+
+func runJob(ctx context.Context, c *client.Client, request client.ChatRequest, interval time.Duration) (*client.ChatResponse, error) {
+    job, err := c.SubmitJob(ctx, request, "")
+    if err != nil {
+        return nil, err
+    }
+    if job, err = c.WaitForJob(ctx, job.ID, interval); err != nil {
+        return nil, err
+    }
+    if job.Status != client.JobSucceeded {
+        return nil, fmt.Errorf("job %s %s: %s", job.ID, job.Status, job.Error)
+    }
+    if job.Result == nil {
+        return &client.ChatResponse{Function: job.Function}, nil
+    }
+    return job.Result, nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+    "errors"
+    "flag"
+    "fmt"
+    "os"
+    "path/filepath"
+    "time"
+
+    client "echo_computer_agent_client"
+    "echo_computer_agent_client/internal/yamlite"
+)
+
+const defaultBaseURL = "http://127.0.0.1:8000"
+
+// fileConfig is the config file, YAML or JSON:
+//
+//	base_url: https://agent.example.com
+//	token: ...
+//	api_key_header: X-API-Key
+//	api_key: ...
+//	timeout: 30s
+type fileConfig struct {
+    BaseURL string `json:"base_url"`
+    Token string `json:"token"`
+    APIKeyHeader string `json:"api_key_header"`
+    APIKey string `json:"api_key"`
+    Timeout string `json:"timeout"`
+}
+
+// settings are the connection and output settings, resolved from flags,
+// then $ECHO_AGENT_URL and $ECHO_AGENT_TOKEN, then the config file.
+type settings struct {
+    configPath string
+    baseURL string
+    token string
+    timeout time.Duration
+    output string
+}
+
+// register adds the shared flags to flags, defaulting to the values
+// already parsed, so they may be given before or after the subcommand.
+func (s *settings) register(flags *flag.FlagSet) {
+    flags.StringVar(&s.configPath, "config", s.configPath, "Config file (default $ECHOCTL_CONFIG or ~/.config/echoctl/config.yaml)")
+    flags.StringVar(&s.baseURL, "base-url", s.baseURL, "Echo Computer Agent base URL (default $ECHO_AGENT_URL, then "+defaultBaseURL+")")
+    flags.StringVar(&s.token, "token", s.token, "Bearer token (default $ECHO_AGENT_TOKEN)")
+    flags.DurationVar(&s.timeout, "timeout", s.timeout, "Timeout for each request")
+    flags.StringVar(&s.output, "output", s.output, "Output format: table or json")
+    flags.BoolFunc("json", "Shorthand for -output json", func(string) error {
+        s.output = "json"
+        return nil
+    })
+}
+
+func (s *settings) json() bool {
+    return s.output == "json"
+}
+
+// client builds the client the settings describe.
+func (s *settings) client() (*client.Client, error) {
+    if s.output != "table" && s.output != "json" {
+        return nil, fmt.Errorf("unknown output format %q", s.output)
+    }
+    file, err := s.loadFile()
+    if err != nil {
+        return nil, err
+    }
+    baseURL := first(s.baseURL, os.Getenv("ECHO_AGENT_URL"), file.BaseURL, defaultBaseURL)
+    timeout := s.timeout
+    if timeout == 0 && file.Timeout != "" {
+        if timeout, err = time.ParseDuration(file.Timeout); err != nil {
+            return nil, fmt.Errorf("config timeout: %w", err)
+        }
+    }
+    c := client.NewClientWithOptions(baseURL, client.WithTimeout(timeout), client.WithUserAgent("echoctl"))
+    if token := first(s.token, os.Getenv("ECHO_AGENT_TOKEN"), file.Token); token != "" {
+        c.SetBearerToken(token)
+    } else if file.APIKey != "" {
+        c.SetAPIKey(first(file.APIKeyHeader, "X-API-Key"), file.APIKey)
+    }
+    return c, nil
+}
+
+// loadFile reads the config file. The default file is optional; one named
+// by -config or $ECHOCTL_CONFIG must exist.
+func (s *settings) loadFile() (fileConfig, error) {
+    var file fileConfig
+    path := first(s.configPath, os.Getenv("ECHOCTL_CONFIG"))
+    required := path != ""
+    if !required {
+        dir, err := os.UserConfigDir()
+        if err != nil {
+            return file, nil
+        }
+        path = filepath.Join(dir, "echoctl", "config.yaml")
+    }
+    raw, err := os.ReadFile(path)
+    if errors.Is(err, os.ErrNotExist) && !required {
+        return file, nil
+    }
+    if err != nil {
+        return file, err
+    }
+    if err := yamlite.Unmarshal(raw, &file); err != nil {
+        return file, fmt.Errorf("%s: %w", path, err)
+    }
+    return file, nil
+}
+
+func first(values ...string) string {
+    for _, v := range values {
+        if v != "" {
+            return v
+        }
+    }
+    return ""
+}
@@ -0,0 +1,381 @@
+// Command echoctl operates an Echo Computer Agent from the shell: listing
+// and describing its functions, chatting, executing functions, and
+// checking its health, or interactively with "echoctl repl". Output is a
+// table, or JSON with -json.
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "flag"
+    "fmt"
+    "log"
+    "os"
+    "os/signal"
+    "sort"
+    "strings"
+    "text/tabwriter"
+    "time"
+
+    client "echo_computer_agent_client"
+    "echo_computer_agent_client/internal/healthcheck"
+)
+
+const usage = `usage: echoctl [flags] command [args]
+
+commands:
+  functions list                          list the agent's functions
+  functions describe name                 show a function's parameters and metadata
+  chat [-execute] [-inputs k=v] message   send a chat message
+  exec function [-inputs k=v]             execute a function directly
+  health [-deep]                          check the agent's health
+  repl [-execute]                         chat interactively, streaming replies
+
+Flags may be given before or after the command; run "echoctl command -h"
+for the full list. The base URL and token come from the flags, then
+$ECHO_AGENT_URL and $ECHO_AGENT_TOKEN, then the config file.`
+
+func main() {
+    log.SetFlags(0)
+    s := &settings{output: "table"}
+    global := flag.NewFlagSet("echoctl", flag.ExitOnError)
+    global.Usage = func() { fmt.Fprintln(os.Stderr, usage) }
+    s.register(global)
+    global.Parse(os.Args[1:])
+    args := global.Args()
+    if len(args) == 0 {
+        log.Fatal(usage)
+    }
+    if args[0] == "repl" {
+        if err := repl(context.Background(), s, args[1:]); err != nil {
+            log.Fatal(err)
+        }
+        return
+    }
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+    defer stop()
+
+    var err error
+    switch {
+    case args[0] == "functions" && len(args) > 1 && args[1] == "list":
+        err = listFunctions(ctx, s, args[2:])
+    case args[0] == "functions" && len(args) > 1 && args[1] == "describe":
+        err = describeFunction(ctx, s, args[2:])
+    case args[0] == "chat":
+        err = chat(ctx, s, args[1:])
+    case args[0] == "exec":
+        err = execFunction(ctx, s, args[1:])
+    case args[0] == "health":
+        err = health(ctx, s, args[1:])
+    default:
+        log.Fatal(usage)
+    }
+    if err != nil {
+        log.Fatal(err)
+    }
+}
+
+// inputs collects repeated key=value flags. Values that parse as JSON,
+// such as 3, true or ["a"], are sent as such; the rest as strings.
+type inputs map[string]any
+
+func (in inputs) String() string { return "" }
+
+func (in inputs) Set(value string) error {
+    key, raw, ok := strings.Cut(value, "=")
+    if !ok || key == "" {
+        return fmt.Errorf("want key=value, got %q", value)
+    }
+    var decoded any
+    if err := json.Unmarshal([]byte(raw), &decoded); err == nil {
+        in[key] = decoded
+    } else {
+        in[key] = raw
+    }
+    return nil
+}
+
+// parse parses args with flags allowed between positional arguments, and
+// returns the positional ones.
+func parse(flags *flag.FlagSet, args []string) []string {
+    var positional []string
+    for {
+        flags.Parse(args)
+        if flags.NArg() == 0 {
+            return positional
+        }
+        positional = append(positional, flags.Arg(0))
+        args = flags.Args()[1:]
+    }
+}
+
+func subcommand(s *settings, name string) *flag.FlagSet {
+    flags := flag.NewFlagSet("echoctl "+name, flag.ExitOnError)
+    s.register(flags)
+    return flags
+}
+
+func listFunctions(ctx context.Context, s *settings, args []string) error {
+    flags := subcommand(s, "functions list")
+    if len(parse(flags, args)) != 0 {
+        return fmt.Errorf("usage: echoctl functions list [flags]")
+    }
+    c, err := s.client()
+    if err != nil {
+        return err
+    }
+    list, err := c.ListFunctions(ctx)
+    if err != nil {
+        return err
+    }
+    if s.json() {
+        return printJSON(list)
+    }
+    functions := client.NewFunctions(list.Functions)
+    w := table()
+    fmt.Fprintln(w, "NAME\tDESCRIPTION")
+    for _, fn := range functions {
+        fmt.Fprintf(w, "%s\t%s\n", fn.Name, firstLine(fn.Description))
+    }
+    return w.Flush()
+}
+
+func describeFunction(ctx context.Context, s *settings, args []string) error {
+    flags := subcommand(s, "functions describe")
+    names := parse(flags, args)
+    if len(names) != 1 {
+        return fmt.Errorf("usage: echoctl functions describe [flags] name")
+    }
+    c, err := s.client()
+    if err != nil {
+        return err
+    }
+    functions, err := c.Functions(ctx)
+    if err != nil {
+        return err
+    }
+    fn, ok := functions.ByName(names[0])
+    if !ok {
+        return fmt.Errorf("no function named %s", names[0])
+    }
+    if s.json() {
+        return printJSON(fn)
+    }
+    fmt.Printf("Name:        %s\n", fn.Name)
+    fmt.Printf("Description: %s\n", fn.Description)
+    properties, _ := fn.Parameters["properties"].(map[string]any)
+    if len(properties) > 0 {
+        required := map[string]bool{}
+        if names, ok := fn.Parameters["required"].([]any); ok {
+            for _, name := range names {
+                if name, ok := name.(string); ok {
+                    required[name] = true
+                }
+            }
+        }
+        fmt.Println("\nParameters:")
+        w := table()
+        fmt.Fprintln(w, "  NAME\tTYPE\tREQUIRED\tDESCRIPTION")
+        for _, name := range sortedKeys(properties) {
+            property, _ := properties[name].(map[string]any)
+            kind, _ := property["type"].(string)
+            if values, ok := property["enum"].([]any); ok {
+                kind = fmt.Sprintf("%s (%s)", kind, joinValues(values))
+            }
+            description, _ := property["description"].(string)
+            fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", name, kind, yesNo(required[name]), firstLine(description))
+        }
+        if err := w.Flush(); err != nil {
+            return err
+        }
+    }
+    if len(fn.Metadata) > 0 {
+        fmt.Println("\nMetadata:")
+        w := table()
+        for _, key := range sortedKeys(fn.Metadata) {
+            fmt.Fprintf(w, "  %s\t%s\n", key, display(fn.Metadata[key]))
+        }
+        return w.Flush()
+    }
+    return nil
+}
+
+func chat(ctx context.Context, s *settings, args []string) error {
+    flags := subcommand(s, "chat")
+    execute := flags.Bool("execute", false, "Execute the selected function instead of planning it")
+    in := inputs{}
+    flags.Var(in, "inputs", "Input as key=value (repeatable)")
+    message := strings.Join(parse(flags, args), " ")
+    if message == "" {
+        return fmt.Errorf("usage: echoctl chat [flags] message")
+    }
+    c, err := s.client()
+    if err != nil {
+        return err
+    }
+    request := client.ChatRequest{Message: message, Execute: execute}
+    if len(in) > 0 {
+        request.Inputs = in
+    }
+    resp, err := c.Chat(ctx, request)
+    if err != nil {
+        return err
+    }
+    if s.json() {
+        return printJSON(resp)
+    }
+    fmt.Printf("[%s] %s\n", resp.Function, resp.Message)
+    return printFields(resp.Data)
+}
+
+func execFunction(ctx context.Context, s *settings, args []string) error {
+    flags := subcommand(s, "exec")
+    in := inputs{}
+    flags.Var(in, "inputs", "Input as key=value (repeatable)")
+    names := parse(flags, args)
+    if len(names) != 1 {
+        return fmt.Errorf("usage: echoctl exec [flags] function")
+    }
+    c, err := s.client()
+    if err != nil {
+        return err
+    }
+    result, err := c.ExecuteFunction(ctx, names[0], in)
+    if err != nil {
+        return err
+    }
+    if s.json() {
+        err = printJSON(result)
+    } else {
+        fmt.Printf("%s %s", result.Function, result.Status)
+        if result.Duration > 0 {
+            fmt.Printf(" in %s", result.Duration.Round(time.Millisecond))
+        }
+        fmt.Println()
+        if result.Error != "" {
+            fmt.Println("error:", result.Error)
+        }
+        for _, line := range result.Logs {
+            fmt.Println("  |", line)
+        }
+        err = printFields(result.Output)
+    }
+    if err == nil && result.Status != client.JobSucceeded {
+        os.Exit(1)
+    }
+    return err
+}
+
+func health(ctx context.Context, s *settings, args []string) error {
+    flags := subcommand(s, "health")
+    deep := flags.Bool("deep", false, "Also list functions and route a test message")
+    if len(parse(flags, args)) != 0 {
+        return fmt.Errorf("usage: echoctl health [-deep] [flags]")
+    }
+    c, err := s.client()
+    if err != nil {
+        return err
+    }
+    h, err := c.Health(ctx)
+    if err != nil {
+        return err
+    }
+    var check *healthcheck.Result
+    if *deep {
+        if check, err = healthcheck.Run(ctx, c); err != nil {
+            return err
+        }
+    }
+    if s.json() {
+        err = printJSON(struct {
+            *client.Health
+            Deep *healthcheck.Result `json:"deep,omitempty"`
+        }{h, check})
+    } else {
+        w := table()
+        fmt.Fprintf(w, "status\t%s\n", h.Status)
+        if h.Version != "" {
+            fmt.Fprintf(w, "version\t%s\n", h.Version)
+        }
+        if h.Uptime > 0 {
+            fmt.Fprintf(w, "uptime\t%.0fs\n", h.Uptime)
+        }
+        fmt.Fprintf(w, "latency\t%s\n", h.Latency.Round(100*time.Microsecond))
+        for _, name := range sortedKeys(h.Checks) {
+            fmt.Fprintf(w, "check %s\t%s\n", name, h.Checks[name])
+        }
+        if check != nil {
+            fmt.Fprintf(w, "functions\t%d\n", check.Functions)
+            fmt.Fprintf(w, "routed\t%s\n", check.Function)
+        }
+        err = w.Flush()
+    }
+    if err == nil && !h.OK() {
+        os.Exit(1)
+    }
+    return err
+}
+
+func table() *tabwriter.Writer {
+    return tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+}
+
+func printJSON(v any) error {
+    encoder := json.NewEncoder(os.Stdout)
+    encoder.SetIndent("", "  ")
+    return encoder.Encode(v)
+}
+
+// printFields prints a reply's data or output as a two-column table.
+func printFields(fields map[string]any) error {
+    if len(fields) == 0 {
+        return nil
+    }
+    w := table()
+    for _, key := range sortedKeys(fields) {
+        fmt.Fprintf(w, "  %s\t%s\n", key, display(fields[key]))
+    }
+    return w.Flush()
+}
+
+// display renders a value for a table cell: strings as is, the rest as
+// compact JSON.
+func display(v any) string {
+    if text, ok := v.(string); ok {
+        return firstLine(text)
+    }
+    encoded, err := json.Marshal(v)
+    if err != nil {
+        return fmt.Sprint(v)
+    }
+    return string(encoded)
+}
+
+func joinValues(values []any) string {
+    parts := make([]string, len(values))
+    for i, v := range values {
+        parts[i] = fmt.Sprint(v)
+    }
+    return strings.Join(parts, "|")
+}
+
+func firstLine(text string) string {
+    line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+    return line
+}
+
+func yesNo(b bool) string {
+    if b {
+        return "yes"
+    }
+    return "no"
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+    keys := make([]string, 0, len(m))
+    for key := range m {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "os/signal"
+    "sort"
+    "strings"
+
+    client "echo_computer_agent_client"
+    "echo_computer_agent_client/internal/lineedit"
+)
+
+var slashCommands = []string{"/execute", "/functions", "/help", "/history", "/quit", "/reset", "/save"}
+
+const replHelp = `Messages are sent to the agent as one conversation, planned unless
+execution is on. Tab completes function names and commands; Ctrl-C stops
+a reply, Ctrl-D or /quit leaves.
+
+  /execute [on|off]   toggle execution, or /execute message to execute once
+  /functions          list the agent's functions
+  /history            show the conversation so far
+  /reset              start a new conversation
+  /save file          save the transcript, as JSON when file ends in .json
+  /help               show this help`
+
+// session is the state of an interactive session.
+type session struct {
+    client *client.Client
+    conversation *client.Conversation
+    execute bool
+    functions []string
+}
+
+// repl runs without the interrupt handling main installs, so Ctrl-C
+// stops one reply instead of the session.
+func repl(ctx context.Context, s *settings, args []string) error {
+    flags := subcommand(s, "repl")
+    execute := flags.Bool("execute", false, "Start with execution on")
+    if len(parse(flags, args)) != 0 {
+        return fmt.Errorf("usage: echoctl repl [flags]")
+    }
+    c, err := s.client()
+    if err != nil {
+        return err
+    }
+    conversation, err := c.NewConversation()
+    if err != nil {
+        return err
+    }
+    r := &session{client: c, conversation: conversation, execute: *execute}
+    if err := r.loadFunctions(ctx); err != nil {
+        fmt.Fprintln(os.Stderr, "warning: cannot list functions:", err)
+    }
+
+    editor := lineedit.New(os.Stdin, os.Stdout)
+    editor.Complete = r.complete
+    fmt.Println(`echoctl repl; /help for commands`)
+    for {
+        editor.Prompt = "echo> "
+        if r.execute {
+            editor.Prompt = "echo!> "
+        }
+        line, err := editor.ReadLine()
+        if errors.Is(err, lineedit.ErrInterrupted) {
+            continue
+        }
+        if err == io.EOF {
+            return nil
+        }
+        if err != nil {
+            return err
+        }
+        line = strings.TrimSpace(line)
+        if line == "" {
+            continue
+        }
+        if strings.HasPrefix(line, "/") {
+            quit, err := r.command(ctx, line)
+            if err != nil {
+                fmt.Fprintln(os.Stderr, "error:", err)
+            }
+            if quit {
+                return nil
+            }
+            continue
+        }
+        if err := r.send(ctx, line, r.execute); err != nil {
+            fmt.Fprintln(os.Stderr, "error:", err)
+        }
+    }
+}
+
+func (r *session) loadFunctions(ctx context.Context) error {
+    r.client.InvalidateCatalog()
+    functions, err := r.client.Functions(ctx)
+    if err != nil {
+        return err
+    }
+    r.functions = functions.Names()
+    return nil
+}
+
+// complete offers commands at the start of a line and function names
+// elsewhere.
+func (r *session) complete(line string) []string {
+    word := line[strings.LastIndex(line, " ")+1:]
+    candidates := r.functions
+    if word == line && strings.HasPrefix(line, "/") {
+        candidates = slashCommands
+    }
+    var matches []string
+    for _, candidate := range candidates {
+        if strings.HasPrefix(candidate, word) {
+            matches = append(matches, candidate)
+        }
+    }
+    return matches
+}
+
+// send streams the reply to message, until it ends or Ctrl-C.
+func (r *session) send(ctx context.Context, message string, execute bool) error {
+    ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+    defer stop()
+    request := client.ChatRequest{Message: message}
+    if execute {
+        request = request.AutoExecute()
+    }
+    streamed := false
+    resp, err := r.conversation.ChatStream(ctx, request, func(chunk client.ChatChunk) error {
+        if selected, ok := chunk.Event.(*client.FunctionSelected); ok {
+            fmt.Printf("[%s] ", selected.Function)
+            streamed = true
+        }
+        if chunk.Text != "" {
+            fmt.Print(chunk.Text)
+            streamed = true
+        }
+        return nil
+    })
+    if streamed {
+        fmt.Println()
+    }
+    if err != nil {
+        if ctx.Err() != nil {
+            return errors.New("reply interrupted")
+        }
+        return err
+    }
+    if !streamed {
+        fmt.Printf("[%s] %s\n", resp.Function, resp.Message)
+    }
+    return printFields(resp.Data)
+}
+
+func (r *session) command(ctx context.Context, line string) (bool, error) {
+    name, rest, _ := strings.Cut(line, " ")
+    rest = strings.TrimSpace(rest)
+    switch name {
+    case "/quit", "/exit":
+        return true, nil
+    case "/help":
+        fmt.Println(replHelp)
+    case "/execute":
+        switch rest {
+        case "":
+            r.execute = !r.execute
+        case "on":
+            r.execute = true
+        case "off":
+            r.execute = false
+        default:
+            return false, r.send(ctx, rest, true)
+        }
+        fmt.Println("execution", map[bool]string{true: "on", false: "off"}[r.execute])
+    case "/functions":
+        if err := r.loadFunctions(ctx); err != nil {
+            return false, err
+        }
+        sorted := append([]string(nil), r.functions...)
+        sort.Strings(sorted)
+        for _, fn := range sorted {
+            fmt.Println(" ", fn)
+        }
+    case "/history":
+        for _, turn := range r.conversation.History() {
+            role := turn.Role
+            if turn.Function != "" {
+                role += " (" + turn.Function + ")"
+            }
+            fmt.Printf("%s: %s\n", role, turn.Content)
+        }
+    case "/reset":
+        if err := r.conversation.Reset(); err != nil {
+            return false, err
+        }
+        fmt.Println("new conversation", r.conversation.ID())
+    case "/save":
+        if rest == "" {
+            return false, errors.New("usage: /save file")
+        }
+        if err := saveTranscript(rest, r.conversation.Transcript()); err != nil {
+            return false, err
+        }
+        fmt.Println("saved", rest)
+    default:
+        return false, fmt.Errorf("unknown command %s; /help lists them", name)
+    }
+    return false, nil
+}
+
+func saveTranscript(path string, transcript client.Transcript) error {
+    f, err := os.Create(path)
+    if err != nil {
+        return err
+    }
+    if strings.HasSuffix(path, ".json") {
+        encoder := json.NewEncoder(f)
+        encoder.SetIndent("", "  ")
+        err = encoder.Encode(transcript)
+    } else {
+        err = transcript.WriteText(f)
+    }
+    if closeErr := f.Close(); err == nil {
+        err = closeErr
+    }
+    return err
+}
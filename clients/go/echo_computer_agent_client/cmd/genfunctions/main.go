@@ -0,0 +1,295 @@
+// Command genfunctions generates typed input structs and Invoke wrappers
+// for the agent's functions from their parameter schemas, so callers of
+// known functions need not build map[string]any inputs by hand.
+//
+//	genfunctions -package agentfns -out agentfns/functions.go
+//
+// Rerun it when the catalog changes; the output is meant to be checked in.
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "flag"
+    "fmt"
+    "go/format"
+    "log"
+    "os"
+    "sort"
+    "strings"
+    "time"
+    "unicode"
+
+    client "echo_computer_agent_client"
+)
+
+func main() {
+    baseURL := flag.String("base-url", "http://127.0.0.1:8000", "Echo Computer Agent base URL")
+    catalog := flag.String("catalog", "", "Read the catalog from this /functions JSON file instead of the agent")
+    pkg := flag.String("package", "agentfunctions", "Package name of the generated file")
+    clientImport := flag.String("client-import", "echo_computer_agent_client", "Import path of the client package")
+    only := flag.String("functions", "", "Comma-separated functions to generate; all when empty")
+    out := flag.String("out", "", "Write the generated file here instead of stdout")
+    flag.Parse()
+
+    functions, err := load(*baseURL, *catalog)
+    if err != nil {
+        log.Fatal(err)
+    }
+    if *only != "" {
+        wanted := map[string]bool{}
+        for _, name := range strings.Split(*only, ",") {
+            wanted[strings.TrimSpace(name)] = true
+        }
+        var kept []client.FunctionDescription
+        for _, fn := range functions {
+            if wanted[fn.Name] {
+                kept = append(kept, fn)
+                delete(wanted, fn.Name)
+            }
+        }
+        for name := range wanted {
+            log.Fatalf("function %s is not in the catalog", name)
+        }
+        functions = kept
+    }
+
+    g := newGenerator(*pkg, *clientImport)
+    for _, fn := range functions {
+        g.function(fn)
+    }
+    source, err := g.source()
+    if err != nil {
+        log.Fatal(err)
+    }
+    if *out == "" {
+        os.Stdout.Write(source)
+        return
+    }
+    if err := os.WriteFile(*out, source, 0o644); err != nil {
+        log.Fatal(err)
+    }
+}
+
+func load(baseURL, catalog string) ([]client.FunctionDescription, error) {
+    var list client.FunctionListResponse
+    if catalog != "" {
+        raw, err := os.ReadFile(catalog)
+        if err != nil {
+            return nil, err
+        }
+        if err := json.Unmarshal(raw, &list); err != nil {
+            return nil, fmt.Errorf("parse %s: %w", catalog, err)
+        }
+    } else {
+        ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+        defer cancel()
+        fetched, err := client.NewClient(baseURL, nil).ListFunctions(ctx)
+        if err != nil {
+            return nil, err
+        }
+        list = *fetched
+    }
+    functions := append([]client.FunctionDescription{}, list.Functions...)
+    sort.Slice(functions, func(i, j int) bool { return functions[i].Name < functions[j].Name })
+    return functions, nil
+}
+
+type generator struct {
+    pkg string
+    clientImport string
+    body strings.Builder
+    types map[string]bool
+}
+
+func newGenerator(pkg, clientImport string) *generator {
+    return &generator{pkg: pkg, clientImport: clientImport, types: map[string]bool{}}
+}
+
+func (g *generator) source() ([]byte, error) {
+    var b strings.Builder
+    b.WriteString("// Code generated by genfunctions from the agent's function catalog. DO NOT EDIT.\n\n")
+    fmt.Fprintf(&b, "package %s\n\n", g.pkg)
+    fmt.Fprintf(&b, "import (\n\"context\"\n\"encoding/json\"\n\nclient %q\n)\n\n", g.clientImport)
+    b.WriteString(g.body.String())
+    b.WriteString(`
+// inputsOf turns a generated input struct into the inputs map the client sends.
+func inputsOf(input any) (map[string]any, error) {
+	encoded, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	var inputs map[string]any
+	if err := json.Unmarshal(encoded, &inputs); err != nil {
+		return nil, err
+	}
+	return inputs, nil
+}
+`)
+    return format.Source([]byte(b.String()))
+}
+
+// function writes fn's input type and its Invoke and Execute wrappers.
+func (g *generator) function(fn client.FunctionDescription) {
+    name := g.unique(exported(fn.Name))
+    input := g.unique(name + "Input")
+    g.structType(input, fn.Parameters, fn.Description)
+
+    fmt.Fprintf(&g.body, "// Invoke%s invokes %s with input.\n", name, fn.Name)
+    fmt.Fprintf(&g.body, "func Invoke%s(ctx context.Context, c *client.Client, input %s) (*client.ChatResponse, error) {\n", name, input)
+    fmt.Fprintf(&g.body, "inputs, err := inputsOf(input)\nif err != nil {\nreturn nil, err\n}\nreturn c.InvokeFunction(ctx, %q, inputs)\n}\n\n", fn.Name)
+    fmt.Fprintf(&g.body, "// Execute%s runs %s through the execute endpoint.\n", name, fn.Name)
+    fmt.Fprintf(&g.body, "func Execute%s(ctx context.Context, c *client.Client, input %s, opts ...client.ExecOption) (*client.ExecutionResult, error) {\n", name, input)
+    fmt.Fprintf(&g.body, "inputs, err := inputsOf(input)\nif err != nil {\nreturn nil, err\n}\nreturn c.ExecuteFunction(ctx, %q, inputs, opts...)\n}\n\n", fn.Name)
+}
+
+// structType writes an object schema as a struct named name, and any
+// nested objects and enums it needs.
+func (g *generator) structType(name string, schema map[string]any, doc string) {
+    properties, _ := schema["properties"].(map[string]any)
+    required := map[string]bool{}
+    if list, ok := schema["required"].([]any); ok {
+        for _, field := range list {
+            if field, ok := field.(string); ok {
+                required[field] = true
+            }
+        }
+    }
+    keys := make([]string, 0, len(properties))
+    for key := range properties {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+
+    var fields strings.Builder
+    used := map[string]bool{}
+    for _, key := range keys {
+        property, _ := properties[key].(map[string]any)
+        field := exported(key)
+        for used[field] {
+            field += "_"
+        }
+        used[field] = true
+        typ := g.typeOf(name+field, property)
+        tag := key
+        if !required[key] {
+            tag += ",omitempty"
+            if scalar(typ) {
+                typ = "*" + typ
+            }
+        }
+        if description, _ := property["description"].(string); description != "" {
+            fmt.Fprintf(&fields, "%s\n", comment(description))
+        }
+        fmt.Fprintf(&fields, "%s %s `json:%q`\n", field, typ, tag)
+    }
+    if doc != "" {
+        fmt.Fprintf(&g.body, "%s\n", comment(doc))
+    }
+    fmt.Fprintf(&g.body, "type %s struct {\n%s}\n\n", name, fields.String())
+}
+
+// typeOf is the Go type for schema, declaring a named type for objects
+// with properties and string enums.
+func (g *generator) typeOf(name string, schema map[string]any) string {
+    if schema == nil {
+        return "any"
+    }
+    kind, _ := schema["type"].(string)
+    if kinds, ok := schema["type"].([]any); ok {
+        // ["string", "null"] and the like: take the first non-null type.
+        for _, k := range kinds {
+            if k, ok := k.(string); ok && k != "null" {
+                kind = k
+                break
+            }
+        }
+    }
+    switch kind {
+    case "string":
+        if values, ok := schema["enum"].([]any); ok && len(values) > 0 {
+            return g.enum(name, values)
+        }
+        return "string"
+    case "integer":
+        return "int64"
+    case "number":
+        return "float64"
+    case "boolean":
+        return "bool"
+    case "array":
+        items, _ := schema["items"].(map[string]any)
+        return "[]" + g.typeOf(name+"Item", items)
+    case "object":
+        if properties, ok := schema["properties"].(map[string]any); ok && len(properties) > 0 {
+            typ := g.unique(name)
+            g.structType(typ, schema, "")
+            return typ
+        }
+        if additional, ok := schema["additionalProperties"].(map[string]any); ok {
+            return "map[string]" + g.typeOf(name+"Value", additional)
+        }
+        return "map[string]any"
+    }
+    return "any"
+}
+
+func (g *generator) enum(name string, values []any) string {
+    typ := g.unique(name)
+    fmt.Fprintf(&g.body, "type %s string\n\nconst (\n", typ)
+    for _, value := range values {
+        text := fmt.Sprint(value)
+        fmt.Fprintf(&g.body, "%s %s = %q\n", g.unique(typ+exported(text)), typ, text)
+    }
+    g.body.WriteString(")\n\n")
+    return typ
+}
+
+func (g *generator) unique(name string) string {
+    candidate := name
+    for i := 2; g.types[candidate]; i++ {
+        candidate = fmt.Sprintf("%s%d", name, i)
+    }
+    g.types[candidate] = true
+    return candidate
+}
+
+func scalar(typ string) bool {
+    switch typ {
+    case "string", "int64", "float64", "bool":
+        return true
+    }
+    return !strings.HasPrefix(typ, "[]") && !strings.HasPrefix(typ, "map[") && typ != "any" && !strings.HasPrefix(typ, "*")
+}
+
+// initialisms keep Go's spelling of common abbreviations.
+var initialisms = map[string]string{"id": "ID", "url": "URL", "uri": "URI", "http": "HTTP", "api": "API", "ip": "IP", "json": "JSON", "dns": "DNS", "tls": "TLS", "cpu": "CPU"}
+
+// exported turns "service.restart" or "max_retries" into an exported
+// identifier: ServiceRestart, MaxRetries.
+func exported(name string) string {
+    words := strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+    var b strings.Builder
+    for _, word := range words {
+        if upper, ok := initialisms[strings.ToLower(word)]; ok {
+            b.WriteString(upper)
+            continue
+        }
+        runes := []rune(word)
+        runes[0] = unicode.ToUpper(runes[0])
+        b.WriteString(string(runes))
+    }
+    identifier := b.String()
+    if identifier == "" || unicode.IsDigit([]rune(identifier)[0]) {
+        identifier = "X" + identifier
+    }
+    return identifier
+}
+
+func comment(text string) string {
+    lines := strings.Split(strings.TrimSpace(text), "\n")
+    for i, line := range lines {
+        lines[i] = strings.TrimRight("// "+strings.TrimSpace(line), " ")
+    }
+    return strings.Join(lines, "\n")
+}
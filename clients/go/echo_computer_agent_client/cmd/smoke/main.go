@@ -1,3 +1,5 @@
+// Command smoke is "echoctl health -deep" without the /health probe, for
+// agents and mocks that do not serve it.
 package main
 
 import (
@@ -7,6 +9,7 @@ import (
     "time"
 
     client "echo_computer_agent_client"
+    "echo_computer_agent_client/internal/healthcheck"
 )
 
 func main() {
@@ -16,22 +19,9 @@ func main() {
     ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
     defer cancel()
 
-    c := client.NewClient(*baseURL, nil)
-    functions, err := c.ListFunctions(ctx)
+    result, err := healthcheck.Run(ctx, client.NewClient(*baseURL, nil))
     if err != nil {
         log.Fatal(err)
     }
-    if len(functions.Functions) == 0 {
-        log.Fatal("no functions returned")
-    }
-
-    chat, err := c.Chat(ctx, client.ChatRequest{Message: "launch echo.bank"})
-    if err != nil {
-        log.Fatal(err)
-    }
-    if chat.Function == "" {
-        log.Fatal("empty function name")
-    }
-
-    log.Printf("chat response: %s", chat.Message)
+    log.Printf("chat response: %s", result.Message)
 }
@@ -0,0 +1,134 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Functions is a catalog sorted by name, so ranging over it is ordered.
+type Functions []FunctionDescription
+
+func NewFunctions(list []FunctionDescription) Functions {
+    functions := make(Functions, len(list))
+    copy(functions, list)
+    sort.Slice(functions, func(i, j int) bool { return functions[i].Name < functions[j].Name })
+    return functions
+}
+
+func (f Functions) ByName(name string) (FunctionDescription, bool) {
+    i := sort.Search(len(f), func(i int) bool { return f[i].Name >= name })
+    if i < len(f) && f[i].Name == name {
+        return f[i], true
+    }
+    return FunctionDescription{}, false
+}
+
+func (f Functions) Names() []string {
+    names := make([]string, len(f))
+    for i, fn := range f {
+        names[i] = fn.Name
+    }
+    return names
+}
+
+// Tags returns the strings listed under Metadata["tags"].
+func Tags(fn FunctionDescription) []string {
+    var tags []string
+    switch list := fn.Metadata["tags"].(type) {
+    case []any:
+        for _, tag := range list {
+            if s, ok := tag.(string); ok {
+                tags = append(tags, s)
+            }
+        }
+    case []string:
+        tags = list
+    }
+    return tags
+}
+
+func (f Functions) WithTag(tag string) Functions {
+    var matched Functions
+    for _, fn := range f {
+        for _, t := range Tags(fn) {
+            if strings.EqualFold(t, tag) {
+                matched = append(matched, fn)
+                break
+            }
+        }
+    }
+    return matched
+}
+
+// Search returns functions whose name, description, or tags contain every
+// word of query, case-insensitively. Name matches rank first; ties keep
+// name order.
+func (f Functions) Search(query string) Functions {
+    words := strings.Fields(strings.ToLower(query))
+    type hit struct {
+        fn FunctionDescription
+        score int
+    }
+    var hits []hit
+    for _, fn := range f {
+        name := strings.ToLower(fn.Name)
+        text := name + " " + strings.ToLower(fn.Description) + " " + strings.ToLower(strings.Join(Tags(fn), " "))
+        score := 0
+        matched := true
+        for _, word := range words {
+            if !strings.Contains(text, word) {
+                matched = false
+                break
+            }
+            if strings.Contains(name, word) {
+                score++
+            }
+        }
+        if matched {
+            hits = append(hits, hit{fn, score})
+        }
+    }
+    sort.SliceStable(hits, func(i, j int) bool { return hits[i].score > hits[j].score })
+    results := make(Functions, len(hits))
+    for i, h := range hits {
+        results[i] = h.fn
+    }
+    return results
+}
+
+// CatalogTTL is how long Functions serves a cached catalog before listing
+// again.
+const CatalogTTL = time.Minute
+
+type catalogCache struct {
+    sync.Mutex
+    functions Functions
+    fetched time.Time
+}
+
+// Functions returns the catalog as a collection, listing it at most once
+// per CatalogTTL.
+func (c *Client) Functions(ctx context.Context) (Functions, error) {
+    c.catalog.Lock()
+    defer c.catalog.Unlock()
+    if c.catalog.functions != nil && time.Since(c.catalog.fetched) < CatalogTTL {
+        return c.catalog.functions, nil
+    }
+    list, err := c.ListFunctions(ctx)
+    if err != nil {
+        return nil, err
+    }
+    c.catalog.functions, c.catalog.fetched = NewFunctions(list.Functions), time.Now()
+    return c.catalog.functions, nil
+}
+
+// InvalidateCatalog drops the cached catalog so the next Functions call
+// lists it again.
+func (c *Client) InvalidateCatalog() {
+    c.catalog.Lock()
+    c.catalog.functions = nil
+    c.catalog.Unlock()
+}
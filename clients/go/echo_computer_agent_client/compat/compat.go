@@ -0,0 +1,65 @@
+// Package compat freezes the client surface as first generated from the
+// OpenAPI document: NewClient, SetDefaultHeader, ListFunctions, and Chat,
+// with plain error values. Code written against that surface can import
+// compat and keep compiling while the root package grows options and
+// typed errors; each breaking change to the root package is absorbed here
+// so callers migrate one call site at a time via Unwrap.
+package compat
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+
+    client "echo_computer_agent_client"
+)
+
+type (
+    ChatRequest = client.ChatRequest
+    ChatResponse = client.ChatResponse
+    FunctionDescription = client.FunctionDescription
+    FunctionListResponse = client.FunctionListResponse
+)
+
+type Client struct {
+    inner *client.Client
+}
+
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+    return &Client{inner: client.NewClient(baseURL, httpClient)}
+}
+
+// Wrap adapts an already configured client.
+func Wrap(c *client.Client) *Client {
+    return &Client{inner: c}
+}
+
+// Unwrap returns the underlying client for call sites that have migrated.
+func (c *Client) Unwrap() *client.Client {
+    return c.inner
+}
+
+func (c *Client) SetDefaultHeader(key, value string) {
+    c.inner.SetDefaultHeader(key, value)
+}
+
+func (c *Client) ListFunctions(ctx context.Context) (*FunctionListResponse, error) {
+    resp, err := c.inner.ListFunctions(ctx)
+    return resp, plainError(err)
+}
+
+func (c *Client) Chat(ctx context.Context, request ChatRequest) (*ChatResponse, error) {
+    resp, err := c.inner.Chat(ctx, request)
+    return resp, plainError(err)
+}
+
+// plainError restores the generated client's error for HTTP failures,
+// which carried nothing beyond the status.
+func plainError(err error) error {
+    var apiErr *client.APIError
+    if errors.As(err, &apiErr) {
+        return fmt.Errorf("request failed with status %d", apiErr.Status)
+    }
+    return err
+}
@@ -0,0 +1,80 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "net/http"
+    "sync"
+)
+
+// validators are the ETag and Last-Modified a response was served with,
+// sent back as If-None-Match and If-Modified-Since to fetch it again only
+// if it changed.
+type validators struct {
+    etag string
+    lastModified string
+}
+
+func (v validators) empty() bool { return v.etag == "" && v.lastModified == "" }
+
+// listingCache is ListFunctions' last listing and its validators.
+type listingCache struct {
+    sync.Mutex
+    validators validators
+    payload *FunctionListResponse
+}
+
+// conditional is a conditional GET in flight: the validators to send, and
+// what the agent answered.
+type conditional struct {
+    send validators
+    received validators
+    notModified bool
+}
+
+type conditionalKey struct{}
+
+func withConditional(ctx context.Context, cond *conditional) context.Context {
+    return context.WithValue(ctx, conditionalKey{}, cond)
+}
+
+func conditionalFrom(ctx context.Context) *conditional {
+    cond, _ := ctx.Value(conditionalKey{}).(*conditional)
+    return cond
+}
+
+func (cond *conditional) decorate(req *http.Request) {
+    if cond.send.etag != "" {
+        req.Header.Set("If-None-Match", cond.send.etag)
+    }
+    if cond.send.lastModified != "" {
+        req.Header.Set("If-Modified-Since", cond.send.lastModified)
+    }
+}
+
+// observe records resp's validators, reporting whether it is a 304 the
+// caller should answer from what it already holds.
+func (cond *conditional) observe(resp *http.Response) bool {
+    cond.notModified = resp.StatusCode == http.StatusNotModified
+    if cond.notModified {
+        cond.received = cond.send
+        return true
+    }
+    cond.received = validators{etag: resp.Header.Get("ETag"), lastModified: resp.Header.Get("Last-Modified")}
+    return false
+}
+
+// listFunctionsSince lists the catalog unless it is unchanged since a
+// listing served with since, in which case it returns nil and true. The
+// returned validators identify the catalog now held.
+func (c *Client) listFunctionsSince(ctx context.Context, since validators) (*FunctionListResponse, validators, bool, error) {
+    cond := &conditional{send: since}
+    var payload FunctionListResponse
+    if err := c.doJSON(withConditional(ctx, cond), http.MethodGet, "/functions", nil, &payload); err != nil {
+        return nil, validators{}, false, err
+    }
+    if cond.notModified {
+        return nil, cond.received, true, nil
+    }
+    c.noteDeprecatedFunctions(&payload)
+    return &payload, cond.received, false, nil
+}
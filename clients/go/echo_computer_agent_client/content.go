@@ -0,0 +1,128 @@
+package echo_computer_agent_client
+
+import (
+    "bytes"
+    "context"
+    "encoding/base64"
+    "fmt"
+    "io"
+    "mime"
+    "strings"
+)
+
+// ContentInput is the input a message's content parts are sent in.
+const ContentInput = "content"
+
+// DefaultInlineLimit is the largest media part sent inline; larger parts
+// are uploaded to the file API and sent by reference.
+const DefaultInlineLimit = 256 << 10
+
+// ContentPart is one piece of a multimodal message, built with TextPart,
+// ImagePart or AudioPart. AttachContent encodes parts for the agent.
+type ContentPart struct {
+    Type string
+    Text string
+    MIMEType string
+    Name string
+    r io.Reader
+}
+
+func TextPart(text string) ContentPart {
+    return ContentPart{Type: "text", Text: text}
+}
+
+// ImagePart is an image read from r, such as "image/png".
+func ImagePart(r io.Reader, mimeType string) ContentPart {
+    return ContentPart{Type: "image", MIMEType: mimeType, r: r}
+}
+
+// AudioPart is an audio clip read from r, such as "audio/wav".
+func AudioPart(r io.Reader, mimeType string) ContentPart {
+    return ContentPart{Type: "audio", MIMEType: mimeType, r: r}
+}
+
+// Named sets the file name a part is uploaded under, when it is too large
+// to send inline.
+func (p ContentPart) Named(name string) ContentPart {
+    p.Name = name
+    return p
+}
+
+// SetInlineLimit sets the largest media part, in bytes, AttachContent
+// sends inline rather than uploading first. Zero uploads every part.
+func (c *Client) SetInlineLimit(limit int64) {
+    c.inlineLimit = &limit
+}
+
+func WithInlineLimit(limit int64) Option {
+    return func(c *Client) { c.SetInlineLimit(limit) }
+}
+
+func (c *Client) contentInlineLimit() int64 {
+    if c.inlineLimit == nil {
+        return DefaultInlineLimit
+    }
+    return *c.inlineLimit
+}
+
+// AttachContent returns request with parts in its "content" input, in
+// order. Text parts become {"type": "text", "text": ...}. Media parts up
+// to the inline limit become {"type", "mime_type", "data"} with the bytes
+// base64-encoded; larger ones are uploaded with UploadFile, without being
+// held in memory, and become {"type", "mime_type", "file_id"}. A message
+// left empty is set to the text parts, so the agent can route on them.
+func (c *Client) AttachContent(ctx context.Context, request ChatRequest, parts ...ContentPart) (ChatRequest, error) {
+    encoded := make([]map[string]any, 0, len(parts))
+    var text []string
+    for i, part := range parts {
+        if part.Type == "text" {
+            encoded = append(encoded, map[string]any{"type": "text", "text": part.Text})
+            text = append(text, part.Text)
+            continue
+        }
+        value, err := c.encodePart(ctx, part, i)
+        if err != nil {
+            return request, fmt.Errorf("content part %d: %w", i, err)
+        }
+        encoded = append(encoded, value)
+    }
+    inputs := make(map[string]any, len(request.Inputs)+1)
+    for key, value := range request.Inputs {
+        inputs[key] = value
+    }
+    inputs[ContentInput] = encoded
+    request.Inputs = inputs
+    if request.Message == "" {
+        request.Message = strings.Join(text, "\n")
+    }
+    return request, nil
+}
+
+func (c *Client) encodePart(ctx context.Context, part ContentPart, index int) (map[string]any, error) {
+    if part.r == nil {
+        return nil, fmt.Errorf("%s part has no content", part.Type)
+    }
+    value := map[string]any{"type": part.Type, "mime_type": part.MIMEType}
+    limit := c.contentInlineLimit()
+    head, err := io.ReadAll(io.LimitReader(part.r, limit+1))
+    if err != nil {
+        return nil, err
+    }
+    if int64(len(head)) <= limit {
+        value["data"] = base64.StdEncoding.EncodeToString(head)
+        return value, nil
+    }
+    name := part.Name
+    if name == "" {
+        name = fmt.Sprintf("%s-%d", part.Type, index)
+        if extensions, _ := mime.ExtensionsByType(part.MIMEType); len(extensions) > 0 {
+            name += extensions[0]
+        }
+    }
+    ref, err := c.UploadFile(ctx, name, part.MIMEType, io.MultiReader(bytes.NewReader(head), part.r))
+    if err != nil {
+        return nil, err
+    }
+    value["file_id"] = ref.ID
+    return value, nil
+}
@@ -0,0 +1,430 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+// FeatureConversationForks is advertised by agents that fork conversations
+// server-side on POST /conversations/{id}/fork.
+const FeatureConversationForks = "conversation_forks"
+
+var ErrUnknownConversation = errors.New("unknown conversation")
+
+// Turn is one message in a conversation.
+type Turn struct {
+    Role string `json:"role"`
+    Content string `json:"content"`
+    Function string `json:"function,omitempty"`
+}
+
+// ConversationBranch is a node of the conversation tree. A fork shares its
+// parent's first ForkedAt turns and owns the turns after them, so branching
+// never changes the parent's history.
+type ConversationBranch struct {
+    ID string `json:"id"`
+    Parent string `json:"parent,omitempty"`
+    ForkedAt int `json:"forked_at,omitempty"`
+    Turns []Turn `json:"turns"`
+    Created time.Time `json:"created"`
+}
+
+// ConversationTree is the client's local record of conversations and the
+// branches forked from them.
+type ConversationTree struct {
+    mu sync.Mutex
+    branches map[string]*ConversationBranch
+}
+
+// Conversations returns the client's conversation tree.
+func (c *Client) Conversations() *ConversationTree {
+    c.conversationsOnce.Do(func() {
+        c.conversations = &ConversationTree{branches: map[string]*ConversationBranch{}}
+    })
+    return c.conversations
+}
+
+// Append records turns on conversation id, starting it if it is new.
+func (t *ConversationTree) Append(id string, turns ...Turn) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    branch, ok := t.branches[id]
+    if !ok {
+        branch = &ConversationBranch{ID: id, Created: time.Now().UTC()}
+        t.branches[id] = branch
+    }
+    branch.Turns = append(branch.Turns, turns...)
+}
+
+// History returns id's full history, including the turns inherited from
+// the branches it was forked from.
+func (t *ConversationTree) History(id string) ([]Turn, error) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    return t.history(id)
+}
+
+func (t *ConversationTree) history(id string) ([]Turn, error) {
+    branch, ok := t.branches[id]
+    if !ok {
+        return nil, fmt.Errorf("%w: %s", ErrUnknownConversation, id)
+    }
+    var turns []Turn
+    if branch.Parent != "" {
+        inherited, err := t.history(branch.Parent)
+        if err != nil {
+            return nil, err
+        }
+        turns = append(turns, inherited[:branch.ForkedAt]...)
+    }
+    return append(turns, branch.Turns...), nil
+}
+
+func (t *ConversationTree) Branch(id string) (ConversationBranch, bool) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    branch, ok := t.branches[id]
+    if !ok {
+        return ConversationBranch{}, false
+    }
+    copied := *branch
+    copied.Turns = append([]Turn(nil), branch.Turns...)
+    return copied, true
+}
+
+// Children returns the IDs of the branches forked directly from id, oldest
+// first.
+func (t *ConversationTree) Children(id string) []string {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    var children []*ConversationBranch
+    for _, branch := range t.branches {
+        if branch.Parent == id {
+            children = append(children, branch)
+        }
+    }
+    sort.Slice(children, func(i, j int) bool { return children[i].Created.Before(children[j].Created) })
+    ids := make([]string, len(children))
+    for i, branch := range children {
+        ids[i] = branch.ID
+    }
+    return ids
+}
+
+// Lineage returns id and its ancestors, root last.
+func (t *ConversationTree) Lineage(id string) []string {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    var ids []string
+    for branch, ok := t.branches[id]; ok; branch, ok = t.branches[branch.Parent] {
+        ids = append(ids, branch.ID)
+    }
+    return ids
+}
+
+// Request builds a request continuing conversation id, sending its ID and
+// history as the "conversation_id" and "history" inputs.
+func (t *ConversationTree) Request(id, message string) (ChatRequest, error) {
+    history, err := t.History(id)
+    if err != nil {
+        return ChatRequest{}, err
+    }
+    inputs := map[string]any{"conversation_id": id}
+    if len(history) > 0 {
+        inputs["history"] = history
+    }
+    return ChatRequest{Message: message, Inputs: inputs}, nil
+}
+
+// remove drops branch id unless others were forked from it.
+func (t *ConversationTree) remove(id string) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    for _, branch := range t.branches {
+        if branch.Parent == id {
+            return
+        }
+    }
+    delete(t.branches, id)
+}
+
+// trim keeps only the last keep turns branch id owns, unless others were
+// forked from it, which would shift their fork points.
+func (t *ConversationTree) trim(id string, keep int) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    branch, ok := t.branches[id]
+    if !ok || len(branch.Turns) <= keep {
+        return
+    }
+    for _, other := range t.branches {
+        if other.Parent == id {
+            return
+        }
+    }
+    branch.Turns = append([]Turn(nil), branch.Turns[len(branch.Turns)-keep:]...)
+}
+
+type forkRequest struct {
+    AtTurn int `json:"at_turn"`
+}
+
+type forkResponse struct {
+    ConversationID string `json:"conversation_id"`
+}
+
+// ForkConversation branches conversation id after its first atTurn turns
+// and returns the new branch, leaving id untouched. Agents advertising
+// FeatureConversationForks fork their own session state and name the
+// branch; otherwise the fork is local and continues by resending history
+// (see ConversationTree.Request).
+func (c *Client) ForkConversation(ctx context.Context, id string, atTurn int) (*ConversationBranch, error) {
+    tree := c.Conversations()
+    history, err := tree.History(id)
+    if err != nil {
+        return nil, err
+    }
+    if atTurn < 0 || atTurn > len(history) {
+        return nil, fmt.Errorf("fork %s at turn %d: conversation has %d turns", id, atTurn, len(history))
+    }
+
+    var forkID string
+    if c.Supports(ctx, FeatureConversationForks) {
+        var resp forkResponse
+        if err := c.doJSON(ctx, http.MethodPost, "/conversations/"+url.PathEscape(id)+"/fork", forkRequest{AtTurn: atTurn}, &resp); err != nil {
+            return nil, err
+        }
+        forkID = resp.ConversationID
+    }
+    if forkID == "" {
+        if forkID, err = randomID("fork-"); err != nil {
+            return nil, err
+        }
+    }
+
+    branch := &ConversationBranch{ID: forkID, Parent: id, ForkedAt: atTurn, Created: time.Now().UTC()}
+    tree.mu.Lock()
+    defer tree.mu.Unlock()
+    if _, exists := tree.branches[forkID]; exists {
+        return nil, fmt.Errorf("fork %s: conversation %s already exists", id, forkID)
+    }
+    tree.branches[forkID] = branch
+    copied := *branch
+    return &copied, nil
+}
+
+func randomID(prefix string) (string, error) {
+    var raw [8]byte
+    if _, err := rand.Read(raw[:]); err != nil {
+        return "", err
+    }
+    return prefix + hex.EncodeToString(raw[:]), nil
+}
+
+// Conversation is a multi-turn chat over a Client, recorded as a branch of
+// the client's ConversationTree. Each Chat sends the conversation's prior
+// turns as the "history" input, trimmed to the last MaxTurns when it is
+// positive. Once the agent names its own conversation, as
+// Metadata["conversation_id"] or ["session_id"] on a reply, later calls
+// send that ID as the "conversation_id" input instead of the history.
+//
+// Calls on one Conversation are serialized so turns stay in order.
+// Limits, when set, block calls past them with a *ConversationLimitError.
+type Conversation struct {
+    MaxTurns int
+    Limits *ConversationLimits
+
+    client *Client
+    mu sync.Mutex
+    id string
+    sessionID string
+    usage conversationUsage
+}
+
+// NewConversation starts an empty conversation.
+func (c *Client) NewConversation() (*Conversation, error) {
+    id, err := randomID("conv-")
+    if err != nil {
+        return nil, err
+    }
+    c.Conversations().Append(id)
+    return &Conversation{client: c, id: id}, nil
+}
+
+// ResumeConversation continues branch id of the client's tree, e.g. one
+// returned by ForkConversation, starting it if it is new.
+func (c *Client) ResumeConversation(id string) *Conversation {
+    c.Conversations().Append(id)
+    return &Conversation{client: c, id: id}
+}
+
+// ID names the conversation's branch in the client's tree.
+func (v *Conversation) ID() string {
+    v.mu.Lock()
+    defer v.mu.Unlock()
+    return v.id
+}
+
+// SessionID is the agent's ID for the conversation, or "" until the agent
+// reports one.
+func (v *Conversation) SessionID() string {
+    v.mu.Lock()
+    defer v.mu.Unlock()
+    return v.sessionID
+}
+
+func (v *Conversation) Chat(ctx context.Context, request ChatRequest) (*ChatResponse, error) {
+    return v.exchange(ctx, request, v.client.Chat)
+}
+
+// ChatStream is Chat with the reply streamed to handle as it arrives; see
+// Client.ChatStream. The turn is recorded once the stream completes.
+func (v *Conversation) ChatStream(ctx context.Context, request ChatRequest, handle func(ChatChunk) error) (*ChatResponse, error) {
+    return v.exchange(ctx, request, func(ctx context.Context, wire ChatRequest) (*ChatResponse, error) {
+        return v.client.ChatStream(ctx, wire, handle)
+    })
+}
+
+// exchange sends request with the conversation's context through send and
+// records the turn.
+func (v *Conversation) exchange(ctx context.Context, request ChatRequest, send func(context.Context, ChatRequest) (*ChatResponse, error)) (*ChatResponse, error) {
+    v.mu.Lock()
+    defer v.mu.Unlock()
+    cost, err := v.checkLimits(ctx, request)
+    if err != nil {
+        return nil, err
+    }
+    tree := v.client.Conversations()
+    inputs := make(map[string]any, len(request.Inputs)+1)
+    for key, value := range request.Inputs {
+        inputs[key] = value
+    }
+    if v.sessionID != "" {
+        inputs["conversation_id"] = v.sessionID
+    } else {
+        history, err := tree.History(v.id)
+        if err != nil {
+            return nil, err
+        }
+        if v.MaxTurns > 0 && len(history) > v.MaxTurns {
+            history = history[len(history)-v.MaxTurns:]
+        }
+        if len(history) > 0 {
+            inputs["history"] = history
+        }
+    }
+    wire := request
+    wire.Inputs = inputs
+    resp, err := send(withConversation(ctx, v.id), wire)
+    v.chargeLimits(request, cost)
+    if err != nil {
+        return nil, err
+    }
+    for _, key := range []string{"conversation_id", "session_id"} {
+        if id, ok := resp.Metadata[key].(string); ok && id != "" {
+            v.sessionID = id
+            break
+        }
+    }
+    tree.Append(v.id, Turn{Role: "user", Content: request.Message}, Turn{Role: "assistant", Content: resp.Message, Function: resp.Function})
+    return resp, nil
+}
+
+// Send is Chat with a plain message.
+func (v *Conversation) Send(ctx context.Context, message string) (*ChatResponse, error) {
+    return v.Chat(ctx, ChatRequest{Message: message})
+}
+
+// History returns every turn so far, including those inherited from the
+// branch the conversation was forked from.
+func (v *Conversation) History() []Turn {
+    v.mu.Lock()
+    defer v.mu.Unlock()
+    history, _ := v.client.Conversations().History(v.id)
+    return history
+}
+
+// Reset forgets the history and the agent's session, so the next call
+// starts afresh. The old branch is dropped from the tree unless it was
+// forked from.
+func (v *Conversation) Reset() error {
+    v.mu.Lock()
+    defer v.mu.Unlock()
+    id, err := randomID("conv-")
+    if err != nil {
+        return err
+    }
+    tree := v.client.Conversations()
+    tree.remove(v.id)
+    tree.Append(id)
+    v.id, v.sessionID = id, ""
+    return nil
+}
+
+// Trim forgets all but the last keep turns of the conversation's own
+// history, for long-lived conversations that send no more than that
+// anyway. Branches that were forked from are left whole.
+func (v *Conversation) Trim(keep int) {
+    v.mu.Lock()
+    defer v.mu.Unlock()
+    v.client.Conversations().trim(v.id, keep)
+}
+
+// Close drops the conversation's branch from the tree unless it was forked
+// from. The conversation must not be used afterwards.
+func (v *Conversation) Close() {
+    v.mu.Lock()
+    defer v.mu.Unlock()
+    v.client.Conversations().remove(v.id)
+}
+
+// Fork branches the conversation after its first atTurn turns; see
+// ForkConversation. The fork starts without the agent's session.
+func (v *Conversation) Fork(ctx context.Context, atTurn int) (*Conversation, error) {
+    branch, err := v.client.ForkConversation(ctx, v.ID(), atTurn)
+    if err != nil {
+        return nil, err
+    }
+    return &Conversation{MaxTurns: v.MaxTurns, Limits: v.Limits, client: v.client, id: branch.ID}, nil
+}
+
+// Transcript is an exported conversation.
+type Transcript struct {
+    ID string `json:"id"`
+    SessionID string `json:"session_id,omitempty"`
+    Exported time.Time `json:"exported"`
+    Turns []Turn `json:"turns"`
+}
+
+func (v *Conversation) Transcript() Transcript {
+    history := v.History()
+    v.mu.Lock()
+    defer v.mu.Unlock()
+    return Transcript{ID: v.id, SessionID: v.sessionID, Exported: time.Now().UTC(), Turns: history}
+}
+
+// WriteText renders the transcript for people, one turn per paragraph,
+// with the function that answered each agent turn.
+func (t Transcript) WriteText(w io.Writer) error {
+    var b strings.Builder
+    fmt.Fprintf(&b, "Conversation %s, exported %s\n", t.ID, t.Exported.Format(time.RFC3339))
+    for _, turn := range t.Turns {
+        b.WriteString("\n" + turn.Role)
+        if turn.Function != "" {
+            b.WriteString(" (" + turn.Function + ")")
+        }
+        b.WriteString(": " + turn.Content + "\n")
+    }
+    _, err := io.WriteString(w, b.String())
+    return err
+}
@@ -0,0 +1,110 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "time"
+)
+
+var ErrConversationLimit = errors.New("conversation limit reached")
+
+const (
+    LimitTurns = "turns"
+    LimitExecutesPerHour = "executes_per_hour"
+    LimitCost = "cost"
+)
+
+// ConversationLimits stop a conversation, typically an automated loop,
+// from running away with the agent. Zero fields are unbounded. Limits
+// count the calls made through the conversation, failed ones included, as
+// a looping caller retries those too. MaxCost bounds the summed
+// estimated cost of its executing calls, as EstimateCall reports it, and
+// calls with no estimate are allowed. Counts survive Reset; a Fork starts
+// its own.
+type ConversationLimits struct {
+    MaxTurns int
+    MaxExecutesPerHour int
+    MaxCost float64
+    // OnExceeded, when set, is told of every call a limit blocks, e.g. to
+    // page someone or publish to a sink.
+    OnExceeded func(ctx context.Context, err *ConversationLimitError)
+}
+
+// ConversationLimitError is a call blocked by one of a conversation's
+// limits. It matches ErrConversationLimit.
+type ConversationLimitError struct {
+    Conversation string
+    Limit string
+    Max float64
+    Current float64
+}
+
+func (e *ConversationLimitError) Error() string {
+    return fmt.Sprintf("conversation %s: %s limit of %v reached (at %v)", e.Conversation, e.Limit, e.Max, e.Current)
+}
+
+func (e *ConversationLimitError) Unwrap() error { return ErrConversationLimit }
+
+// conversationUsage is what a conversation has used against its limits.
+type conversationUsage struct {
+    turns int
+    executes []time.Time
+    cost float64
+}
+
+// checkLimits reports whether request may be sent, and the estimated cost
+// to charge for it. Called with v.mu held.
+func (v *Conversation) checkLimits(ctx context.Context, request ChatRequest) (float64, error) {
+    limits := v.Limits
+    if limits == nil {
+        return 0, nil
+    }
+    blocked := func(limit string, max, current float64) (float64, error) {
+        err := &ConversationLimitError{Conversation: v.id, Limit: limit, Max: max, Current: current}
+        if limits.OnExceeded != nil {
+            limits.OnExceeded(ctx, err)
+        }
+        return 0, err
+    }
+    if limits.MaxTurns > 0 && v.usage.turns >= limits.MaxTurns {
+        return blocked(LimitTurns, float64(limits.MaxTurns), float64(v.usage.turns))
+    }
+    if !executes(v.client.withDefaultExecute(request)) {
+        return 0, nil
+    }
+    if limits.MaxExecutesPerHour > 0 {
+        cutoff := time.Now().Add(-time.Hour)
+        recent := v.usage.executes[:0]
+        for _, at := range v.usage.executes {
+            if at.After(cutoff) {
+                recent = append(recent, at)
+            }
+        }
+        v.usage.executes = recent
+        if len(recent) >= limits.MaxExecutesPerHour {
+            return blocked(LimitExecutesPerHour, float64(limits.MaxExecutesPerHour), float64(len(recent)))
+        }
+    }
+    if limits.MaxCost <= 0 {
+        return 0, nil
+    }
+    estimate, err := v.client.EstimateCall(ctx, request)
+    if err != nil {
+        return 0, err
+    }
+    if estimate.Known && v.usage.cost+estimate.Cost > limits.MaxCost {
+        return blocked(LimitCost, limits.MaxCost, v.usage.cost+estimate.Cost)
+    }
+    return estimate.Cost, nil
+}
+
+// chargeLimits counts a call checkLimits let through. Called with v.mu
+// held.
+func (v *Conversation) chargeLimits(request ChatRequest, cost float64) {
+    v.usage.turns++
+    if executes(v.client.withDefaultExecute(request)) {
+        v.usage.executes = append(v.usage.executes, time.Now())
+        v.usage.cost += cost
+    }
+}
@@ -0,0 +1,189 @@
+// Package daemon gives the long-running commands one way of handling
+// signals: SIGINT and SIGTERM shut the process down gracefully, draining
+// work in flight, and SIGHUP reloads configuration without a restart.
+package daemon
+
+import (
+    "context"
+    "errors"
+    "log"
+    "net/http"
+    "os"
+    "os/signal"
+    "sync"
+    "syscall"
+    "time"
+
+    client "echo_computer_agent_client"
+    "echo_computer_agent_client/systemd"
+)
+
+// DefaultDrainTimeout bounds shutdown when Daemon.DrainTimeout is unset.
+const DefaultDrainTimeout = 10 * time.Second
+
+// Daemon runs a component until it is signalled to stop. Shutdown hooks
+// run in the order they were added, so a server registered before the
+// client it calls stops taking requests before the client drains. A
+// second SIGINT or SIGTERM during shutdown cancels the hooks' context.
+type Daemon struct {
+    Name string
+    DrainTimeout time.Duration
+    mu sync.Mutex
+    reloads []func() error
+    shutdowns []func(context.Context) error
+}
+
+func New(name string) *Daemon {
+    return &Daemon{Name: name}
+}
+
+// OnReload adds fn to the hooks run on SIGHUP. A hook that fails is logged
+// and should leave the configuration it replaces in place.
+func (d *Daemon) OnReload(fn func() error) {
+    d.mu.Lock()
+    defer d.mu.Unlock()
+    d.reloads = append(d.reloads, fn)
+}
+
+// OnShutdown adds fn to the hooks run once the daemon is told to stop.
+func (d *Daemon) OnShutdown(fn func(context.Context) error) {
+    d.mu.Lock()
+    defer d.mu.Unlock()
+    d.shutdowns = append(d.shutdowns, fn)
+}
+
+// Serve shuts server down gracefully on stop and returns a run func for
+// Run that serves on its address.
+func (d *Daemon) Serve(server *http.Server) func(context.Context) error {
+    d.OnShutdown(server.Shutdown)
+    return func(context.Context) error {
+        return ignoreClosed(server.ListenAndServe())
+    }
+}
+
+// ServeTLS is Serve over TLS.
+func (d *Daemon) ServeTLS(server *http.Server, certFile, keyFile string) func(context.Context) error {
+    d.OnShutdown(server.Shutdown)
+    return func(context.Context) error {
+        return ignoreClosed(server.ListenAndServeTLS(certFile, keyFile))
+    }
+}
+
+// Drain drains c on stop; see client.Client.Drain.
+func (d *Daemon) Drain(c *client.Client) {
+    d.OnShutdown(c.Drain)
+}
+
+// Run calls run with a context that is cancelled when SIGINT or SIGTERM
+// arrives, or when parent is done, then runs the shutdown hooks and waits
+// for run to return. SIGHUP runs the reload hooks; without any it is
+// ignored rather than terminating the process. Under systemd, reloads are
+// reported with RELOADING=1 and READY=1. Run returns run's error, or the
+// first error from a shutdown hook.
+func (d *Daemon) Run(parent context.Context, run func(context.Context) error) error {
+    signals := make(chan os.Signal, 2)
+    signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+    defer signal.Stop(signals)
+
+    ctx, cancel := context.WithCancel(parent)
+    defer cancel()
+    done := make(chan error, 1)
+    go func() { done <- run(ctx) }()
+
+    var runErr error
+    running := true
+    for running {
+        select {
+        case sig := <-signals:
+            if sig == syscall.SIGHUP {
+                d.reload()
+                continue
+            }
+            d.logf("%s received, shutting down", sig)
+            running = false
+        case <-parent.Done():
+            running = false
+        case runErr = <-done:
+            done = nil
+            running = false
+        }
+    }
+    cancel()
+
+    drainCtx, cancelDrain := context.WithTimeout(context.Background(), d.drainTimeout())
+    defer cancelDrain()
+    go func() {
+        select {
+        case sig := <-signals:
+            d.logf("%s received, abandoning shutdown", sig)
+            cancelDrain()
+        case <-drainCtx.Done():
+        }
+    }()
+    shutdownErr := d.shutdown(drainCtx)
+    if done != nil {
+        select {
+        case runErr = <-done:
+        case <-drainCtx.Done():
+        }
+    }
+    if runErr != nil {
+        return runErr
+    }
+    return shutdownErr
+}
+
+func (d *Daemon) reload() {
+    d.mu.Lock()
+    hooks := append([]func() error(nil), d.reloads...)
+    d.mu.Unlock()
+    if len(hooks) == 0 {
+        return
+    }
+    systemd.Notify("RELOADING=1")
+    failed := false
+    for _, hook := range hooks {
+        if err := hook(); err != nil {
+            d.logf("reload: %v", err)
+            failed = true
+        }
+    }
+    if !failed {
+        d.logf("reloaded")
+    }
+    systemd.Notify("READY=1")
+}
+
+func (d *Daemon) shutdown(ctx context.Context) error {
+    d.mu.Lock()
+    hooks := append([]func(context.Context) error(nil), d.shutdowns...)
+    d.mu.Unlock()
+    var first error
+    for _, hook := range hooks {
+        if err := hook(ctx); err != nil && first == nil {
+            first = err
+        }
+    }
+    return first
+}
+
+func (d *Daemon) drainTimeout() time.Duration {
+    if d.DrainTimeout > 0 {
+        return d.DrainTimeout
+    }
+    return DefaultDrainTimeout
+}
+
+func (d *Daemon) logf(format string, args ...any) {
+    if d.Name != "" {
+        format = d.Name + ": " + format
+    }
+    log.Printf(format, args...)
+}
+
+func ignoreClosed(err error) error {
+    if errors.Is(err, http.ErrServerClosed) {
+        return nil
+    }
+    return err
+}
@@ -0,0 +1,332 @@
+package echo_computer_agent_client
+
+import (
+    "encoding/json"
+    "errors"
+    "html/template"
+    "net/http"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+// recentErrors bounds how many failures the debug view keeps.
+const recentErrors = 20
+
+// debugState is the bookkeeping behind DebugHandler.
+type debugState struct {
+    sync.Mutex
+    nextID int
+    inflight map[int]InflightRequest
+    endpoints map[string]*EndpointStats
+    errors []RecentError
+    cacheHits int
+    cacheMisses int
+}
+
+type InflightRequest struct {
+    Method string `json:"method"`
+    Endpoint string `json:"endpoint"`
+    Started time.Time `json:"started"`
+    Elapsed time.Duration `json:"elapsed_ns"`
+}
+
+// EndpointStats tallies the requests made to one endpoint; path segments
+// naming a function, job, or conversation are folded into "{id}".
+type EndpointStats struct {
+    Endpoint string `json:"endpoint"`
+    Requests int `json:"requests"`
+    Errors int `json:"errors"`
+    LastStatus int `json:"last_status,omitempty"`
+    LastError string `json:"last_error,omitempty"`
+    LastLatency time.Duration `json:"last_latency_ns"`
+    LastSeen time.Time `json:"last_seen"`
+}
+
+type RecentError struct {
+    Time time.Time `json:"time"`
+    Method string `json:"method"`
+    Endpoint string `json:"endpoint"`
+    Status int `json:"status,omitempty"`
+    Error string `json:"error"`
+}
+
+type CacheStats struct {
+    Enabled bool `json:"enabled"`
+    Hits int `json:"hits"`
+    Misses int `json:"misses"`
+}
+
+type FunctionLatency struct {
+    Function string `json:"function"`
+    Samples int `json:"samples"`
+    Mean time.Duration `json:"mean_ns"`
+    Max time.Duration `json:"max_ns"`
+}
+
+// DebugSnapshot is a point-in-time view of the client's internals.
+type DebugSnapshot struct {
+    Time time.Time `json:"time"`
+    BaseURL string `json:"base_url"`
+    APIVersion string `json:"api_version,omitempty"`
+    ThrottledUntil *time.Time `json:"throttled_until,omitempty"`
+    Circuit string `json:"circuit,omitempty"`
+    ClockSkew *time.Duration `json:"clock_skew_ns,omitempty"`
+    Capabilities *Capabilities `json:"capabilities,omitempty"`
+    CatalogSize int `json:"catalog_size"`
+    CatalogFetched *time.Time `json:"catalog_fetched,omitempty"`
+    Cache CacheStats `json:"cache"`
+    Inflight []InflightRequest `json:"inflight"`
+    Endpoints []EndpointStats `json:"endpoints"`
+    RecentErrors []RecentError `json:"recent_errors"`
+    Latency []FunctionLatency `json:"latency"`
+}
+
+// staticSegments are the path segments endpoint names keep as written.
+var staticSegments = map[string]bool{
+    "chat": true, "stream": true, "functions": true, "invoke": true, "execute": true,
+    "batch": true, "events": true, "jobs": true, "conversations": true, "fork": true,
+    "files": true, "audit": true, "usage": true, "records": true, "capabilities": true,
+    "health": true, "session": true, "connect": true, "embed": true,
+    "uploads": true, "chunks": true, "complete": true, "executions": true,
+    "memory": true, "query": true,
+}
+
+func endpointName(path string) string {
+    path, _, _ = strings.Cut(path, "?")
+    segments := strings.Split(strings.Trim(path, "/"), "/")
+    for i, segment := range segments {
+        if !staticSegments[segment] {
+            segments[i] = "{id}"
+        }
+    }
+    return "/" + strings.Join(segments, "/")
+}
+
+// track records an outgoing request; call the returned func with its
+// status (0 when none arrived) and error once it completes.
+func (c *Client) track(method, path string) func(status int, err error) {
+    endpoint := endpointName(path)
+    started := time.Now()
+    d := &c.debug
+    d.Lock()
+    if d.inflight == nil {
+        d.inflight = map[int]InflightRequest{}
+        d.endpoints = map[string]*EndpointStats{}
+    }
+    d.nextID++
+    id := d.nextID
+    d.inflight[id] = InflightRequest{Method: method, Endpoint: endpoint, Started: started}
+    d.Unlock()
+    metrics := c.metrics
+    if metrics != nil {
+        metrics.RequestStarted(method, endpoint)
+    }
+    return func(status int, err error) {
+        latency := time.Since(started)
+        if metrics != nil {
+            metrics.RequestFinished(method, endpoint, status, err, latency)
+        }
+        d.Lock()
+        defer d.Unlock()
+        delete(d.inflight, id)
+        stats, ok := d.endpoints[endpoint]
+        if !ok {
+            stats = &EndpointStats{Endpoint: endpoint}
+            d.endpoints[endpoint] = stats
+        }
+        stats.Requests++
+        stats.LastStatus, stats.LastLatency, stats.LastSeen = status, latency, time.Now()
+        stats.LastError = ""
+        if err == nil {
+            return
+        }
+        stats.Errors++
+        stats.LastError = err.Error()
+        d.errors = append(d.errors, RecentError{Time: time.Now(), Method: method, Endpoint: endpoint, Status: status, Error: err.Error()})
+        if len(d.errors) > recentErrors {
+            d.errors = d.errors[len(d.errors)-recentErrors:]
+        }
+    }
+}
+
+// do waits out the rate limit, checks the clock and circuit breaker,
+// authenticates req, and sends it through the interceptors and
+// transport, keeping the debug view's tallies.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+    if err := c.awaitRateLimit(req.Context()); err != nil {
+        return nil, err
+    }
+    if err := c.checkClock(); err != nil {
+        return nil, err
+    }
+    if err := c.allowRequest(); err != nil {
+        return nil, err
+    }
+    release, err := c.admit()
+    if err != nil {
+        return nil, err
+    }
+    path := strings.TrimPrefix(req.URL.String(), c.baseURL)
+    done := c.track(req.Method, path)
+    c.logRequest(req, endpointName(path))
+    started := time.Now()
+    resp, err := c.sendAuthenticated(req)
+    if err == nil {
+        c.observeClock(req, resp, started, time.Now())
+        c.noteRateLimit(resp.Header)
+        c.recordOutcome(resp.StatusCode, nil)
+    } else {
+        c.recordOutcome(0, err)
+    }
+    status := 0
+    switch {
+    case err != nil:
+        done(0, err)
+    case resp.StatusCode >= 400:
+        status = resp.StatusCode
+        done(status, errors.New(resp.Status))
+    default:
+        status = resp.StatusCode
+        done(status, nil)
+    }
+    c.logResponse(req, endpointName(path), status, err, time.Since(started))
+    if err != nil {
+        release()
+        return nil, err
+    }
+    // A request stays in flight until its body is closed, so streams count.
+    resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+    return resp, nil
+}
+
+func (c *Client) noteCacheLookup(hit bool) {
+    c.debug.Lock()
+    if hit {
+        c.debug.cacheHits++
+    } else {
+        c.debug.cacheMisses++
+    }
+    c.debug.Unlock()
+}
+
+func (c *Client) DebugSnapshot() DebugSnapshot {
+    now := time.Now()
+    snapshot := DebugSnapshot{Time: now, BaseURL: c.baseURL, APIVersion: c.NegotiatedAPIVersion()}
+
+    c.throttle.Lock()
+    if until := c.throttle.until; until.After(now) {
+        snapshot.ThrottledUntil = &until
+    }
+    c.throttle.Unlock()
+    c.breaker.Lock()
+    if c.breaker.config != nil {
+        snapshot.Circuit = c.breaker.state.String()
+    }
+    c.breaker.Unlock()
+    if skew, measured := c.ClockSkew(); !measured.IsZero() {
+        snapshot.ClockSkew = &skew
+    }
+    c.capabilities.Lock()
+    snapshot.Capabilities = c.capabilities.value
+    c.capabilities.Unlock()
+    c.catalog.Lock()
+    snapshot.CatalogSize = len(c.catalog.functions)
+    if fetched := c.catalog.fetched; !fetched.IsZero() {
+        snapshot.CatalogFetched = &fetched
+    }
+    c.catalog.Unlock()
+
+    c.debug.Lock()
+    snapshot.Cache = CacheStats{Enabled: c.responseCache != nil, Hits: c.debug.cacheHits, Misses: c.debug.cacheMisses}
+    snapshot.Inflight = make([]InflightRequest, 0, len(c.debug.inflight))
+    for _, request := range c.debug.inflight {
+        request.Elapsed = now.Sub(request.Started)
+        snapshot.Inflight = append(snapshot.Inflight, request)
+    }
+    snapshot.Endpoints = make([]EndpointStats, 0, len(c.debug.endpoints))
+    for _, stats := range c.debug.endpoints {
+        snapshot.Endpoints = append(snapshot.Endpoints, *stats)
+    }
+    snapshot.RecentErrors = append([]RecentError{}, c.debug.errors...)
+    c.debug.Unlock()
+    sort.Slice(snapshot.Inflight, func(i, j int) bool { return snapshot.Inflight[i].Started.Before(snapshot.Inflight[j].Started) })
+    sort.Slice(snapshot.Endpoints, func(i, j int) bool { return snapshot.Endpoints[i].Endpoint < snapshot.Endpoints[j].Endpoint })
+
+    c.latency.Lock()
+    snapshot.Latency = make([]FunctionLatency, 0, len(c.latency.samples))
+    for function, window := range c.latency.samples {
+        latency := FunctionLatency{Function: function, Samples: len(window)}
+        var total time.Duration
+        for _, d := range window {
+            total += d
+            if d > latency.Max {
+                latency.Max = d
+            }
+        }
+        if len(window) > 0 {
+            latency.Mean = total / time.Duration(len(window))
+        }
+        snapshot.Latency = append(snapshot.Latency, latency)
+    }
+    c.latency.Unlock()
+    sort.Slice(snapshot.Latency, func(i, j int) bool { return snapshot.Latency[i].Function < snapshot.Latency[j].Function })
+    return snapshot
+}
+
+// DebugHandler serves DebugSnapshot as a small HTML page, or as JSON to
+// requests that accept application/json or pass ?format=json. It exposes
+// endpoint paths and error text, so mount it on an internal listener.
+func (c *Client) DebugHandler() http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        snapshot := c.DebugSnapshot()
+        if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+            w.Header().Set("Content-Type", "application/json")
+            enc := json.NewEncoder(w)
+            enc.SetIndent("", "  ")
+            enc.Encode(snapshot)
+            return
+        }
+        w.Header().Set("Content-Type", "text/html; charset=utf-8")
+        debugPage.Execute(w, snapshot)
+    })
+}
+
+var debugPage = template.Must(template.New("debug").Funcs(template.FuncMap{
+    "ms": func(d time.Duration) string { return d.Round(time.Millisecond).String() },
+    "since": func(now time.Time, t *time.Time) string { return now.Sub(*t).Round(time.Second).String() },
+    "until": func(now time.Time, t *time.Time) string { return t.Sub(now).Round(time.Second).String() },
+}).Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><meta http-equiv="refresh" content="5"><title>Agent client</title>
+<style>
+body { font: 14px system-ui, sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+.bad { background: #fdecea; }
+</style></head><body>
+<h1>Agent client</h1>
+<p>{{.BaseURL}}{{with .APIVersion}}, API version {{.}}{{end}}.
+Catalog: {{.CatalogSize}} functions{{with .CatalogFetched}}, listed {{since $.Time .}} ago{{end}}.
+Response cache: {{if .Cache.Enabled}}{{.Cache.Hits}} hits, {{.Cache.Misses}} misses{{else}}off{{end}}.
+{{with .ClockSkew}}Clock skew: {{ms .}}.{{end}}
+{{with .Circuit}}Circuit breaker: {{if eq . "closed"}}{{.}}{{else}}<strong>{{.}}</strong>{{end}}.{{end}}
+{{with .ThrottledUntil}}<strong>Throttled by the agent for {{until $.Time .}} more.</strong>{{end}}</p>
+<h2>In flight</h2>
+<table><tr><th>Method</th><th>Endpoint</th><th>Elapsed</th></tr>
+{{range .Inflight}}<tr><td>{{.Method}}</td><td>{{.Endpoint}}</td><td>{{ms .Elapsed}}</td></tr>
+{{else}}<tr><td colspan="3">none</td></tr>{{end}}</table>
+<h2>Endpoints</h2>
+<table><tr><th>Endpoint</th><th>Requests</th><th>Errors</th><th>Last status</th><th>Last latency</th><th>Last error</th></tr>
+{{range .Endpoints}}<tr{{if .LastError}} class="bad"{{end}}><td>{{.Endpoint}}</td><td>{{.Requests}}</td><td>{{.Errors}}</td><td>{{.LastStatus}}</td><td>{{ms .LastLatency}}</td><td>{{.LastError}}</td></tr>
+{{end}}</table>
+<h2>Recent errors</h2>
+<table><tr><th>Time</th><th>Request</th><th>Status</th><th>Error</th></tr>
+{{range .RecentErrors}}<tr><td>{{.Time.Format "15:04:05"}}</td><td>{{.Method}} {{.Endpoint}}</td><td>{{.Status}}</td><td>{{.Error}}</td></tr>
+{{else}}<tr><td colspan="4">none</td></tr>{{end}}</table>
+<h2>Function latency</h2>
+<table><tr><th>Function</th><th>Samples</th><th>Mean</th><th>Max</th></tr>
+{{range .Latency}}<tr><td>{{.Function}}</td><td>{{.Samples}}</td><td>{{ms .Mean}}</td><td>{{ms .Max}}</td></tr>
+{{end}}</table>
+</body></html>
+`))
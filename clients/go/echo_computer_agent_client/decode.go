@@ -0,0 +1,148 @@
+package echo_computer_agent_client
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "reflect"
+    "strconv"
+    "strings"
+)
+
+// DecodeError explains why a response payload does not fit the caller's
+// type: required fields it lacks (dotted paths) and the first field whose
+// value has the wrong JSON type.
+type DecodeError struct {
+    Source string
+    Missing []string
+    Field string
+    Want string
+    Got string
+}
+
+func (e *DecodeError) Error() string {
+    var problems []string
+    if len(e.Missing) > 0 {
+        problems = append(problems, "missing "+strings.Join(e.Missing, ", "))
+    }
+    if e.Field != "" {
+        problems = append(problems, fmt.Sprintf("%s is %s, want %s", e.Field, e.Got, e.Want))
+    }
+    return "decode " + e.Source + ": " + strings.Join(problems, "; ")
+}
+
+// DecodeData decodes resp.Data into T using T's json tags. Struct fields
+// are required unless they are pointers or tagged omitempty; a missing
+// required field or a value of the wrong type fails with a *DecodeError
+// naming every offending field. Fields T does not declare are ignored, so
+// agents can add to their replies.
+func DecodeData[T any](resp *ChatResponse) (T, error) {
+    if resp == nil {
+        var zero T
+        return zero, errors.New("decode data: nil response")
+    }
+    return decodePayload[T]("data", resp.Data)
+}
+
+// DecodeMetadata decodes resp.Metadata into T like DecodeData.
+func DecodeMetadata[T any](resp *ChatResponse) (T, error) {
+    if resp == nil {
+        var zero T
+        return zero, errors.New("decode metadata: nil response")
+    }
+    return decodePayload[T]("metadata", resp.Metadata)
+}
+
+func decodePayload[T any](source string, payload map[string]any) (T, error) {
+    var out T
+    decodeErr := &DecodeError{Source: source, Missing: missingFields(reflect.TypeOf(out), payload, "")}
+    raw, err := json.Marshal(payload)
+    if err != nil {
+        return out, fmt.Errorf("decode %s: %w", source, err)
+    }
+    if err := json.Unmarshal(raw, &out); err != nil {
+        var typeErr *json.UnmarshalTypeError
+        if !errors.As(err, &typeErr) {
+            return out, fmt.Errorf("decode %s: %w", source, err)
+        }
+        decodeErr.Field, decodeErr.Want, decodeErr.Got = typeErr.Field, typeErr.Type.String(), typeErr.Value
+        if decodeErr.Field == "" {
+            decodeErr.Field = source
+        }
+    }
+    if len(decodeErr.Missing) > 0 || decodeErr.Field != "" {
+        return out, decodeErr
+    }
+    return out, nil
+}
+
+// missingFields lists the required fields of struct type t absent from
+// value, descending into nested structs and slices of them.
+func missingFields(t reflect.Type, value any, prefix string) []string {
+    for t != nil && t.Kind() == reflect.Pointer {
+        t = t.Elem()
+    }
+    if t == nil {
+        return nil
+    }
+    switch t.Kind() {
+    case reflect.Slice, reflect.Array:
+        items, _ := value.([]any)
+        var missing []string
+        for i, item := range items {
+            missing = append(missing, missingFields(t.Elem(), item, prefix+"."+strconv.Itoa(i))...)
+        }
+        return missing
+    case reflect.Struct:
+    default:
+        return nil
+    }
+    object, ok := value.(map[string]any)
+    if !ok && value != nil {
+        // A wrong type here is reported by the decoder instead.
+        return nil
+    }
+    var missing []string
+    for i := 0; i < t.NumField(); i++ {
+        field := t.Field(i)
+        if !field.IsExported() {
+            continue
+        }
+        name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+        if name == "-" && options == "" {
+            continue
+        }
+        if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+            missing = append(missing, missingFields(field.Type, value, prefix)...)
+            continue
+        }
+        if name == "" {
+            name = field.Name
+        }
+        path := strings.TrimPrefix(prefix+"."+name, ".")
+        fieldValue, present := lookupField(object, name)
+        optional := field.Type.Kind() == reflect.Pointer || strings.Contains(","+options+",", ",omitempty,")
+        if !present || fieldValue == nil {
+            if !optional {
+                missing = append(missing, path)
+            }
+            continue
+        }
+        missing = append(missing, missingFields(field.Type, fieldValue, path)...)
+    }
+    return missing
+}
+
+// lookupField matches keys the way encoding/json does: exactly, then
+// case-insensitively.
+func lookupField(object map[string]any, name string) (any, bool) {
+    if value, ok := object[name]; ok {
+        return value, true
+    }
+    for key, value := range object {
+        if strings.EqualFold(key, name) {
+            return value, true
+        }
+    }
+    return nil, false
+}
@@ -0,0 +1,106 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "sync"
+    "sync/atomic"
+    "time"
+)
+
+// Environment variables NewClientFromEnv reads.
+const (
+    EnvAgentURL = "ECHO_AGENT_URL"
+    EnvAgentToken = "ECHO_AGENT_TOKEN"
+    EnvAgentTimeout = "ECHO_AGENT_TIMEOUT"
+)
+
+// DefaultBaseURL is the agent NewClientFromEnv talks to when
+// $ECHO_AGENT_URL is unset.
+const DefaultBaseURL = "http://127.0.0.1:8000"
+
+// NewClientFromEnv builds a client for $ECHO_AGENT_URL, sending
+// $ECHO_AGENT_TOKEN as a bearer token when set and bounding requests by
+// $ECHO_AGENT_TIMEOUT, a duration such as "30s". opts are applied after.
+func NewClientFromEnv(opts ...Option) (*Client, error) {
+    baseURL := os.Getenv(EnvAgentURL)
+    if baseURL == "" {
+        baseURL = DefaultBaseURL
+    }
+    var envOpts []Option
+    if raw := os.Getenv(EnvAgentTimeout); raw != "" {
+        timeout, err := time.ParseDuration(raw)
+        if err != nil {
+            return nil, fmt.Errorf("$%s: %w", EnvAgentTimeout, err)
+        }
+        envOpts = append(envOpts, WithTimeout(timeout))
+    }
+    c := NewClientWithOptions(baseURL, append(envOpts, opts...)...)
+    if token := os.Getenv(EnvAgentToken); token != "" {
+        c.SetBearerToken(token)
+    }
+    return c, nil
+}
+
+var (
+    defaultClient atomic.Pointer[Client]
+    envOnce sync.Once
+    envClient *Client
+    envErr error
+)
+
+// SetDefault makes c the client the package-level functions use; nil
+// goes back to the one built from the environment. It may be called while
+// other goroutines use the default, but c should be configured first: a
+// Client's Set methods are not safe to call concurrently with its calls.
+func SetDefault(c *Client) {
+    defaultClient.Store(c)
+}
+
+// Default is the client set by SetDefault or, failing that, the one
+// NewClientFromEnv builds on first use.
+func Default() (*Client, error) {
+    if c := defaultClient.Load(); c != nil {
+        return c, nil
+    }
+    envOnce.Do(func() { envClient, envErr = NewClientFromEnv() })
+    return envClient, envErr
+}
+
+// Chat sends message with the default client, for scripts and small tools
+// that need no other configuration.
+func Chat(ctx context.Context, message string) (*ChatResponse, error) {
+    c, err := Default()
+    if err != nil {
+        return nil, err
+    }
+    return c.Chat(ctx, ChatRequest{Message: message})
+}
+
+// Plan asks the default client which function message would route to.
+func Plan(ctx context.Context, message string) (*ChatResponse, error) {
+    c, err := Default()
+    if err != nil {
+        return nil, err
+    }
+    return c.Plan(ctx, ChatRequest{Message: message})
+}
+
+// ExecuteFunction runs the named function with the default client.
+func ExecuteFunction(ctx context.Context, name string, inputs map[string]any, opts ...ExecOption) (*ExecutionResult, error) {
+    c, err := Default()
+    if err != nil {
+        return nil, err
+    }
+    return c.ExecuteFunction(ctx, name, inputs, opts...)
+}
+
+// ListFunctions lists the agent's catalog with the default client.
+func ListFunctions(ctx context.Context) (*FunctionListResponse, error) {
+    c, err := Default()
+    if err != nil {
+        return nil, err
+    }
+    return c.ListFunctions(ctx)
+}
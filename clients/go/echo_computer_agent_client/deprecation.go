@@ -0,0 +1,170 @@
+package echo_computer_agent_client
+
+import (
+    "fmt"
+    "io"
+    "net/http"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// DeprecationWarning reports an endpoint (from Deprecation/Sunset response
+// headers) or a catalog function (from its metadata) that is being
+// retired. Zero times mean the agent did not say.
+type DeprecationWarning struct {
+    Path string
+    Function string
+    Deprecated time.Time
+    Sunset time.Time
+    Replacement string
+    Link string
+    Message string
+}
+
+func (w DeprecationWarning) String() string {
+    subject := "endpoint " + w.Path
+    if w.Function != "" {
+        subject = "function " + w.Function
+    }
+    text := subject + " is deprecated"
+    if !w.Deprecated.IsZero() {
+        text += " since " + w.Deprecated.Format("2006-01-02")
+    }
+    if !w.Sunset.IsZero() {
+        text += " and will be removed on " + w.Sunset.Format("2006-01-02")
+    }
+    if w.Replacement != "" {
+        text += "; use " + w.Replacement + " instead"
+    }
+    if w.Message != "" {
+        text += ": " + w.Message
+    }
+    if w.Link != "" {
+        text += " (" + w.Link + ")"
+    }
+    return text
+}
+
+// SetDeprecationHandler registers a hook called once per deprecated
+// endpoint or function the client encounters.
+func (c *Client) SetDeprecationHandler(handler func(DeprecationWarning)) {
+    c.deprecations.Lock()
+    c.deprecations.handler = handler
+    c.deprecations.Unlock()
+}
+
+// WriteDeprecations returns a handler printing notices to w, typically
+// os.Stderr in command-line tools.
+func WriteDeprecations(w io.Writer) func(DeprecationWarning) {
+    return func(warning DeprecationWarning) {
+        fmt.Fprintf(w, "warning: %s\n", warning)
+    }
+}
+
+type deprecationState struct {
+    sync.Mutex
+    handler func(DeprecationWarning)
+    functions map[string]DeprecationWarning
+    warned map[string]bool
+}
+
+func (c *Client) warnDeprecated(key string, warning DeprecationWarning) {
+    c.deprecations.Lock()
+    handler := c.deprecations.handler
+    if handler == nil || c.deprecations.warned[key] {
+        c.deprecations.Unlock()
+        return
+    }
+    if c.deprecations.warned == nil {
+        c.deprecations.warned = map[string]bool{}
+    }
+    c.deprecations.warned[key] = true
+    c.deprecations.Unlock()
+    handler(warning)
+}
+
+// observeDeprecation inspects Deprecation (RFC 9745 "@unix", the earlier
+// "true", or an HTTP date), Sunset, and Link rel="deprecation" headers.
+func (c *Client) observeDeprecation(path string, header http.Header) {
+    deprecation, sunset := header.Get("Deprecation"), header.Get("Sunset")
+    if deprecation == "" && sunset == "" {
+        return
+    }
+    warning := DeprecationWarning{Path: path, Sunset: parseHTTPDate(sunset)}
+    if unix, ok := strings.CutPrefix(deprecation, "@"); ok {
+        if seconds, err := strconv.ParseInt(unix, 10, 64); err == nil {
+            warning.Deprecated = time.Unix(seconds, 0).UTC()
+        }
+    } else {
+        warning.Deprecated = parseHTTPDate(deprecation)
+    }
+    for _, link := range header.Values("Link") {
+        for _, part := range strings.Split(link, ",") {
+            if strings.Contains(part, `rel="deprecation"`) || strings.Contains(part, "rel=deprecation") {
+                target, _, _ := strings.Cut(strings.TrimSpace(part), ";")
+                warning.Link = strings.Trim(target, "<> ")
+            }
+        }
+    }
+    c.warnDeprecated("path "+path, warning)
+}
+
+// noteDeprecatedFunctions remembers catalog functions marked deprecated in
+// their metadata ("deprecated": true or a message, optional "sunset" and
+// "replacement") and warns about each.
+func (c *Client) noteDeprecatedFunctions(catalog *FunctionListResponse) {
+    functions := map[string]DeprecationWarning{}
+    for _, fn := range catalog.Functions {
+        var warning DeprecationWarning
+        switch flag := fn.Metadata["deprecated"].(type) {
+        case bool:
+            if !flag {
+                continue
+            }
+        case string:
+            warning.Message = flag
+        default:
+            continue
+        }
+        warning.Function = fn.Name
+        if sunset, ok := fn.Metadata["sunset"].(string); ok {
+            if t, err := time.Parse(time.RFC3339, sunset); err == nil {
+                warning.Sunset = t
+            } else if t, err := time.Parse("2006-01-02", sunset); err == nil {
+                warning.Sunset = t
+            }
+        }
+        warning.Replacement, _ = fn.Metadata["replacement"].(string)
+        functions[fn.Name] = warning
+    }
+    c.deprecations.Lock()
+    c.deprecations.functions = functions
+    c.deprecations.Unlock()
+    for name, warning := range functions {
+        c.warnDeprecated("function "+name, warning)
+    }
+}
+
+// checkDeprecatedFunction warns when a call routes to a function the last
+// catalog listing marked deprecated.
+func (c *Client) checkDeprecatedFunction(name string) {
+    c.deprecations.Lock()
+    warning, ok := c.deprecations.functions[name]
+    c.deprecations.Unlock()
+    if ok {
+        c.warnDeprecated("function "+name, warning)
+    }
+}
+
+func parseHTTPDate(value string) time.Time {
+    if value == "" {
+        return time.Time{}
+    }
+    t, err := http.ParseTime(value)
+    if err != nil {
+        return time.Time{}
+    }
+    return t.UTC()
+}
@@ -0,0 +1,80 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "errors"
+    "io"
+    "sync"
+)
+
+// ErrDraining is returned without contacting the agent once Drain has been
+// called.
+var ErrDraining = errors.New("client is draining")
+
+type drainState struct {
+    sync.Mutex
+    draining bool
+    active int
+    idle chan struct{}
+}
+
+// Drain stops the client sending new requests, which fail with
+// ErrDraining, and waits for those in flight to finish. It returns ctx's
+// error if they are still running when ctx is done. A call between
+// requests, such as one backing off before a retry, fails rather than
+// being waited for. Drain is meant for process shutdown; a drained client
+// stays drained.
+func (c *Client) Drain(ctx context.Context) error {
+    c.drain.Lock()
+    c.drain.draining = true
+    if c.drain.idle == nil {
+        c.drain.idle = make(chan struct{})
+        if c.drain.active == 0 {
+            close(c.drain.idle)
+        }
+    }
+    idle := c.drain.idle
+    c.drain.Unlock()
+    select {
+    case <-idle:
+        return nil
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+}
+
+// InFlight reports how many requests the client is waiting on.
+func (c *Client) InFlight() int {
+    c.drain.Lock()
+    defer c.drain.Unlock()
+    return c.drain.active
+}
+
+// admit counts a request in flight until the returned func is called.
+func (c *Client) admit() (func(), error) {
+    c.drain.Lock()
+    defer c.drain.Unlock()
+    if c.drain.draining {
+        return nil, ErrDraining
+    }
+    c.drain.active++
+    return func() {
+        c.drain.Lock()
+        defer c.drain.Unlock()
+        if c.drain.active--; c.drain.active == 0 && c.drain.idle != nil {
+            close(c.drain.idle)
+        }
+    }, nil
+}
+
+type releasingBody struct {
+    io.ReadCloser
+    once sync.Once
+    release func()
+}
+
+func (b *releasingBody) Close() error {
+    err := b.ReadCloser.Close()
+    b.once.Do(b.release)
+    return err
+}
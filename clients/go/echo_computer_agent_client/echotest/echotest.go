@@ -0,0 +1,584 @@
+// Package echotest runs an in-process fake agent for testing code built on
+// the client. A Server serves a programmable catalog, canned or computed
+// chat replies, and function results, can be made slow or failing per
+// endpoint, and records every request for assertions:
+//
+//	agent := echotest.NewServer(client.FunctionDescription{Name: "service.restart"})
+//	defer agent.Close()
+//	agent.ReplyTo("restart", client.ChatResponse{Function: "service.restart", Message: "restarted"})
+//	agent.FailNext("/chat", http.StatusServiceUnavailable, 1)
+//	... exercise code using agent.Client() ...
+//	agent.AssertChatted(t, "restart api")
+package echotest
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strconv"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    client "echo_computer_agent_client"
+)
+
+// FunctionHandler computes a function's output for InvokeFunction,
+// ExecuteFunction and SubmitExecution calls; an error is reported as the
+// function failing.
+type FunctionHandler func(inputs map[string]any) (map[string]any, error)
+
+// ChatHandler computes the reply to a chat request. An error answers with
+// a 500 carrying its text.
+type ChatHandler func(request client.ChatRequest) (*client.ChatResponse, error)
+
+// Request is one request the server received.
+type Request struct {
+    Method string
+    Path string
+    Header http.Header
+    Body []byte
+    Time time.Time
+}
+
+// Chat decodes the request as a chat request, for requests to /chat and
+// /chat/stream.
+func (r Request) Chat() (client.ChatRequest, bool) {
+    var request client.ChatRequest
+    if r.Path != "/chat" && r.Path != "/chat/stream" || json.Unmarshal(r.Body, &request) != nil {
+        return client.ChatRequest{}, false
+    }
+    return request, true
+}
+
+type chatRule struct {
+    contains string
+    handle ChatHandler
+}
+
+type latency struct {
+    prefix string
+    delay time.Duration
+}
+
+type fault struct {
+    prefix string
+    status int
+    remaining int
+}
+
+// Server is a fake agent listening on a loopback address; it embeds the
+// underlying httptest.Server for URL and Close.
+type Server struct {
+    *httptest.Server
+
+    mu sync.Mutex
+    functions []client.FunctionDescription
+    version string
+    features []string
+    rules []chatRule
+    handlers map[string]FunctionHandler
+    latencies []latency
+    faults []*fault
+    requests []Request
+    executions int
+    async map[string]*client.ExecutionResult
+    memories []client.Memory
+    memoryLag int
+}
+
+// NewServer starts a fake agent serving functions. It advertises only
+// streaming until SetFeatures says otherwise.
+func NewServer(functions ...client.FunctionDescription) *Server {
+    s := &Server{
+        functions: append([]client.FunctionDescription(nil), functions...),
+        version: "echotest",
+        features: []string{client.FeatureStreaming},
+        handlers: map[string]FunctionHandler{},
+        async: map[string]*client.ExecutionResult{},
+    }
+    s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+    return s
+}
+
+// Client returns a client for the server, configured by opts.
+func (s *Server) Client(opts ...client.Option) *client.Client {
+    return client.NewClientWithOptions(s.URL, opts...)
+}
+
+func (s *Server) SetFunctions(functions ...client.FunctionDescription) {
+    s.mu.Lock()
+    s.functions = append([]client.FunctionDescription(nil), functions...)
+    s.mu.Unlock()
+}
+
+// AddFunction adds fn to the catalog, replacing a function of its name.
+func (s *Server) AddFunction(fn client.FunctionDescription) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.removeFunction(fn.Name)
+    s.functions = append(s.functions, fn)
+}
+
+// SetFeatures sets the features /capabilities advertises.
+func (s *Server) SetFeatures(features ...string) {
+    s.mu.Lock()
+    s.features = append([]string{}, features...)
+    s.mu.Unlock()
+}
+
+// ReplyTo answers chat messages containing substring, case-insensitively,
+// with resp; an empty substring matches every message. Rules are tried in
+// the order added.
+func (s *Server) ReplyTo(substring string, resp client.ChatResponse) {
+    s.HandleChat(substring, func(client.ChatRequest) (*client.ChatResponse, error) {
+        reply := resp
+        return &reply, nil
+    })
+}
+
+// HandleChat computes the reply to chat messages containing substring;
+// see ReplyTo. Messages no rule matches are routed to the first function
+// whose name's words all appear in the message, or else the first
+// function, with the message "ok"; dry runs are answered the same way.
+func (s *Server) HandleChat(substring string, handle ChatHandler) {
+    s.mu.Lock()
+    s.rules = append(s.rules, chatRule{contains: strings.ToLower(substring), handle: handle})
+    s.mu.Unlock()
+}
+
+// HandleFunction computes name's output. Functions without a handler
+// return their inputs.
+func (s *Server) HandleFunction(name string, handle FunctionHandler) {
+    s.mu.Lock()
+    s.handlers[name] = handle
+    s.mu.Unlock()
+}
+
+// SetLatency delays responses to paths starting with prefix by delay;
+// the longest matching prefix applies, and "" matches every path.
+func (s *Server) SetLatency(prefix string, delay time.Duration) {
+    s.mu.Lock()
+    s.latencies = append(s.latencies, latency{prefix, delay})
+    s.mu.Unlock()
+}
+
+// FailNext answers the next times requests to paths starting with prefix
+// with status and a JSON error body; times of zero or less fails them
+// until Reset.
+func (s *Server) FailNext(prefix string, status, times int) {
+    s.mu.Lock()
+    s.faults = append(s.faults, &fault{prefix: prefix, status: status, remaining: times})
+    s.mu.Unlock()
+}
+
+// Reset forgets recorded requests, latencies and faults; the catalog and
+// handlers stay.
+func (s *Server) Reset() {
+    s.mu.Lock()
+    s.requests, s.latencies, s.faults = nil, nil, nil
+    s.mu.Unlock()
+}
+
+// Requests returns the requests received so far, oldest first.
+func (s *Server) Requests() []Request {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return append([]Request(nil), s.requests...)
+}
+
+// ChatRequests returns the chat requests received so far.
+func (s *Server) ChatRequests() []client.ChatRequest {
+    var chats []client.ChatRequest
+    for _, r := range s.Requests() {
+        if request, ok := r.Chat(); ok {
+            chats = append(chats, request)
+        }
+    }
+    return chats
+}
+
+// Count returns how many requests were made to method and path; an empty
+// method counts every method.
+func (s *Server) Count(method, path string) int {
+    n := 0
+    for _, r := range s.Requests() {
+        if r.Path == path && (method == "" || r.Method == method) {
+            n++
+        }
+    }
+    return n
+}
+
+func (s *Server) AssertRequested(t testing.TB, method, path string) {
+    t.Helper()
+    if s.Count(method, path) == 0 {
+        t.Errorf("echotest: no %s %s request; got %s", method, path, s.summary())
+    }
+}
+
+func (s *Server) AssertNotRequested(t testing.TB, method, path string) {
+    t.Helper()
+    if n := s.Count(method, path); n > 0 {
+        t.Errorf("echotest: %d unexpected %s %s requests", n, method, path)
+    }
+}
+
+func (s *Server) AssertRequestCount(t testing.TB, method, path string, want int) {
+    t.Helper()
+    if n := s.Count(method, path); n != want {
+        t.Errorf("echotest: %d %s %s requests, want %d", n, method, path, want)
+    }
+}
+
+// AssertChatted checks that some chat message contained substring.
+func (s *Server) AssertChatted(t testing.TB, substring string) {
+    t.Helper()
+    var messages []string
+    for _, request := range s.ChatRequests() {
+        if strings.Contains(request.Message, substring) {
+            return
+        }
+        messages = append(messages, fmt.Sprintf("%q", request.Message))
+    }
+    t.Errorf("echotest: no chat message contains %q; got [%s]", substring, strings.Join(messages, ", "))
+}
+
+func (s *Server) summary() string {
+    var lines []string
+    for _, r := range s.Requests() {
+        lines = append(lines, r.Method+" "+r.Path)
+    }
+    if len(lines) == 0 {
+        return "none"
+    }
+    return strings.Join(lines, ", ")
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+    body, _ := io.ReadAll(r.Body)
+    s.mu.Lock()
+    s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, Header: r.Header.Clone(), Body: body, Time: time.Now()})
+    delay, failure := s.injected(r.URL.Path)
+    s.mu.Unlock()
+
+    if delay > 0 {
+        select {
+        case <-time.After(delay):
+        case <-r.Context().Done():
+            return
+        }
+    }
+    if failure != 0 {
+        writeError(w, failure, "injected failure")
+        return
+    }
+    r.Body = io.NopCloser(bytes.NewReader(body))
+    segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+    switch {
+    case r.URL.Path == "/functions" && r.Method == http.MethodGet:
+        s.mu.Lock()
+        list := client.FunctionListResponse{Functions: append([]client.FunctionDescription{}, s.functions...)}
+        s.mu.Unlock()
+        writeJSON(w, list)
+    case r.URL.Path == "/functions" && r.Method == http.MethodPost:
+        var fn client.FunctionDescription
+        if json.Unmarshal(body, &fn) != nil || fn.Name == "" {
+            writeError(w, http.StatusBadRequest, "invalid function")
+            return
+        }
+        s.AddFunction(fn)
+        w.WriteHeader(http.StatusCreated)
+    case len(segments) == 2 && segments[0] == "functions" && r.Method == http.MethodDelete:
+        s.mu.Lock()
+        found := s.removeFunction(segments[1])
+        s.mu.Unlock()
+        if !found {
+            writeError(w, http.StatusNotFound, "unknown function")
+            return
+        }
+        w.WriteHeader(http.StatusNoContent)
+    case len(segments) == 3 && segments[0] == "functions" && (segments[2] == "invoke" || segments[2] == "execute"):
+        s.serveFunction(w, segments[1], segments[2], body)
+    case r.URL.Path == "/executions" && r.Method == http.MethodPost:
+        s.submitExecution(w, body)
+    case len(segments) == 2 && segments[0] == "executions" && r.Method == http.MethodGet:
+        s.mu.Lock()
+        result, ok := s.async[segments[1]]
+        var snapshot client.ExecutionResult
+        if ok {
+            snapshot = *result
+        }
+        s.mu.Unlock()
+        if !ok {
+            writeError(w, http.StatusNotFound, "unknown execution")
+            return
+        }
+        writeJSON(w, snapshot)
+    case r.URL.Path == "/memory" && r.Method == http.MethodPost:
+        s.storeMemory(w, body)
+    case r.URL.Path == "/memory/query" && r.Method == http.MethodPost:
+        s.queryMemory(w, body)
+    case r.URL.Path == "/chat":
+        resp, err := s.reply(body)
+        if err != nil {
+            writeError(w, http.StatusInternalServerError, err.Error())
+            return
+        }
+        writeJSON(w, resp)
+    case r.URL.Path == "/chat/stream":
+        s.serveStream(w, body)
+    case r.URL.Path == "/capabilities":
+        s.mu.Lock()
+        caps := client.Capabilities{Version: s.version, Features: append([]string{}, s.features...)}
+        s.mu.Unlock()
+        writeJSON(w, caps)
+    case r.URL.Path == "/health":
+        writeJSON(w, client.Health{Status: "ok", Version: s.version})
+    default:
+        writeError(w, http.StatusNotFound, "not found")
+    }
+}
+
+// injected is called with s.mu held.
+func (s *Server) injected(path string) (time.Duration, int) {
+    var delay time.Duration
+    longest := -1
+    for _, l := range s.latencies {
+        if strings.HasPrefix(path, l.prefix) && len(l.prefix) > longest {
+            delay, longest = l.delay, len(l.prefix)
+        }
+    }
+    for i, f := range s.faults {
+        if !strings.HasPrefix(path, f.prefix) {
+            continue
+        }
+        if f.remaining > 0 {
+            if f.remaining--; f.remaining == 0 {
+                s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+            }
+        }
+        return delay, f.status
+    }
+    return delay, 0
+}
+
+// removeFunction is called with s.mu held.
+func (s *Server) removeFunction(name string) bool {
+    for i, fn := range s.functions {
+        if fn.Name == name {
+            s.functions = append(s.functions[:i], s.functions[i+1:]...)
+            return true
+        }
+    }
+    return false
+}
+
+func (s *Server) reply(body []byte) (*client.ChatResponse, error) {
+    var request client.ChatRequest
+    if err := json.Unmarshal(body, &request); err != nil {
+        return nil, err
+    }
+    s.mu.Lock()
+    rules := append([]chatRule(nil), s.rules...)
+    functions := append([]client.FunctionDescription(nil), s.functions...)
+    s.mu.Unlock()
+    message := strings.ToLower(request.Message)
+    for _, rule := range rules {
+        if strings.Contains(message, rule.contains) {
+            return rule.handle(request)
+        }
+    }
+    return &client.ChatResponse{Function: route(message, functions), Message: "ok", Data: map[string]any{}, Metadata: map[string]any{}}, nil
+}
+
+func route(message string, functions []client.FunctionDescription) string {
+    for _, fn := range functions {
+        words := strings.FieldsFunc(strings.ToLower(fn.Name), func(r rune) bool { return r == '.' || r == '_' || r == '-' })
+        all := len(words) > 0
+        for _, word := range words {
+            if !strings.Contains(message, word) {
+                all = false
+                break
+            }
+        }
+        if all {
+            return fn.Name
+        }
+    }
+    if len(functions) > 0 {
+        return functions[0].Name
+    }
+    return ""
+}
+
+func (s *Server) serveFunction(w http.ResponseWriter, name, action string, body []byte) {
+    var payload struct {
+        Inputs map[string]any `json:"inputs"`
+    }
+    json.Unmarshal(body, &payload)
+    s.mu.Lock()
+    handle := s.handlers[name]
+    known := false
+    for _, fn := range s.functions {
+        known = known || fn.Name == name
+    }
+    s.mu.Unlock()
+    if !known && handle == nil {
+        writeError(w, http.StatusNotFound, "unknown function "+name)
+        return
+    }
+    output, err := payload.Inputs, error(nil)
+    if handle != nil {
+        output, err = handle(payload.Inputs)
+    }
+    if action == "execute" {
+        s.mu.Lock()
+        s.executions++
+        id := fmt.Sprintf("exec-%d", s.executions)
+        s.mu.Unlock()
+        result := client.ExecutionResult{ID: id, Function: name, Status: client.JobSucceeded, Output: output}
+        if err != nil {
+            result.Status, result.Output, result.Error = client.JobFailed, nil, err.Error()
+        }
+        writeJSON(w, result)
+        return
+    }
+    if err != nil {
+        writeError(w, http.StatusInternalServerError, err.Error())
+        return
+    }
+    writeJSON(w, client.ChatResponse{Function: name, Message: "ok", Data: output, Metadata: map[string]any{}})
+}
+
+// submitExecution runs the function in the background, reporting it
+// running until its handler returns. Agents only serve this when
+// SetFeatures includes client.FeatureAsyncExecution.
+func (s *Server) submitExecution(w http.ResponseWriter, body []byte) {
+    var payload struct {
+        Function string `json:"function"`
+        Inputs map[string]any `json:"inputs"`
+    }
+    json.Unmarshal(body, &payload)
+    s.mu.Lock()
+    handle := s.handlers[payload.Function]
+    known := false
+    for _, fn := range s.functions {
+        known = known || fn.Name == payload.Function
+    }
+    if !known && handle == nil {
+        s.mu.Unlock()
+        writeError(w, http.StatusNotFound, "unknown function "+payload.Function)
+        return
+    }
+    s.executions++
+    result := &client.ExecutionResult{ID: fmt.Sprintf("exec-%d", s.executions), Function: payload.Function, Status: client.JobRunning}
+    s.async[result.ID] = result
+    accepted := *result
+    s.mu.Unlock()
+    go func() {
+        start := time.Now()
+        output, err := payload.Inputs, error(nil)
+        if handle != nil {
+            output, err = handle(payload.Inputs)
+        }
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        result.Status, result.Output, result.Duration = client.JobSucceeded, output, time.Since(start)
+        if err != nil {
+            result.Status, result.Output, result.Error = client.JobFailed, nil, err.Error()
+        }
+    }()
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusAccepted)
+    json.NewEncoder(w).Encode(accepted)
+}
+
+// SetMemoryLag makes the next memory queries answer as a replica behind
+// by lag writes, catching up by one write per query.
+func (s *Server) SetMemoryLag(lag int) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.memoryLag = lag
+}
+
+func (s *Server) storeMemory(w http.ResponseWriter, body []byte) {
+    var memory client.Memory
+    if json.Unmarshal(body, &memory) != nil {
+        writeError(w, http.StatusBadRequest, "invalid memory")
+        return
+    }
+    s.mu.Lock()
+    if memory.ID == "" {
+        memory.ID = fmt.Sprintf("mem-%d", len(s.memories)+1)
+    }
+    s.memories = append(s.memories, memory)
+    write := client.MemoryWrite{ID: memory.ID, Token: strconv.Itoa(len(s.memories))}
+    s.mu.Unlock()
+    writeJSON(w, write)
+}
+
+// queryMemory matches memories containing the query text.
+func (s *Server) queryMemory(w http.ResponseWriter, body []byte) {
+    var query client.MemoryQuery
+    json.Unmarshal(body, &query)
+    s.mu.Lock()
+    visible := max(len(s.memories)-s.memoryLag, 0)
+    if s.memoryLag > 0 {
+        s.memoryLag--
+    }
+    result := client.MemoryResult{Matches: []client.MemoryMatch{}, Version: strconv.Itoa(visible)}
+    for _, memory := range s.memories[:visible] {
+        if memory.Namespace == query.Namespace && strings.Contains(strings.ToLower(memory.Text), strings.ToLower(query.Query)) {
+            result.Matches = append(result.Matches, client.MemoryMatch{Memory: memory, Score: 1})
+        }
+    }
+    s.mu.Unlock()
+    if query.Limit > 0 && len(result.Matches) > query.Limit {
+        result.Matches = result.Matches[:query.Limit]
+    }
+    writeJSON(w, result)
+}
+
+// serveStream streams the reply a word at a time.
+func (s *Server) serveStream(w http.ResponseWriter, body []byte) {
+    resp, err := s.reply(body)
+    if err != nil {
+        writeError(w, http.StatusInternalServerError, err.Error())
+        return
+    }
+    w.Header().Set("Content-Type", "text/event-stream")
+    flusher, _ := w.(http.Flusher)
+    id := 0
+    event := func(kind string, data any) {
+        encoded, _ := json.Marshal(data)
+        id++
+        fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, kind, encoded)
+        if flusher != nil {
+            flusher.Flush()
+        }
+    }
+    event("function_selected", client.FunctionSelected{Function: resp.Function})
+    words := strings.SplitAfter(resp.Message, " ")
+    for _, word := range words {
+        if word != "" {
+            event("text_delta", client.TextDelta{Text: word})
+        }
+    }
+    event("done", resp)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": http.StatusText(status), "message": message}})
+}
@@ -0,0 +1,213 @@
+package echo_computer_agent_client
+
+import (
+    "fmt"
+    "net/http"
+    "sort"
+    "time"
+)
+
+// EffectiveConfig is a client's configuration as resolved from its
+// options, setters and defaults: what it will actually do, rather than
+// what was asked of it. Header values and credentials the client's
+// Redactor names are replaced with "[REDACTED]"; hooks and middleware are
+// shown by type. Durations are in nanoseconds, as in DebugSnapshot.
+type EffectiveConfig struct {
+    BaseURL string `json:"base_url"`
+    Transport string `json:"transport"`
+    Timeout time.Duration `json:"timeout_ns"`
+    APIVersion string `json:"api_version,omitempty"`
+    Language string `json:"language,omitempty"`
+    DefaultExecute *bool `json:"default_execute,omitempty"`
+    Headers map[string]string `json:"headers"`
+    Auth AuthConfig `json:"auth"`
+    Retry RetryPolicy `json:"retry"`
+    ThrottleWait time.Duration `json:"throttle_wait_ns"`
+    RateLimit *RateLimitConfig `json:"rate_limit,omitempty"`
+    CircuitBreaker *BreakerConfig `json:"circuit_breaker,omitempty"`
+    ClockSkew ClockSkewConfig `json:"clock_skew"`
+    Guard *FunctionGuard `json:"guard,omitempty"`
+    Policy *Policy `json:"policy,omitempty"`
+    Confirmer string `json:"confirmer,omitempty"`
+    Budget *Budget `json:"budget,omitempty"`
+    InputValidation bool `json:"input_validation"`
+    InlineLimit int64 `json:"inline_limit"`
+    ResponseCache string `json:"response_cache,omitempty"`
+    ResponseTTL time.Duration `json:"response_ttl_ns,omitempty"`
+    SecretSchemes []string `json:"secret_schemes,omitempty"`
+    Experiments []string `json:"experiments,omitempty"`
+    AuditSink string `json:"audit_sink,omitempty"`
+    Metrics string `json:"metrics,omitempty"`
+    Logging bool `json:"logging"`
+    Redactor Redactor `json:"redactor"`
+    // Middleware lists the stages a call passes through, in order, from
+    // the caller to the wire and back; only stages that are configured
+    // appear, except the transport, which always does.
+    Middleware []string `json:"middleware"`
+}
+
+// AuthConfig describes how requests are authenticated. Mode is "none",
+// "header" (a static bearer token or API key) or "token_source".
+type AuthConfig struct {
+    Mode string `json:"mode"`
+    Header string `json:"header,omitempty"`
+    Value string `json:"value,omitempty"`
+    TokenSource string `json:"token_source,omitempty"`
+    Signer string `json:"signer,omitempty"`
+}
+
+type RateLimitConfig struct {
+    RPS float64 `json:"rps"`
+    Burst int `json:"burst"`
+    FailFast bool `json:"fail_fast"`
+}
+
+type BreakerConfig struct {
+    FailureThreshold int `json:"failure_threshold"`
+    ProbeInterval time.Duration `json:"probe_interval_ns"`
+}
+
+type ClockSkewConfig struct {
+    Threshold time.Duration `json:"threshold_ns"`
+    Strict bool `json:"strict"`
+}
+
+// EffectiveConfig reports the client's resolved configuration; see
+// EffectiveConfig. It is safe to call while the client is in use.
+func (c *Client) EffectiveConfig() EffectiveConfig {
+    redactor := c.logRedactor()
+    cfg := EffectiveConfig{
+        BaseURL: c.baseURL,
+        Transport: typeName(c.transport),
+        Timeout: c.timeout,
+        APIVersion: c.apiVersion,
+        Language: c.language,
+        DefaultExecute: c.defaultExecute,
+        Headers: map[string]string{},
+        Retry: c.retry,
+        ThrottleWait: c.throttleWait(),
+        Guard: c.guard,
+        Policy: c.policy,
+        Budget: c.budget,
+        InputValidation: c.validateInputs,
+        InlineLimit: c.contentInlineLimit(),
+        Logging: c.logger != nil,
+        Redactor: redactor,
+    }
+    if cfg.Retry.MaxAttempts == 0 {
+        cfg.Retry.MaxAttempts = 1
+    }
+    for name, value := range c.defaultHeaders {
+        if redactor.hides(name, redactor.Headers) {
+            value = redactedValue
+        }
+        cfg.Headers[http.CanonicalHeaderKey(name)] = value
+    }
+    if c.confirm != nil {
+        cfg.Confirmer = typeName(c.confirm)
+    }
+    if c.responseCache != nil {
+        cfg.ResponseCache, cfg.ResponseTTL = typeName(c.responseCache), c.responseTTL
+    }
+    for scheme := range c.secrets {
+        cfg.SecretSchemes = append(cfg.SecretSchemes, scheme)
+    }
+    sort.Strings(cfg.SecretSchemes)
+    for _, experiment := range c.experiments {
+        cfg.Experiments = append(cfg.Experiments, experiment.Name)
+    }
+    if c.auditSink != nil {
+        cfg.AuditSink = typeName(c.auditSink)
+    }
+    if c.metrics != nil {
+        cfg.Metrics = typeName(c.metrics)
+    }
+
+    c.auth.mu.Lock()
+    switch {
+    case c.auth.source != nil:
+        cfg.Auth = AuthConfig{Mode: "token_source", TokenSource: typeName(c.auth.source)}
+    case c.auth.header != "":
+        cfg.Auth = AuthConfig{Mode: "header", Header: c.auth.header, Value: redactedValue}
+    default:
+        cfg.Auth = AuthConfig{Mode: "none"}
+    }
+    c.auth.mu.Unlock()
+    c.signing.Lock()
+    if c.signing.signer != nil {
+        cfg.Auth.Signer = typeName(c.signing.signer)
+    }
+    c.signing.Unlock()
+
+    c.rateLimit.Lock()
+    if c.rateLimit.bucket != nil {
+        cfg.RateLimit = &RateLimitConfig{RPS: c.rateLimit.bucket.rate, Burst: c.rateLimit.burst, FailFast: c.rateLimit.failFast}
+    }
+    c.rateLimit.Unlock()
+    c.breaker.Lock()
+    if breaker := c.breaker.config; breaker != nil {
+        cfg.CircuitBreaker = &BreakerConfig{FailureThreshold: breaker.FailureThreshold, ProbeInterval: breaker.ProbeInterval}
+    }
+    c.breaker.Unlock()
+    if cfg.CircuitBreaker != nil {
+        if cfg.CircuitBreaker.FailureThreshold <= 0 {
+            cfg.CircuitBreaker.FailureThreshold = DefaultFailureThreshold
+        }
+        if cfg.CircuitBreaker.ProbeInterval <= 0 {
+            cfg.CircuitBreaker.ProbeInterval = DefaultProbeInterval
+        }
+    }
+    c.clock.Lock()
+    cfg.ClockSkew = ClockSkewConfig{Threshold: c.clock.policy.Threshold, Strict: c.clock.policy.Strict}
+    c.clock.Unlock()
+    if cfg.ClockSkew.Threshold <= 0 {
+        cfg.ClockSkew.Threshold = DefaultClockSkewThreshold
+    }
+
+    cfg.Middleware = c.middleware(cfg)
+    return cfg
+}
+
+// middleware lists the stages in the order chat, sendJSON and do run
+// them.
+func (c *Client) middleware(cfg EffectiveConfig) []string {
+    var stages []string
+    add := func(stage string, on bool) {
+        if on {
+            stages = append(stages, stage)
+        }
+    }
+    add("experiments", len(c.experiments) > 0)
+    add("input validation", c.validateInputs)
+    add("function guard", c.guard != nil)
+    add("policy", c.policy != nil)
+    add("budget", c.budget != nil)
+    add("response cache", c.responseCache != nil)
+    for _, filter := range c.requestFilters {
+        stages = append(stages, "request filter "+typeName(filter))
+    }
+    add("secret resolution", len(c.secrets) > 0)
+    add("retries", cfg.Retry.MaxAttempts > 1)
+    add("throttle wait", cfg.ThrottleWait > 0)
+    add("rate limit", cfg.RateLimit != nil)
+    add("clock skew check", cfg.ClockSkew.Strict)
+    add("circuit breaker", cfg.CircuitBreaker != nil)
+    add("logging", cfg.Logging)
+    add("authentication ("+cfg.Auth.Mode+")", cfg.Auth.Mode != "none")
+    add("request signing", cfg.Auth.Signer != "")
+    for _, interceptor := range c.interceptors {
+        stages = append(stages, "interceptor "+typeName(interceptor))
+    }
+    stages = append(stages, "transport "+cfg.Transport)
+    for _, filter := range c.responseFilters {
+        stages = append(stages, "response filter "+typeName(filter))
+    }
+    for _, processor := range c.responseProcessors {
+        stages = append(stages, "response processor "+typeName(processor))
+    }
+    return stages
+}
+
+func typeName(v any) string {
+    return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,128 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sort"
+    "sync"
+    "time"
+)
+
+var ErrOverBudget = errors.New("estimated call exceeds budget")
+
+// Estimate predicts the duration and cost of calling Function. Duration
+// comes from latency the client has observed once it has enough samples,
+// and from the function's "estimated_duration_ms" metadata before that;
+// Cost comes from "estimated_cost" (in "cost_currency"). Known is false
+// when neither source says anything.
+type Estimate struct {
+    Function string
+    Duration time.Duration
+    P95 time.Duration
+    Samples int
+    Source string
+    Cost float64
+    Currency string
+    Known bool
+}
+
+// Budget bounds executing calls by their estimate; zero fields are
+// unbounded.
+type Budget struct {
+    MaxDuration time.Duration `json:"max_duration_ns,omitempty"`
+    MaxCost float64 `json:"max_cost,omitempty"`
+}
+
+// SetBudget makes executing calls fail with ErrOverBudget when their
+// estimate exceeds budget. Calls with no estimate are allowed.
+func (c *Client) SetBudget(budget *Budget) {
+    c.budget = budget
+}
+
+const (
+    latencyWindow = 50
+    minLatencySamples = 5
+)
+
+type latencyStats struct {
+    sync.Mutex
+    samples map[string][]time.Duration
+}
+
+func (c *Client) observeLatency(function string, d time.Duration) {
+    if function == "" {
+        return
+    }
+    c.latency.Lock()
+    defer c.latency.Unlock()
+    if c.latency.samples == nil {
+        c.latency.samples = map[string][]time.Duration{}
+    }
+    window := append(c.latency.samples[function], d)
+    if len(window) > latencyWindow {
+        window = window[len(window)-latencyWindow:]
+    }
+    c.latency.samples[function] = window
+}
+
+// EstimateCall plans request to learn which function it routes to and
+// estimates that function.
+func (c *Client) EstimateCall(ctx context.Context, request ChatRequest) (Estimate, error) {
+    plan, err := c.Plan(ctx, request)
+    if err != nil {
+        return Estimate{}, err
+    }
+    return c.EstimateFunction(ctx, plan.Function)
+}
+
+func (c *Client) EstimateFunction(ctx context.Context, name string) (Estimate, error) {
+    estimate := Estimate{Function: name}
+    functions, err := c.Functions(ctx)
+    if err != nil {
+        return estimate, err
+    }
+    if fn, ok := functions.ByName(name); ok {
+        if ms, ok := number(fn.Metadata["estimated_duration_ms"]); ok {
+            estimate.Duration, estimate.P95 = time.Duration(ms*float64(time.Millisecond)), time.Duration(ms*float64(time.Millisecond))
+            estimate.Source, estimate.Known = "metadata", true
+        }
+        if cost, ok := number(fn.Metadata["estimated_cost"]); ok {
+            estimate.Cost, estimate.Known = cost, true
+            estimate.Currency, _ = fn.Metadata["cost_currency"].(string)
+        }
+    }
+
+    c.latency.Lock()
+    window := append([]time.Duration(nil), c.latency.samples[name]...)
+    c.latency.Unlock()
+    estimate.Samples = len(window)
+    if len(window) >= minLatencySamples || (len(window) > 0 && estimate.Source == "") {
+        var total time.Duration
+        for _, d := range window {
+            total += d
+        }
+        sort.Slice(window, func(i, j int) bool { return window[i] < window[j] })
+        estimate.Duration = total / time.Duration(len(window))
+        estimate.P95 = window[(len(window)*95-1)/100]
+        estimate.Source, estimate.Known = "observed", true
+    }
+    return estimate, nil
+}
+
+func (c *Client) checkBudget(ctx context.Context, function string) error {
+    if c.budget == nil {
+        return nil
+    }
+    estimate, err := c.EstimateFunction(ctx, function)
+    if err != nil || !estimate.Known {
+        return err
+    }
+    if c.budget.MaxDuration > 0 && estimate.Duration > c.budget.MaxDuration {
+        return fmt.Errorf("%w: %s takes about %s (budget %s)", ErrOverBudget, function, estimate.Duration.Round(time.Millisecond), c.budget.MaxDuration)
+    }
+    if c.budget.MaxCost > 0 && estimate.Cost > c.budget.MaxCost {
+        return fmt.Errorf("%w: %s costs about %g %s (budget %g)", ErrOverBudget, function, estimate.Cost, estimate.Currency, c.budget.MaxCost)
+    }
+    return nil
+}
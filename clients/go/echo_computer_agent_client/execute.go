@@ -0,0 +1,36 @@
+package echo_computer_agent_client
+
+// DryRun returns a copy of r that asks the agent to route without
+// executing.
+func (r ChatRequest) DryRun() ChatRequest {
+    execute := false
+    r.Execute = &execute
+    return r
+}
+
+// AutoExecute returns a copy of r that asks the agent to execute the
+// function it routes to.
+func (r ChatRequest) AutoExecute() ChatRequest {
+    execute := true
+    r.Execute = &execute
+    return r
+}
+
+// SetDefaultExecute decides what Chat does with requests that leave
+// Execute unset. Without it the field is omitted and the agent's own
+// default (dry run) applies. A client with a policy attached never
+// executes by default: requests must opt in with AutoExecute.
+func (c *Client) SetDefaultExecute(execute bool) {
+    c.defaultExecute = &execute
+}
+
+// withDefaultExecute fills in an unset Execute from the client default.
+func (c *Client) withDefaultExecute(request ChatRequest) ChatRequest {
+    if request.Execute != nil || c.defaultExecute == nil {
+        return request
+    }
+    if c.policy != nil || !*c.defaultExecute {
+        return request.DryRun()
+    }
+    return request.AutoExecute()
+}
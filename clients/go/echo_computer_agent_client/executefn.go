@@ -0,0 +1,160 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/url"
+    "time"
+)
+
+// HeaderIdempotencyKey lets the agent recognise a repeated execution.
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// ExecutionResult is the outcome of ExecuteFunction. Status is
+// JobSucceeded or JobFailed; a failed execution carries the agent's
+// explanation in Error. Duration is the agent's measurement when it
+// reports one, otherwise the round trip.
+type ExecutionResult struct {
+    ID string `json:"id,omitempty"`
+    Function string `json:"function"`
+    Status string `json:"status"`
+    Output map[string]any `json:"output,omitempty"`
+    Logs []string `json:"logs,omitempty"`
+    Error string `json:"error,omitempty"`
+    Duration time.Duration `json:"-"`
+}
+
+func (r ExecutionResult) MarshalJSON() ([]byte, error) {
+    type plain ExecutionResult
+    return json.Marshal(struct {
+        plain
+        DurationMS float64 `json:"duration_ms,omitempty"`
+    }{plain(r), float64(r.Duration) / float64(time.Millisecond)})
+}
+
+func (r *ExecutionResult) UnmarshalJSON(data []byte) error {
+    type plain ExecutionResult
+    var wire struct {
+        plain
+        DurationMS float64 `json:"duration_ms"`
+    }
+    if err := json.Unmarshal(data, &wire); err != nil {
+        return err
+    }
+    *r = ExecutionResult(wire.plain)
+    r.Duration = time.Duration(wire.DurationMS * float64(time.Millisecond))
+    return nil
+}
+
+// Err returns the failure of an execution that did not succeed.
+func (r *ExecutionResult) Err() error {
+    if r.Status == JobSucceeded {
+        return nil
+    }
+    if r.Error == "" {
+        return fmt.Errorf("execute %s: %s", r.Function, r.Status)
+    }
+    return fmt.Errorf("execute %s: %s: %s", r.Function, r.Status, r.Error)
+}
+
+// ExecOption adjusts one ExecuteFunction call.
+type ExecOption func(*execSettings)
+
+type execSettings struct {
+    idempotencyKey string
+    timeout time.Duration
+}
+
+// WithIdempotencyKey sends key so the agent runs the execution at most
+// once, which also lets the client retry it under its RetryPolicy.
+func WithIdempotencyKey(key string) ExecOption {
+    return func(s *execSettings) { s.idempotencyKey = key }
+}
+
+// WithExecTimeout asks the agent to abandon the execution after timeout
+// and bounds the call by the same.
+func WithExecTimeout(timeout time.Duration) ExecOption {
+    return func(s *execSettings) { s.timeout = timeout }
+}
+
+type executeRequest struct {
+    Inputs map[string]any `json:"inputs,omitempty"`
+    TimeoutSeconds float64 `json:"timeout_seconds,omitempty"`
+}
+
+type idempotencyKey struct{}
+
+// ExecuteFunction runs the named function on the agent's
+// /functions/{name}/execute route, bypassing natural-language routing. It
+// is authorized and audited like InvokeFunction. A call the agent ran is
+// returned without error even when the execution failed; see
+// ExecutionResult.Err.
+func (c *Client) ExecuteFunction(ctx context.Context, name string, inputs map[string]any, opts ...ExecOption) (*ExecutionResult, error) {
+    var settings execSettings
+    for _, opt := range opts {
+        opt(&settings)
+    }
+    request, wire, err := c.checkExecution(ctx, name, inputs)
+    if err != nil {
+        return nil, err
+    }
+    body := executeRequest{Inputs: wire, TimeoutSeconds: settings.timeout.Seconds()}
+    callCtx := ctx
+    if settings.timeout > 0 {
+        var cancel context.CancelFunc
+        callCtx, cancel = context.WithTimeout(ctx, settings.timeout)
+        defer cancel()
+    }
+    if settings.idempotencyKey != "" {
+        callCtx = withIdempotent(context.WithValue(callCtx, idempotencyKey{}, settings.idempotencyKey))
+    }
+
+    var result ExecutionResult
+    start := time.Now()
+    err = c.doJSON(callCtx, http.MethodPost, "/functions/"+url.PathEscape(name)+"/execute", body, &result)
+    if err == nil {
+        if result.Function == "" {
+            result.Function = name
+        }
+        if result.Duration == 0 {
+            result.Duration = time.Since(start)
+        }
+        err = c.filterExecution(ctx, &result)
+    }
+    if err != nil {
+        c.audit(ctx, name, request, nil, err)
+        return nil, err
+    }
+    c.observeLatency(name, result.Duration)
+    c.audit(ctx, name, request, result.chatResponse(), result.Err())
+    c.checkDeprecatedFunction(name)
+    return &result, nil
+}
+
+// chatResponse is the view of r that response filters and audit records
+// work on; logs travel in Data so redaction reaches them.
+func (r *ExecutionResult) chatResponse() *ChatResponse {
+    logs := make([]any, len(r.Logs))
+    for i, line := range r.Logs {
+        logs[i] = line
+    }
+    return &ChatResponse{Function: r.Function, Message: r.Error, Data: map[string]any{"output": r.Output, "logs": logs}, Metadata: map[string]any{"status": r.Status}}
+}
+
+func (c *Client) filterExecution(ctx context.Context, r *ExecutionResult) error {
+    // Processors expect chat replies, so only the filters see this view.
+    view := r.chatResponse()
+    if err := c.runResponseFilters(ctx, view); err != nil {
+        return err
+    }
+    r.Error = view.Message
+    r.Output, _ = view.Data["output"].(map[string]any)
+    logs, _ := view.Data["logs"].([]any)
+    r.Logs = r.Logs[:0]
+    for _, line := range logs {
+        r.Logs = append(r.Logs, fmt.Sprint(line))
+    }
+    return nil
+}
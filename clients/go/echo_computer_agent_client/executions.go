@@ -0,0 +1,172 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "net/http"
+    "net/url"
+    "strconv"
+    "time"
+)
+
+// FeatureAsyncExecution is advertised by agents that accept executions
+// under /executions to run in the background.
+const FeatureAsyncExecution = "async_execution"
+
+type executionSubmission struct {
+    Function string `json:"function"`
+    Inputs map[string]any `json:"inputs,omitempty"`
+    TimeoutSeconds float64 `json:"timeout_seconds,omitempty"`
+}
+
+// SubmitExecution queues the named function on the agent's /executions
+// endpoint and returns the execution's id without waiting for it, for
+// functions that run longer than a request can stay open. It is
+// authorized and audited like ExecuteFunction; WithExecTimeout asks the
+// agent to abandon the execution after the timeout.
+//
+// Agents known not to support FeatureAsyncExecution run the function with
+// ExecuteFunction instead; the id returned is then for a finished
+// execution that the first GetExecution answers for locally.
+func (c *Client) SubmitExecution(ctx context.Context, name string, inputs map[string]any, opts ...ExecOption) (string, error) {
+    if caps, err := c.Capabilities(ctx); err == nil && caps.lacks(FeatureAsyncExecution) {
+        result, err := c.ExecuteFunction(ctx, name, inputs, opts...)
+        if err != nil {
+            return "", err
+        }
+        return c.localJobs.addExecution(result), nil
+    }
+    var settings execSettings
+    for _, opt := range opts {
+        opt(&settings)
+    }
+    request, wire, err := c.checkExecution(ctx, name, inputs)
+    if err != nil {
+        return "", err
+    }
+    callCtx := ctx
+    if settings.idempotencyKey != "" {
+        callCtx = withIdempotent(context.WithValue(ctx, idempotencyKey{}, settings.idempotencyKey))
+    }
+    var result ExecutionResult
+    err = c.doJSON(callCtx, http.MethodPost, "/executions", executionSubmission{Function: name, Inputs: wire, TimeoutSeconds: settings.timeout.Seconds()}, &result)
+    c.audit(ctx, name, request, nil, err)
+    if err != nil {
+        return "", err
+    }
+    return result.ID, nil
+}
+
+// GetExecution reports the status of an execution started with
+// SubmitExecution, with its output and logs once it has finished.
+func (c *Client) GetExecution(ctx context.Context, id string) (*ExecutionResult, error) {
+    if result, ok := c.localJobs.execution(id); ok {
+        return result, nil
+    }
+    var result ExecutionResult
+    if err := c.doJSON(ctx, http.MethodGet, "/executions/"+url.PathEscape(id), nil, &result); err != nil {
+        return nil, err
+    }
+    if result.ID == "" {
+        result.ID = id
+    }
+    if err := c.filterExecution(ctx, &result); err != nil {
+        return nil, err
+    }
+    return &result, nil
+}
+
+// Done reports whether the execution has finished, successfully or not.
+func (r *ExecutionResult) Done() bool {
+    switch r.Status {
+    case JobSucceeded, JobFailed, JobCancelled:
+        return true
+    }
+    return false
+}
+
+// PollOptions pace WaitForExecution. Polls start Interval apart, 500ms by
+// default, and the gap grows by half each time up to MaxInterval, 10s by
+// default. Timeout, when positive, bounds the wait as well as ctx.
+type PollOptions struct {
+    Interval time.Duration
+    MaxInterval time.Duration
+    Timeout time.Duration
+}
+
+// WaitForExecution polls GetExecution until the execution finishes and
+// returns its result, which carries any failure of the function itself;
+// see ExecutionResult.Err. A transient polling failure is tried again at
+// the next poll. It fails with ctx's error once ctx is done or the
+// timeout has passed.
+func (c *Client) WaitForExecution(ctx context.Context, id string, opts PollOptions) (*ExecutionResult, error) {
+    if opts.Timeout > 0 {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+        defer cancel()
+    }
+    interval, limit := opts.Interval, opts.MaxInterval
+    if interval <= 0 {
+        interval = 500 * time.Millisecond
+    }
+    if limit <= 0 {
+        limit = 10 * time.Second
+    }
+    for {
+        result, err := c.GetExecution(ctx, id)
+        switch {
+        case err == nil && result.Done():
+            return result, nil
+        case err != nil && (ctx.Err() != nil || !transient(err)):
+            return nil, err
+        }
+        if err := sleepCtx(ctx, interval); err != nil {
+            return nil, err
+        }
+        interval = min(interval+interval/2, limit)
+    }
+}
+
+// checkExecution validates and authorizes a direct execution of name,
+// auditing refusals. It returns the request to audit and the inputs to
+// send, with secrets resolved.
+func (c *Client) checkExecution(ctx context.Context, name string, inputs map[string]any) (ChatRequest, map[string]any, error) {
+    request := ChatRequest{Message: name, Inputs: inputs}
+    if err := c.checkInputs(ctx, name, inputs, false); err != nil {
+        c.audit(ctx, name, request, nil, err)
+        return request, nil, err
+    }
+    if err := c.authorize(ctx, name, request); err != nil {
+        c.audit(ctx, name, request, nil, err)
+        return request, nil, err
+    }
+    wire := request
+    if err := c.prepare(ctx, &wire); err != nil {
+        return request, nil, err
+    }
+    return request, wire.Inputs, nil
+}
+
+func (s *localJobStore) addExecution(result *ExecutionResult) string {
+    s.Lock()
+    defer s.Unlock()
+    if s.executions == nil {
+        s.executions = map[string]*ExecutionResult{}
+    }
+    s.next++
+    id := "local-exec-" + strconv.Itoa(s.next)
+    result.ID = id
+    s.executions[id] = result
+    s.track(id)
+    return id
+}
+
+func (s *localJobStore) execution(id string) (*ExecutionResult, bool) {
+    s.Lock()
+    defer s.Unlock()
+    result, ok := s.executions[id]
+    if ok {
+        delete(s.executions, id)
+        delete(s.added, id)
+    }
+    return result, ok
+}
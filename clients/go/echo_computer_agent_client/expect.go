@@ -0,0 +1,178 @@
+package echo_computer_agent_client
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "reflect"
+    "strings"
+
+    "echo_computer_agent_client/internal/reftemplate"
+)
+
+var ErrExpectationFailed = errors.New("response did not meet expectations")
+
+// ExpectationFailure is one failed check. Path is the dotted location in
+// the response, e.g. "data.status".
+type ExpectationFailure struct {
+    Check string `json:"check"`
+    Path string `json:"path"`
+    Want any `json:"want,omitempty"`
+    Got any `json:"got,omitempty"`
+}
+
+func (f ExpectationFailure) String() string {
+    switch f.Check {
+    case "present":
+        return f.Path + " is missing"
+    case "absent":
+        return fmt.Sprintf("%s is %v, want it absent", f.Path, f.Got)
+    case "contains":
+        return fmt.Sprintf("%s %q does not contain %q", f.Path, f.Got, f.Want)
+    }
+    return fmt.Sprintf("%s is %v, want %v", f.Path, f.Got, f.Want)
+}
+
+// ExpectationError lists every failed check; it matches
+// ErrExpectationFailed with errors.Is.
+type ExpectationError struct {
+    Failures []ExpectationFailure
+}
+
+func (e *ExpectationError) Error() string {
+    parts := make([]string, len(e.Failures))
+    for i, failure := range e.Failures {
+        parts[i] = failure.String()
+    }
+    return ErrExpectationFailed.Error() + ": " + strings.Join(parts, "; ")
+}
+
+func (e *ExpectationError) Unwrap() error { return ErrExpectationFailed }
+
+// Expectation accumulates checks against one response. Every check runs,
+// so Err reports all mismatches at once:
+//
+//  err := Expect(resp).Function("launch_service").DataField("status", "ok").Err()
+type Expectation struct {
+    resp *ChatResponse
+    failures []ExpectationFailure
+}
+
+func Expect(resp *ChatResponse) *Expectation {
+    e := &Expectation{resp: resp}
+    if resp == nil {
+        e.failures = append(e.failures, ExpectationFailure{Check: "present", Path: "response"})
+    }
+    return e
+}
+
+func (e *Expectation) fail(failure ExpectationFailure) *Expectation {
+    e.failures = append(e.failures, failure)
+    return e
+}
+
+func (e *Expectation) Function(name string) *Expectation {
+    if e.resp != nil && e.resp.Function != name {
+        e.fail(ExpectationFailure{Check: "equal", Path: "function", Want: name, Got: e.resp.Function})
+    }
+    return e
+}
+
+func (e *Expectation) MessageContains(text string) *Expectation {
+    if e.resp != nil && !strings.Contains(e.resp.Message, text) {
+        e.fail(ExpectationFailure{Check: "contains", Path: "message", Want: text, Got: e.resp.Message})
+    }
+    return e
+}
+
+// DataField checks the value at a dotted path in Data. Values compare by
+// their JSON form, so 3 matches a decoded 3.0.
+func (e *Expectation) DataField(path string, want any) *Expectation {
+    return e.field("data", e.data(), path, want)
+}
+
+func (e *Expectation) HasDataField(path string) *Expectation {
+    if e.resp == nil {
+        return e
+    }
+    if _, ok := reftemplate.Lookup(e.data(), path); !ok {
+        e.fail(ExpectationFailure{Check: "present", Path: "data." + path})
+    }
+    return e
+}
+
+func (e *Expectation) NoDataField(path string) *Expectation {
+    if e.resp == nil {
+        return e
+    }
+    if got, ok := reftemplate.Lookup(e.data(), path); ok {
+        e.fail(ExpectationFailure{Check: "absent", Path: "data." + path, Got: got})
+    }
+    return e
+}
+
+func (e *Expectation) MetadataField(path string, want any) *Expectation {
+    var metadata any
+    if e.resp != nil && e.resp.Metadata != nil {
+        metadata = e.resp.Metadata
+    }
+    return e.field("metadata", metadata, path, want)
+}
+
+// Satisfies runs a custom check, recording its error under name.
+func (e *Expectation) Satisfies(name string, check func(*ChatResponse) error) *Expectation {
+    if e.resp == nil {
+        return e
+    }
+    if err := check(e.resp); err != nil {
+        e.fail(ExpectationFailure{Check: name, Path: "response", Got: err.Error()})
+    }
+    return e
+}
+
+func (e *Expectation) data() any {
+    if e.resp == nil || e.resp.Data == nil {
+        return nil
+    }
+    return e.resp.Data
+}
+
+func (e *Expectation) field(root string, data any, path string, want any) *Expectation {
+    if e.resp == nil {
+        return e
+    }
+    got, ok := reftemplate.Lookup(data, path)
+    if !ok {
+        return e.fail(ExpectationFailure{Check: "present", Path: root + "." + path, Want: want})
+    }
+    if !sameJSON(got, want) {
+        e.fail(ExpectationFailure{Check: "equal", Path: root + "." + path, Want: want, Got: got})
+    }
+    return e
+}
+
+// Failures returns the failed checks so far.
+func (e *Expectation) Failures() []ExpectationFailure {
+    return e.failures
+}
+
+// Err returns an *ExpectationError, or nil when every check passed.
+func (e *Expectation) Err() error {
+    if len(e.failures) == 0 {
+        return nil
+    }
+    return &ExpectationError{Failures: e.failures}
+}
+
+func sameJSON(a, b any) bool {
+    var left, right any
+    ra, err := json.Marshal(a)
+    if err != nil || json.Unmarshal(ra, &left) != nil {
+        return reflect.DeepEqual(a, b)
+    }
+    rb, err := json.Marshal(b)
+    if err != nil || json.Unmarshal(rb, &right) != nil {
+        return reflect.DeepEqual(a, b)
+    }
+    return reflect.DeepEqual(left, right)
+}
@@ -0,0 +1,195 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "hash/fnv"
+    "math/rand"
+    "sort"
+    "sync"
+)
+
+// ControlVariant names the requests an experiment leaves unchanged.
+const ControlVariant = "control"
+
+// Variant is one treatment of an Experiment. Rewrite, when set, produces
+// the variant's request (e.g. an alternate phrasing); Inputs are then
+// merged over the request's inputs. Weight is the variant's share of the
+// enrolled requests relative to the others; zero weights share equally.
+type Variant struct {
+    Name string
+    Weight float64
+    Rewrite func(ChatRequest) ChatRequest
+    Inputs map[string]any
+}
+
+// Experiment rewrites a Fraction of Chat requests into one of its
+// Variants and leaves the rest as the control group. Requests made for an
+// Actor are assigned by hashing the actor's ID, so a user sees the same
+// variant on every call; others are assigned at random. Match, when set,
+// limits the experiment to the requests it accepts.
+//
+// Replies are tagged with their variant under Metadata["experiments"]
+// (experiment name to variant name), and Report tallies where each
+// variant's requests were routed.
+type Experiment struct {
+    Name string
+    Fraction float64
+    Variants []Variant
+    Match func(ChatRequest) bool
+
+    mu sync.Mutex
+    outcomes map[string]*VariantOutcome
+}
+
+// VariantOutcome tallies the Chat calls a variant received: the function
+// each successful one was routed to, and how many failed.
+type VariantOutcome struct {
+    Variant string `json:"variant"`
+    Requests int `json:"requests"`
+    Errors int `json:"errors"`
+    Functions map[string]int `json:"functions"`
+}
+
+// AddExperiment runs experiment on the client's Chat calls. Experiments
+// apply in the order they are added, each to the request the previous one
+// produced.
+func (c *Client) AddExperiment(experiment *Experiment) {
+    c.experiments = append(c.experiments, experiment)
+}
+
+type assignment struct {
+    experiment *Experiment
+    variant string
+}
+
+func (c *Client) experimentChat(ctx context.Context, request ChatRequest) (*ChatResponse, error) {
+    var assignments []assignment
+    for _, experiment := range c.experiments {
+        if experiment.Match != nil && !experiment.Match(request) {
+            continue
+        }
+        var variant string
+        request, variant = experiment.apply(ctx, request)
+        assignments = append(assignments, assignment{experiment, variant})
+    }
+    resp, err := c.chat(ctx, request)
+    for _, a := range assignments {
+        a.experiment.record(a.variant, resp, err)
+    }
+    if resp != nil && len(assignments) > 0 {
+        tagged := *resp
+        tagged.Metadata = make(map[string]any, len(resp.Metadata)+1)
+        for key, value := range resp.Metadata {
+            tagged.Metadata[key] = value
+        }
+        variants := map[string]any{}
+        if existing, ok := resp.Metadata["experiments"].(map[string]any); ok {
+            for key, value := range existing {
+                variants[key] = value
+            }
+        }
+        for _, a := range assignments {
+            variants[a.experiment.Name] = a.variant
+        }
+        tagged.Metadata["experiments"] = variants
+        resp = &tagged
+    }
+    return resp, err
+}
+
+// apply assigns request to a variant and returns the request that variant
+// sends.
+func (e *Experiment) apply(ctx context.Context, request ChatRequest) (ChatRequest, string) {
+    roll := rand.Float64()
+    if actor, ok := ActorFromContext(ctx); ok && actor.ID != "" {
+        h := fnv.New64a()
+        h.Write([]byte(e.Name + "\x00" + actor.ID))
+        roll = float64(h.Sum64()>>11) / (1 << 53)
+    }
+    if len(e.Variants) == 0 || roll >= e.Fraction {
+        return request, ControlVariant
+    }
+    // Rescale the roll within the enrolled fraction to pick the variant.
+    variant := e.pick(roll / e.Fraction)
+    if variant.Rewrite != nil {
+        request = variant.Rewrite(request)
+    }
+    if len(variant.Inputs) > 0 {
+        inputs := make(map[string]any, len(request.Inputs)+len(variant.Inputs))
+        for key, value := range request.Inputs {
+            inputs[key] = value
+        }
+        for key, value := range variant.Inputs {
+            inputs[key] = value
+        }
+        request.Inputs = inputs
+    }
+    return request, variant.Name
+}
+
+func (e *Experiment) pick(roll float64) Variant {
+    total := 0.0
+    for _, v := range e.Variants {
+        total += v.Weight
+    }
+    for _, v := range e.Variants {
+        share := v.Weight / total
+        if total == 0 {
+            share = 1 / float64(len(e.Variants))
+        }
+        if roll < share {
+            return v
+        }
+        roll -= share
+    }
+    return e.Variants[len(e.Variants)-1]
+}
+
+func (e *Experiment) record(variant string, resp *ChatResponse, err error) {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    if e.outcomes == nil {
+        e.outcomes = map[string]*VariantOutcome{}
+    }
+    outcome, ok := e.outcomes[variant]
+    if !ok {
+        outcome = &VariantOutcome{Variant: variant, Functions: map[string]int{}}
+        e.outcomes[variant] = outcome
+    }
+    outcome.Requests++
+    if err != nil || resp == nil {
+        outcome.Errors++
+        return
+    }
+    outcome.Functions[resp.Function]++
+}
+
+// Report returns the outcomes recorded so far, control first and the
+// variants by name.
+func (e *Experiment) Report() []VariantOutcome {
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    report := make([]VariantOutcome, 0, len(e.outcomes))
+    for _, outcome := range e.outcomes {
+        copied := *outcome
+        copied.Functions = make(map[string]int, len(outcome.Functions))
+        for function, n := range outcome.Functions {
+            copied.Functions[function] = n
+        }
+        report = append(report, copied)
+    }
+    sort.Slice(report, func(i, j int) bool {
+        if (report[i].Variant == ControlVariant) != (report[j].Variant == ControlVariant) {
+            return report[i].Variant == ControlVariant
+        }
+        return report[i].Variant < report[j].Variant
+    })
+    return report
+}
+
+// Reset discards the recorded outcomes, e.g. when starting a new round.
+func (e *Experiment) Reset() {
+    e.mu.Lock()
+    e.outcomes = nil
+    e.mu.Unlock()
+}
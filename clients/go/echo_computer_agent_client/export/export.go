@@ -0,0 +1,174 @@
+// Package export flattens BulkInvoke results into tabular files for
+// analysts, selecting response fields through a FieldMap.
+package export
+
+import (
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "io"
+    "strconv"
+    "strings"
+
+    client "echo_computer_agent_client"
+)
+
+// Column names one output column and the dotted path it is read from.
+// Paths start at the result: "index", "function", "error", "message",
+// "inputs.<key>", "data.<key>", or "metadata.<key>"; further segments walk
+// nested objects, and numeric segments index arrays.
+type Column struct {
+    Name string
+    Path string
+}
+
+type FieldMap []Column
+
+// ParseFieldMap reads "name=path,name=path" specs; a bare path is also its
+// column name.
+func ParseFieldMap(spec string) (FieldMap, error) {
+    var fields FieldMap
+    for _, part := range strings.Split(spec, ",") {
+        part = strings.TrimSpace(part)
+        if part == "" {
+            continue
+        }
+        name, path, ok := strings.Cut(part, "=")
+        if !ok {
+            path = name
+        }
+        name, path = strings.TrimSpace(name), strings.TrimSpace(path)
+        if name == "" || path == "" {
+            return nil, fmt.Errorf("export: invalid field %q", part)
+        }
+        fields = append(fields, Column{Name: name, Path: path})
+    }
+    if len(fields) == 0 {
+        return nil, fmt.Errorf("export: empty field map")
+    }
+    return fields, nil
+}
+
+// DefaultFields covers the columns every result has.
+var DefaultFields = FieldMap{
+    {Name: "index", Path: "index"},
+    {Name: "function", Path: "function"},
+    {Name: "message", Path: "message"},
+    {Name: "error", Path: "error"},
+}
+
+// Rows flattens results. A nil cell means the path was absent, which CSV
+// writes as an empty string and Parquet as null. Scalars are formatted
+// plainly; objects and arrays are written as JSON.
+func Rows(results []client.BulkResult, fields FieldMap) [][]*string {
+    rows := make([][]*string, len(results))
+    for i, result := range results {
+        row := make([]*string, len(fields))
+        for j, field := range fields {
+            if v, ok := lookup(result, field.Path); ok && v != nil {
+                s := format(v)
+                row[j] = &s
+            }
+        }
+        rows[i] = row
+    }
+    return rows
+}
+
+func WriteCSV(w io.Writer, results []client.BulkResult, fields FieldMap) error {
+    out := csv.NewWriter(w)
+    header := make([]string, len(fields))
+    for i, field := range fields {
+        header[i] = field.Name
+    }
+    if err := out.Write(header); err != nil {
+        return err
+    }
+    record := make([]string, len(fields))
+    for _, row := range Rows(results, fields) {
+        for i, cell := range row {
+            record[i] = ""
+            if cell != nil {
+                record[i] = *cell
+            }
+        }
+        if err := out.Write(record); err != nil {
+            return err
+        }
+    }
+    out.Flush()
+    return out.Error()
+}
+
+func lookup(result client.BulkResult, path string) (any, bool) {
+    segments := strings.Split(path, ".")
+    var root any
+    switch segments[0] {
+    case "index":
+        root = result.Index
+    case "function":
+        root = result.Function
+    case "error":
+        if result.Error == "" {
+            return nil, false
+        }
+        root = result.Error
+    case "inputs":
+        root = result.Inputs
+    case "message", "data", "metadata":
+        if result.Response == nil {
+            return nil, false
+        }
+        switch segments[0] {
+        case "message":
+            root = result.Response.Message
+        case "data":
+            root = result.Response.Data
+        default:
+            root = result.Response.Metadata
+        }
+    default:
+        return nil, false
+    }
+    return walk(root, segments[1:])
+}
+
+func walk(v any, segments []string) (any, bool) {
+    for _, segment := range segments {
+        switch node := v.(type) {
+        case map[string]any:
+            next, ok := node[segment]
+            if !ok {
+                return nil, false
+            }
+            v = next
+        case []any:
+            i, err := strconv.Atoi(segment)
+            if err != nil || i < 0 || i >= len(node) {
+                return nil, false
+            }
+            v = node[i]
+        default:
+            return nil, false
+        }
+    }
+    return v, true
+}
+
+func format(v any) string {
+    switch value := v.(type) {
+    case string:
+        return value
+    case int:
+        return strconv.Itoa(value)
+    case float64:
+        return strconv.FormatFloat(value, 'f', -1, 64)
+    case bool:
+        return strconv.FormatBool(value)
+    }
+    raw, err := json.Marshal(v)
+    if err != nil {
+        return fmt.Sprint(v)
+    }
+    return string(raw)
+}
@@ -0,0 +1,227 @@
+package export
+
+import (
+    "bytes"
+    "encoding/binary"
+    "io"
+
+    client "echo_computer_agent_client"
+)
+
+// WriteParquet writes results as a single-row-group Parquet file. Every
+// column is an optional UTF8 string, PLAIN encoded and uncompressed, which
+// keeps the writer dependency-free while staying readable by pyarrow,
+// DuckDB, Spark, and pandas.
+func WriteParquet(w io.Writer, results []client.BulkResult, fields FieldMap) error {
+    rows := Rows(results, fields)
+    var file bytes.Buffer
+    file.WriteString("PAR1")
+
+    type chunk struct {
+        offset int64
+        size int64
+        values int64
+    }
+    chunks := make([]chunk, len(fields))
+    for col := range fields {
+        page := columnPage(rows, col)
+
+        var header thriftWriter
+        header.i32(1, 0) // type: DATA_PAGE
+        header.i32(2, int32(len(page)))
+        header.i32(3, int32(len(page)))
+        header.beginStruct(5)
+        header.i32(1, int32(len(rows)))
+        header.i32(2, 0) // encoding: PLAIN
+        header.i32(3, 3) // definition levels: RLE
+        header.i32(4, 3) // repetition levels: RLE
+        header.endStruct()
+        header.stop()
+
+        chunks[col] = chunk{offset: int64(file.Len()), size: int64(header.buf.Len() + len(page)), values: int64(len(rows))}
+        file.Write(header.buf.Bytes())
+        file.Write(page)
+    }
+
+    var meta thriftWriter
+    meta.i32(1, 1)
+    meta.beginList(2, thriftStruct, len(fields)+1)
+    meta.beginElem()
+    meta.binary(4, "schema")
+    meta.i32(5, int32(len(fields)))
+    meta.endElem()
+    for _, field := range fields {
+        meta.beginElem()
+        meta.i32(1, 6) // BYTE_ARRAY
+        meta.i32(3, 1) // OPTIONAL
+        meta.binary(4, field.Name)
+        meta.i32(6, 0) // UTF8
+        meta.endElem()
+    }
+    meta.i64(3, int64(len(rows)))
+    meta.beginList(4, thriftStruct, 1)
+    meta.beginElem()
+    meta.beginList(1, thriftStruct, len(fields))
+    var total int64
+    for col, field := range fields {
+        c := chunks[col]
+        total += c.size
+        meta.beginElem()
+        meta.i64(2, c.offset)
+        meta.beginStruct(3)
+        meta.i32(1, 6)
+        meta.beginList(2, thriftI32, 2)
+        meta.varint(0) // PLAIN
+        meta.varint(zigzag(3)) // RLE
+        meta.beginList(3, thriftBinary, 1)
+        meta.str(field.Name)
+        meta.i32(4, 0) // UNCOMPRESSED
+        meta.i64(5, c.values)
+        meta.i64(6, c.size)
+        meta.i64(7, c.size)
+        meta.i64(9, c.offset)
+        meta.endStruct()
+        meta.endElem()
+    }
+    meta.i64(2, total)
+    meta.i64(3, int64(len(rows)))
+    meta.endElem()
+    meta.binary(6, "echo_computer_agent_client")
+    meta.stop()
+
+    file.Write(meta.buf.Bytes())
+    binary.Write(&file, binary.LittleEndian, uint32(meta.buf.Len()))
+    file.WriteString("PAR1")
+    _, err := w.Write(file.Bytes())
+    return err
+}
+
+// columnPage encodes one column's definition levels (RLE runs, bit width
+// 1, length-prefixed) followed by the PLAIN values of its non-null cells.
+func columnPage(rows [][]*string, col int) []byte {
+    var levels bytes.Buffer
+    for i := 0; i < len(rows); {
+        defined := rows[i][col] != nil
+        run := 1
+        for i+run < len(rows) && (rows[i+run][col] != nil) == defined {
+            run++
+        }
+        levels.Write(binary.AppendUvarint(nil, uint64(run)<<1))
+        if defined {
+            levels.WriteByte(1)
+        } else {
+            levels.WriteByte(0)
+        }
+        i += run
+    }
+    var page bytes.Buffer
+    binary.Write(&page, binary.LittleEndian, uint32(levels.Len()))
+    page.Write(levels.Bytes())
+    for _, row := range rows {
+        if cell := row[col]; cell != nil {
+            binary.Write(&page, binary.LittleEndian, uint32(len(*cell)))
+            page.WriteString(*cell)
+        }
+    }
+    return page.Bytes()
+}
+
+const (
+    thriftI32 = 5
+    thriftI64 = 6
+    thriftBinary = 8
+    thriftList = 9
+    thriftStruct = 12
+)
+
+// thriftWriter emits the Thrift compact protocol used by Parquet headers
+// and footers. Struct nesting is tracked so field id deltas restart in
+// each struct.
+type thriftWriter struct {
+    buf bytes.Buffer
+    last int16
+    stack []int16
+}
+
+func (t *thriftWriter) field(id int16, kind byte) {
+    if delta := id - t.last; delta > 0 && delta <= 15 {
+        t.buf.WriteByte(byte(delta)<<4 | kind)
+    } else {
+        t.buf.WriteByte(kind)
+        t.varint(zigzag(int64(id)))
+    }
+    t.last = id
+}
+
+func (t *thriftWriter) varint(v uint64) {
+    t.buf.Write(binary.AppendUvarint(nil, v))
+}
+
+func (t *thriftWriter) str(s string) {
+    t.varint(uint64(len(s)))
+    t.buf.WriteString(s)
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+    t.field(id, thriftI32)
+    t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+    t.field(id, thriftI64)
+    t.varint(zigzag(v))
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+    t.field(id, thriftBinary)
+    t.str(s)
+}
+
+func (t *thriftWriter) beginStruct(id int16) {
+    t.field(id, thriftStruct)
+    t.push()
+}
+
+func (t *thriftWriter) endStruct() {
+    t.stop()
+    t.pop()
+}
+
+// beginList starts a list field. Struct elements are each wrapped in
+// beginElem/endElem; the list itself has no terminator.
+func (t *thriftWriter) beginList(id int16, elem byte, size int) {
+    t.field(id, thriftList)
+    if size < 15 {
+        t.buf.WriteByte(byte(size)<<4 | elem)
+    } else {
+        t.buf.WriteByte(0xf0 | elem)
+        t.varint(uint64(size))
+    }
+}
+
+func (t *thriftWriter) beginElem() {
+    t.push()
+}
+
+func (t *thriftWriter) endElem() {
+    t.stop()
+    t.pop()
+}
+
+func (t *thriftWriter) stop() {
+    t.buf.WriteByte(0)
+}
+
+func (t *thriftWriter) push() {
+    t.stack = append(t.stack, t.last)
+    t.last = 0
+}
+
+func (t *thriftWriter) pop() {
+    t.last = t.stack[len(t.stack)-1]
+    t.stack = t.stack[:len(t.stack)-1]
+}
+
+func zigzag(v int64) uint64 {
+    return uint64(v<<1) ^ uint64(v>>63)
+}
@@ -0,0 +1,74 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "mime/multipart"
+    "net/http"
+    "net/textproto"
+)
+
+// FileRef identifies a file stored by the agent's file API. Pass ID in
+// Inputs to let functions read it.
+type FileRef struct {
+    ID string `json:"id"`
+    Name string `json:"name"`
+    ContentType string `json:"content_type,omitempty"`
+    Size int64 `json:"size,omitempty"`
+}
+
+// UploadFile streams r to the agent's /files endpoint as a multipart upload.
+// A streamed body cannot be resent, so a 429 fails the upload, though it
+// still holds back later calls until the Retry-After horizon.
+func (c *Client) UploadFile(ctx context.Context, name, contentType string, r io.Reader) (*FileRef, error) {
+    ctx, cancel := c.callContext(ctx, 0)
+    defer cancel()
+    if _, err := c.awaitThrottle(ctx, 0); err != nil {
+        return nil, err
+    }
+    body, writer := io.Pipe()
+    form := multipart.NewWriter(writer)
+    go func() {
+        header := textproto.MIMEHeader{}
+        header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, name))
+        if contentType == "" {
+            contentType = "application/octet-stream"
+        }
+        header.Set("Content-Type", contentType)
+        part, err := form.CreatePart(header)
+        if err == nil {
+            _, err = io.Copy(part, r)
+        }
+        if err == nil {
+            err = form.Close()
+        }
+        writer.CloseWithError(err)
+    }()
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/files", body)
+    if err != nil {
+        body.Close()
+        return nil, err
+    }
+    req.Header.Set("Content-Type", form.FormDataContentType())
+    c.decorate(ctx, req)
+    resp, err := c.do(req)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+    version := c.observeVersion(resp)
+    c.observeDeprecation("/files", resp.Header)
+    if resp.StatusCode == http.StatusTooManyRequests {
+        c.noteThrottled(resp.Header)
+    }
+    if resp.StatusCode >= 400 {
+        return nil, c.apiError(req, resp)
+    }
+    var ref FileRef
+    if err := decodeVersioned(resp.Body, version, "/files", &ref); err != nil {
+        return nil, err
+    }
+    return &ref, nil
+}
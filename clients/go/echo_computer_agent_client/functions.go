@@ -0,0 +1,19 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "net/http"
+    "net/url"
+)
+
+// RegisterFunction adds fn to the agent's catalog. Functions registered by
+// a client are typically served by it, e.g. over ServeReverse.
+func (c *Client) RegisterFunction(ctx context.Context, fn FunctionDescription) error {
+    defer c.InvalidateCatalog()
+    return c.doJSON(ctx, http.MethodPost, "/functions", fn, nil)
+}
+
+func (c *Client) UnregisterFunction(ctx context.Context, name string) error {
+    defer c.InvalidateCatalog()
+    return c.doJSON(ctx, http.MethodDelete, "/functions/"+url.PathEscape(name), nil, nil)
+}
@@ -0,0 +1,239 @@
+// Package grpcgw serves the client as the echo.agent.v1.EchoAgent gRPC
+// service described in echo_agent.proto, so services in other languages
+// can route agent calls through one Go sidecar.
+//
+// The gateway implements gRPC's HTTP/2 framing on net/http, which
+// negotiates HTTP/2 only over TLS; serve it with ListenAndServeTLS (a
+// loopback certificate is enough for a sidecar). Message compression is
+// not supported.
+package grpcgw
+
+import (
+    "context"
+    "crypto/subtle"
+    "encoding/binary"
+    "errors"
+    "io"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+    "time"
+
+    client "echo_computer_agent_client"
+)
+
+const servicePrefix = "/echo.agent.v1.EchoAgent/"
+
+// gRPC status codes.
+const (
+    codeOK = 0
+    codeCanceled = 1
+    codeInvalidArgument = 3
+    codeDeadlineExceeded = 4
+    codeNotFound = 5
+    codePermissionDenied = 7
+    codeResourceExhausted = 8
+    codeFailedPrecondition = 9
+    codeUnimplemented = 12
+    codeUnavailable = 14
+    codeUnauthenticated = 16
+)
+
+// Server handles unary EchoAgent calls. When Token or Actors is set every
+// call must carry "authorization: Bearer <token>" metadata with Token or
+// one of Actors' keys. A call made with an Actors token is attributed to
+// that token's Actor, so policy and audit see the authenticated caller.
+// The x-echo-actor-id and x-echo-actor-roles metadata are ignored unless
+// TrustActorHeaders is set, for a gateway only reachable through a proxy
+// that sets them itself.
+type Server struct {
+    Client *client.Client
+    Token string
+    Actors map[string]client.Actor
+    TrustActorHeaders bool
+    MaxMessageBytes int
+}
+
+func New(c *client.Client, token string) *Server {
+    return &Server{Client: c, Token: token, MaxMessageBytes: 4 << 20}
+}
+
+type status struct {
+    code int
+    message string
+}
+
+// authenticate checks the bearer token, returning the Actor it is issued
+// to, if any.
+func (s *Server) authenticate(r *http.Request) (*client.Actor, bool) {
+    if s.Token == "" && len(s.Actors) == 0 {
+        return nil, true
+    }
+    token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+    var found *client.Actor
+    matched := false
+    // Every token is compared, so timing does not tell which one matched.
+    for issued, actor := range s.Actors {
+        if subtle.ConstantTimeCompare([]byte(token), []byte(issued)) == 1 {
+            actor := actor
+            found, matched = &actor, true
+        }
+    }
+    if s.Token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) == 1 {
+        matched = true
+    }
+    return found, matched && token != ""
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    method, ok := strings.CutPrefix(r.URL.Path, servicePrefix)
+    if !ok || r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+        http.Error(w, "not a gRPC request", http.StatusUnsupportedMediaType)
+        return
+    }
+    w.Header().Set("Content-Type", "application/grpc+proto")
+    w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+
+    reply, st := s.call(r, method)
+    w.WriteHeader(http.StatusOK)
+    if st.code == codeOK {
+        frame := make([]byte, 5, 5+len(reply))
+        binary.BigEndian.PutUint32(frame[1:], uint32(len(reply)))
+        w.Write(append(frame, reply...))
+    }
+    w.Header().Set("Grpc-Status", strconv.Itoa(st.code))
+    if st.message != "" {
+        w.Header().Set("Grpc-Message", url.PathEscape(st.message))
+    }
+}
+
+func (s *Server) call(r *http.Request, method string) ([]byte, status) {
+    actor, authenticated := s.authenticate(r)
+    if !authenticated {
+        return nil, status{codeUnauthenticated, "invalid token"}
+    }
+    message, st := s.readMessage(r)
+    if st.code != codeOK {
+        return nil, st
+    }
+
+    ctx := r.Context()
+    if timeout, ok := parseTimeout(r.Header.Get("Grpc-Timeout")); ok {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, timeout)
+        defer cancel()
+    }
+    if actor == nil && s.TrustActorHeaders {
+        if id := r.Header.Get(client.HeaderActorID); id != "" {
+            actor = &client.Actor{ID: id, Tenant: r.Header.Get(client.HeaderTenant)}
+            if roles := r.Header.Get(client.HeaderActorRoles); roles != "" {
+                actor.Roles = strings.Split(roles, ",")
+            }
+        }
+    }
+    if actor != nil {
+        ctx = client.WithActor(ctx, *actor)
+    }
+
+    switch method {
+    case "ListFunctions":
+        catalog, err := s.Client.ListFunctions(ctx)
+        if err != nil {
+            return nil, errorStatus(err)
+        }
+        return encodeFunctionList(catalog), status{}
+    case "Chat", "Plan":
+        request, err := decodeChatRequest(message)
+        if err != nil {
+            return nil, status{codeInvalidArgument, err.Error()}
+        }
+        request.Inputs = s.Client.StripSecretRefs(request.Inputs)
+        var resp *client.ChatResponse
+        if method == "Plan" {
+            resp, err = s.Client.Plan(ctx, request)
+        } else {
+            resp, err = s.Client.Chat(ctx, request)
+        }
+        if err != nil {
+            return nil, errorStatus(err)
+        }
+        return encodeChatResponse(resp), status{}
+    case "InvokeFunction":
+        name, inputs, err := decodeInvokeRequest(message)
+        if err != nil {
+            return nil, status{codeInvalidArgument, err.Error()}
+        }
+        if name == "" {
+            return nil, status{codeInvalidArgument, "name is required"}
+        }
+        resp, err := s.Client.InvokeFunction(ctx, name, s.Client.StripSecretRefs(inputs))
+        if err != nil {
+            return nil, errorStatus(err)
+        }
+        return encodeChatResponse(resp), status{}
+    case "Health":
+        health, err := s.Client.Health(ctx)
+        if err != nil {
+            return nil, errorStatus(err)
+        }
+        return encodeHealth(health), status{}
+    }
+    return nil, status{codeUnimplemented, "unknown method " + method}
+}
+
+// readMessage reads the single length-prefixed message of a unary call.
+func (s *Server) readMessage(r *http.Request) ([]byte, status) {
+    var prefix [5]byte
+    if _, err := io.ReadFull(r.Body, prefix[:]); err != nil {
+        return nil, status{codeInvalidArgument, "missing request message"}
+    }
+    if prefix[0] != 0 {
+        return nil, status{codeUnimplemented, "compressed messages are not supported"}
+    }
+    length := binary.BigEndian.Uint32(prefix[1:])
+    if s.MaxMessageBytes > 0 && int64(length) > int64(s.MaxMessageBytes) {
+        return nil, status{codeInvalidArgument, "request message too large"}
+    }
+    message := make([]byte, length)
+    if _, err := io.ReadFull(r.Body, message); err != nil {
+        return nil, status{codeInvalidArgument, "truncated request message"}
+    }
+    return message, status{}
+}
+
+func errorStatus(err error) status {
+    code := codeUnavailable
+    switch {
+    case errors.Is(err, client.ErrFunctionNotAllowed), errors.Is(err, client.ErrPolicyDenied), errors.Is(err, client.ErrContentBlocked):
+        code = codePermissionDenied
+    case errors.Is(err, client.ErrConfirmationRequired), errors.Is(err, client.ErrOverBudget):
+        code = codeFailedPrecondition
+    case client.IsNotFound(err), client.IsFunctionNotFound(err):
+        code = codeNotFound
+    case client.IsRateLimited(err):
+        code = codeResourceExhausted
+    case errors.Is(err, context.DeadlineExceeded):
+        code = codeDeadlineExceeded
+    case errors.Is(err, context.Canceled):
+        code = codeCanceled
+    }
+    return status{code, err.Error()}
+}
+
+// parseTimeout reads the grpc-timeout header, e.g. "250m" or "5S".
+func parseTimeout(value string) (time.Duration, bool) {
+    if len(value) < 2 {
+        return 0, false
+    }
+    n, err := strconv.ParseInt(value[:len(value)-1], 10, 64)
+    if err != nil || n < 0 {
+        return 0, false
+    }
+    units := map[byte]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second, 'm': time.Millisecond, 'u': time.Microsecond, 'n': time.Nanosecond}
+    unit, ok := units[value[len(value)-1]]
+    if !ok {
+        return 0, false
+    }
+    return time.Duration(n) * unit, true
+}
@@ -0,0 +1,292 @@
+package grpcgw
+
+import (
+    "encoding/binary"
+    "errors"
+    "fmt"
+    "math"
+    "sort"
+
+    client "echo_computer_agent_client"
+)
+
+// Protobuf wire types.
+const (
+    wireVarint = 0
+    wireFixed64 = 1
+    wireBytes = 2
+    wireFixed32 = 5
+)
+
+var errMalformed = errors.New("grpcgw: malformed protobuf message")
+
+type encoder struct {
+    buf []byte
+}
+
+func (e *encoder) tag(field, wire int) {
+    e.buf = binary.AppendUvarint(e.buf, uint64(field)<<3|uint64(wire))
+}
+
+func (e *encoder) bytes(field int, b []byte) {
+    e.tag(field, wireBytes)
+    e.buf = binary.AppendUvarint(e.buf, uint64(len(b)))
+    e.buf = append(e.buf, b...)
+}
+
+// str omits empty strings, as proto3 does for scalar defaults.
+func (e *encoder) str(field int, s string) {
+    if s != "" {
+        e.bytes(field, []byte(s))
+    }
+}
+
+func (e *encoder) varint(field int, v uint64) {
+    e.tag(field, wireVarint)
+    e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+func (e *encoder) double(field int, f float64) {
+    e.tag(field, wireFixed64)
+    e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(f))
+}
+
+func (e *encoder) message(field int, build func(*encoder)) {
+    var inner encoder
+    build(&inner)
+    e.bytes(field, inner.buf)
+}
+
+// structValue encodes a google.protobuf.Struct; nil maps are omitted.
+func (e *encoder) structValue(field int, m map[string]any) {
+    if m == nil {
+        return
+    }
+    e.message(field, func(s *encoder) { s.structFields(m) })
+}
+
+func (e *encoder) structFields(m map[string]any) {
+    keys := make([]string, 0, len(m))
+    for key := range m {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    for _, key := range keys {
+        value := m[key]
+        e.message(1, func(entry *encoder) {
+            entry.str(1, key)
+            entry.message(2, func(v *encoder) { v.value(value) })
+        })
+    }
+}
+
+// value encodes a google.protobuf.Value. Oneof members are written even
+// when they hold their zero value.
+func (e *encoder) value(v any) {
+    switch x := v.(type) {
+    case nil:
+        e.varint(1, 0)
+    case float64:
+        e.double(2, x)
+    case float32:
+        e.double(2, float64(x))
+    case int:
+        e.double(2, float64(x))
+    case int64:
+        e.double(2, float64(x))
+    case string:
+        e.bytes(3, []byte(x))
+    case bool:
+        if x {
+            e.varint(4, 1)
+        } else {
+            e.varint(4, 0)
+        }
+    case map[string]any:
+        e.message(5, func(s *encoder) { s.structFields(x) })
+    case []any:
+        e.message(6, func(list *encoder) {
+            for _, item := range x {
+                list.message(1, func(v *encoder) { v.value(item) })
+            }
+        })
+    default:
+        e.bytes(3, []byte(fmt.Sprint(x)))
+    }
+}
+
+// fields walks a message, calling fn with each field's number, wire type,
+// and either its varint/fixed value or its length-delimited payload.
+func fields(b []byte, fn func(field, wire int, n uint64, data []byte) error) error {
+    for len(b) > 0 {
+        key, size := binary.Uvarint(b)
+        if size <= 0 {
+            return errMalformed
+        }
+        b = b[size:]
+        field, wire := int(key>>3), int(key&7)
+        var n uint64
+        var data []byte
+        switch wire {
+        case wireVarint:
+            n, size = binary.Uvarint(b)
+            if size <= 0 {
+                return errMalformed
+            }
+            b = b[size:]
+        case wireFixed64:
+            if len(b) < 8 {
+                return errMalformed
+            }
+            n, b = binary.LittleEndian.Uint64(b), b[8:]
+        case wireFixed32:
+            if len(b) < 4 {
+                return errMalformed
+            }
+            n, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+        case wireBytes:
+            length, size := binary.Uvarint(b)
+            if size <= 0 || uint64(len(b)-size) < length {
+                return errMalformed
+            }
+            data, b = b[size:size+int(length)], b[size+int(length):]
+        default:
+            return errMalformed
+        }
+        if err := fn(field, wire, n, data); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func decodeStruct(b []byte) (map[string]any, error) {
+    m := map[string]any{}
+    err := fields(b, func(field, wire int, _ uint64, entry []byte) error {
+        if field != 1 || wire != wireBytes {
+            return nil
+        }
+        var key string
+        var value any
+        err := fields(entry, func(field, wire int, _ uint64, data []byte) error {
+            var err error
+            switch {
+            case field == 1 && wire == wireBytes:
+                key = string(data)
+            case field == 2 && wire == wireBytes:
+                value, err = decodeValue(data)
+            }
+            return err
+        })
+        m[key] = value
+        return err
+    })
+    return m, err
+}
+
+func decodeValue(b []byte) (any, error) {
+    var value any
+    err := fields(b, func(field, wire int, n uint64, data []byte) error {
+        var err error
+        switch field {
+        case 1:
+            value = nil
+        case 2:
+            value = math.Float64frombits(n)
+        case 3:
+            value = string(data)
+        case 4:
+            value = n != 0
+        case 5:
+            value, err = decodeStruct(data)
+        case 6:
+            list := []any{}
+            err = fields(data, func(field, wire int, _ uint64, item []byte) error {
+                if field != 1 {
+                    return nil
+                }
+                v, err := decodeValue(item)
+                list = append(list, v)
+                return err
+            })
+            value = list
+        }
+        return err
+    })
+    return value, err
+}
+
+func decodeChatRequest(b []byte) (client.ChatRequest, error) {
+    var request client.ChatRequest
+    err := fields(b, func(field, wire int, n uint64, data []byte) error {
+        var err error
+        switch field {
+        case 1:
+            request.Message = string(data)
+        case 2:
+            request.Inputs, err = decodeStruct(data)
+        case 3:
+            execute := n != 0
+            request.Execute = &execute
+        }
+        return err
+    })
+    return request, err
+}
+
+func decodeInvokeRequest(b []byte) (name string, inputs map[string]any, err error) {
+    err = fields(b, func(field, wire int, n uint64, data []byte) error {
+        var err error
+        switch field {
+        case 1:
+            name = string(data)
+        case 2:
+            inputs, err = decodeStruct(data)
+        }
+        return err
+    })
+    return name, inputs, err
+}
+
+func encodeChatResponse(resp *client.ChatResponse) []byte {
+    var e encoder
+    e.str(1, resp.Function)
+    e.str(2, resp.Message)
+    e.structValue(3, resp.Data)
+    e.structValue(4, resp.Metadata)
+    return e.buf
+}
+
+func encodeFunctionList(catalog *client.FunctionListResponse) []byte {
+    var e encoder
+    for _, fn := range catalog.Functions {
+        e.message(1, func(f *encoder) {
+            f.str(1, fn.Name)
+            f.str(2, fn.Description)
+            f.structValue(3, fn.Parameters)
+            f.structValue(4, fn.Metadata)
+        })
+    }
+    return e.buf
+}
+
+func encodeHealth(health *client.Health) []byte {
+    var e encoder
+    e.str(1, health.Status)
+    e.str(2, health.Version)
+    if health.Uptime != 0 {
+        e.double(3, health.Uptime)
+    }
+    keys := make([]string, 0, len(health.Checks))
+    for key := range health.Checks {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    for _, key := range keys {
+        e.message(4, func(entry *encoder) {
+            entry.str(1, key)
+            entry.str(2, health.Checks[key])
+        })
+    }
+    return e.buf
+}
@@ -0,0 +1,50 @@
+package echo_computer_agent_client
+
+import (
+    "errors"
+    "fmt"
+    "path"
+)
+
+var ErrFunctionNotAllowed = errors.New("function not allowed")
+
+// FunctionGuard constrains which functions the client lets the agent execute.
+// Entries are matched with path.Match, so "echo.*" style patterns work. Deny
+// always wins; an empty Allow list permits anything not denied.
+type FunctionGuard struct {
+    Allow []string `json:"allow,omitempty"`
+    Deny []string `json:"deny,omitempty"`
+}
+
+func (g *FunctionGuard) Check(name string) error {
+    if g == nil {
+        return nil
+    }
+    if matchAny(g.Deny, name) {
+        return fmt.Errorf("%w: %s is denied", ErrFunctionNotAllowed, name)
+    }
+    if len(g.Allow) > 0 && !matchAny(g.Allow, name) {
+        return fmt.Errorf("%w: %s is not in the allowlist", ErrFunctionNotAllowed, name)
+    }
+    return nil
+}
+
+// SetFunctionGuard installs a guard checked before every execute=true Chat
+// call. The target function is resolved with a dry-run Plan first, so a
+// refused request never reaches the agent in executing form.
+func (c *Client) SetFunctionGuard(guard *FunctionGuard) {
+    c.guard = guard
+}
+
+func matchAny(patterns []string, name string) bool {
+    for _, pattern := range patterns {
+        if ok, err := path.Match(pattern, name); err == nil && ok {
+            return true
+        }
+    }
+    return false
+}
+
+func executes(request ChatRequest) bool {
+    return request.Execute != nil && *request.Execute
+}
@@ -0,0 +1,138 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "sync"
+    "time"
+)
+
+// Health is the agent's /health report. Checks holds per-dependency
+// statuses when the agent reports them.
+type Health struct {
+    Status string `json:"status"`
+    Version string `json:"version,omitempty"`
+    Uptime float64 `json:"uptime_seconds,omitempty"`
+    Checks map[string]string `json:"checks,omitempty"`
+    Latency time.Duration `json:"-"`
+}
+
+func (h *Health) OK() bool {
+    return h.Status == "ok" || h.Status == "healthy" || h.Status == "pass"
+}
+
+func (c *Client) Health(ctx context.Context) (*Health, error) {
+    start := time.Now()
+    var health Health
+    if err := c.doJSON(ctx, http.MethodGet, "/health", nil, &health); err != nil {
+        return nil, err
+    }
+    health.Latency = time.Since(start)
+    return &health, nil
+}
+
+// AgentUsage is the server-side usage report from /usage. Counters maps
+// counter names (requests, executions, tokens, ...) to totals and
+// ByFunction breaks the request count down per function.
+type AgentUsage struct {
+    Since time.Time `json:"since,omitempty"`
+    Counters map[string]float64 `json:"counters"`
+    ByFunction map[string]float64 `json:"by_function,omitempty"`
+}
+
+func (c *Client) AgentUsage(ctx context.Context) (*AgentUsage, error) {
+    var usage AgentUsage
+    if err := c.doJSON(ctx, http.MethodGet, "/usage", nil, &usage); err != nil {
+        return nil, err
+    }
+    return &usage, nil
+}
+
+// ProbeStatus is the prober's latest view of the agent.
+type ProbeStatus struct {
+    Healthy bool `json:"healthy"`
+    LastProbe time.Time `json:"last_probe"`
+    LastSuccess time.Time `json:"last_success"`
+    LastError string `json:"last_error,omitempty"`
+    ConsecutiveFailures int `json:"consecutive_failures"`
+    Health *Health `json:"health,omitempty"`
+}
+
+// HealthProber polls /health in the background. The agent counts as
+// unhealthy only after FailureThreshold consecutive failed probes, so one
+// slow response does not flap readiness.
+type HealthProber struct {
+    Client *Client
+    Interval time.Duration
+    Timeout time.Duration
+    FailureThreshold int
+
+    mu sync.Mutex
+    status ProbeStatus
+}
+
+func (c *Client) NewHealthProber(interval time.Duration) *HealthProber {
+    return &HealthProber{Client: c, Interval: interval, Timeout: 5 * time.Second, FailureThreshold: 3}
+}
+
+// Run probes immediately and then every Interval until ctx is done.
+func (p *HealthProber) Run(ctx context.Context) {
+    interval := p.Interval
+    if interval <= 0 {
+        interval = 10 * time.Second
+    }
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    for {
+        p.Probe(ctx)
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+func (p *HealthProber) Probe(ctx context.Context) ProbeStatus {
+    timeout := p.Timeout
+    if timeout <= 0 {
+        timeout = 5 * time.Second
+    }
+    probeCtx, cancel := context.WithTimeout(ctx, timeout)
+    defer cancel()
+    health, err := p.Client.Health(probeCtx)
+    if err == nil && !health.OK() {
+        err = fmt.Errorf("agent reports status %q", health.Status)
+    }
+
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    p.status.LastProbe = time.Now()
+    if health != nil {
+        p.status.Health = health
+    }
+    threshold := p.FailureThreshold
+    if threshold <= 0 {
+        threshold = 1
+    }
+    if err != nil {
+        p.status.LastError = err.Error()
+        p.status.ConsecutiveFailures++
+        if p.status.ConsecutiveFailures >= threshold {
+            p.status.Healthy = false
+        }
+    } else {
+        p.status.LastError = ""
+        p.status.ConsecutiveFailures = 0
+        p.status.LastSuccess = p.status.LastProbe
+        p.status.Healthy = true
+    }
+    return p.status
+}
+
+func (p *HealthProber) Status() ProbeStatus {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    return p.status
+}
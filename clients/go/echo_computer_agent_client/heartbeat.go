@@ -0,0 +1,55 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "time"
+
+    "echo_computer_agent_client/internal/wsconn"
+)
+
+// Heartbeat configures liveness checks on persistent connections. Interval
+// is how often the client pings; a connection that shows no activity for
+// Interval+Timeout is considered dead and is re-established.
+type Heartbeat struct {
+    Interval time.Duration
+    Timeout time.Duration
+}
+
+func (h Heartbeat) withDefaults() Heartbeat {
+    if h.Interval <= 0 {
+        h.Interval = 30 * time.Second
+    }
+    if h.Timeout <= 0 {
+        h.Timeout = 10 * time.Second
+    }
+    return h
+}
+
+func (h Heartbeat) idle() time.Duration {
+    return h.Interval + h.Timeout
+}
+
+// keepAlive pings conn every Interval until ctx is done and pushes the read
+// deadline out whenever a pong arrives. Callers also call touch after each
+// message they read.
+func keepAlive(ctx context.Context, conn *wsconn.Conn, hb Heartbeat) (touch func()) {
+    hb = hb.withDefaults()
+    touch = func() { conn.SetReadDeadline(time.Now().Add(hb.idle())) }
+    conn.OnPong = func([]byte) { touch() }
+    touch()
+    go func() {
+        ticker := time.NewTicker(hb.Interval)
+        defer ticker.Stop()
+        for {
+            select {
+            case <-ctx.Done():
+                return
+            case <-ticker.C:
+                if conn.Ping(nil) != nil {
+                    return
+                }
+            }
+        }
+    }()
+    return touch
+}
@@ -0,0 +1,171 @@
+package echo_computer_agent_client
+
+import (
+    "bufio"
+    "crypto/sha256"
+    "crypto/tls"
+    "crypto/x509"
+    "encoding/base64"
+    "errors"
+    "fmt"
+    "net"
+    "net/http"
+    "net/url"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+)
+
+// HostKeyChangedError reports an agent presenting a different public key
+// than the one recorded for it, which is what a man in the middle looks
+// like. It is never trusted automatically.
+type HostKeyChangedError struct {
+    Host string
+    Recorded string
+    Presented string
+    Path string
+}
+
+func (e *HostKeyChangedError) Error() string {
+    return fmt.Sprintf("HOST KEY FOR %s HAS CHANGED: recorded %s, presented %s; someone may be intercepting the connection. If the agent's key was replaced on purpose, remove its line from %s", e.Host, e.Recorded, e.Presented, e.Path)
+}
+
+// KnownHosts is a trust-on-first-use store of agent public keys, one
+// "host:port SHA256:fingerprint" line per agent, in the manner of SSH's
+// known_hosts. The fingerprint is of the certificate's public key, so a
+// renewed certificate for the same key is still trusted.
+type KnownHosts struct {
+    path string
+    mu sync.Mutex
+}
+
+func NewKnownHosts(path string) *KnownHosts {
+    return &KnownHosts{path: path}
+}
+
+// Fingerprint is the SHA-256 of cert's public key as KnownHosts records it.
+func Fingerprint(cert *x509.Certificate) string {
+    sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+    return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// Verify records cert for host on first contact and otherwise checks it
+// against the recorded key. The file is read on every call, so processes
+// sharing it see each other's entries.
+func (k *KnownHosts) Verify(host string, cert *x509.Certificate) error {
+    presented := Fingerprint(cert)
+    k.mu.Lock()
+    defer k.mu.Unlock()
+    recorded, err := k.lookup(host)
+    if err != nil {
+        return err
+    }
+    if recorded == "" {
+        return k.record(host, presented)
+    }
+    if recorded != presented {
+        return &HostKeyChangedError{Host: host, Recorded: recorded, Presented: presented, Path: k.path}
+    }
+    return nil
+}
+
+func (k *KnownHosts) lookup(host string) (string, error) {
+    f, err := os.Open(k.path)
+    if errors.Is(err, os.ErrNotExist) {
+        return "", nil
+    }
+    if err != nil {
+        return "", err
+    }
+    defer f.Close()
+    scanner := bufio.NewScanner(f)
+    for scanner.Scan() {
+        fields := strings.Fields(scanner.Text())
+        if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+            continue
+        }
+        if fields[0] == host {
+            return fields[1], nil
+        }
+    }
+    return "", scanner.Err()
+}
+
+func (k *KnownHosts) record(host, fingerprint string) error {
+    if err := os.MkdirAll(filepath.Dir(k.path), 0o700); err != nil {
+        return err
+    }
+    f, err := os.OpenFile(k.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+    if err != nil {
+        return err
+    }
+    if _, err := fmt.Fprintf(f, "%s %s\n", host, fingerprint); err != nil {
+        f.Close()
+        return err
+    }
+    return f.Close()
+}
+
+// TLSConfig derives from base a config that trusts host by its recorded
+// key instead of by certificate authority, so self-signed certificates
+// work. Connections to other server names are keyed by that name.
+func (k *KnownHosts) TLSConfig(host string, base *tls.Config) *tls.Config {
+    cfg := &tls.Config{}
+    if base != nil {
+        cfg = base.Clone()
+    }
+    hostname, _, _ := net.SplitHostPort(host)
+    // The key pin stands in for the chain and hostname checks.
+    cfg.InsecureSkipVerify = true
+    cfg.VerifyConnection = func(state tls.ConnectionState) error {
+        if len(state.PeerCertificates) == 0 {
+            return errors.New("agent presented no certificate")
+        }
+        key := host
+        if state.ServerName != "" && state.ServerName != hostname {
+            key = state.ServerName
+        }
+        return k.Verify(key, state.PeerCertificates[0])
+    }
+    return cfg
+}
+
+// SetTrustOnFirstUse switches the client to trust-on-first-use: the
+// agent's public key is recorded in the known-hosts file at path on first
+// contact, and any later connection presenting another key fails with a
+// *HostKeyChangedError. It is meant for self-hosted agents without a
+// certificate authority, and applies to WebSocket sessions too. The base
+// URL must be https and the transport an *http.Client.
+func (c *Client) SetTrustOnFirstUse(path string) error {
+    u, err := url.Parse(c.baseURL)
+    if err != nil {
+        return err
+    }
+    if u.Scheme != "https" {
+        return fmt.Errorf("trust on first use needs an https base URL, not %q", c.baseURL)
+    }
+    host := u.Host
+    if u.Port() == "" {
+        host = net.JoinHostPort(u.Hostname(), "443")
+    }
+    httpClient, ok := c.transport.(*http.Client)
+    if !ok {
+        return fmt.Errorf("trust on first use needs an *http.Client transport, not %T", c.transport)
+    }
+    var transport *http.Transport
+    switch t := httpClient.Transport.(type) {
+    case nil:
+        transport = http.DefaultTransport.(*http.Transport).Clone()
+    case *http.Transport:
+        transport = t.Clone()
+    default:
+        return fmt.Errorf("trust on first use needs an *http.Transport, not %T", t)
+    }
+    transport.TLSClientConfig = NewKnownHosts(path).TLSConfig(host, transport.TLSClientConfig)
+    // The caller's client is left as it was; it may be http.DefaultClient.
+    pinned := *httpClient
+    pinned.Transport = transport
+    c.transport = &pinned
+    return nil
+}
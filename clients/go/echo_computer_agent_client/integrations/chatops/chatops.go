@@ -0,0 +1,169 @@
+// Package chatops holds the conversation layer shared by the chat platform
+// integrations: each channel, thread, or DM is a scope with its own history.
+package chatops
+
+import (
+    "context"
+    "strings"
+    "sync"
+    "time"
+
+    client "echo_computer_agent_client"
+)
+
+type Turn = client.Turn
+
+// Sessions keeps a client.Conversation per scope, so every message carries
+// the scope's last MaxTurns turns, or the agent's own conversation ID once
+// it reports one. MaxTurns applies to scopes started after it is set, and
+// only that many turns are kept. A scope idle for IdleTimeout is forgotten
+// and starts over on its next message.
+type Sessions struct {
+    Client *client.Client
+    MaxTurns int
+    IdleTimeout time.Duration
+
+    mu sync.Mutex
+    conversations map[string]*client.Conversation
+    used map[string]time.Time
+}
+
+func NewSessions(c *client.Client) *Sessions {
+    return &Sessions{Client: c, MaxTurns: 20, IdleTimeout: 24 * time.Hour, conversations: map[string]*client.Conversation{}}
+}
+
+func (s *Sessions) conversation(scope string) (*client.Conversation, error) {
+    s.mu.Lock()
+    if s.conversations == nil {
+        s.conversations = map[string]*client.Conversation{}
+    }
+    idle := s.evictIdle()
+    conversation, ok := s.conversations[scope]
+    if !ok {
+        var err error
+        if conversation, err = s.Client.NewConversation(); err != nil {
+            s.mu.Unlock()
+            return nil, err
+        }
+        conversation.MaxTurns = s.MaxTurns
+        s.conversations[scope] = conversation
+    }
+    s.touch(scope)
+    s.mu.Unlock()
+    // Closing waits for a call still finishing on the conversation, so it
+    // happens outside s.mu.
+    for _, evicted := range idle {
+        evicted.Close()
+    }
+    return conversation, nil
+}
+
+// evictIdle removes the scopes idle past IdleTimeout and returns their
+// conversations. s.mu must be held.
+func (s *Sessions) evictIdle() []*client.Conversation {
+    if s.IdleTimeout <= 0 {
+        return nil
+    }
+    var idle []*client.Conversation
+    for scope, at := range s.used {
+        if time.Since(at) > s.IdleTimeout {
+            idle = append(idle, s.conversations[scope])
+            delete(s.conversations, scope)
+            delete(s.used, scope)
+        }
+    }
+    return idle
+}
+
+// touch marks scope as used now. s.mu must be held.
+func (s *Sessions) touch(scope string) {
+    if s.used == nil {
+        s.used = map[string]time.Time{}
+    }
+    s.used[scope] = time.Now()
+}
+
+// exchanged trims the scope to MaxTurns after a message and marks it used,
+// so a long call does not leave it looking idle.
+func (s *Sessions) exchanged(scope string, conversation *client.Conversation) {
+    if conversation.MaxTurns > 0 {
+        conversation.Trim(conversation.MaxTurns)
+    }
+    s.mu.Lock()
+    if s.conversations[scope] == conversation {
+        s.touch(scope)
+    }
+    s.mu.Unlock()
+}
+
+func (s *Sessions) Send(ctx context.Context, scope, text string, inputs map[string]any) (*client.ChatResponse, error) {
+    conversation, err := s.conversation(scope)
+    if err != nil {
+        return nil, err
+    }
+    defer s.exchanged(scope, conversation)
+    return conversation.Chat(ctx, client.ChatRequest{Message: text, Inputs: inputs})
+}
+
+// Stream is Send with the reply streamed. update is called with the reply
+// so far, and with the latest progress stage until text arrives, at most
+// once per interval; the caller renders the final response itself.
+func (s *Sessions) Stream(ctx context.Context, scope, text string, inputs map[string]any, interval time.Duration, update func(reply, stage string)) (*client.ChatResponse, error) {
+    conversation, err := s.conversation(scope)
+    if err != nil {
+        return nil, err
+    }
+    defer s.exchanged(scope, conversation)
+    var reply strings.Builder
+    stage := ""
+    var last time.Time
+    return conversation.ChatStream(ctx, client.ChatRequest{Message: text, Inputs: inputs}, func(chunk client.ChatChunk) error {
+        switch event := chunk.Event.(type) {
+        case *client.TextDelta:
+            reply.WriteString(event.Text)
+        case *client.Progress:
+            if event.Stage == "" {
+                return nil
+            }
+            stage = event.Stage
+        default:
+            return nil
+        }
+        if time.Since(last) < interval {
+            return nil
+        }
+        last = time.Now()
+        update(reply.String(), stage)
+        return nil
+    })
+}
+
+// History returns the scope's last MaxTurns turns.
+func (s *Sessions) History(scope string) []Turn {
+    s.mu.Lock()
+    conversation, ok := s.conversations[scope]
+    s.mu.Unlock()
+    if !ok {
+        return nil
+    }
+    turns := conversation.History()
+    if conversation.MaxTurns > 0 && len(turns) > conversation.MaxTurns {
+        turns = turns[len(turns)-conversation.MaxTurns:]
+    }
+    return turns
+}
+
+// Conversation returns the scope's conversation, e.g. to export its
+// transcript.
+func (s *Sessions) Conversation(scope string) (*client.Conversation, error) {
+    return s.conversation(scope)
+}
+
+func (s *Sessions) Reset(scope string) {
+    s.mu.Lock()
+    conversation, ok := s.conversations[scope]
+    s.mu.Unlock()
+    if ok {
+        conversation.Reset()
+    }
+}
@@ -0,0 +1,488 @@
+// Package discord connects Discord to the agent, mirroring the Slack
+// integration: slash commands arrive on the interactions endpoint, channel
+// messages over the Gateway, and both map onto per-channel conversations
+// from the shared chatops layer. Gateway messages are answered when they
+// mention the bot, arrive as DMs, or land in one of Channels. Replies are
+// posted as a placeholder and edited in place as they stream, and message
+// attachments are uploaded to the agent's file API and passed as
+// Inputs["files"].
+package discord
+
+import (
+    "bytes"
+    "context"
+    "crypto/ed25519"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+    "unicode/utf8"
+
+    client "echo_computer_agent_client"
+    "echo_computer_agent_client/integrations/chatops"
+    "echo_computer_agent_client/internal/wsconn"
+)
+
+const (
+    defaultAPIBase = "https://discord.com/api/v10"
+
+    intentGuildMessages = 1 << 9
+    intentDirectMessages = 1 << 12
+    intentMessageContent = 1 << 15
+
+    maxContentRunes = 2000
+)
+
+type Bot struct {
+    Sessions *chatops.Sessions
+    Agent *client.Client
+    BotToken string
+    ApplicationID string
+    PublicKey ed25519.PublicKey
+    HTTPClient *http.Client
+    APIBase string
+    Placeholder string
+    // CommandOption is the slash-command option that carries the prompt.
+    CommandOption string
+    MaxAttachmentBytes int64
+    // Channels are answered without a mention.
+    Channels []string
+    // UpdateInterval spaces the edits made while a reply streams.
+    UpdateInterval time.Duration
+    OnError func(error)
+
+    mu sync.Mutex
+    userID string
+}
+
+// NewBot creates a bot; publicKey is the application's hex-encoded public
+// key and is required for the interactions endpoint.
+func NewBot(sessions *chatops.Sessions, agent *client.Client, botToken, applicationID, publicKey string) (*Bot, error) {
+    key, err := hex.DecodeString(publicKey)
+    if err != nil || len(key) != ed25519.PublicKeySize {
+        return nil, fmt.Errorf("discord: invalid application public key")
+    }
+    return &Bot{
+        Sessions: sessions,
+        Agent: agent,
+        BotToken: botToken,
+        ApplicationID: applicationID,
+        PublicKey: key,
+        Placeholder: "*Working on it…*",
+        CommandOption: "message",
+        MaxAttachmentBytes: 25 << 20,
+        UpdateInterval: time.Second,
+    }, nil
+}
+
+type user struct {
+    ID string `json:"id"`
+    Bot bool `json:"bot"`
+}
+
+type attachment struct {
+    Filename string `json:"filename"`
+    ContentType string `json:"content_type"`
+    Size int64 `json:"size"`
+    URL string `json:"url"`
+}
+
+type interaction struct {
+    Type int `json:"type"`
+    Token string `json:"token"`
+    GuildID string `json:"guild_id"`
+    ChannelID string `json:"channel_id"`
+    Member *struct {
+        User user `json:"user"`
+    } `json:"member"`
+    User *user `json:"user"`
+    Data struct {
+        Name string `json:"name"`
+        Options []struct {
+            Name string `json:"name"`
+            Value any `json:"value"`
+        } `json:"options"`
+    } `json:"data"`
+}
+
+// InteractionsHandler serves the interactions endpoint URL. Commands are
+// acknowledged with a deferred response and the agent's reply is edited in
+// once it arrives.
+func (b *Bot) InteractionsHandler() http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+        if err != nil {
+            http.Error(w, "read body", http.StatusBadRequest)
+            return
+        }
+        signature, err := hex.DecodeString(r.Header.Get("X-Signature-Ed25519"))
+        if err != nil || !ed25519.Verify(b.PublicKey, append([]byte(r.Header.Get("X-Signature-Timestamp")), body...), signature) {
+            http.Error(w, "invalid request signature", http.StatusUnauthorized)
+            return
+        }
+        var in interaction
+        if err := json.Unmarshal(body, &in); err != nil {
+            http.Error(w, "invalid payload", http.StatusBadRequest)
+            return
+        }
+        w.Header().Set("Content-Type", "application/json")
+        switch in.Type {
+        case 1:
+            io.WriteString(w, `{"type":1}`)
+        case 2:
+            io.WriteString(w, `{"type":5}`)
+            go b.handleCommand(context.Background(), in)
+        default:
+            http.Error(w, "unsupported interaction", http.StatusBadRequest)
+        }
+    })
+}
+
+func (b *Bot) handleCommand(ctx context.Context, in interaction) {
+    prompt := ""
+    for _, option := range in.Data.Options {
+        if option.Name == b.CommandOption {
+            prompt = fmt.Sprint(option.Value)
+        }
+    }
+    author := in.User
+    if in.Member != nil {
+        author = &in.Member.User
+    }
+    if author != nil {
+        ctx = client.WithActor(ctx, client.Actor{ID: "discord:" + author.ID, Tenant: in.GuildID})
+    }
+    path := fmt.Sprintf("/webhooks/%s/%s/messages/@original", b.ApplicationID, in.Token)
+    edit := func(content string) {
+        b.reportError(b.api(ctx, http.MethodPatch, path, map[string]any{"content": content}, nil))
+    }
+    edit(b.reply(ctx, scope(in.GuildID, in.ChannelID), prompt, nil, edit))
+}
+
+type gatewayPayload struct {
+    Op int `json:"op"`
+    D json.RawMessage `json:"d,omitempty"`
+    S *int64 `json:"s,omitempty"`
+    T string `json:"t,omitempty"`
+}
+
+type message struct {
+    ID string `json:"id"`
+    ChannelID string `json:"channel_id"`
+    GuildID string `json:"guild_id"`
+    Author user `json:"author"`
+    Content string `json:"content"`
+    Mentions []user `json:"mentions"`
+    Attachments []attachment `json:"attachments"`
+}
+
+// RunGateway connects to the Discord Gateway and answers channel messages
+// until ctx is cancelled, reconnecting on failure.
+func (b *Bot) RunGateway(ctx context.Context) error {
+    for {
+        err := b.gatewaySession(ctx)
+        if ctx.Err() != nil {
+            return ctx.Err()
+        }
+        b.reportError(err)
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-time.After(5 * time.Second):
+        }
+    }
+}
+
+func (b *Bot) gatewaySession(ctx context.Context) error {
+    var gateway struct {
+        URL string `json:"url"`
+    }
+    if err := b.api(ctx, http.MethodGet, "/gateway/bot", nil, &gateway); err != nil {
+        return err
+    }
+    conn, _, err := wsconn.Dial(ctx, gateway.URL+"/?v=10&encoding=json", nil, nil)
+    if err != nil {
+        return err
+    }
+    sessionCtx, cancel := context.WithCancel(ctx)
+    defer cancel()
+    defer conn.Close(wsconn.CloseNormal, "")
+    go func() {
+        <-sessionCtx.Done()
+        conn.Close(wsconn.CloseGoingAway, "")
+    }()
+
+    var seqMu sync.Mutex
+    var seq *int64
+    send := func(op int, d any) error {
+        encoded, err := json.Marshal(map[string]any{"op": op, "d": d})
+        if err != nil {
+            return err
+        }
+        return conn.WriteMessage(wsconn.OpText, encoded)
+    }
+    for {
+        _, raw, err := conn.ReadMessage()
+        if err != nil {
+            return err
+        }
+        var payload gatewayPayload
+        if err := json.Unmarshal(raw, &payload); err != nil {
+            continue
+        }
+        if payload.S != nil {
+            seqMu.Lock()
+            seq = payload.S
+            seqMu.Unlock()
+        }
+        switch payload.Op {
+        case 10:
+            var hello struct {
+                HeartbeatInterval int64 `json:"heartbeat_interval"`
+            }
+            json.Unmarshal(payload.D, &hello)
+            go func() {
+                ticker := time.NewTicker(time.Duration(hello.HeartbeatInterval) * time.Millisecond)
+                defer ticker.Stop()
+                for {
+                    select {
+                    case <-sessionCtx.Done():
+                        return
+                    case <-ticker.C:
+                        seqMu.Lock()
+                        last := seq
+                        seqMu.Unlock()
+                        if send(1, last) != nil {
+                            cancel()
+                            return
+                        }
+                    }
+                }
+            }()
+            identify := map[string]any{
+                "token": b.BotToken,
+                "intents": intentGuildMessages | intentDirectMessages | intentMessageContent,
+                "properties": map[string]string{"os": "linux", "browser": "echo-agent", "device": "echo-agent"},
+            }
+            if err := send(2, identify); err != nil {
+                return err
+            }
+        case 1:
+            seqMu.Lock()
+            last := seq
+            seqMu.Unlock()
+            send(1, last)
+        case 7, 9:
+            // Reconnect or invalid session: start over with a fresh session.
+            return nil
+        case 0:
+            switch payload.T {
+            case "READY":
+                var ready struct {
+                    User user `json:"user"`
+                }
+                if json.Unmarshal(payload.D, &ready) == nil {
+                    b.mu.Lock()
+                    b.userID = ready.User.ID
+                    b.mu.Unlock()
+                }
+            case "MESSAGE_CREATE":
+                var msg message
+                if err := json.Unmarshal(payload.D, &msg); err != nil || msg.Author.Bot || !b.addressed(msg) {
+                    continue
+                }
+                go b.handleMessage(ctx, msg)
+            }
+        }
+    }
+}
+
+// addressed reports whether msg is meant for the bot: a DM, a message in
+// one of Channels, or one that mentions the bot.
+func (b *Bot) addressed(msg message) bool {
+    if msg.GuildID == "" {
+        return true
+    }
+    for _, channel := range b.Channels {
+        if channel == msg.ChannelID {
+            return true
+        }
+    }
+    b.mu.Lock()
+    self := b.userID
+    b.mu.Unlock()
+    for _, mentioned := range msg.Mentions {
+        if self != "" && mentioned.ID == self {
+            return true
+        }
+    }
+    return false
+}
+
+func (b *Bot) handleMessage(ctx context.Context, msg message) {
+    b.mu.Lock()
+    self := b.userID
+    b.mu.Unlock()
+    if self != "" {
+        msg.Content = strings.NewReplacer("<@"+self+">", "", "<@!"+self+">", "").Replace(msg.Content)
+    }
+    if strings.TrimSpace(msg.Content) == "" && len(msg.Attachments) == 0 {
+        return
+    }
+    ctx = client.WithActor(ctx, client.Actor{ID: "discord:" + msg.Author.ID, Tenant: msg.GuildID})
+    var posted struct {
+        ID string `json:"id"`
+    }
+    channelPath := "/channels/" + msg.ChannelID + "/messages"
+    placeholder := map[string]any{"content": b.Placeholder, "message_reference": map[string]string{"message_id": msg.ID}}
+    if err := b.api(ctx, http.MethodPost, channelPath, placeholder, &posted); err != nil {
+        b.reportError(err)
+        return
+    }
+    edit := func(content string) {
+        b.reportError(b.api(ctx, http.MethodPatch, channelPath+"/"+posted.ID, map[string]any{"content": content}, nil))
+    }
+    edit(b.reply(ctx, scope(msg.GuildID, msg.ChannelID), msg.Content, msg.Attachments, edit))
+}
+
+// reply streams the agent's answer through edit and returns the final
+// content.
+func (b *Bot) reply(ctx context.Context, scope, prompt string, attachments []attachment, edit func(string)) string {
+    inputs := map[string]any{}
+    if len(attachments) > 0 {
+        files, err := b.passthrough(ctx, attachments)
+        if err != nil {
+            return "Sorry, uploading the attachments failed: " + err.Error()
+        }
+        inputs["files"] = files
+    }
+    progress := func(reply, stage string) {
+        if reply == "" {
+            reply = "*" + stage + "…*"
+        }
+        edit(truncate(reply))
+    }
+    resp, err := b.Sessions.Stream(ctx, scope, prompt, inputs, b.UpdateInterval, progress)
+    if err != nil {
+        return truncate("Sorry, the agent request failed: " + err.Error())
+    }
+    return truncate(resp.Message)
+}
+
+// truncate fits content into a Discord message, cutting on a rune
+// boundary.
+func truncate(content string) string {
+    if utf8.RuneCountInString(content) <= maxContentRunes {
+        return content
+    }
+    runes := []rune(content)
+    return string(runes[:maxContentRunes-1]) + "…"
+}
+
+func (b *Bot) passthrough(ctx context.Context, attachments []attachment) ([]*client.FileRef, error) {
+    httpClient := b.httpClient()
+    refs := make([]*client.FileRef, 0, len(attachments))
+    for _, a := range attachments {
+        if b.MaxAttachmentBytes > 0 && a.Size > b.MaxAttachmentBytes {
+            return nil, fmt.Errorf("%s exceeds the attachment size limit", a.Filename)
+        }
+        req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.URL, nil)
+        if err != nil {
+            return nil, err
+        }
+        resp, err := httpClient.Do(req)
+        if err != nil {
+            return nil, err
+        }
+        if resp.StatusCode < 200 || resp.StatusCode > 299 {
+            resp.Body.Close()
+            return nil, fmt.Errorf("download %s failed with status %d", a.Filename, resp.StatusCode)
+        }
+        var body io.Reader = resp.Body
+        if b.MaxAttachmentBytes > 0 {
+            // The reported size is not trusted; the download itself is
+            // capped.
+            body = &cappedReader{r: io.LimitReader(resp.Body, b.MaxAttachmentBytes+1), max: b.MaxAttachmentBytes, name: a.Filename}
+        }
+        ref, err := b.Agent.UploadFile(ctx, a.Filename, a.ContentType, body)
+        resp.Body.Close()
+        if err != nil {
+            return nil, err
+        }
+        refs = append(refs, ref)
+    }
+    return refs, nil
+}
+
+// cappedReader fails once more than max bytes have been read.
+type cappedReader struct {
+    r io.Reader
+    max int64
+    read int64
+    name string
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+    n, err := c.r.Read(p)
+    c.read += int64(n)
+    if c.read > c.max {
+        return 0, fmt.Errorf("%s exceeds the attachment size limit", c.name)
+    }
+    return n, err
+}
+
+func scope(guild, channel string) string {
+    return "discord:" + guild + ":" + channel
+}
+
+func (b *Bot) api(ctx context.Context, method, path string, payload, out any) error {
+    base := b.APIBase
+    if base == "" {
+        base = defaultAPIBase
+    }
+    var body io.Reader = http.NoBody
+    if payload != nil {
+        encoded, err := json.Marshal(payload)
+        if err != nil {
+            return err
+        }
+        body = bytes.NewReader(encoded)
+    }
+    req, err := http.NewRequestWithContext(ctx, method, base+path, body)
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Authorization", "Bot "+b.BotToken)
+    if payload != nil {
+        req.Header.Set("Content-Type", "application/json")
+    }
+    resp, err := b.httpClient().Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode >= 400 {
+        return fmt.Errorf("discord %s %s failed with status %d", method, path, resp.StatusCode)
+    }
+    if out == nil {
+        return nil
+    }
+    return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (b *Bot) httpClient() *http.Client {
+    if b.HTTPClient != nil {
+        return b.HTTPClient
+    }
+    return http.DefaultClient
+}
+
+func (b *Bot) reportError(err error) {
+    if err != nil && b.OnError != nil {
+        b.OnError(err)
+    }
+}
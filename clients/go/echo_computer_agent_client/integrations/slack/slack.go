@@ -0,0 +1,405 @@
+// Package slack connects Slack workspaces to the agent over the Events API
+// or Socket Mode. The bot answers when mentioned and in direct messages.
+// Each thread is its own conversation, a top-level message starting a new
+// one; replies are posted as a placeholder that is updated
+// as the agent's answer streams in, and function Data is rendered as Block
+// Kit.
+package slack
+
+import (
+    "bytes"
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "sort"
+    "regexp"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+    "unicode/utf8"
+
+    client "echo_computer_agent_client"
+    "echo_computer_agent_client/integrations/chatops"
+    "echo_computer_agent_client/internal/wsconn"
+)
+
+const (
+    defaultAPIBase = "https://slack.com/api"
+
+    // seenTTL is how long a message is remembered so the `message` and
+    // `app_mention` events Slack sends for one mention get a single reply.
+    seenTTL = 10 * time.Minute
+)
+
+type Bot struct {
+    Sessions *chatops.Sessions
+    BotToken string
+    SigningSecret string
+    // AppToken (xapp-...) is required only for Socket Mode.
+    AppToken string
+    HTTPClient *http.Client
+    APIBase string
+    Placeholder string
+    // UpdateInterval spaces the chat.update calls made while a reply
+    // streams; Slack rate-limits updates to roughly one per second.
+    UpdateInterval time.Duration
+    OnError func(error)
+
+    mu sync.Mutex
+    seen map[string]time.Time
+    userID string
+}
+
+// leadingMention matches the mention that opens an app_mention's text, for
+// when the bot's own user ID is unknown.
+var leadingMention = regexp.MustCompile(`^\s*<@[A-Z0-9]+>`)
+
+func NewBot(sessions *chatops.Sessions, botToken, signingSecret string) *Bot {
+    return &Bot{Sessions: sessions, BotToken: botToken, SigningSecret: signingSecret, Placeholder: "_Working on it…_", UpdateInterval: time.Second}
+}
+
+type messageEvent struct {
+    Type string `json:"type"`
+    Subtype string `json:"subtype"`
+    BotID string `json:"bot_id"`
+    Channel string `json:"channel"`
+    ChannelType string `json:"channel_type"`
+    User string `json:"user"`
+    Text string `json:"text"`
+    TS string `json:"ts"`
+    ThreadTS string `json:"thread_ts"`
+}
+
+type eventCallback struct {
+    Type string `json:"type"`
+    Challenge string `json:"challenge"`
+    TeamID string `json:"team_id"`
+    Event messageEvent `json:"event"`
+}
+
+// EventsHandler serves the Events API request URL. Requests are verified
+// against SigningSecret and acknowledged immediately; agent calls happen in
+// the background because Slack expects an answer within three seconds.
+func (b *Bot) EventsHandler() http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+        if err != nil {
+            http.Error(w, "read body", http.StatusBadRequest)
+            return
+        }
+        if !b.verify(r.Header, body) {
+            http.Error(w, "invalid signature", http.StatusUnauthorized)
+            return
+        }
+        if r.Header.Get("X-Slack-Retry-Num") != "" {
+            // Slack retries when our ack was slow; the first delivery is
+            // already being handled.
+            w.WriteHeader(http.StatusOK)
+            return
+        }
+        var callback eventCallback
+        if err := json.Unmarshal(body, &callback); err != nil {
+            http.Error(w, "invalid payload", http.StatusBadRequest)
+            return
+        }
+        if callback.Type == "url_verification" {
+            w.Header().Set("Content-Type", "text/plain")
+            io.WriteString(w, callback.Challenge)
+            return
+        }
+        w.WriteHeader(http.StatusOK)
+        if callback.Type == "event_callback" {
+            go b.handle(context.Background(), callback.TeamID, callback.Event)
+        }
+    })
+}
+
+// RunSocketMode connects with AppToken and processes events until ctx is
+// cancelled, reconnecting when Slack rotates the connection.
+func (b *Bot) RunSocketMode(ctx context.Context) error {
+    for {
+        err := b.socketSession(ctx)
+        if ctx.Err() != nil {
+            return ctx.Err()
+        }
+        b.reportError(err)
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-time.After(2 * time.Second):
+        }
+    }
+}
+
+func (b *Bot) socketSession(ctx context.Context) error {
+    var opened struct {
+        OK bool `json:"ok"`
+        URL string `json:"url"`
+        Error string `json:"error"`
+    }
+    if err := b.api(ctx, b.AppToken, "apps.connections.open", nil, &opened); err != nil {
+        return err
+    }
+    conn, _, err := wsconn.Dial(ctx, opened.URL, nil, nil)
+    if err != nil {
+        return err
+    }
+    defer conn.Close(wsconn.CloseNormal, "")
+    go func() {
+        <-ctx.Done()
+        conn.Close(wsconn.CloseGoingAway, "")
+    }()
+    for {
+        _, raw, err := conn.ReadMessage()
+        if err != nil {
+            return err
+        }
+        var envelope struct {
+            EnvelopeID string `json:"envelope_id"`
+            Type string `json:"type"`
+            Payload eventCallback `json:"payload"`
+        }
+        if err := json.Unmarshal(raw, &envelope); err != nil {
+            continue
+        }
+        if envelope.EnvelopeID != "" {
+            ack, _ := json.Marshal(map[string]string{"envelope_id": envelope.EnvelopeID})
+            if err := conn.WriteMessage(wsconn.OpText, ack); err != nil {
+                return err
+            }
+        }
+        switch envelope.Type {
+        case "events_api":
+            go b.handle(ctx, envelope.Payload.TeamID, envelope.Payload.Event)
+        case "disconnect":
+            return nil
+        }
+    }
+}
+
+func (b *Bot) handle(ctx context.Context, team string, event messageEvent) {
+    if (event.Type != "message" && event.Type != "app_mention") || event.Subtype != "" || event.BotID != "" || strings.TrimSpace(event.Text) == "" {
+        return
+    }
+    // Plain channel messages are not meant for the bot unless they mention
+    // it, and those arrive again as app_mention.
+    if event.Type == "message" && event.ChannelType != "im" {
+        return
+    }
+    if !b.firstDelivery(team, event.Channel, event.TS) {
+        return
+    }
+    text := b.stripMention(ctx, event.Text)
+    if strings.TrimSpace(text) == "" {
+        return
+    }
+    thread := event.ThreadTS
+    if thread == "" {
+        thread = event.TS
+    }
+    // The reply opens a thread on a top-level message, so both are keyed on
+    // that thread for follow-ups to land in the same conversation.
+    scope := "slack:" + team + ":" + event.Channel + ":" + thread
+    ctx = client.WithActor(ctx, client.Actor{ID: "slack:" + event.User, Tenant: team})
+
+    var posted struct {
+        OK bool `json:"ok"`
+        TS string `json:"ts"`
+        Error string `json:"error"`
+    }
+    if err := b.api(ctx, b.BotToken, "chat.postMessage", map[string]any{"channel": event.Channel, "thread_ts": thread, "text": b.Placeholder}, &posted); err != nil {
+        b.reportError(err)
+        return
+    }
+    progress := func(reply, stage string) {
+        text := reply
+        if text == "" {
+            text = "_" + stage + "…_"
+        }
+        b.reportError(b.api(ctx, b.BotToken, "chat.update", map[string]any{"channel": event.Channel, "ts": posted.TS, "text": text}, nil))
+    }
+    update := map[string]any{"channel": event.Channel, "ts": posted.TS}
+    resp, err := b.Sessions.Stream(ctx, scope, text, nil, b.UpdateInterval, progress)
+    if err != nil {
+        update["text"] = "Sorry, the agent request failed: " + err.Error()
+    } else {
+        update["text"] = resp.Message
+        update["blocks"] = Blocks(resp)
+    }
+    if err := b.api(ctx, b.BotToken, "chat.update", update, nil); err != nil {
+        b.reportError(err)
+    }
+}
+
+// stripMention removes the bot's mentions from text. The bot's user ID is
+// looked up with auth.test once; until that succeeds only a leading
+// mention is removed.
+func (b *Bot) stripMention(ctx context.Context, text string) string {
+    b.mu.Lock()
+    self := b.userID
+    b.mu.Unlock()
+    if self == "" {
+        var auth struct {
+            UserID string `json:"user_id"`
+        }
+        if err := b.api(ctx, b.BotToken, "auth.test", nil, &auth); err != nil {
+            b.reportError(err)
+        } else {
+            self = auth.UserID
+            b.mu.Lock()
+            b.userID = self
+            b.mu.Unlock()
+        }
+    }
+    if self == "" {
+        return strings.TrimSpace(leadingMention.ReplaceAllLiteralString(text, ""))
+    }
+    return strings.TrimSpace(strings.ReplaceAll(text, "<@"+self+">", ""))
+}
+
+// firstDelivery reports whether the message has not been handled yet.
+// Mentions arrive as both a `message` and an `app_mention` event with the
+// same ts.
+func (b *Bot) firstDelivery(team, channel, ts string) bool {
+    key := team + ":" + channel + ":" + ts
+    now := time.Now()
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    if b.seen == nil {
+        b.seen = map[string]time.Time{}
+    }
+    if _, ok := b.seen[key]; ok {
+        return false
+    }
+    for k, at := range b.seen {
+        if now.Sub(at) > seenTTL {
+            delete(b.seen, k)
+        }
+    }
+    b.seen[key] = now
+    return true
+}
+
+// Blocks renders a reply as Block Kit: the message as a section, scalar Data
+// fields as a two-column field list, nested Data as a JSON code block, and
+// the routed function as context.
+func Blocks(resp *client.ChatResponse) []map[string]any {
+    blocks := []map[string]any{{
+        "type": "section",
+        "text": map[string]any{"type": "mrkdwn", "text": resp.Message},
+    }}
+    keys := make([]string, 0, len(resp.Data))
+    for k := range resp.Data {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+    var fields []map[string]any
+    nested := map[string]any{}
+    for _, k := range keys {
+        switch v := resp.Data[k].(type) {
+        case map[string]any, []any:
+            nested[k] = v
+        default:
+            fields = append(fields, map[string]any{"type": "mrkdwn", "text": fmt.Sprintf("*%s*\n%v", k, v)})
+        }
+    }
+    for len(fields) > 0 {
+        // Slack allows at most ten fields per section.
+        n := len(fields)
+        if n > 10 {
+            n = 10
+        }
+        blocks = append(blocks, map[string]any{"type": "section", "fields": fields[:n]})
+        fields = fields[n:]
+    }
+    if len(nested) > 0 {
+        encoded, _ := json.MarshalIndent(nested, "", "  ")
+        text := string(encoded)
+        if utf8.RuneCountInString(text) > 2900 {
+            text = string([]rune(text)[:2900]) + "\n…"
+        }
+        blocks = append(blocks, map[string]any{
+            "type": "section",
+            "text": map[string]any{"type": "mrkdwn", "text": "```" + text + "```"},
+        })
+    }
+    if resp.Function != "" {
+        blocks = append(blocks, map[string]any{
+            "type": "context",
+            "elements": []map[string]any{{"type": "mrkdwn", "text": "function: `" + resp.Function + "`"}},
+        })
+    }
+    return blocks
+}
+
+func (b *Bot) verify(header http.Header, body []byte) bool {
+    ts := header.Get("X-Slack-Request-Timestamp")
+    unix, err := strconv.ParseInt(ts, 10, 64)
+    if err != nil || time.Since(time.Unix(unix, 0)).Abs() > 5*time.Minute {
+        return false
+    }
+    mac := hmac.New(sha256.New, []byte(b.SigningSecret))
+    fmt.Fprintf(mac, "v0:%s:%s", ts, body)
+    expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+    return hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature")))
+}
+
+func (b *Bot) api(ctx context.Context, token, method string, payload, out any) error {
+    base := b.APIBase
+    if base == "" {
+        base = defaultAPIBase
+    }
+    var body io.Reader = http.NoBody
+    if payload != nil {
+        encoded, err := json.Marshal(payload)
+        if err != nil {
+            return err
+        }
+        body = bytes.NewReader(encoded)
+    }
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+"/"+method, body)
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Authorization", "Bearer "+token)
+    req.Header.Set("Content-Type", "application/json; charset=utf-8")
+    httpClient := b.HTTPClient
+    if httpClient == nil {
+        httpClient = http.DefaultClient
+    }
+    resp, err := httpClient.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    raw, err := io.ReadAll(resp.Body)
+    if err != nil {
+        return err
+    }
+    var status struct {
+        OK bool `json:"ok"`
+        Error string `json:"error"`
+    }
+    if err := json.Unmarshal(raw, &status); err != nil {
+        return fmt.Errorf("slack %s: %w", method, err)
+    }
+    if !status.OK {
+        return fmt.Errorf("slack %s: %s", method, status.Error)
+    }
+    if out == nil {
+        return nil
+    }
+    return json.Unmarshal(raw, out)
+}
+
+func (b *Bot) reportError(err error) {
+    if err != nil && b.OnError != nil {
+        b.OnError(err)
+    }
+}
@@ -0,0 +1,57 @@
+package echo_computer_agent_client
+
+import (
+    "net/http"
+)
+
+// Interceptor sees every request the client sends through its transport
+// and the response that comes back. It calls next.Do to pass the request
+// on, and may change the request first, change or replace the response,
+// or send the request more than once (bodies can be re-read through
+// req.GetBody), e.g. to refresh credentials after a 401. Returning without
+// calling next answers the request itself.
+//
+// Interceptors run below retries, throttling and the response cache, so a
+// retried call passes through them once per attempt. WebSocket sessions
+// bypass them, like the transport.
+type Interceptor interface {
+    Intercept(req *http.Request, next Transport) (*http.Response, error)
+}
+
+type InterceptorFunc func(req *http.Request, next Transport) (*http.Response, error)
+
+func (f InterceptorFunc) Intercept(req *http.Request, next Transport) (*http.Response, error) {
+    return f(req, next)
+}
+
+// TransportFunc adapts a function to Transport.
+type TransportFunc func(req *http.Request) (*http.Response, error)
+
+func (f TransportFunc) Do(req *http.Request) (*http.Response, error) {
+    return f(req)
+}
+
+// AddInterceptor appends interceptors to the client's chain. The first
+// added is outermost: it sees requests first and responses last.
+func (c *Client) AddInterceptor(interceptors ...Interceptor) {
+    c.interceptors = append(c.interceptors, interceptors...)
+}
+
+func WithInterceptors(interceptors ...Interceptor) Option {
+    return func(c *Client) { c.AddInterceptor(interceptors...) }
+}
+
+// roundTrip sends req through the interceptor chain to the transport.
+func (c *Client) roundTrip(req *http.Request) (*http.Response, error) {
+    return c.chain(0).Do(req)
+}
+
+func (c *Client) chain(i int) Transport {
+    if i == len(c.interceptors) {
+        return c.transport
+    }
+    interceptor := c.interceptors[i]
+    return TransportFunc(func(req *http.Request) (*http.Response, error) {
+        return interceptor.Intercept(req, c.chain(i+1))
+    })
+}
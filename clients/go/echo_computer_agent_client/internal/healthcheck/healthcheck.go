@@ -0,0 +1,38 @@
+// Package healthcheck is the end-to-end check behind "echoctl health -deep"
+// and cmd/smoke: the agent has to list its functions and route a message
+// to one of them.
+package healthcheck
+
+import (
+    "context"
+    "errors"
+
+    client "echo_computer_agent_client"
+)
+
+// Message is routed, not executed, so the check has no side effects.
+const Message = "launch echo.bank"
+
+type Result struct {
+    Functions int `json:"functions"`
+    Function string `json:"function"`
+    Message string `json:"message"`
+}
+
+func Run(ctx context.Context, c *client.Client) (*Result, error) {
+    functions, err := c.ListFunctions(ctx)
+    if err != nil {
+        return nil, err
+    }
+    if len(functions.Functions) == 0 {
+        return nil, errors.New("no functions returned")
+    }
+    chat, err := c.Chat(ctx, client.ChatRequest{Message: Message})
+    if err != nil {
+        return nil, err
+    }
+    if chat.Function == "" {
+        return nil, errors.New("empty function name")
+    }
+    return &Result{Functions: len(functions.Functions), Function: chat.Function, Message: chat.Message}, nil
+}
@@ -0,0 +1,232 @@
+// Package lineedit reads lines from a terminal with editing, history, and
+// tab completion, without dependencies outside the standard library. When
+// input is not a terminal, or raw mode is unavailable, lines are read
+// plainly.
+package lineedit
+
+import (
+    "bufio"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "strings"
+    "unicode"
+)
+
+// ErrInterrupted is returned by ReadLine when the user presses Ctrl-C.
+var ErrInterrupted = errors.New("interrupted")
+
+// Editor reads lines. Complete, when set, returns the candidates for the
+// word ending at the cursor, given the line up to it; Tab inserts what
+// they have in common and a second Tab lists them.
+type Editor struct {
+    Prompt string
+    Complete func(line string) []string
+
+    in *os.File
+    out io.Writer
+    reader *bufio.Reader
+    history []string
+}
+
+func New(in *os.File, out io.Writer) *Editor {
+    return &Editor{in: in, out: out, reader: bufio.NewReader(in)}
+}
+
+// History returns the lines read so far, oldest first.
+func (e *Editor) History() []string {
+    return append([]string(nil), e.history...)
+}
+
+// ReadLine reads one line, without its newline. It returns io.EOF on
+// Ctrl-D at an empty line or at the end of input.
+func (e *Editor) ReadLine() (string, error) {
+    restore, err := e.raw()
+    if err != nil {
+        return e.readPlain()
+    }
+    defer restore()
+    line, err := e.edit()
+    fmt.Fprint(e.out, "\n")
+    if err == nil && strings.TrimSpace(line) != "" {
+        if n := len(e.history); n == 0 || e.history[n-1] != line {
+            e.history = append(e.history, line)
+        }
+    }
+    return line, err
+}
+
+func (e *Editor) raw() (func(), error) {
+    info, err := e.in.Stat()
+    if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+        return nil, errors.New("not a terminal")
+    }
+    return makeRaw(e.in.Fd())
+}
+
+func (e *Editor) readPlain() (string, error) {
+    fmt.Fprint(e.out, e.Prompt)
+    line, err := e.reader.ReadString('\n')
+    if err != nil && (err != io.EOF || line == "") {
+        return "", err
+    }
+    line = strings.TrimRight(line, "\r\n")
+    if strings.TrimSpace(line) != "" {
+        e.history = append(e.history, line)
+    }
+    return line, nil
+}
+
+// edit runs the editing loop on a raw terminal.
+func (e *Editor) edit() (string, error) {
+    var line []rune
+    cursor := 0
+    recall := len(e.history)
+    var pending []rune
+    tabbed := false
+    e.redraw(line, cursor)
+    for {
+        r, _, err := e.reader.ReadRune()
+        if err != nil {
+            return "", err
+        }
+        wasTab := tabbed
+        tabbed = false
+        switch r {
+        case '\r', '\n':
+            return string(line), nil
+        case 3: // Ctrl-C
+            return "", ErrInterrupted
+        case 4: // Ctrl-D
+            if len(line) == 0 {
+                return "", io.EOF
+            }
+            if cursor < len(line) {
+                line = append(line[:cursor], line[cursor+1:]...)
+            }
+        case 1: // Ctrl-A
+            cursor = 0
+        case 5: // Ctrl-E
+            cursor = len(line)
+        case 21: // Ctrl-U
+            line, cursor = line[cursor:], 0
+        case 11: // Ctrl-K
+            line = line[:cursor]
+        case 23: // Ctrl-W
+            start := cursor
+            for start > 0 && line[start-1] == ' ' {
+                start--
+            }
+            for start > 0 && line[start-1] != ' ' {
+                start--
+            }
+            line, cursor = append(line[:start], line[cursor:]...), start
+        case 12: // Ctrl-L
+            fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+        case 127, 8: // Backspace
+            if cursor > 0 {
+                line, cursor = append(line[:cursor-1], line[cursor:]...), cursor-1
+            }
+        case '\t':
+            line, cursor = e.complete(line, cursor, wasTab)
+            tabbed = true
+        case 27: // escape sequence
+            seq := e.escape()
+            switch seq {
+            case "[D":
+                cursor = max(cursor-1, 0)
+            case "[C":
+                cursor = min(cursor+1, len(line))
+            case "[H", "[1~", "OH":
+                cursor = 0
+            case "[F", "[4~", "OF":
+                cursor = len(line)
+            case "[3~":
+                if cursor < len(line) {
+                    line = append(line[:cursor], line[cursor+1:]...)
+                }
+            case "[A", "[B":
+                if recall == len(e.history) {
+                    pending = append([]rune(nil), line...)
+                }
+                if seq == "[A" && recall > 0 {
+                    recall--
+                } else if seq == "[B" && recall < len(e.history) {
+                    recall++
+                }
+                if recall == len(e.history) {
+                    line = append([]rune(nil), pending...)
+                } else {
+                    line = []rune(e.history[recall])
+                }
+                cursor = len(line)
+            }
+        default:
+            if unicode.IsPrint(r) {
+                line = append(line[:cursor], append([]rune{r}, line[cursor:]...)...)
+                cursor++
+            }
+        }
+        e.redraw(line, cursor)
+    }
+}
+
+// escape reads the rest of an escape sequence, such as "[A" for Up.
+func (e *Editor) escape() string {
+    first, _, err := e.reader.ReadRune()
+    if err != nil || first != '[' && first != 'O' {
+        return ""
+    }
+    seq := []rune{first}
+    for {
+        r, _, err := e.reader.ReadRune()
+        if err != nil {
+            return ""
+        }
+        seq = append(seq, r)
+        if r >= 0x40 && r <= 0x7e {
+            return string(seq)
+        }
+    }
+}
+
+func (e *Editor) redraw(line []rune, cursor int) {
+    fmt.Fprintf(e.out, "\r%s%s\x1b[K", e.Prompt, string(line))
+    if back := len(line) - cursor; back > 0 {
+        fmt.Fprintf(e.out, "\x1b[%dD", back)
+    }
+}
+
+func (e *Editor) complete(line []rune, cursor int, list bool) ([]rune, int) {
+    if e.Complete == nil {
+        return line, cursor
+    }
+    start := cursor
+    for start > 0 && line[start-1] != ' ' {
+        start--
+    }
+    word := string(line[start:cursor])
+    candidates := e.Complete(string(line[:cursor]))
+    if len(candidates) == 0 {
+        return line, cursor
+    }
+    prefix := candidates[0]
+    for _, candidate := range candidates[1:] {
+        for !strings.HasPrefix(candidate, prefix) {
+            prefix = prefix[:len(prefix)-1]
+        }
+    }
+    if len(candidates) == 1 {
+        prefix += " "
+    }
+    if len(prefix) > len(word) && strings.HasPrefix(prefix, word) {
+        insert := []rune(prefix[len(word):])
+        line = append(line[:cursor], append(insert, line[cursor:]...)...)
+        return line, cursor + len(insert)
+    }
+    if list && len(candidates) > 1 {
+        fmt.Fprintf(e.out, "\n%s\n", strings.Join(candidates, "  "))
+    }
+    return line, cursor
+}
@@ -0,0 +1,33 @@
+//go:build linux
+
+package lineedit
+
+import (
+    "syscall"
+    "unsafe"
+)
+
+// makeRaw puts the terminal on fd in raw mode, returning a func that
+// restores it. Output processing is left on, so "\n" still starts a new
+// line.
+func makeRaw(fd uintptr) (func(), error) {
+    var saved syscall.Termios
+    if err := ioctl(fd, syscall.TCGETS, &saved); err != nil {
+        return nil, err
+    }
+    raw := saved
+    raw.Iflag &^= syscall.IXON | syscall.ICRNL | syscall.INLCR | syscall.IGNCR
+    raw.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+    raw.Cc[syscall.VMIN], raw.Cc[syscall.VTIME] = 1, 0
+    if err := ioctl(fd, syscall.TCSETS, &raw); err != nil {
+        return nil, err
+    }
+    return func() { ioctl(fd, syscall.TCSETS, &saved) }, nil
+}
+
+func ioctl(fd uintptr, request uintptr, termios *syscall.Termios) error {
+    if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(unsafe.Pointer(termios))); errno != 0 {
+        return errno
+    }
+    return nil
+}
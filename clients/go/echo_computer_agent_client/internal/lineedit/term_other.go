@@ -0,0 +1,9 @@
+//go:build !linux
+
+package lineedit
+
+import "errors"
+
+func makeRaw(fd uintptr) (func(), error) {
+    return nil, errors.New("raw terminal mode is not supported on this platform")
+}
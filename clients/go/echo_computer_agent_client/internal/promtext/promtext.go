@@ -0,0 +1,106 @@
+// Package promtext writes the Prometheus text exposition format.
+package promtext
+
+import (
+    "fmt"
+    "io"
+    "math"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+type Writer struct {
+    w io.Writer
+    declared map[string]bool
+}
+
+func NewWriter(w io.Writer) *Writer {
+    return &Writer{w: w, declared: map[string]bool{}}
+}
+
+// Sample writes one sample, emitting HELP and TYPE lines the first time name
+// is seen.
+func (w *Writer) Sample(name, kind, help string, labels map[string]string, value float64) {
+    if !w.declared[name] {
+        w.declared[name] = true
+        fmt.Fprintf(w.w, "# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(help), name, kind)
+    }
+    fmt.Fprintf(w.w, "%s%s %s\n", name, FormatLabels(labels), FormatValue(value))
+}
+
+// Histogram writes a histogram's cumulative buckets, sum and count. counts
+// holds the observations at or below each of bounds, then those above the
+// last bound.
+func (w *Writer) Histogram(name, help string, labels map[string]string, bounds []float64, counts []uint64, sum float64) {
+    if !w.declared[name] {
+        w.declared[name] = true
+        fmt.Fprintf(w.w, "# HELP %s %s\n# TYPE %s histogram\n", name, escapeHelp(help), name)
+    }
+    withLE := make(map[string]string, len(labels)+1)
+    for k, v := range labels {
+        withLE[k] = v
+    }
+    var total uint64
+    for i, count := range counts {
+        total += count
+        le := math.Inf(1)
+        if i < len(bounds) {
+            le = bounds[i]
+        }
+        withLE["le"] = FormatValue(le)
+        fmt.Fprintf(w.w, "%s_bucket%s %d\n", name, FormatLabels(withLE), total)
+    }
+    fmt.Fprintf(w.w, "%s_sum%s %s\n", name, FormatLabels(labels), FormatValue(sum))
+    fmt.Fprintf(w.w, "%s_count%s %d\n", name, FormatLabels(labels), total)
+}
+
+func FormatLabels(labels map[string]string) string {
+    if len(labels) == 0 {
+        return ""
+    }
+    keys := make([]string, 0, len(labels))
+    for k := range labels {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+    parts := make([]string, 0, len(keys))
+    for _, k := range keys {
+        parts = append(parts, k+`="`+escapeLabel(labels[k])+`"`)
+    }
+    return "{" + strings.Join(parts, ",") + "}"
+}
+
+func FormatValue(v float64) string {
+    switch {
+    case math.IsInf(v, 1):
+        return "+Inf"
+    case math.IsInf(v, -1):
+        return "-Inf"
+    case math.IsNaN(v):
+        return "NaN"
+    }
+    return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// MetricName converts an arbitrary key into a valid metric name fragment.
+func MetricName(s string) string {
+    var b strings.Builder
+    for i, r := range s {
+        switch {
+        case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r >= '0' && r <= '9' && i > 0:
+            b.WriteRune(r)
+        default:
+            b.WriteByte('_')
+        }
+    }
+    return b.String()
+}
+
+func escapeLabel(s string) string {
+    return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
+
+func escapeHelp(s string) string {
+    return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
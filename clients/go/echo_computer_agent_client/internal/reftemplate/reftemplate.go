@@ -0,0 +1,76 @@
+// Package reftemplate renders the "{source.path}" templates shared by the
+// HTTP-facing packages.
+package reftemplate
+
+import (
+    "fmt"
+    "regexp"
+    "strconv"
+    "strings"
+)
+
+// Source resolves the dotted key after a source name.
+type Source func(key string) (any, bool)
+
+var reference = regexp.MustCompile(`\{([a-z]+)\.([^{}]+)\}`)
+
+// Render expands template. A template that is exactly one reference keeps
+// the referenced value's type; references embedded in longer strings are
+// interpolated as text. References to sources not in sources are left as
+// they are.
+func Render(template string, sources map[string]Source) (any, error) {
+    if m := reference.FindStringSubmatch(template); m != nil && m[0] == template {
+        if source, ok := sources[m[1]]; ok {
+            value, ok := source(m[2])
+            if !ok {
+                return nil, fmt.Errorf("%s.%s is missing", m[1], m[2])
+            }
+            return value, nil
+        }
+    }
+    var missing error
+    out := reference.ReplaceAllStringFunc(template, func(ref string) string {
+        m := reference.FindStringSubmatch(ref)
+        source, ok := sources[m[1]]
+        if !ok {
+            return ref
+        }
+        value, ok := source(m[2])
+        if !ok {
+            missing = fmt.Errorf("%s.%s is missing", m[1], m[2])
+            return ""
+        }
+        return fmt.Sprint(value)
+    })
+    return out, missing
+}
+
+// Lookup walks a dotted path through decoded JSON; numeric segments index
+// arrays.
+func Lookup(data any, dotted string) (any, bool) {
+    current := data
+    for _, key := range strings.Split(dotted, ".") {
+        switch node := current.(type) {
+        case map[string]any:
+            next, ok := node[key]
+            if !ok {
+                return nil, false
+            }
+            current = next
+        case []any:
+            i, err := strconv.Atoi(key)
+            if err != nil || i < 0 || i >= len(node) {
+                return nil, false
+            }
+            current = node[i]
+        default:
+            return nil, false
+        }
+    }
+    return current, true
+}
+
+// Map returns a Source over decoded JSON.
+func Map(data any) Source {
+    return func(key string) (any, bool) { return Lookup(data, key) }
+}
@@ -0,0 +1,86 @@
+// Package sse parses text/event-stream bodies.
+package sse
+
+import (
+    "bufio"
+    "bytes"
+    "io"
+    "strconv"
+    "strings"
+    "time"
+)
+
+type Event struct {
+    ID string
+    Type string
+    Data []byte
+}
+
+// Reader yields events from a stream. Comment lines (": keepalive") are
+// reported through OnActivity like any other line, so callers can treat
+// them as heartbeats.
+type Reader struct {
+    scanner *bufio.Scanner
+    OnActivity func()
+    // Retry holds the most recent "retry:" hint, zero if none was sent.
+    Retry time.Duration
+    // LastID is the last event ID seen, which persists across events as
+    // the spec requires.
+    LastID string
+}
+
+func NewReader(r io.Reader) *Reader {
+    scanner := bufio.NewScanner(r)
+    scanner.Buffer(make([]byte, 64*1024), 4<<20)
+    return &Reader{scanner: scanner}
+}
+
+// Next returns the next dispatched event, or io.EOF when the stream ends.
+func (r *Reader) Next() (Event, error) {
+    var event Event
+    var data bytes.Buffer
+    hasData := false
+    for r.scanner.Scan() {
+        if r.OnActivity != nil {
+            r.OnActivity()
+        }
+        line := r.scanner.Text()
+        if line == "" {
+            if !hasData {
+                event = Event{}
+                continue
+            }
+            event.ID = r.LastID
+            event.Data = bytes.TrimSuffix(data.Bytes(), []byte("\n"))
+            if event.Type == "" {
+                event.Type = "message"
+            }
+            return event, nil
+        }
+        if strings.HasPrefix(line, ":") {
+            continue
+        }
+        field, value, _ := strings.Cut(line, ":")
+        value = strings.TrimPrefix(value, " ")
+        switch field {
+        case "event":
+            event.Type = value
+        case "data":
+            data.WriteString(value)
+            data.WriteByte('\n')
+            hasData = true
+        case "id":
+            if !strings.ContainsRune(value, 0) {
+                r.LastID = value
+            }
+        case "retry":
+            if ms, err := strconv.Atoi(value); err == nil {
+                r.Retry = time.Duration(ms) * time.Millisecond
+            }
+        }
+    }
+    if err := r.scanner.Err(); err != nil {
+        return Event{}, err
+    }
+    return Event{}, io.EOF
+}
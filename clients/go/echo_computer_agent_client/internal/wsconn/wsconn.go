@@ -0,0 +1,367 @@
+// Package wsconn is a small RFC 6455 WebSocket implementation covering what
+// the client needs: dialing, accepting, text/binary messages, ping/pong, and
+// close handshakes. It keeps the module free of third-party dependencies.
+package wsconn
+
+import (
+    "bufio"
+    "context"
+    "crypto/rand"
+    "crypto/sha1"
+    "crypto/tls"
+    "encoding/base64"
+    "encoding/binary"
+    "errors"
+    "fmt"
+    "io"
+    "net"
+    "net/http"
+    "net/url"
+    "strings"
+    "sync"
+    "time"
+)
+
+const (
+    OpContinuation = 0x0
+    OpText = 0x1
+    OpBinary = 0x2
+    OpClose = 0x8
+    OpPing = 0x9
+    OpPong = 0xA
+
+    CloseNormal = 1000
+    CloseGoingAway = 1001
+
+    acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+    maxMessageBytes = 32 << 20
+)
+
+var ErrClosed = errors.New("wsconn: connection closed")
+
+// CloseError reports the peer's close frame.
+type CloseError struct {
+    Code int
+    Reason string
+}
+
+func (e *CloseError) Error() string {
+    return fmt.Sprintf("wsconn: closed by peer (%d %s)", e.Code, e.Reason)
+}
+
+type Conn struct {
+    conn net.Conn
+    br *bufio.Reader
+    client bool
+    wmu sync.Mutex
+    closeOnce sync.Once
+    // OnPong, when set, is called with the payload of every pong received.
+    OnPong func([]byte)
+}
+
+// Dial opens a client connection to rawURL; http(s) schemes are accepted and
+// mapped to ws(s).
+func Dial(ctx context.Context, rawURL string, header http.Header, tlsConfig *tls.Config) (*Conn, *http.Response, error) {
+    u, err := url.Parse(rawURL)
+    if err != nil {
+        return nil, nil, err
+    }
+    secure := false
+    switch u.Scheme {
+    case "ws", "http":
+    case "wss", "https":
+        secure = true
+    default:
+        return nil, nil, fmt.Errorf("wsconn: unsupported scheme %q", u.Scheme)
+    }
+    host := u.Host
+    if u.Port() == "" {
+        if secure {
+            host += ":443"
+        } else {
+            host += ":80"
+        }
+    }
+    var dialer net.Dialer
+    conn, err := dialer.DialContext(ctx, "tcp", host)
+    if err != nil {
+        return nil, nil, err
+    }
+    if secure {
+        cfg := &tls.Config{}
+        if tlsConfig != nil {
+            cfg = tlsConfig.Clone()
+        }
+        if cfg.ServerName == "" {
+            cfg.ServerName = u.Hostname()
+        }
+        tlsConn := tls.Client(conn, cfg)
+        if err := tlsConn.HandshakeContext(ctx); err != nil {
+            conn.Close()
+            return nil, nil, err
+        }
+        conn = tlsConn
+    }
+    if deadline, ok := ctx.Deadline(); ok {
+        conn.SetDeadline(deadline)
+    }
+
+    nonce := make([]byte, 16)
+    if _, err := rand.Read(nonce); err != nil {
+        conn.Close()
+        return nil, nil, err
+    }
+    key := base64.StdEncoding.EncodeToString(nonce)
+    httpURL := *u
+    if secure {
+        httpURL.Scheme = "https"
+    } else {
+        httpURL.Scheme = "http"
+    }
+    req, err := http.NewRequest(http.MethodGet, httpURL.String(), nil)
+    if err != nil {
+        conn.Close()
+        return nil, nil, err
+    }
+    for k, values := range header {
+        for _, v := range values {
+            req.Header.Add(k, v)
+        }
+    }
+    req.Header.Set("Upgrade", "websocket")
+    req.Header.Set("Connection", "Upgrade")
+    req.Header.Set("Sec-WebSocket-Key", key)
+    req.Header.Set("Sec-WebSocket-Version", "13")
+    if err := req.Write(conn); err != nil {
+        conn.Close()
+        return nil, nil, err
+    }
+    br := bufio.NewReader(conn)
+    resp, err := http.ReadResponse(br, req)
+    if err != nil {
+        conn.Close()
+        return nil, nil, err
+    }
+    if resp.StatusCode != http.StatusSwitchingProtocols {
+        conn.Close()
+        return nil, resp, fmt.Errorf("wsconn: handshake failed with status %d", resp.StatusCode)
+    }
+    if resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+        conn.Close()
+        return nil, resp, errors.New("wsconn: invalid Sec-WebSocket-Accept")
+    }
+    conn.SetDeadline(time.Time{})
+    return &Conn{conn: conn, br: br, client: true}, resp, nil
+}
+
+// Accept upgrades an incoming HTTP request to a server-side connection.
+func Accept(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+    if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+        http.Error(w, "websocket upgrade required", http.StatusBadRequest)
+        return nil, errors.New("wsconn: not a websocket upgrade")
+    }
+    key := r.Header.Get("Sec-WebSocket-Key")
+    if key == "" || r.Header.Get("Sec-WebSocket-Version") != "13" {
+        http.Error(w, "unsupported websocket version", http.StatusBadRequest)
+        return nil, errors.New("wsconn: bad handshake")
+    }
+    hijacker, ok := w.(http.Hijacker)
+    if !ok {
+        http.Error(w, "hijacking not supported", http.StatusInternalServerError)
+        return nil, errors.New("wsconn: response writer cannot hijack")
+    }
+    conn, rw, err := hijacker.Hijack()
+    if err != nil {
+        return nil, err
+    }
+    response := "HTTP/1.1 101 Switching Protocols\r\n" +
+        "Upgrade: websocket\r\n" +
+        "Connection: Upgrade\r\n" +
+        "Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+    if _, err := rw.WriteString(response); err != nil {
+        conn.Close()
+        return nil, err
+    }
+    if err := rw.Flush(); err != nil {
+        conn.Close()
+        return nil, err
+    }
+    return &Conn{conn: conn, br: rw.Reader}, nil
+}
+
+// ReadMessage returns the next complete text or binary message. Pings are
+// answered and pongs reported to OnPong transparently.
+func (c *Conn) ReadMessage() (int, []byte, error) {
+    var (
+        opcode int
+        message []byte
+    )
+    for {
+        fin, op, payload, err := c.readFrame()
+        if err != nil {
+            return 0, nil, err
+        }
+        switch op {
+        case OpPing:
+            if err := c.writeFrame(OpPong, payload); err != nil {
+                return 0, nil, err
+            }
+            continue
+        case OpPong:
+            if c.OnPong != nil {
+                c.OnPong(payload)
+            }
+            continue
+        case OpClose:
+            closeErr := &CloseError{Code: 1005}
+            if len(payload) >= 2 {
+                closeErr.Code = int(binary.BigEndian.Uint16(payload))
+                closeErr.Reason = string(payload[2:])
+            }
+            c.writeFrame(OpClose, payload)
+            c.conn.Close()
+            return 0, nil, closeErr
+        case OpContinuation:
+            if opcode == 0 {
+                return 0, nil, errors.New("wsconn: unexpected continuation frame")
+            }
+        default:
+            opcode = op
+            message = nil
+        }
+        if len(message)+len(payload) > maxMessageBytes {
+            return 0, nil, errors.New("wsconn: message too large")
+        }
+        message = append(message, payload...)
+        if fin {
+            return opcode, message, nil
+        }
+    }
+}
+
+func (c *Conn) WriteMessage(opcode int, data []byte) error {
+    return c.writeFrame(opcode, data)
+}
+
+func (c *Conn) Ping(data []byte) error {
+    return c.writeFrame(OpPing, data)
+}
+
+// Close sends a close frame and closes the underlying connection.
+func (c *Conn) Close(code int, reason string) error {
+    var err error
+    c.closeOnce.Do(func() {
+        payload := make([]byte, 2+len(reason))
+        binary.BigEndian.PutUint16(payload, uint16(code))
+        copy(payload[2:], reason)
+        c.conn.SetWriteDeadline(time.Now().Add(time.Second))
+        c.writeFrame(OpClose, payload)
+        err = c.conn.Close()
+    })
+    return err
+}
+
+func (c *Conn) SetReadDeadline(t time.Time) error {
+    return c.conn.SetReadDeadline(t)
+}
+
+func (c *Conn) readFrame() (bool, int, []byte, error) {
+    var header [2]byte
+    if _, err := io.ReadFull(c.br, header[:]); err != nil {
+        return false, 0, nil, err
+    }
+    fin := header[0]&0x80 != 0
+    opcode := int(header[0] & 0x0F)
+    masked := header[1]&0x80 != 0
+    length := uint64(header[1] & 0x7F)
+    switch length {
+    case 126:
+        var ext [2]byte
+        if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+            return false, 0, nil, err
+        }
+        length = uint64(binary.BigEndian.Uint16(ext[:]))
+    case 127:
+        var ext [8]byte
+        if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+            return false, 0, nil, err
+        }
+        length = binary.BigEndian.Uint64(ext[:])
+    }
+    if length > maxMessageBytes {
+        return false, 0, nil, errors.New("wsconn: frame too large")
+    }
+    var mask [4]byte
+    if masked {
+        if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+            return false, 0, nil, err
+        }
+    }
+    payload := make([]byte, length)
+    if _, err := io.ReadFull(c.br, payload); err != nil {
+        return false, 0, nil, err
+    }
+    if masked {
+        for i := range payload {
+            payload[i] ^= mask[i%4]
+        }
+    }
+    return fin, opcode, payload, nil
+}
+
+func (c *Conn) writeFrame(opcode int, payload []byte) error {
+    c.wmu.Lock()
+    defer c.wmu.Unlock()
+    frame := make([]byte, 0, len(payload)+14)
+    frame = append(frame, 0x80|byte(opcode))
+    maskBit := byte(0)
+    if c.client {
+        maskBit = 0x80
+    }
+    switch n := len(payload); {
+    case n < 126:
+        frame = append(frame, maskBit|byte(n))
+    case n <= 0xFFFF:
+        frame = append(frame, maskBit|126, byte(n>>8), byte(n))
+    default:
+        frame = append(frame, maskBit|127)
+        frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+    }
+    if c.client {
+        var mask [4]byte
+        if _, err := rand.Read(mask[:]); err != nil {
+            return err
+        }
+        frame = append(frame, mask[:]...)
+        start := len(frame)
+        frame = append(frame, payload...)
+        for i := range payload {
+            frame[start+i] ^= mask[i%4]
+        }
+    } else {
+        frame = append(frame, payload...)
+    }
+    if _, err := c.conn.Write(frame); err != nil {
+        if errors.Is(err, net.ErrClosed) {
+            return ErrClosed
+        }
+        return err
+    }
+    return nil
+}
+
+func acceptKey(key string) string {
+    sum := sha1.Sum([]byte(key + acceptGUID))
+    return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func headerContains(header http.Header, name, token string) bool {
+    for _, value := range header.Values(name) {
+        for _, part := range strings.Split(value, ",") {
+            if strings.EqualFold(strings.TrimSpace(part), token) {
+                return true
+            }
+        }
+    }
+    return false
+}
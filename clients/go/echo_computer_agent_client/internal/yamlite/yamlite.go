@@ -0,0 +1,579 @@
+// Package yamlite reads the subset of YAML used by the client's config
+// files: block mappings and sequences, plain and quoted scalars, literal
+// (|) and folded (>) block scalars, flow sequences and mappings on one
+// line, and comments. Anchors, tags, and multi-document streams are not
+// supported. Marshal writes documents in the same subset.
+package yamlite
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "strconv"
+    "strings"
+)
+
+type line struct {
+    indent int
+    text string
+    number int
+}
+
+type parser struct {
+    lines []line
+    pos int
+}
+
+// Unmarshal decodes data into v by way of its JSON form, so v's json tags
+// apply.
+func Unmarshal(data []byte, v any) error {
+    value, err := Parse(data)
+    if err != nil {
+        return err
+    }
+    encoded, err := json.Marshal(value)
+    if err != nil {
+        return err
+    }
+    return json.Unmarshal(encoded, v)
+}
+
+// Parse returns the document as map[string]any, []any, string, float64,
+// bool, or nil values.
+func Parse(data []byte) (any, error) {
+    p := &parser{}
+    for i, raw := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+        text := strings.TrimRight(raw, " \t")
+        trimmed := strings.TrimLeft(text, " ")
+        if strings.HasPrefix(trimmed, "\t") {
+            return nil, fmt.Errorf("yaml: line %d: tabs are not allowed for indentation", i+1)
+        }
+        p.lines = append(p.lines, line{indent: len(text) - len(trimmed), text: trimmed, number: i + 1})
+    }
+    p.skipBlank()
+    if p.pos < len(p.lines) && p.lines[p.pos].text == "---" {
+        p.pos++
+        p.skipBlank()
+    }
+    if p.pos >= len(p.lines) {
+        return nil, nil
+    }
+    value, err := p.node(p.lines[p.pos].indent)
+    if err != nil {
+        return nil, err
+    }
+    p.skipBlank()
+    if p.pos < len(p.lines) {
+        return nil, p.errorf("unexpected content %q", p.lines[p.pos].text)
+    }
+    return value, nil
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+    number := 0
+    if p.pos < len(p.lines) {
+        number = p.lines[p.pos].number
+    }
+    return fmt.Errorf("yaml: line %d: %s", number, fmt.Sprintf(format, args...))
+}
+
+// skipBlank moves past blank and comment-only lines.
+func (p *parser) skipBlank() {
+    for p.pos < len(p.lines) {
+        text := p.lines[p.pos].text
+        if text != "" && !strings.HasPrefix(text, "#") {
+            return
+        }
+        p.pos++
+    }
+}
+
+func (p *parser) node(indent int) (any, error) {
+    l := p.lines[p.pos]
+    if l.text == "-" || strings.HasPrefix(l.text, "- ") {
+        return p.sequence(indent)
+    }
+    if _, _, ok := splitKey(stripComment(l.text)); ok {
+        return p.mapping(indent)
+    }
+    p.pos++
+    return scalar(stripComment(l.text))
+}
+
+func (p *parser) sequence(indent int) ([]any, error) {
+    items := []any{}
+    for {
+        p.skipBlank()
+        if p.pos >= len(p.lines) {
+            return items, nil
+        }
+        l := p.lines[p.pos]
+        if l.indent != indent || (l.text != "-" && !strings.HasPrefix(l.text, "- ")) {
+            if l.indent > indent {
+                return nil, p.errorf("bad indentation")
+            }
+            return items, nil
+        }
+        rest := strings.TrimLeft(strings.TrimPrefix(l.text, "-"), " ")
+        if rest == "" || strings.HasPrefix(rest, "#") {
+            p.pos++
+            value, err := p.child(indent)
+            if err != nil {
+                return nil, err
+            }
+            items = append(items, value)
+            continue
+        }
+        // Treat "- key: value" as a mapping starting at the item's content.
+        p.lines[p.pos] = line{indent: indent + len(l.text) - len(rest), text: rest, number: l.number}
+        value, err := p.node(p.lines[p.pos].indent)
+        if err != nil {
+            return nil, err
+        }
+        items = append(items, value)
+    }
+}
+
+func (p *parser) mapping(indent int) (map[string]any, error) {
+    m := map[string]any{}
+    for {
+        p.skipBlank()
+        if p.pos >= len(p.lines) {
+            return m, nil
+        }
+        l := p.lines[p.pos]
+        if l.indent < indent {
+            return m, nil
+        }
+        if l.indent > indent {
+            return nil, p.errorf("bad indentation")
+        }
+        key, rest, ok := splitKey(stripComment(l.text))
+        if !ok {
+            if l.text == "-" || strings.HasPrefix(l.text, "- ") {
+                return m, nil
+            }
+            return nil, p.errorf("expected key: value, got %q", l.text)
+        }
+        if _, dup := m[key]; dup {
+            return nil, p.errorf("duplicate key %q", key)
+        }
+        p.pos++
+        var value any
+        var err error
+        switch {
+        case rest == "":
+            value, err = p.child(indent)
+        case rest[0] == '|' || rest[0] == '>':
+            value, err = p.blockScalar(indent, rest)
+        default:
+            value, err = scalar(rest)
+        }
+        if err != nil {
+            return nil, err
+        }
+        m[key] = value
+    }
+}
+
+// child parses the value nested under a key or bare "-" at indent: a more
+// indented node, a sequence at the same indent, or null.
+func (p *parser) child(indent int) (any, error) {
+    p.skipBlank()
+    if p.pos >= len(p.lines) {
+        return nil, nil
+    }
+    l := p.lines[p.pos]
+    if l.indent > indent || (l.indent == indent && (l.text == "-" || strings.HasPrefix(l.text, "- "))) {
+        return p.node(l.indent)
+    }
+    return nil, nil
+}
+
+func (p *parser) blockScalar(indent int, header string) (string, error) {
+    folded := header[0] == '>'
+    chomp := strings.TrimSpace(stripComment(header[1:]))
+    var body []string
+    contentIndent := -1
+    for p.pos < len(p.lines) {
+        l := p.lines[p.pos]
+        if l.text != "" && l.indent <= indent {
+            break
+        }
+        if l.text != "" && contentIndent < 0 {
+            contentIndent = l.indent
+        }
+        if l.text == "" {
+            body = append(body, "")
+        } else {
+            if l.indent < contentIndent {
+                return "", p.errorf("bad indentation in block scalar")
+            }
+            body = append(body, strings.Repeat(" ", l.indent-contentIndent)+l.text)
+        }
+        p.pos++
+    }
+    trailing := 0
+    for len(body) > 0 && body[len(body)-1] == "" {
+        body = body[:len(body)-1]
+        trailing++
+    }
+    var text string
+    if folded {
+        var b strings.Builder
+        for i, part := range body {
+            switch {
+            case i == 0:
+            case part == "" || body[i-1] == "":
+                b.WriteString("\n")
+            case strings.HasPrefix(part, " "):
+                b.WriteString("\n")
+            default:
+                b.WriteString(" ")
+            }
+            b.WriteString(part)
+        }
+        text = b.String()
+    } else {
+        text = strings.Join(body, "\n")
+    }
+    switch chomp {
+    case "-":
+    case "+":
+        text += "\n" + strings.Repeat("\n", trailing)
+    default:
+        if len(body) > 0 {
+            text += "\n"
+        }
+    }
+    return text, nil
+}
+
+// splitKey splits "key: value" at the first unquoted ": " or trailing ":".
+func splitKey(text string) (key, rest string, ok bool) {
+    if text == "" || text[0] == '[' || text[0] == '{' {
+        return "", "", false
+    }
+    end := -1
+    if text[0] == '"' || text[0] == '\'' {
+        end = closingQuote(text)
+        if end < 0 {
+            return "", "", false
+        }
+        end++
+    }
+    start := 0
+    if end > 0 {
+        start = end
+    }
+    for i := start; i < len(text); i++ {
+        if text[i] == ':' && (i == len(text)-1 || text[i+1] == ' ') {
+            raw := strings.TrimSpace(text[:i])
+            if end > 0 {
+                if strings.TrimSpace(text[end:i]) != "" {
+                    return "", "", false
+                }
+                unquoted, err := scalar(raw)
+                if err != nil {
+                    return "", "", false
+                }
+                raw = fmt.Sprint(unquoted)
+            }
+            return raw, strings.TrimSpace(text[i+1:]), true
+        }
+        if end < 0 && text[i] == ' ' && i+1 < len(text) && text[i+1] == '#' {
+            break
+        }
+    }
+    return "", "", false
+}
+
+// closingQuote returns the index of the quote closing the string that
+// opens text, or -1.
+func closingQuote(text string) int {
+    quote := text[0]
+    for i := 1; i < len(text); i++ {
+        switch {
+        case quote == '"' && text[i] == '\\':
+            i++
+        case text[i] == quote && quote == '\'' && i+1 < len(text) && text[i+1] == '\'':
+            i++
+        case text[i] == quote:
+            return i
+        }
+    }
+    return -1
+}
+
+// stripComment removes a trailing " # comment" outside quotes.
+func stripComment(text string) string {
+    var quote byte
+    for i := 0; i < len(text); i++ {
+        c := text[i]
+        switch {
+        case quote != 0:
+            if c == '\\' && quote == '"' {
+                i++
+            } else if c == quote {
+                quote = 0
+            }
+        case c == '"' || c == '\'':
+            if i == 0 || text[i-1] == ' ' || text[i-1] == '[' || text[i-1] == '{' || text[i-1] == ',' || text[i-1] == ':' {
+                quote = c
+            }
+        case c == '#' && (i == 0 || text[i-1] == ' '):
+            return strings.TrimRight(text[:i], " ")
+        }
+    }
+    return text
+}
+
+func scalar(text string) (any, error) {
+    text = strings.TrimSpace(stripComment(text))
+    if text == "" {
+        return nil, nil
+    }
+    switch text[0] {
+    case '"':
+        if closingQuote(text) != len(text)-1 {
+            return nil, fmt.Errorf("yaml: unterminated string %s", text)
+        }
+        return strconv.Unquote(text)
+    case '\'':
+        if closingQuote(text) != len(text)-1 {
+            return nil, fmt.Errorf("yaml: unterminated string %s", text)
+        }
+        return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+    case '[', '{':
+        return flow(text)
+    }
+    switch text {
+    case "null", "Null", "NULL", "~":
+        return nil, nil
+    case "true", "True", "TRUE":
+        return true, nil
+    case "false", "False", "FALSE":
+        return false, nil
+    }
+    if strings.ContainsRune("+-.0123456789", rune(text[0])) && !strings.ContainsAny(text, "xXpP_") {
+        if f, err := strconv.ParseFloat(text, 64); err == nil {
+            return f, nil
+        }
+    }
+    return text, nil
+}
+
+// flow parses a one-line [a, b] or {k: v} collection.
+func flow(text string) (any, error) {
+    open, close := text[0], byte(']')
+    if open == '{' {
+        close = '}'
+    }
+    if text[len(text)-1] != close {
+        return nil, fmt.Errorf("yaml: unterminated flow collection %s", text)
+    }
+    parts, err := splitFlow(text[1 : len(text)-1])
+    if err != nil {
+        return nil, err
+    }
+    if open == '[' {
+        items := []any{}
+        for _, part := range parts {
+            value, err := scalar(part)
+            if err != nil {
+                return nil, err
+            }
+            items = append(items, value)
+        }
+        return items, nil
+    }
+    m := map[string]any{}
+    for _, part := range parts {
+        key, rest, ok := splitKey(part)
+        if !ok {
+            return nil, fmt.Errorf("yaml: expected key: value in %s", text)
+        }
+        value, err := scalar(rest)
+        if err != nil {
+            return nil, err
+        }
+        m[key] = value
+    }
+    return m, nil
+}
+
+// splitFlow splits on top-level commas, skipping empty trailing entries.
+func splitFlow(text string) ([]string, error) {
+    var parts []string
+    depth, start := 0, 0
+    var quote byte
+    for i := 0; i < len(text); i++ {
+        c := text[i]
+        switch {
+        case quote != 0:
+            if c == '\\' && quote == '"' {
+                i++
+            } else if c == quote {
+                quote = 0
+            }
+        case c == '"' || c == '\'':
+            quote = c
+        case c == '[' || c == '{':
+            depth++
+        case c == ']' || c == '}':
+            depth--
+        case c == ',' && depth == 0:
+            parts = append(parts, strings.TrimSpace(text[start:i]))
+            start = i + 1
+        }
+    }
+    if quote != 0 || depth != 0 {
+        return nil, fmt.Errorf("yaml: unbalanced flow collection [%s]", text)
+    }
+    if last := strings.TrimSpace(text[start:]); last != "" {
+        parts = append(parts, last)
+    }
+    return parts, nil
+}
+
+// Marshal encodes v by way of its JSON form as block YAML that Parse
+// reads back. Struct fields keep their declaration order; map keys are
+// sorted, as encoding/json sorts them.
+func Marshal(v any) ([]byte, error) {
+    raw, err := json.Marshal(v)
+    if err != nil {
+        return nil, err
+    }
+    dec := json.NewDecoder(bytes.NewReader(raw))
+    dec.UseNumber()
+    value, err := readOrdered(dec)
+    if err != nil {
+        return nil, err
+    }
+    var b strings.Builder
+    switch value.(type) {
+    case ordered, []any:
+        writeBlock(&b, value, 0)
+    default:
+        b.WriteString(inline(value) + "\n")
+    }
+    return []byte(b.String()), nil
+}
+
+type pair struct {
+    key string
+    value any
+}
+
+// ordered is a JSON object with its keys in document order.
+type ordered []pair
+
+func readOrdered(dec *json.Decoder) (any, error) {
+    token, err := dec.Token()
+    if err != nil {
+        return nil, err
+    }
+    switch token {
+    case json.Delim('{'):
+        object := ordered{}
+        for dec.More() {
+            key, err := dec.Token()
+            if err != nil {
+                return nil, err
+            }
+            value, err := readOrdered(dec)
+            if err != nil {
+                return nil, err
+            }
+            object = append(object, pair{key.(string), value})
+        }
+        _, err := dec.Token()
+        return object, err
+    case json.Delim('['):
+        list := []any{}
+        for dec.More() {
+            value, err := readOrdered(dec)
+            if err != nil {
+                return nil, err
+            }
+            list = append(list, value)
+        }
+        _, err := dec.Token()
+        return list, err
+    }
+    return token, nil
+}
+
+// writeBlock writes a non-empty object or list whose lines start at indent.
+func writeBlock(b *strings.Builder, value any, indent int) {
+    pad := strings.Repeat(" ", indent)
+    switch v := value.(type) {
+    case ordered:
+        for _, p := range v {
+            b.WriteString(pad + quoteScalar(p.key) + ":")
+            writeValue(b, p.value, indent+2)
+        }
+    case []any:
+        for _, item := range v {
+            if object, ok := item.(ordered); ok && len(object) > 0 {
+                // The first key shares the dash's line; the rest align with it.
+                var nested strings.Builder
+                writeBlock(&nested, object, indent+2)
+                b.WriteString(pad + "- " + strings.TrimPrefix(nested.String(), pad+"  "))
+                continue
+            }
+            b.WriteString(pad + "-")
+            writeValue(b, item, indent+2)
+        }
+    }
+}
+
+// writeValue finishes a "key:" or "-" line with value.
+func writeValue(b *strings.Builder, value any, indent int) {
+    switch v := value.(type) {
+    case ordered:
+        if len(v) > 0 {
+            b.WriteString("\n")
+            writeBlock(b, v, indent)
+            return
+        }
+    case []any:
+        if len(v) > 0 {
+            b.WriteString("\n")
+            writeBlock(b, v, indent)
+            return
+        }
+    }
+    b.WriteString(" " + inline(value) + "\n")
+}
+
+func inline(value any) string {
+    switch v := value.(type) {
+    case nil:
+        return "null"
+    case bool:
+        return strconv.FormatBool(v)
+    case json.Number:
+        return v.String()
+    case string:
+        return quoteScalar(v)
+    case ordered:
+        return "{}"
+    case []any:
+        return "[]"
+    }
+    return fmt.Sprint(value)
+}
+
+// quoteScalar leaves s plain when Parse would read it back unchanged.
+func quoteScalar(s string) string {
+    if s == "" || strings.TrimSpace(s) != s || strings.ContainsAny(s, "\n\t\"'") ||
+        strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>%@`") ||
+        strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+        return strconv.Quote(s)
+    }
+    if parsed, err := scalar(s); err != nil || parsed != s {
+        return strconv.Quote(s)
+    }
+    return s
+}
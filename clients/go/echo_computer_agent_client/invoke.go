@@ -0,0 +1,52 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "net/http"
+    "net/url"
+    "time"
+)
+
+type invokeRequest struct {
+    Inputs map[string]any `json:"inputs,omitempty"`
+}
+
+// InvokeFunction executes the named function directly, bypassing the
+// agent's natural-language routing. The guard and policy are checked
+// against name without a dry-run plan, and the call is audited like an
+// executing Chat.
+func (c *Client) InvokeFunction(ctx context.Context, name string, inputs map[string]any) (*ChatResponse, error) {
+    request := ChatRequest{Message: name, Inputs: inputs}
+    if err := c.checkInputs(ctx, name, inputs, false); err != nil {
+        c.audit(ctx, name, request, nil, err)
+        return nil, err
+    }
+    if err := c.authorize(ctx, name, request); err != nil {
+        c.audit(ctx, name, request, nil, err)
+        return nil, err
+    }
+    resp, err := c.cachedCall(ctx, name, responseKey(ctx, name, inputs), func() (*ChatResponse, error) {
+        wire := request
+        if err := c.prepare(ctx, &wire); err != nil {
+            return nil, err
+        }
+        var payload ChatResponse
+        start := time.Now()
+        err := c.doJSON(ctx, http.MethodPost, "/functions/"+url.PathEscape(name)+"/invoke", invokeRequest{Inputs: wire.Inputs}, &payload)
+        if err == nil {
+            err = c.filterResponse(ctx, &payload)
+        }
+        if err != nil {
+            return nil, err
+        }
+        c.observeLatency(name, time.Since(start))
+        return &payload, nil
+    })
+    if err != nil {
+        c.audit(ctx, name, request, nil, err)
+        return nil, err
+    }
+    c.audit(ctx, name, request, resp, nil)
+    c.checkDeprecatedFunction(name)
+    return resp, nil
+}
@@ -0,0 +1,190 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "net/url"
+    "strconv"
+    "sync"
+    "time"
+)
+
+const (
+    JobQueued = "queued"
+    JobRunning = "running"
+    JobSucceeded = "succeeded"
+    JobFailed = "failed"
+    JobCancelled = "cancelled"
+)
+
+// ErrCallbackUnsupported is returned by SubmitJob for a callback the agent
+// cannot deliver because it runs jobs synchronously.
+var ErrCallbackUnsupported = errors.New("agent cannot deliver job callbacks")
+
+// localResultTTL bounds how long an unread local job or execution is kept.
+const localResultTTL = 10 * time.Minute
+
+// Job is an asynchronously executing chat request.
+type Job struct {
+    ID string `json:"id"`
+    Status string `json:"status"`
+    Function string `json:"function,omitempty"`
+    Result *ChatResponse `json:"result,omitempty"`
+    Error string `json:"error,omitempty"`
+    Progress *Progress `json:"progress,omitempty"`
+    CreatedAt time.Time `json:"created_at,omitempty"`
+    UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+func (j *Job) Done() bool {
+    switch j.Status {
+    case JobSucceeded, JobFailed, JobCancelled:
+        return true
+    }
+    return false
+}
+
+type jobSubmission struct {
+    ChatRequest
+    CallbackURL string `json:"callback_url,omitempty"`
+}
+
+// SubmitJob queues request on the agent's /jobs endpoint and returns
+// immediately. When callbackURL is set the agent posts a job.completed
+// webhook there once the job finishes. Executing jobs pass through the same
+// guard, policy, and audit checks as Chat.
+//
+// Agents known not to support FeatureAsyncJobs run the request
+// synchronously instead; the returned job is already finished and the
+// first GetJob for it answers locally. Such agents send no callbacks, so a
+// callbackURL fails with ErrCallbackUnsupported.
+func (c *Client) SubmitJob(ctx context.Context, request ChatRequest, callbackURL string) (*Job, error) {
+    if caps, err := c.Capabilities(ctx); err == nil && caps.lacks(FeatureAsyncJobs) {
+        if callbackURL != "" {
+            return nil, ErrCallbackUnsupported
+        }
+        return c.runLocalJob(ctx, request), nil
+    }
+    function := ""
+    if executes(request) {
+        var err error
+        if function, err = c.preflight(ctx, request); err != nil {
+            return nil, err
+        }
+    }
+    original := request
+    if err := c.prepare(ctx, &request); err != nil {
+        return nil, err
+    }
+    var job Job
+    err := c.doJSON(ctx, http.MethodPost, "/jobs", jobSubmission{ChatRequest: request, CallbackURL: callbackURL}, &job)
+    if executes(original) {
+        c.audit(ctx, function, original, nil, err)
+    }
+    if err != nil {
+        return nil, err
+    }
+    return &job, nil
+}
+
+func (c *Client) GetJob(ctx context.Context, id string) (*Job, error) {
+    if job, ok := c.localJobs.get(id); ok {
+        return job, nil
+    }
+    var job Job
+    if err := c.doJSON(ctx, http.MethodGet, "/jobs/"+url.PathEscape(id), nil, &job); err != nil {
+        return nil, err
+    }
+    return &job, nil
+}
+
+// WaitForJob polls GetJob every interval until the job finishes or ctx is
+// done. Progress the job reports is sent to the channel set by
+// WithProgress, with each log line sent once.
+func (c *Client) WaitForJob(ctx context.Context, id string, interval time.Duration) (*Job, error) {
+    if interval <= 0 {
+        interval = time.Second
+    }
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    var tracker progressTracker
+    for {
+        job, err := c.GetJob(ctx, id)
+        if err != nil {
+            return nil, err
+        }
+        if progress, changed := tracker.next(job.Progress); changed {
+            reportProgress(ctx, progress)
+        }
+        if job.Done() {
+            return job, nil
+        }
+        select {
+        case <-ctx.Done():
+            return nil, ctx.Err()
+        case <-ticker.C:
+        }
+    }
+}
+
+// localJobStore holds the results of jobs and executions run synchronously
+// for agents without async support. Each is dropped once read, or after
+// localResultTTL if it never is.
+type localJobStore struct {
+    sync.Mutex
+    next int
+    jobs map[string]*Job
+    executions map[string]*ExecutionResult
+    added map[string]time.Time
+}
+
+func (s *localJobStore) get(id string) (*Job, bool) {
+    s.Lock()
+    defer s.Unlock()
+    job, ok := s.jobs[id]
+    if ok {
+        delete(s.jobs, id)
+        delete(s.added, id)
+    }
+    return job, ok
+}
+
+// track records id as added now and drops expired results. s must be
+// locked.
+func (s *localJobStore) track(id string) {
+    now := time.Now()
+    if s.added == nil {
+        s.added = map[string]time.Time{}
+    }
+    for old, at := range s.added {
+        if now.Sub(at) > localResultTTL {
+            delete(s.jobs, old)
+            delete(s.executions, old)
+            delete(s.added, old)
+        }
+    }
+    s.added[id] = now
+}
+
+func (c *Client) runLocalJob(ctx context.Context, request ChatRequest) *Job {
+    created := time.Now()
+    resp, err := c.Chat(ctx, request)
+    job := &Job{Status: JobSucceeded, Result: resp, CreatedAt: created, UpdatedAt: time.Now()}
+    if resp != nil {
+        job.Function = resp.Function
+    }
+    if err != nil {
+        job.Status, job.Error = JobFailed, err.Error()
+    }
+    c.localJobs.Lock()
+    defer c.localJobs.Unlock()
+    if c.localJobs.jobs == nil {
+        c.localJobs.jobs = map[string]*Job{}
+    }
+    c.localJobs.next++
+    job.ID = "local-" + strconv.Itoa(c.localJobs.next)
+    c.localJobs.jobs[job.ID] = job
+    c.localJobs.track(job.ID)
+    return job
+}
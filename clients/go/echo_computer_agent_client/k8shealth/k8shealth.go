@@ -0,0 +1,70 @@
+// Package k8shealth wires the client's agent health prober into Kubernetes
+// liveness and readiness probes, and offers Lease-based leader election for
+// singleton pollers running as replicated sidecars.
+package k8shealth
+
+import (
+    "encoding/json"
+    "net/http"
+    "time"
+
+    client "echo_computer_agent_client"
+)
+
+// Liveness reports whether the prober loop itself is alive: it fails only
+// when no probe has run for StaleAfter. Agent outages deliberately do not
+// fail liveness, since restarting the sidecar cannot fix the agent.
+type Liveness struct {
+    Prober *client.HealthProber
+    StaleAfter time.Duration
+}
+
+func (l Liveness) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    status := l.Prober.Status()
+    stale := l.StaleAfter
+    if stale <= 0 {
+        stale = 5 * l.interval()
+    }
+    if status.LastProbe.IsZero() || time.Since(status.LastProbe) <= stale {
+        write(w, http.StatusOK, status)
+        return
+    }
+    write(w, http.StatusServiceUnavailable, status)
+}
+
+func (l Liveness) interval() time.Duration {
+    if l.Prober.Interval > 0 {
+        return l.Prober.Interval
+    }
+    return 10 * time.Second
+}
+
+// Readiness fails while the prober considers the agent unhealthy, taking
+// the pod out of Service endpoints until the agent recovers.
+type Readiness struct {
+    Prober *client.HealthProber
+}
+
+func (rd Readiness) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    status := rd.Prober.Status()
+    if status.Healthy {
+        write(w, http.StatusOK, status)
+        return
+    }
+    write(w, http.StatusServiceUnavailable, status)
+}
+
+// Mux serves /livez and /readyz.
+func Mux(prober *client.HealthProber) *http.ServeMux {
+    mux := http.NewServeMux()
+    mux.Handle("/livez", Liveness{Prober: prober})
+    mux.Handle("/readyz", Readiness{Prober: prober})
+    return mux
+}
+
+func write(w http.ResponseWriter, code int, status client.ProbeStatus) {
+    w.Header().Set("Content-Type", "application/json")
+    w.Header().Set("Cache-Control", "no-store")
+    w.WriteHeader(code)
+    json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,258 @@
+package k8shealth
+
+import (
+    "bytes"
+    "context"
+    "crypto/tls"
+    "crypto/x509"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net"
+    "net/http"
+    "os"
+    "strings"
+    "time"
+)
+
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// LeaderElector holds a coordination.k8s.io/v1 Lease so that only one
+// replica runs OnStartedLeading at a time. It talks to the API server with
+// the pod's service account; the account needs get, create, and update on
+// leases in Namespace.
+type LeaderElector struct {
+    Namespace string
+    Name string
+    Identity string
+    LeaseDuration time.Duration
+    RetryPeriod time.Duration
+    OnStartedLeading func(ctx context.Context)
+    OnStoppedLeading func()
+
+    APIServer string
+    Token string
+    HTTPClient *http.Client
+}
+
+// InClusterLeaderElector configures an elector from the pod environment,
+// using the hostname (the pod name) as identity.
+func InClusterLeaderElector(name string) (*LeaderElector, error) {
+    host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+    if host == "" || port == "" {
+        return nil, errors.New("k8shealth: not running in a cluster")
+    }
+    token, err := os.ReadFile(serviceAccountDir + "/token")
+    if err != nil {
+        return nil, err
+    }
+    namespace, err := os.ReadFile(serviceAccountDir + "/namespace")
+    if err != nil {
+        return nil, err
+    }
+    ca, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+    if err != nil {
+        return nil, err
+    }
+    pool := x509.NewCertPool()
+    if !pool.AppendCertsFromPEM(ca) {
+        return nil, errors.New("k8shealth: invalid service account CA")
+    }
+    identity, _ := os.Hostname()
+    return &LeaderElector{
+        Namespace: strings.TrimSpace(string(namespace)),
+        Name: name,
+        Identity: identity,
+        LeaseDuration: 15 * time.Second,
+        RetryPeriod: 2 * time.Second,
+        APIServer: "https://" + net.JoinHostPort(host, port),
+        Token: strings.TrimSpace(string(token)),
+        HTTPClient: &http.Client{
+            Timeout: 10 * time.Second,
+            Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+        },
+    }, nil
+}
+
+type lease struct {
+    APIVersion string `json:"apiVersion"`
+    Kind string `json:"kind"`
+    Metadata struct {
+        Name string `json:"name"`
+        Namespace string `json:"namespace"`
+        ResourceVersion string `json:"resourceVersion,omitempty"`
+    } `json:"metadata"`
+    Spec struct {
+        HolderIdentity string `json:"holderIdentity,omitempty"`
+        LeaseDurationSeconds int `json:"leaseDurationSeconds,omitempty"`
+        AcquireTime string `json:"acquireTime,omitempty"`
+        RenewTime string `json:"renewTime,omitempty"`
+        LeaseTransitions int `json:"leaseTransitions,omitempty"`
+    } `json:"spec"`
+}
+
+const microTime = "2006-01-02T15:04:05.000000Z07:00"
+
+// Run campaigns for the lease until ctx is done. While leading it renews
+// every RetryPeriod; losing the lease cancels the context passed to
+// OnStartedLeading and calls OnStoppedLeading, after which it campaigns
+// again.
+func (e *LeaderElector) Run(ctx context.Context) error {
+    retry := e.RetryPeriod
+    if retry <= 0 {
+        retry = 2 * time.Second
+    }
+    var (
+        leading bool
+        cancelLeader context.CancelFunc
+        lastRenew time.Time
+    )
+    stop := func() {
+        if leading {
+            leading = false
+            cancelLeader()
+            if e.OnStoppedLeading != nil {
+                e.OnStoppedLeading()
+            }
+        }
+    }
+    defer stop()
+    ticker := time.NewTicker(retry)
+    defer ticker.Stop()
+    for {
+        acquired, err := e.tryAcquireOrRenew(ctx)
+        switch {
+        case err == nil && acquired:
+            lastRenew = time.Now()
+            if !leading {
+                leading = true
+                cancelLeader = e.startLeading(ctx)
+            }
+        case err == nil:
+            stop()
+        default:
+            // Keep leading through transient API errors until the lease
+            // would have expired for everyone else.
+            if leading && time.Since(lastRenew) > e.leaseDuration() {
+                stop()
+            }
+        }
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-ticker.C:
+        }
+    }
+}
+
+func (e *LeaderElector) startLeading(ctx context.Context) context.CancelFunc {
+    leaderCtx, cancel := context.WithCancel(ctx)
+    if e.OnStartedLeading != nil {
+        go e.OnStartedLeading(leaderCtx)
+    }
+    return cancel
+}
+
+func (e *LeaderElector) tryAcquireOrRenew(ctx context.Context) (bool, error) {
+    now := time.Now().UTC()
+    current, err := e.get(ctx)
+    if err != nil {
+        return false, err
+    }
+    if current == nil {
+        l := &lease{APIVersion: "coordination.k8s.io/v1", Kind: "Lease"}
+        l.Metadata.Name, l.Metadata.Namespace = e.Name, e.Namespace
+        e.claim(l, now, true)
+        return e.write(ctx, http.MethodPost, e.collectionPath(), l)
+    }
+    holder := current.Spec.HolderIdentity
+    if holder != "" && holder != e.Identity {
+        renewed, err := time.Parse(microTime, current.Spec.RenewTime)
+        duration := time.Duration(current.Spec.LeaseDurationSeconds) * time.Second
+        if err == nil && now.Before(renewed.Add(duration)) {
+            return false, nil
+        }
+    }
+    e.claim(current, now, holder != e.Identity)
+    return e.write(ctx, http.MethodPut, e.collectionPath()+"/"+e.Name, current)
+}
+
+func (e *LeaderElector) claim(l *lease, now time.Time, transition bool) {
+    if transition {
+        l.Spec.AcquireTime = now.Format(microTime)
+        if l.Spec.HolderIdentity != "" {
+            l.Spec.LeaseTransitions++
+        }
+    }
+    l.Spec.HolderIdentity = e.Identity
+    l.Spec.LeaseDurationSeconds = int(e.leaseDuration() / time.Second)
+    l.Spec.RenewTime = now.Format(microTime)
+}
+
+func (e *LeaderElector) get(ctx context.Context) (*lease, error) {
+    resp, err := e.do(ctx, http.MethodGet, e.collectionPath()+"/"+e.Name, nil)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode == http.StatusNotFound {
+        return nil, nil
+    }
+    if resp.StatusCode >= 400 {
+        return nil, fmt.Errorf("k8shealth: get lease failed with status %d", resp.StatusCode)
+    }
+    var l lease
+    if err := json.NewDecoder(resp.Body).Decode(&l); err != nil {
+        return nil, err
+    }
+    return &l, nil
+}
+
+// write returns false without error when another replica won the race
+// (409 Conflict on the resource version).
+func (e *LeaderElector) write(ctx context.Context, method, path string, l *lease) (bool, error) {
+    body, err := json.Marshal(l)
+    if err != nil {
+        return false, err
+    }
+    resp, err := e.do(ctx, method, path, body)
+    if err != nil {
+        return false, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode == http.StatusConflict {
+        return false, nil
+    }
+    if resp.StatusCode >= 400 {
+        return false, fmt.Errorf("k8shealth: write lease failed with status %d", resp.StatusCode)
+    }
+    return true, nil
+}
+
+func (e *LeaderElector) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+    req, err := http.NewRequestWithContext(ctx, method, e.APIServer+path, bytes.NewReader(body))
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Authorization", "Bearer "+e.Token)
+    req.Header.Set("Accept", "application/json")
+    if body != nil {
+        req.Header.Set("Content-Type", "application/json")
+    }
+    httpClient := e.HTTPClient
+    if httpClient == nil {
+        httpClient = http.DefaultClient
+    }
+    return httpClient.Do(req)
+}
+
+func (e *LeaderElector) collectionPath() string {
+    return "/apis/coordination.k8s.io/v1/namespaces/" + e.Namespace + "/leases"
+}
+
+func (e *LeaderElector) leaseDuration() time.Duration {
+    if e.LeaseDuration > 0 {
+        return e.LeaseDuration
+    }
+    return 15 * time.Second
+}
@@ -0,0 +1,80 @@
+// Package langchaintool adapts agent functions to LangChainGo's tools.Tool
+// interface (Name, Description, Call). The interface is satisfied
+// structurally, so this package does not import LangChainGo itself:
+//
+//    agentTools, _ := langchaintool.Tools(ctx, c)
+//    lcTools := make([]tools.Tool, len(agentTools))
+//    for i, t := range agentTools {
+//        lcTools[i] = t
+//    }
+package langchaintool
+
+import (
+    "context"
+    "encoding/json"
+    "strings"
+
+    client "echo_computer_agent_client"
+)
+
+type Tool struct {
+    Client *client.Client
+    Function client.FunctionDescription
+}
+
+func New(c *client.Client, fn client.FunctionDescription) *Tool {
+    return &Tool{Client: c, Function: fn}
+}
+
+// Tools wraps every function in the agent's catalog.
+func Tools(ctx context.Context, c *client.Client) ([]*Tool, error) {
+    catalog, err := c.ListFunctions(ctx)
+    if err != nil {
+        return nil, err
+    }
+    tools := make([]*Tool, 0, len(catalog.Functions))
+    for _, fn := range catalog.Functions {
+        tools = append(tools, New(c, fn))
+    }
+    return tools, nil
+}
+
+func (t *Tool) Name() string {
+    return client.ToolName(t.Function.Name)
+}
+
+// Description includes the input schema because LangChainGo passes tool
+// input as a single string; models need the schema to format it as JSON.
+func (t *Tool) Description() string {
+    schema, err := json.Marshal(t.Schema())
+    if err != nil {
+        return t.Function.Description
+    }
+    return strings.TrimSpace(t.Function.Description) + " Input must be a JSON object matching this schema: " + string(schema)
+}
+
+func (t *Tool) Schema() map[string]any {
+    return t.Function.OpenAITool().Function.Parameters
+}
+
+// Call invokes the function. Input that is not a JSON object is passed as
+// {"input": "<text>"}. The result is the agent's message followed by the
+// JSON-encoded Data.
+func (t *Tool) Call(ctx context.Context, input string) (string, error) {
+    inputs := map[string]any{}
+    if trimmed := strings.TrimSpace(input); trimmed != "" {
+        if err := json.Unmarshal([]byte(trimmed), &inputs); err != nil {
+            inputs = map[string]any{"input": input}
+        }
+    }
+    // The input is model-written, so it may not name local secrets.
+    resp, err := t.Client.InvokeFunction(ctx, t.Function.Name, t.Client.StripSecretRefs(inputs))
+    if err != nil {
+        return "", err
+    }
+    data, err := json.Marshal(resp.Data)
+    if err != nil {
+        return resp.Message, nil
+    }
+    return resp.Message + "\n" + string(data), nil
+}
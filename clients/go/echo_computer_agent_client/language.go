@@ -0,0 +1,133 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "strings"
+    "sync"
+)
+
+type languageKey struct{}
+
+// WithLanguage sets the BCP 47 language tag (e.g. "fr-CA") sent as
+// Accept-Language on calls made with ctx.
+func WithLanguage(ctx context.Context, tag string) context.Context {
+    return context.WithValue(ctx, languageKey{}, tag)
+}
+
+// SetLanguage sets the client's default Accept-Language.
+func (c *Client) SetLanguage(tag string) {
+    c.language = tag
+}
+
+// LanguageFromContext returns the tag set by WithLanguage.
+func LanguageFromContext(ctx context.Context) (string, bool) {
+    tag, ok := ctx.Value(languageKey{}).(string)
+    return tag, ok && tag != ""
+}
+
+func (c *Client) setLanguageHeader(ctx context.Context, req *http.Request) {
+    tag, ok := LanguageFromContext(ctx)
+    if !ok {
+        tag = c.language
+    }
+    if tag != "" {
+        req.Header.Set("Accept-Language", tag)
+    }
+}
+
+// LocalizedMessage returns the variant of the reply for tag from
+// Metadata["localized_messages"] (a map of language tag to text), falling
+// back from "pt-BR" to "pt" and finally to Message.
+func (r *ChatResponse) LocalizedMessage(tag string) string {
+    variants, _ := r.Metadata["localized_messages"].(map[string]any)
+    for _, candidate := range languageFallbacks(tag) {
+        for key, value := range variants {
+            if text, ok := value.(string); ok && strings.EqualFold(key, candidate) {
+                return text
+            }
+        }
+    }
+    return r.Message
+}
+
+func languageFallbacks(tag string) []string {
+    var tags []string
+    for tag != "" {
+        tags = append(tags, tag)
+        i := strings.LastIndexAny(tag, "-_")
+        if i < 0 {
+            break
+        }
+        tag = tag[:i]
+    }
+    return tags
+}
+
+var (
+    messagesMu sync.RWMutex
+    // messages maps a language to translations of the client's sentinel
+    // errors, keyed by their English text.
+    messages = map[string]map[string]string{
+        "es": {
+            ErrFunctionNotAllowed.Error(): "función no permitida",
+            ErrPolicyDenied.Error(): "ejecución denegada por la política",
+            ErrConfirmationRequired.Error(): "la ejecución requiere confirmación",
+            ErrContentBlocked.Error(): "contenido bloqueado por el filtro de seguridad",
+            ErrBinaryNotAllowed.Error(): "binario no permitido por el entorno aislado",
+        },
+        "fr": {
+            ErrFunctionNotAllowed.Error(): "fonction non autorisée",
+            ErrPolicyDenied.Error(): "exécution refusée par la politique",
+            ErrConfirmationRequired.Error(): "l'exécution nécessite une confirmation",
+            ErrContentBlocked.Error(): "contenu bloqué par le filtre de sécurité",
+            ErrBinaryNotAllowed.Error(): "binaire non autorisé par le bac à sable",
+        },
+        "de": {
+            ErrFunctionNotAllowed.Error(): "Funktion nicht erlaubt",
+            ErrPolicyDenied.Error(): "Ausführung durch Richtlinie verweigert",
+            ErrConfirmationRequired.Error(): "Ausführung erfordert Bestätigung",
+            ErrContentBlocked.Error(): "Inhalt vom Sicherheitsfilter blockiert",
+            ErrBinaryNotAllowed.Error(): "Programm in der Sandbox nicht erlaubt",
+        },
+    }
+    sentinels = []error{ErrFunctionNotAllowed, ErrPolicyDenied, ErrConfirmationRequired, ErrContentBlocked, ErrBinaryNotAllowed}
+)
+
+// RegisterMessages adds or overrides translations for lang. Keys are the
+// English texts of the client's sentinel errors.
+func RegisterMessages(lang string, translations map[string]string) {
+    messagesMu.Lock()
+    defer messagesMu.Unlock()
+    lang = strings.ToLower(lang)
+    if messages[lang] == nil {
+        messages[lang] = map[string]string{}
+    }
+    for key, text := range translations {
+        messages[lang][key] = text
+    }
+}
+
+// LocalizeError renders err for a user speaking tag. The sentinel error it
+// wraps is replaced by its translation; wrapping detail such as a policy
+// rule name is kept. Untranslated errors keep their English text.
+func LocalizeError(err error, tag string) string {
+    if err == nil {
+        return ""
+    }
+    text := err.Error()
+    messagesMu.RLock()
+    defer messagesMu.RUnlock()
+    for _, sentinel := range sentinels {
+        if !errors.Is(err, sentinel) {
+            continue
+        }
+        for _, lang := range languageFallbacks(strings.ToLower(tag)) {
+            if translated, ok := messages[lang][sentinel.Error()]; ok {
+                return strings.Replace(text, sentinel.Error(), translated, 1)
+            }
+        }
+    }
+    return text
+}
@@ -0,0 +1,240 @@
+// Package launch runs the usual shape of a launch against the agent: dry
+// run the request, confirm the plan, invoke the planned function
+// (optionally as an execution that is polled to completion), then verify
+// the result with a follow-up health function.
+package launch
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "strings"
+    "time"
+
+    client "echo_computer_agent_client"
+)
+
+var (
+    ErrDeclined = errors.New("launch: plan declined")
+    ErrUnhealthy = errors.New("launch: verification failed")
+)
+
+type Phase string
+
+const (
+    PhasePlan Phase = "plan"
+    PhaseConfirm Phase = "confirm"
+    PhaseExecute Phase = "execute"
+    PhaseVerify Phase = "verify"
+)
+
+// Error is a launch that failed in Phase. Report holds what the earlier
+// phases produced.
+type Error struct {
+    Phase Phase
+    Report *Report
+    Err error
+}
+
+func (e *Error) Error() string { return fmt.Sprintf("launch %s: %v", e.Phase, e.Err) }
+func (e *Error) Unwrap() error { return e.Err }
+
+// Report is what each phase of a launch produced. For an async launch
+// Execution is the finished execution and Result carries its Output as
+// Data.
+type Report struct {
+    Plan *client.ChatResponse
+    Execution *client.ExecutionResult
+    Result *client.ChatResponse
+    Health *client.ChatResponse
+    VerifyAttempts int
+}
+
+// Launcher holds the settings for launches made through Client.
+type Launcher struct {
+    Client *client.Client
+    // Function, when set, is the function the plan must route to.
+    Function string
+    // Async submits the planned function with SubmitExecution and polls
+    // it, starting PollInterval apart.
+    Async bool
+    PollInterval time.Duration
+
+    // Confirm is asked to approve the plan; nil approves it.
+    Confirm func(ctx context.Context, plan *client.ChatResponse) (bool, error)
+    // OnPhase is called as each phase completes. An error aborts the launch
+    // with that phase's Error.
+    OnPhase func(ctx context.Context, phase Phase, report *Report) error
+
+    // Verify builds the follow-up request that checks the launched result,
+    // e.g. a health function given the service the result names. Launches
+    // without one end after execution.
+    Verify func(result *client.ChatResponse) client.ChatRequest
+    // Healthy judges the follow-up's response; nil uses Healthy.
+    Healthy func(health *client.ChatResponse) bool
+    // VerifyAttempts and VerifyDelay bound how long a freshly launched
+    // result gets to come up; they default to 5 and 2s.
+    VerifyAttempts int
+    VerifyDelay time.Duration
+}
+
+func New(c *client.Client) *Launcher {
+    return &Launcher{Client: c}
+}
+
+// Launch runs request through every phase, returning the report even when
+// a phase fails with an *Error.
+func (l *Launcher) Launch(ctx context.Context, request client.ChatRequest) (*Report, error) {
+    report := &Report{}
+    fail := func(phase Phase, err error) (*Report, error) {
+        return report, &Error{Phase: phase, Report: report, Err: err}
+    }
+    done := func(phase Phase) error {
+        if l.OnPhase == nil {
+            return nil
+        }
+        return l.OnPhase(ctx, phase, report)
+    }
+
+    plan, err := l.Client.Plan(ctx, request)
+    if err != nil {
+        return fail(PhasePlan, err)
+    }
+    report.Plan = plan
+    if l.Function != "" && plan.Function != l.Function {
+        return fail(PhasePlan, fmt.Errorf("agent routed to %s, launch requires %s", plan.Function, l.Function))
+    }
+    if err := done(PhasePlan); err != nil {
+        return fail(PhasePlan, err)
+    }
+
+    if l.Confirm != nil {
+        ok, err := l.Confirm(ctx, plan)
+        if err != nil {
+            return fail(PhaseConfirm, err)
+        }
+        if !ok {
+            return fail(PhaseConfirm, ErrDeclined)
+        }
+    }
+    if err := done(PhaseConfirm); err != nil {
+        return fail(PhaseConfirm, err)
+    }
+
+    if report.Result, err = l.execute(ctx, plan.Function, plannedInputs(plan, request), report); err != nil {
+        return fail(PhaseExecute, err)
+    }
+    if err := done(PhaseExecute); err != nil {
+        return fail(PhaseExecute, err)
+    }
+
+    if l.Verify == nil {
+        return report, nil
+    }
+    if err := l.verify(ctx, report); err != nil {
+        return fail(PhaseVerify, err)
+    }
+    if err := done(PhaseVerify); err != nil {
+        return fail(PhaseVerify, err)
+    }
+    return report, nil
+}
+
+// execute invokes the confirmed function itself, so the agent cannot route
+// to another, and fails if the agent reports running anything else.
+func (l *Launcher) execute(ctx context.Context, function string, inputs map[string]any, report *Report) (*client.ChatResponse, error) {
+    if function == "" {
+        return nil, errors.New("plan did not route to a function")
+    }
+    var result *client.ChatResponse
+    if !l.Async {
+        var err error
+        if result, err = l.Client.InvokeFunction(ctx, function, inputs); err != nil {
+            return nil, err
+        }
+    } else {
+        id, err := l.Client.SubmitExecution(ctx, function, inputs)
+        if err != nil {
+            return nil, err
+        }
+        execution, err := l.Client.WaitForExecution(ctx, id, client.PollOptions{Interval: l.PollInterval})
+        if err != nil {
+            return nil, err
+        }
+        report.Execution = execution
+        result = &client.ChatResponse{Function: execution.Function, Message: execution.Error, Data: execution.Output}
+        if err := execution.Err(); err != nil {
+            return result, err
+        }
+    }
+    if result.Function != "" && result.Function != function {
+        return result, fmt.Errorf("agent ran %s, plan confirmed %s", result.Function, function)
+    }
+    return result, nil
+}
+
+// plannedInputs are the inputs the plan says the agent would use, or the
+// request's own.
+func plannedInputs(plan *client.ChatResponse, request client.ChatRequest) map[string]any {
+    if inputs, ok := plan.Data["inputs"].(map[string]any); ok {
+        return inputs
+    }
+    return request.Inputs
+}
+
+func (l *Launcher) verify(ctx context.Context, report *Report) error {
+    attempts, delay := l.VerifyAttempts, l.VerifyDelay
+    if attempts <= 0 {
+        attempts = 5
+    }
+    if delay <= 0 {
+        delay = 2 * time.Second
+    }
+    healthy := l.Healthy
+    if healthy == nil {
+        healthy = Healthy
+    }
+    request := l.Verify(report.Result).AutoExecute()
+    var lastErr error
+    for report.VerifyAttempts < attempts {
+        if report.VerifyAttempts > 0 {
+            select {
+            case <-ctx.Done():
+                return ctx.Err()
+            case <-time.After(delay):
+            }
+        }
+        report.VerifyAttempts++
+        health, err := l.Client.Chat(ctx, request)
+        if err != nil {
+            if ctx.Err() != nil {
+                return ctx.Err()
+            }
+            lastErr = err
+            continue
+        }
+        report.Health = health
+        if healthy(health) {
+            return nil
+        }
+        lastErr = nil
+    }
+    if lastErr != nil {
+        return fmt.Errorf("%w after %d attempts: %v", ErrUnhealthy, attempts, lastErr)
+    }
+    return fmt.Errorf("%w after %d attempts", ErrUnhealthy, attempts)
+}
+
+// Healthy reads the conventional health fields of a response's Data: a
+// "healthy" boolean, or a "status" of ok, healthy, pass, up, or running.
+func Healthy(health *client.ChatResponse) bool {
+    if ok, isBool := health.Data["healthy"].(bool); isBool {
+        return ok
+    }
+    status, _ := health.Data["status"].(string)
+    switch strings.ToLower(status) {
+    case "ok", "healthy", "pass", "up", "running":
+        return true
+    }
+    return false
+}
@@ -0,0 +1,238 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "log/slog"
+    "net/http"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+// redactedValue replaces whatever a Redactor hides.
+const redactedValue = "[REDACTED]"
+
+// maxLoggedBody bounds how much of a request or error body is logged.
+const maxLoggedBody = 4 << 10
+
+// Redactor decides what logs must not show. A header or JSON field is
+// hidden when its name contains one of the fragments, case-insensitively;
+// fields are matched at any depth of request and error bodies. Values the
+// client resolved from secret references are hidden wherever they appear.
+type Redactor struct {
+    Headers []string `json:"headers"`
+    Fields []string `json:"fields"`
+}
+
+// DefaultRedactor hides credentials in headers and the usual names of
+// sensitive inputs.
+var DefaultRedactor = Redactor{
+    Headers: []string{"authorization", "cookie", "api-key", "token", "secret", "signature"},
+    Fields: []string{"password", "passwd", "secret", "token", "api_key", "apikey", "credential", "private_key", "authorization"},
+}
+
+// SetLogger makes the client log to logger: each request and its outcome
+// at debug level, with its body and any error body the agent returns,
+// retries and throttling at info, and failed requests at warn. Headers
+// and fields the client's Redactor names, and resolved secrets, are never
+// logged; bodies that are not JSON are logged by size only.
+func (c *Client) SetLogger(logger *slog.Logger) {
+    c.logger = logger
+}
+
+// SetRedactor replaces DefaultRedactor for the client's logs.
+func (c *Client) SetRedactor(redactor Redactor) {
+    c.redactor = &redactor
+}
+
+func WithLogger(logger *slog.Logger) Option {
+    return func(c *Client) { c.SetLogger(logger) }
+}
+
+func WithRedactor(redactor Redactor) Option {
+    return func(c *Client) { c.SetRedactor(redactor) }
+}
+
+func (c *Client) logRedactor() Redactor {
+    if c.redactor != nil {
+        return *c.redactor
+    }
+    return DefaultRedactor
+}
+
+func (r Redactor) hides(name string, fragments []string) bool {
+    name = strings.ToLower(name)
+    for _, fragment := range fragments {
+        if strings.Contains(name, strings.ToLower(fragment)) {
+            return true
+        }
+    }
+    return false
+}
+
+func (r Redactor) header(header http.Header) slog.Attr {
+    names := make([]string, 0, len(header))
+    for name := range header {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    attrs := make([]any, 0, len(names))
+    for _, name := range names {
+        value := strings.Join(header[name], ", ")
+        if r.hides(name, r.Headers) {
+            value = redactedValue
+        }
+        attrs = append(attrs, slog.String(name, value))
+    }
+    return slog.Group("headers", attrs...)
+}
+
+// loggedSecrets holds the secret values a logging client has resolved.
+type loggedSecrets struct {
+    sync.Mutex
+    values map[string]bool
+}
+
+// noteSecret remembers a resolved secret so logs can hide it.
+func (c *Client) noteSecret(value string) {
+    if c.logger == nil || value == "" {
+        return
+    }
+    c.resolvedSecrets.Lock()
+    if c.resolvedSecrets.values == nil {
+        c.resolvedSecrets.values = map[string]bool{}
+    }
+    c.resolvedSecrets.values[value] = true
+    c.resolvedSecrets.Unlock()
+}
+
+// logBody renders raw for a log, hiding redacted fields and secrets when
+// it is JSON and truncating it either way. Bodies that are not JSON are
+// logged only by size, as nothing in them can be told apart.
+func (c *Client) logBody(raw []byte) string {
+    var document any
+    if json.Unmarshal(raw, &document) != nil {
+        return fmt.Sprintf("(%d bytes)", len(raw))
+    }
+    c.resolvedSecrets.Lock()
+    encoded, _ := json.Marshal(c.redactValue(c.logRedactor(), document))
+    c.resolvedSecrets.Unlock()
+    if len(encoded) > maxLoggedBody {
+        return string(encoded[:maxLoggedBody]) + "..."
+    }
+    return string(encoded)
+}
+
+// redactValue is called with c.resolvedSecrets held.
+func (c *Client) redactValue(r Redactor, v any) any {
+    switch v := v.(type) {
+    case string:
+        if c.resolvedSecrets.values[v] {
+            return redactedValue
+        }
+    case map[string]any:
+        out := make(map[string]any, len(v))
+        for key, value := range v {
+            if r.hides(key, r.Fields) {
+                out[key] = redactedValue
+            } else {
+                out[key] = c.redactValue(r, value)
+            }
+        }
+        return out
+    case []any:
+        out := make([]any, len(v))
+        for i, value := range v {
+            out[i] = c.redactValue(r, value)
+        }
+        return out
+    }
+    return v
+}
+
+func (c *Client) logEnabled(ctx context.Context, level slog.Level) bool {
+    return c.logger != nil && c.logger.Enabled(ctx, level)
+}
+
+// logRequest logs req as it is sent. Its body is read through GetBody, so
+// only bodies that can be resent, such as JSON calls, are logged.
+func (c *Client) logRequest(req *http.Request, endpoint string) {
+    ctx := req.Context()
+    if !c.logEnabled(ctx, slog.LevelDebug) {
+        return
+    }
+    redactor := c.logRedactor()
+    attrs := []slog.Attr{slog.String("method", req.Method), slog.String("endpoint", endpoint), redactor.header(req.Header)}
+    if req.GetBody != nil {
+        if body, err := req.GetBody(); err == nil {
+            raw, _ := io.ReadAll(io.LimitReader(body, 64<<10))
+            body.Close()
+            if len(raw) > 0 {
+                attrs = append(attrs, slog.String("body", c.logBody(raw)))
+            }
+        }
+    }
+    c.logger.LogAttrs(ctx, slog.LevelDebug, "agent request", attrs...)
+}
+
+func (c *Client) logResponse(req *http.Request, endpoint string, status int, err error, latency time.Duration) {
+    ctx := req.Context()
+    level := slog.LevelDebug
+    if err != nil || status >= 400 {
+        level = slog.LevelWarn
+    }
+    if !c.logEnabled(ctx, level) {
+        return
+    }
+    attrs := []slog.Attr{slog.String("method", req.Method), slog.String("endpoint", endpoint), slog.Duration("latency", latency)}
+    if status > 0 {
+        attrs = append(attrs, slog.Int("status", status))
+    }
+    if err != nil {
+        attrs = append(attrs, slog.String("error", err.Error()))
+    }
+    message := "agent response"
+    if err != nil {
+        message = "agent request failed"
+    }
+    c.logger.LogAttrs(ctx, level, message, attrs...)
+}
+
+// apiError reads resp's error document, logging what the agent said.
+func (c *Client) apiError(req *http.Request, resp *http.Response) *APIError {
+    e := newAPIError(resp)
+    ctx := req.Context()
+    if !c.logEnabled(ctx, slog.LevelDebug) {
+        return e
+    }
+    attrs := []slog.Attr{slog.String("method", req.Method), slog.String("endpoint", endpointName(req.URL.Path)), slog.Int("status", e.Status)}
+    if e.Code != "" {
+        attrs = append(attrs, slog.String("code", e.Code))
+    }
+    if e.Message != "" {
+        attrs = append(attrs, slog.String("message", e.Message))
+    }
+    if len(e.Body) > 0 {
+        attrs = append(attrs, slog.String("body", c.logBody(e.Body)))
+    }
+    c.logger.LogAttrs(ctx, slog.LevelDebug, "agent error", attrs...)
+    return e
+}
+
+func (c *Client) logRetry(ctx context.Context, method, path string, attempt int, err error) {
+    if !c.logEnabled(ctx, slog.LevelInfo) {
+        return
+    }
+    c.logger.LogAttrs(ctx, slog.LevelInfo, "retrying agent request", slog.String("method", method), slog.String("endpoint", endpointName(path)), slog.Int("attempt", attempt), slog.String("error", err.Error()))
+}
+
+func (c *Client) logThrottled(ctx context.Context, method, path string) {
+    if !c.logEnabled(ctx, slog.LevelInfo) {
+        return
+    }
+    c.logger.LogAttrs(ctx, slog.LevelInfo, "agent throttled request; waiting to resend", slog.String("method", method), slog.String("endpoint", endpointName(path)), slog.Duration("wait", c.throttleWait()))
+}
@@ -0,0 +1,240 @@
+package mcp
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "os/exec"
+    "strconv"
+    "sync"
+
+    client "echo_computer_agent_client"
+)
+
+var ErrClientClosed = errors.New("mcp: client closed")
+
+// Client talks to an upstream MCP server over newline-delimited JSON-RPC.
+type Client struct {
+    ServerName string
+
+    w io.Writer
+    closer func() error
+    wmu sync.Mutex
+    mu sync.Mutex
+    nextID int
+    pending map[string]chan rpcMessage
+    done chan struct{}
+    err error
+}
+
+// Connect starts a client over an established stream pair and performs the
+// initialize handshake.
+func Connect(ctx context.Context, r io.Reader, w io.Writer, closer func() error) (*Client, error) {
+    c := &Client{w: w, closer: closer, pending: map[string]chan rpcMessage{}, done: make(chan struct{})}
+    go c.readLoop(r)
+    var info struct {
+        ServerInfo struct {
+            Name string `json:"name"`
+        } `json:"serverInfo"`
+    }
+    err := c.call(ctx, "initialize", map[string]any{
+        "protocolVersion": ProtocolVersion,
+        "capabilities": map[string]any{},
+        "clientInfo": map[string]any{"name": "echo-computer-agent-client", "version": "1.0.0"},
+    }, &info)
+    if err != nil {
+        c.Close()
+        return nil, err
+    }
+    c.ServerName = info.ServerInfo.Name
+    if err := c.notify("notifications/initialized"); err != nil {
+        c.Close()
+        return nil, err
+    }
+    return c, nil
+}
+
+// StartStdio launches an MCP server subprocess and connects to its stdio.
+func StartStdio(ctx context.Context, command string, args ...string) (*Client, error) {
+    cmd := exec.Command(command, args...)
+    stdin, err := cmd.StdinPipe()
+    if err != nil {
+        return nil, err
+    }
+    stdout, err := cmd.StdoutPipe()
+    if err != nil {
+        return nil, err
+    }
+    if err := cmd.Start(); err != nil {
+        return nil, err
+    }
+    return Connect(ctx, stdout, stdin, func() error {
+        stdin.Close()
+        return cmd.Wait()
+    })
+}
+
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+    page, err := c.ListToolPages(ctx, "")
+    if err != nil {
+        return nil, err
+    }
+    return page.All(ctx)
+}
+
+// ListToolPages pages through the server's tools from cursor.
+func (c *Client) ListToolPages(ctx context.Context, cursor client.Cursor) (*client.Page[Tool], error) {
+    return client.NewPage(ctx, cursor, func(ctx context.Context, cursor client.Cursor) ([]Tool, client.Cursor, error) {
+        params := map[string]any{}
+        if cursor != "" {
+            params["cursor"] = cursor
+        }
+        var page struct {
+            Tools []Tool `json:"tools"`
+            NextCursor client.Cursor `json:"nextCursor"`
+        }
+        if err := c.call(ctx, "tools/list", params, &page); err != nil {
+            return nil, "", err
+        }
+        return page.Tools, page.NextCursor, nil
+    })
+}
+
+func (c *Client) CallTool(ctx context.Context, name string, arguments map[string]any) (*CallToolResult, error) {
+    var result CallToolResult
+    if err := c.call(ctx, "tools/call", callToolParams{Name: name, Arguments: arguments}, &result); err != nil {
+        return nil, err
+    }
+    return &result, nil
+}
+
+func (c *Client) Close() error {
+    if c.closer == nil {
+        return nil
+    }
+    closer := c.closer
+    c.closer = nil
+    return closer()
+}
+
+// RegisterTools registers every upstream tool with the agent as
+// "<prefix><tool name>" and returns handlers that serve the agent's calls to
+// them by forwarding to this MCP server, ready for
+// client.ReverseOptions.Tools.
+func (c *Client) RegisterTools(ctx context.Context, agent *client.Client, prefix string) (map[string]client.ToolHandler, error) {
+    tools, err := c.ListTools(ctx)
+    if err != nil {
+        return nil, err
+    }
+    handlers := make(map[string]client.ToolHandler, len(tools))
+    for _, tool := range tools {
+        name := prefix + tool.Name
+        fn := client.FunctionDescription{
+            Name: name,
+            Description: tool.Description,
+            Parameters: tool.InputSchema,
+            Metadata: map[string]any{"source": "mcp", "mcp_server": c.ServerName, "mcp_tool": tool.Name},
+        }
+        if err := agent.RegisterFunction(ctx, fn); err != nil {
+            return nil, fmt.Errorf("register %s: %w", name, err)
+        }
+        upstream := tool.Name
+        handlers[name] = func(ctx context.Context, call client.ToolCall) (map[string]any, error) {
+            result, err := c.CallTool(ctx, upstream, call.Inputs)
+            if err != nil {
+                return nil, err
+            }
+            output := map[string]any{"content": result.Content}
+            if result.StructuredContent != nil {
+                output["structured"] = result.StructuredContent
+            }
+            if result.IsError {
+                return output, fmt.Errorf("mcp tool %s reported an error", upstream)
+            }
+            return output, nil
+        }
+    }
+    return handlers, nil
+}
+
+func (c *Client) call(ctx context.Context, method string, params, out any) error {
+    encoded, err := json.Marshal(params)
+    if err != nil {
+        return err
+    }
+    c.mu.Lock()
+    if c.err != nil {
+        c.mu.Unlock()
+        return c.err
+    }
+    c.nextID++
+    id := strconv.Itoa(c.nextID)
+    reply := make(chan rpcMessage, 1)
+    c.pending[id] = reply
+    c.mu.Unlock()
+    defer func() {
+        c.mu.Lock()
+        delete(c.pending, id)
+        c.mu.Unlock()
+    }()
+
+    if err := c.write(rpcMessage{JSONRPC: "2.0", ID: json.RawMessage(id), Method: method, Params: encoded}); err != nil {
+        return err
+    }
+    select {
+    case <-ctx.Done():
+        return ctx.Err()
+    case <-c.done:
+        return c.err
+    case message := <-reply:
+        if message.Error != nil {
+            return message.Error
+        }
+        if out == nil {
+            return nil
+        }
+        return json.Unmarshal(message.Result, out)
+    }
+}
+
+func (c *Client) notify(method string) error {
+    return c.write(rpcMessage{JSONRPC: "2.0", Method: method})
+}
+
+func (c *Client) write(message rpcMessage) error {
+    encoded, err := json.Marshal(message)
+    if err != nil {
+        return err
+    }
+    c.wmu.Lock()
+    defer c.wmu.Unlock()
+    _, err = c.w.Write(append(encoded, '\n'))
+    return err
+}
+
+func (c *Client) readLoop(r io.Reader) {
+    scanner := bufio.NewScanner(r)
+    scanner.Buffer(make([]byte, 64<<10), 16<<20)
+    for scanner.Scan() {
+        var message rpcMessage
+        if err := json.Unmarshal(scanner.Bytes(), &message); err != nil || len(message.ID) == 0 {
+            continue
+        }
+        c.mu.Lock()
+        reply, ok := c.pending[string(message.ID)]
+        c.mu.Unlock()
+        if ok {
+            reply <- message
+        }
+    }
+    c.mu.Lock()
+    c.err = ErrClientClosed
+    if err := scanner.Err(); err != nil {
+        c.err = fmt.Errorf("%w: %v", ErrClientClosed, err)
+    }
+    c.mu.Unlock()
+    close(c.done)
+}
@@ -0,0 +1,56 @@
+// Package mcp bridges the Echo agent and the Model Context Protocol: Server
+// exposes agent functions as MCP tools, and Client imports tools from other
+// MCP servers into the agent catalog.
+package mcp
+
+import "encoding/json"
+
+const ProtocolVersion = "2024-11-05"
+
+type rpcMessage struct {
+    JSONRPC string `json:"jsonrpc"`
+    ID json.RawMessage `json:"id,omitempty"`
+    Method string `json:"method,omitempty"`
+    Params json.RawMessage `json:"params,omitempty"`
+    Result json.RawMessage `json:"result,omitempty"`
+    Error *RPCError `json:"error,omitempty"`
+}
+
+type RPCError struct {
+    Code int `json:"code"`
+    Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+    return e.Message
+}
+
+const (
+    codeParseError = -32700
+    codeInvalidRequest = -32600
+    codeMethodNotFound = -32601
+    codeInvalidParams = -32602
+)
+
+// Tool is an MCP tool definition.
+type Tool struct {
+    Name string `json:"name"`
+    Description string `json:"description,omitempty"`
+    InputSchema map[string]any `json:"inputSchema"`
+}
+
+type Content struct {
+    Type string `json:"type"`
+    Text string `json:"text,omitempty"`
+}
+
+type CallToolResult struct {
+    Content []Content `json:"content"`
+    StructuredContent map[string]any `json:"structuredContent,omitempty"`
+    IsError bool `json:"isError,omitempty"`
+}
+
+type callToolParams struct {
+    Name string `json:"name"`
+    Arguments map[string]any `json:"arguments,omitempty"`
+}
@@ -0,0 +1,121 @@
+package mcp
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "io"
+    "sync"
+
+    client "echo_computer_agent_client"
+)
+
+// Server advertises every /functions entry as an MCP tool and proxies
+// tools/call to InvokeFunction. It speaks newline-delimited JSON-RPC, the
+// MCP stdio transport.
+type Server struct {
+    Client *client.Client
+    Name string
+    Version string
+}
+
+func NewServer(c *client.Client) *Server {
+    return &Server{Client: c, Name: "echo-computer-agent", Version: "1.0.0"}
+}
+
+// Serve reads requests from r and writes responses to w until r is
+// exhausted or ctx is cancelled.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+    var wmu sync.Mutex
+    encoder := json.NewEncoder(w)
+    write := func(message rpcMessage) {
+        wmu.Lock()
+        defer wmu.Unlock()
+        encoder.Encode(message)
+    }
+    scanner := bufio.NewScanner(r)
+    scanner.Buffer(make([]byte, 64<<10), 16<<20)
+    var wg sync.WaitGroup
+    defer wg.Wait()
+    for scanner.Scan() {
+        if ctx.Err() != nil {
+            return ctx.Err()
+        }
+        var message rpcMessage
+        if err := json.Unmarshal(scanner.Bytes(), &message); err != nil {
+            write(rpcMessage{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &RPCError{Code: codeParseError, Message: "parse error"}})
+            continue
+        }
+        if len(message.ID) == 0 {
+            // Notifications need no reply.
+            continue
+        }
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            result, rpcErr := s.handle(ctx, message.Method, message.Params)
+            reply := rpcMessage{JSONRPC: "2.0", ID: message.ID, Error: rpcErr}
+            if rpcErr == nil {
+                reply.Result, _ = json.Marshal(result)
+            }
+            write(reply)
+        }()
+    }
+    return scanner.Err()
+}
+
+func (s *Server) handle(ctx context.Context, method string, params json.RawMessage) (any, *RPCError) {
+    switch method {
+    case "initialize":
+        return map[string]any{
+            "protocolVersion": ProtocolVersion,
+            "capabilities": map[string]any{"tools": map[string]any{}},
+            "serverInfo": map[string]any{"name": s.Name, "version": s.Version},
+        }, nil
+    case "ping":
+        return map[string]any{}, nil
+    case "tools/list":
+        tools, err := s.Tools(ctx)
+        if err != nil {
+            return nil, &RPCError{Code: codeInvalidRequest, Message: err.Error()}
+        }
+        return map[string]any{"tools": tools}, nil
+    case "tools/call":
+        var call callToolParams
+        if err := json.Unmarshal(params, &call); err != nil || call.Name == "" {
+            return nil, &RPCError{Code: codeInvalidParams, Message: "tools/call requires a tool name"}
+        }
+        return s.Call(ctx, call.Name, call.Arguments), nil
+    default:
+        return nil, &RPCError{Code: codeMethodNotFound, Message: "method not found: " + method}
+    }
+}
+
+// Tools maps the agent catalog onto MCP tool definitions.
+func (s *Server) Tools(ctx context.Context) ([]Tool, error) {
+    catalog, err := s.Client.ListFunctions(ctx)
+    if err != nil {
+        return nil, err
+    }
+    tools := make([]Tool, 0, len(catalog.Functions))
+    for _, fn := range catalog.Functions {
+        tools = append(tools, Tool{Name: fn.Name, Description: fn.Description, InputSchema: InputSchema(fn.Parameters)})
+    }
+    return tools, nil
+}
+
+// Call invokes the tool's function. Agent failures are reported in-band
+// with IsError so MCP hosts can show them to the model.
+func (s *Server) Call(ctx context.Context, name string, arguments map[string]any) CallToolResult {
+    resp, err := s.Client.InvokeFunction(ctx, name, s.Client.StripSecretRefs(arguments))
+    if err != nil {
+        return CallToolResult{Content: []Content{{Type: "text", Text: err.Error()}}, IsError: true}
+    }
+    return CallToolResult{Content: []Content{{Type: "text", Text: resp.Message}}, StructuredContent: resp.Data}
+}
+
+// InputSchema returns parameters as an MCP input schema, which must be a
+// JSON Schema object.
+func InputSchema(parameters map[string]any) map[string]any {
+    return client.FunctionDescription{Parameters: parameters}.AnthropicTool().InputSchema
+}
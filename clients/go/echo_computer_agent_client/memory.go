@@ -0,0 +1,214 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// FeatureMemory is advertised by agents with a /memory store.
+const FeatureMemory = "memory"
+
+// ErrStaleRead is returned by QueryMemory when the agent has still not
+// caught up with the requested write once memoryReadWait has passed.
+var ErrStaleRead = errors.New("memory read did not reach the requested version")
+
+// memoryReadWait bounds how long QueryMemory retries a read that is behind
+// its consistency token.
+const memoryReadWait = 5 * time.Second
+
+type Memory struct {
+    ID string `json:"id,omitempty"`
+    Namespace string `json:"namespace,omitempty"`
+    Text string `json:"text"`
+    Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// MemoryWrite acknowledges a stored memory. Token is the version the store
+// reached with the write; reads made with it see the write.
+type MemoryWrite struct {
+    ID string `json:"id"`
+    Token string `json:"version"`
+}
+
+// MemoryQuery searches a namespace. MinVersion is sent for the agent to
+// wait on, when it can; it is filled in from the consistency token.
+type MemoryQuery struct {
+    Namespace string `json:"namespace,omitempty"`
+    Query string `json:"query"`
+    Limit int `json:"limit,omitempty"`
+    MinVersion string `json:"min_version,omitempty"`
+}
+
+type MemoryMatch struct {
+    Memory
+    Score float64 `json:"score"`
+}
+
+// MemoryResult is a query's matches and the version of the store that
+// answered it.
+type MemoryResult struct {
+    Matches []MemoryMatch `json:"matches"`
+    Version string `json:"version"`
+}
+
+type consistencyKey struct{}
+
+// WithConsistencyToken makes QueryMemory calls made with ctx read at least
+// the write token came from, retrying reads from a replica that is behind.
+func WithConsistencyToken(ctx context.Context, token string) context.Context {
+    return context.WithValue(ctx, consistencyKey{}, token)
+}
+
+// SetReadYourWrites makes the client remember the token of its latest write
+// to each namespace and read at least that far in it, as if every query
+// were made with WithConsistencyToken.
+func (c *Client) SetReadYourWrites(enabled bool) {
+    c.memory.Lock()
+    defer c.memory.Unlock()
+    c.memory.readYourWrites = enabled
+}
+
+func WithReadYourWrites() Option {
+    return func(c *Client) { c.SetReadYourWrites(true) }
+}
+
+// StoreMemory adds memory to the agent's store.
+//
+// Agents known not to support FeatureMemory get a store local to the
+// client instead, which is always consistent and searched by word overlap.
+func (c *Client) StoreMemory(ctx context.Context, memory Memory) (*MemoryWrite, error) {
+    var write MemoryWrite
+    if caps, err := c.Capabilities(ctx); err == nil && caps.lacks(FeatureMemory) {
+        write = c.memory.local.store(memory)
+    } else if err := c.doJSON(ctx, http.MethodPost, "/memory", memory, &write); err != nil {
+        return nil, err
+    }
+    c.memory.Lock()
+    defer c.memory.Unlock()
+    if c.memory.readYourWrites && write.Token != "" {
+        if c.memory.tokens == nil {
+            c.memory.tokens = map[string]string{}
+        }
+        if newerVersion(write.Token, c.memory.tokens[memory.Namespace]) {
+            c.memory.tokens[memory.Namespace] = write.Token
+        }
+    }
+    return &write, nil
+}
+
+// QueryMemory searches the agent's store. With a consistency token, from
+// ctx or the last write to the namespace under SetReadYourWrites, reads
+// that answer from an older version are retried with backoff until they
+// catch up, ctx is done, or they fail with ErrStaleRead.
+func (c *Client) QueryMemory(ctx context.Context, query MemoryQuery) (*MemoryResult, error) {
+    if caps, err := c.Capabilities(ctx); err == nil && caps.lacks(FeatureMemory) {
+        return c.memory.local.query(query), nil
+    }
+    token, _ := ctx.Value(consistencyKey{}).(string)
+    c.memory.Lock()
+    if last := c.memory.tokens[query.Namespace]; c.memory.readYourWrites && newerVersion(last, token) {
+        token = last
+    }
+    c.memory.Unlock()
+    if newerVersion(token, query.MinVersion) {
+        query.MinVersion = token
+    }
+    deadline := time.Now().Add(memoryReadWait)
+    interval := 100 * time.Millisecond
+    for {
+        var result MemoryResult
+        if err := c.doJSON(withIdempotent(ctx), http.MethodPost, "/memory/query", query, &result); err != nil {
+            return nil, err
+        }
+        if query.MinVersion == "" || !newerVersion(query.MinVersion, result.Version) {
+            return &result, nil
+        }
+        if time.Now().Add(interval).After(deadline) {
+            return nil, ErrStaleRead
+        }
+        if err := sleepCtx(ctx, interval); err != nil {
+            return nil, err
+        }
+        interval *= 2
+    }
+}
+
+// newerVersion reports whether version a is past b. Versions are compared
+// as integers when both are, and otherwise by length and then bytes, which
+// orders zero-padded or hex counters too. The empty version is oldest.
+func newerVersion(a, b string) bool {
+    if a == "" || b == "" {
+        return b == "" && a != ""
+    }
+    x, errX := strconv.ParseUint(a, 10, 64)
+    y, errY := strconv.ParseUint(b, 10, 64)
+    if errX == nil && errY == nil {
+        return x > y
+    }
+    if len(a) != len(b) {
+        return len(a) > len(b)
+    }
+    return a > b
+}
+
+type memoryState struct {
+    sync.Mutex
+    readYourWrites bool
+    tokens map[string]string
+    local localMemoryStore
+}
+
+// localMemoryStore backs the memory calls for agents without one.
+type localMemoryStore struct {
+    sync.Mutex
+    version uint64
+    memories []Memory
+}
+
+func (s *localMemoryStore) store(memory Memory) MemoryWrite {
+    s.Lock()
+    defer s.Unlock()
+    s.version++
+    if memory.ID == "" {
+        memory.ID = "local-mem-" + strconv.FormatUint(s.version, 10)
+    }
+    s.memories = append(s.memories, memory)
+    return MemoryWrite{ID: memory.ID, Token: strconv.FormatUint(s.version, 10)}
+}
+
+func (s *localMemoryStore) query(query MemoryQuery) *MemoryResult {
+    s.Lock()
+    defer s.Unlock()
+    words := strings.Fields(strings.ToLower(query.Query))
+    result := &MemoryResult{Matches: []MemoryMatch{}, Version: strconv.FormatUint(s.version, 10)}
+    for _, memory := range s.memories {
+        if memory.Namespace != query.Namespace {
+            continue
+        }
+        text := strings.ToLower(memory.Text)
+        found := 0
+        for _, word := range words {
+            if strings.Contains(text, word) {
+                found++
+            }
+        }
+        if found > 0 || len(words) == 0 {
+            score := 1.0
+            if len(words) > 0 {
+                score = float64(found) / float64(len(words))
+            }
+            result.Matches = append(result.Matches, MemoryMatch{Memory: memory, Score: score})
+        }
+    }
+    sort.SliceStable(result.Matches, func(i, j int) bool { return result.Matches[i].Score > result.Matches[j].Score })
+    if query.Limit > 0 && len(result.Matches) > query.Limit {
+        result.Matches = result.Matches[:query.Limit]
+    }
+    return result
+}
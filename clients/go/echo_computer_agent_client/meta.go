@@ -0,0 +1,101 @@
+package echo_computer_agent_client
+
+import (
+    "strconv"
+    "time"
+)
+
+// ResponseMeta is the typed view of the common ChatResponse.Metadata keys.
+// Raw is the untouched map, for keys the struct does not cover.
+type ResponseMeta struct {
+    Model string
+    Latency time.Duration
+    PromptTokens int
+    CompletionTokens int
+    TotalTokens int
+    Credits float64
+    TraceID string
+    Timestamp time.Time
+    Raw map[string]any
+}
+
+// Meta parses the response metadata. It accepts the spellings agents have
+// used: latency as "latency_ms", seconds in "latency_seconds", or a
+// duration string in "latency"; tokens as a "tokens" or "usage" object
+// (prompt/completion/total, optionally suffixed "_tokens") or a bare total;
+// credits as "credits" at the top level or in that object; "trace_id" or
+// "traceId"; and "timestamp" as RFC 3339 or Unix seconds.
+func (r *ChatResponse) Meta() ResponseMeta {
+    m := r.Metadata
+    meta := ResponseMeta{Raw: m}
+    meta.Model, _ = m["model"].(string)
+    if trace, ok := m["trace_id"].(string); ok {
+        meta.TraceID = trace
+    } else {
+        meta.TraceID, _ = m["traceId"].(string)
+    }
+
+    if ms, ok := number(m["latency_ms"]); ok {
+        meta.Latency = time.Duration(ms * float64(time.Millisecond))
+    } else if s, ok := number(m["latency_seconds"]); ok {
+        meta.Latency = time.Duration(s * float64(time.Second))
+    } else if text, ok := m["latency"].(string); ok {
+        meta.Latency, _ = time.ParseDuration(text)
+    } else if s, ok := number(m["latency"]); ok {
+        meta.Latency = time.Duration(s * float64(time.Second))
+    }
+
+    usage, ok := m["tokens"].(map[string]any)
+    if !ok {
+        usage, _ = m["usage"].(map[string]any)
+    }
+    tokens := func(key string) int {
+        if n, ok := number(usage[key]); ok {
+            return int(n)
+        }
+        n, _ := number(usage[key+"_tokens"])
+        return int(n)
+    }
+    meta.PromptTokens, meta.CompletionTokens, meta.TotalTokens = tokens("prompt"), tokens("completion"), tokens("total")
+    if n, ok := number(m["tokens"]); ok {
+        meta.TotalTokens = int(n)
+    }
+    if meta.TotalTokens == 0 {
+        meta.TotalTokens = meta.PromptTokens + meta.CompletionTokens
+    }
+    if credits, ok := number(m["credits"]); ok {
+        meta.Credits = credits
+    } else {
+        meta.Credits, _ = number(usage["credits"])
+    }
+
+    switch ts := m["timestamp"].(type) {
+    case string:
+        if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+            meta.Timestamp = t
+        } else if unix, err := strconv.ParseFloat(ts, 64); err == nil {
+            meta.Timestamp = unixTime(unix)
+        }
+    case float64:
+        meta.Timestamp = unixTime(ts)
+    }
+    return meta
+}
+
+func number(v any) (float64, bool) {
+    switch n := v.(type) {
+    case float64:
+        return n, true
+    case int:
+        return float64(n), true
+    case string:
+        f, err := strconv.ParseFloat(n, 64)
+        return f, err == nil
+    }
+    return 0, false
+}
+
+func unixTime(seconds float64) time.Time {
+    whole := int64(seconds)
+    return time.Unix(whole, int64((seconds-float64(whole))*1e9)).UTC()
+}
@@ -0,0 +1,24 @@
+package echo_computer_agent_client
+
+import (
+    "time"
+)
+
+// Metrics receives the client's request telemetry, one call as each
+// request starts and one as it completes. Endpoints are named as in the
+// debug view, with IDs folded into "{id}", so label sets stay bounded.
+// Status is 0 when no response arrived; err is set for those and for
+// statuses of 400 and up. Calls may be concurrent. Package prommetrics has
+// a Prometheus implementation.
+type Metrics interface {
+    RequestStarted(method, endpoint string)
+    RequestFinished(method, endpoint string, status int, err error, latency time.Duration)
+}
+
+func (c *Client) SetMetrics(metrics Metrics) {
+    c.metrics = metrics
+}
+
+func WithMetrics(metrics Metrics) Option {
+    return func(c *Client) { c.SetMetrics(metrics) }
+}
@@ -0,0 +1,110 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "errors"
+    "fmt"
+)
+
+// ModeInput is the input a request's mode is sent in, for functions that
+// list modes under Metadata["modes"].
+const ModeInput = "mode"
+
+// Modes functions commonly advertise.
+const (
+    ModeSafe = "safe"
+    ModeFast = "fast"
+    ModeThorough = "thorough"
+)
+
+var ErrUnsupportedMode = errors.New("function does not support mode")
+
+// Mode is one way a function can run. Default marks the mode used when a
+// request names none.
+type Mode struct {
+    Name string `json:"name"`
+    Description string `json:"description,omitempty"`
+    Default bool `json:"default,omitempty"`
+}
+
+// Modes returns the modes fn lists under Metadata["modes"], given either
+// as names or as {"name", "description", "default"} objects. A
+// Metadata["default_mode"] name marks its mode as the default too.
+func Modes(fn FunctionDescription) []Mode {
+    var modes []Mode
+    list, _ := fn.Metadata["modes"].([]any)
+    if names, ok := fn.Metadata["modes"].([]string); ok {
+        for _, name := range names {
+            list = append(list, name)
+        }
+    }
+    for _, item := range list {
+        switch item := item.(type) {
+        case string:
+            modes = append(modes, Mode{Name: item})
+        case map[string]any:
+            mode := Mode{}
+            mode.Name, _ = item["name"].(string)
+            mode.Description, _ = item["description"].(string)
+            mode.Default, _ = item["default"].(bool)
+            if mode.Name != "" {
+                modes = append(modes, mode)
+            }
+        }
+    }
+    if name, ok := fn.Metadata["default_mode"].(string); ok {
+        for i := range modes {
+            if modes[i].Name == name {
+                modes[i].Default = true
+            }
+        }
+    }
+    return modes
+}
+
+// SupportsMode reports whether fn lists mode.
+func SupportsMode(fn FunctionDescription, mode string) bool {
+    for _, m := range Modes(fn) {
+        if m.Name == mode {
+            return true
+        }
+    }
+    return false
+}
+
+// WithModeSupport returns the functions that list mode.
+func (f Functions) WithModeSupport(mode string) Functions {
+    var matched Functions
+    for _, fn := range f {
+        if SupportsMode(fn, mode) {
+            matched = append(matched, fn)
+        }
+    }
+    return matched
+}
+
+// WithMode returns a copy of r that asks for mode in its "mode" input.
+func (r ChatRequest) WithMode(mode string) ChatRequest {
+    inputs := make(map[string]any, len(r.Inputs)+1)
+    for key, value := range r.Inputs {
+        inputs[key] = value
+    }
+    inputs[ModeInput] = mode
+    r.Inputs = inputs
+    return r
+}
+
+// CheckMode fails with ErrUnsupportedMode when function lists modes in
+// the cached catalog and mode is not one of them. Functions that list
+// none, or are not in the catalog, accept any mode.
+func (c *Client) CheckMode(ctx context.Context, function, mode string) error {
+    functions, err := c.Functions(ctx)
+    if err != nil {
+        return err
+    }
+    fn, ok := functions.ByName(function)
+    if !ok || len(Modes(fn)) == 0 || SupportsMode(fn, mode) {
+        return nil
+    }
+    return fmt.Errorf("%w: %s has no mode %q", ErrUnsupportedMode, function, mode)
+}
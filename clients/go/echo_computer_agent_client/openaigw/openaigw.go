@@ -0,0 +1,352 @@
+// Package openaigw serves an OpenAI-compatible chat-completions API backed by
+// the Echo agent, so existing OpenAI clients can talk to it unchanged.
+package openaigw
+
+import (
+    "crypto/rand"
+    "crypto/subtle"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strings"
+    "time"
+
+    client "echo_computer_agent_client"
+)
+
+const DefaultModel = "echo-agent"
+
+type Message struct {
+    Role string `json:"role"`
+    Content string `json:"content"`
+    Name string `json:"name,omitempty"`
+    ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+    ToolCallID string `json:"tool_call_id,omitempty"`
+}
+
+type ToolCall struct {
+    ID string `json:"id"`
+    Type string `json:"type"`
+    Function FunctionCall `json:"function"`
+}
+
+type FunctionCall struct {
+    Name string `json:"name"`
+    Arguments string `json:"arguments"`
+}
+
+type Tool struct {
+    Type string `json:"type"`
+    Function ToolFunction `json:"function"`
+}
+
+type ToolFunction struct {
+    Name string `json:"name"`
+    Description string `json:"description,omitempty"`
+    Parameters map[string]any `json:"parameters,omitempty"`
+}
+
+// CompletionRequest is the subset of the chat-completions request the
+// gateway understands. EchoExecute is an extension: when true, a selected
+// tool is executed by the agent and its result returned as content instead
+// of a tool call.
+type CompletionRequest struct {
+    Model string `json:"model"`
+    Messages []Message `json:"messages"`
+    Tools []Tool `json:"tools,omitempty"`
+    Stream bool `json:"stream,omitempty"`
+    User string `json:"user,omitempty"`
+    EchoExecute bool `json:"echo_execute,omitempty"`
+}
+
+type Choice struct {
+    Index int `json:"index"`
+    Message *Message `json:"message,omitempty"`
+    Delta *Message `json:"delta,omitempty"`
+    FinishReason string `json:"finish_reason"`
+}
+
+type CompletionResponse struct {
+    ID string `json:"id"`
+    Object string `json:"object"`
+    Created int64 `json:"created"`
+    Model string `json:"model"`
+    Choices []Choice `json:"choices"`
+    Usage *Usage `json:"usage,omitempty"`
+    Echo *client.ChatResponse `json:"echo,omitempty"`
+}
+
+type Usage struct {
+    PromptTokens int `json:"prompt_tokens"`
+    CompletionTokens int `json:"completion_tokens"`
+    TotalTokens int `json:"total_tokens"`
+}
+
+// Gateway handles /v1/chat/completions and /v1/models. The latest user
+// message becomes the agent prompt and earlier turns are passed as
+// Inputs["history"]. When the request lists tools and the agent routes to
+// one of them, the reply is a tool call whose arguments are the inputs the
+// agent planned for it. A conversation ending in the results of such calls
+// is answered with those results rather than planned again, which would
+// only repeat the call.
+//
+// When APIKeys is set every request needs "Authorization: Bearer <key>"
+// with one of its keys, and is made on behalf of that key's actor rather
+// than the request's user field.
+type Gateway struct {
+    Client *client.Client
+    Model string
+    APIKeys map[string]client.Actor
+}
+
+func New(c *client.Client) *Gateway {
+    return &Gateway{Client: c, Model: DefaultModel}
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    actor, ok := g.authenticate(r)
+    if !ok {
+        writeError(w, http.StatusUnauthorized, "invalid_api_key", "invalid or missing API key")
+        return
+    }
+    if actor != nil {
+        r = r.WithContext(client.WithActor(r.Context(), *actor))
+    }
+    switch {
+    case r.URL.Path == "/v1/models" && r.Method == http.MethodGet:
+        writeJSON(w, http.StatusOK, map[string]any{
+            "object": "list",
+            "data": []map[string]any{{"id": g.model(), "object": "model", "owned_by": "echo"}},
+        })
+    case r.URL.Path == "/v1/chat/completions" && r.Method == http.MethodPost:
+        g.completions(w, r)
+    default:
+        writeError(w, http.StatusNotFound, "not_found", "unknown endpoint")
+    }
+}
+
+func (g *Gateway) completions(w http.ResponseWriter, r *http.Request) {
+    var request CompletionRequest
+    if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<20)).Decode(&request); err != nil {
+        writeError(w, http.StatusBadRequest, "invalid_request_error", "invalid JSON body")
+        return
+    }
+    chat, err := ToChatRequest(request)
+    if err != nil {
+        writeError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+        return
+    }
+    if message, ok := toolReply(request.Messages); ok {
+        g.respond(w, request, message, "stop", nil)
+        return
+    }
+    ctx := r.Context()
+    if lang := r.Header.Get("Accept-Language"); lang != "" {
+        ctx = client.WithLanguage(ctx, lang)
+    }
+    if _, ok := client.ActorFromContext(ctx); !ok && request.User != "" {
+        ctx = client.WithActor(ctx, client.Actor{ID: request.User})
+    }
+
+    reply, err := g.Client.Plan(ctx, chat)
+    if err != nil {
+        writeError(w, http.StatusBadGateway, "upstream_error", err.Error())
+        return
+    }
+    message := Message{Role: "assistant", Content: reply.Message}
+    finish := "stop"
+    if tool, ok := selectedTool(request.Tools, reply.Function); ok {
+        if request.EchoExecute {
+            // The planned function is invoked directly; routing the message
+            // again could pick a different one.
+            planned := reply.Function
+            if reply, err = g.Client.InvokeFunction(ctx, planned, g.Client.StripSecretRefs(plannedInputs(reply, chat))); err != nil {
+                writeError(w, http.StatusBadGateway, "upstream_error", err.Error())
+                return
+            }
+            if reply.Function != "" && reply.Function != planned {
+                writeError(w, http.StatusBadGateway, "upstream_error", fmt.Sprintf("agent ran %s, planned %s", reply.Function, planned))
+                return
+            }
+            message.Content = reply.Message
+        } else {
+            arguments, _ := json.Marshal(plannedInputs(reply, chat))
+            message.Content = ""
+            message.ToolCalls = []ToolCall{{
+                ID: "call_" + randomID(),
+                Type: "function",
+                Function: FunctionCall{Name: tool, Arguments: string(arguments)},
+            }}
+            finish = "tool_calls"
+        }
+    }
+
+    g.respond(w, request, message, finish, reply)
+}
+
+// respond writes message as the completion, with the agent's reply, when
+// there is one, attached for its usage and as the Echo extension.
+func (g *Gateway) respond(w http.ResponseWriter, request CompletionRequest, message Message, finish string, reply *client.ChatResponse) {
+    response := CompletionResponse{
+        ID: "chatcmpl-" + randomID(),
+        Object: "chat.completion",
+        Created: time.Now().Unix(),
+        Model: g.model(),
+        Echo: reply,
+    }
+    if reply != nil {
+        if meta := reply.Meta(); meta.TotalTokens > 0 {
+            response.Usage = &Usage{PromptTokens: meta.PromptTokens, CompletionTokens: meta.CompletionTokens, TotalTokens: meta.TotalTokens}
+        }
+    }
+    if !request.Stream {
+        response.Choices = []Choice{{Message: &message, FinishReason: finish}}
+        writeJSON(w, http.StatusOK, response)
+        return
+    }
+    // The agent replies in one piece, so a stream is a single delta chunk.
+    response.Object = "chat.completion.chunk"
+    response.Echo = nil
+    response.Choices = []Choice{{Delta: &message, FinishReason: finish}}
+    w.Header().Set("Content-Type", "text/event-stream")
+    w.Header().Set("Cache-Control", "no-cache")
+    chunk, _ := json.Marshal(response)
+    fmt.Fprintf(w, "data: %s\n\ndata: [DONE]\n\n", chunk)
+}
+
+// ToChatRequest maps a completion request onto an agent ChatRequest.
+func ToChatRequest(request CompletionRequest) (client.ChatRequest, error) {
+    last := -1
+    for i := len(request.Messages) - 1; i >= 0; i-- {
+        if request.Messages[i].Role == "user" {
+            last = i
+            break
+        }
+    }
+    if last < 0 {
+        return client.ChatRequest{}, fmt.Errorf("messages must include a user message")
+    }
+    chat := client.ChatRequest{Message: request.Messages[last].Content}
+    if history := request.Messages[:last]; len(history) > 0 {
+        turns := make([]map[string]any, 0, len(history))
+        for _, m := range history {
+            turn := map[string]any{"role": m.Role, "content": m.Content}
+            if len(m.ToolCalls) > 0 {
+                turn["tool_calls"] = m.ToolCalls
+            }
+            if m.ToolCallID != "" {
+                turn["tool_call_id"] = m.ToolCallID
+            }
+            if m.Name != "" {
+                turn["name"] = m.Name
+            }
+            turns = append(turns, turn)
+        }
+        chat.Inputs = map[string]any{"history": turns}
+    }
+    return chat, nil
+}
+
+// toolReply builds the final assistant message for a conversation that
+// ends in tool results, from their content. Results from several calls
+// are each labelled with the tool that produced them.
+func toolReply(messages []Message) (Message, bool) {
+    first := len(messages)
+    for first > 0 && messages[first-1].Role == "tool" {
+        first--
+    }
+    results := messages[first:]
+    if len(results) == 0 {
+        return Message{}, false
+    }
+    names := map[string]string{}
+    for _, m := range messages[:first] {
+        for _, call := range m.ToolCalls {
+            names[call.ID] = call.Function.Name
+        }
+    }
+    if len(results) == 1 {
+        return Message{Role: "assistant", Content: results[0].Content}, true
+    }
+    parts := make([]string, 0, len(results))
+    for _, m := range results {
+        name := m.Name
+        if name == "" {
+            name = names[m.ToolCallID]
+        }
+        if name == "" {
+            parts = append(parts, m.Content)
+            continue
+        }
+        parts = append(parts, name+": "+m.Content)
+    }
+    return Message{Role: "assistant", Content: strings.Join(parts, "\n\n")}, true
+}
+
+// authenticate checks the request's API key against every key, so timing
+// does not tell which one matched, and returns the key's actor if it has
+// one.
+func (g *Gateway) authenticate(r *http.Request) (*client.Actor, bool) {
+    if len(g.APIKeys) == 0 {
+        return nil, true
+    }
+    key, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+    var found *client.Actor
+    matched := false
+    for issued, actor := range g.APIKeys {
+        if subtle.ConstantTimeCompare([]byte(key), []byte(issued)) == 1 {
+            matched = true
+            if actor.ID != "" {
+                actor := actor
+                found = &actor
+            }
+        }
+    }
+    return found, matched && key != ""
+}
+
+func selectedTool(tools []Tool, function string) (string, bool) {
+    for _, tool := range tools {
+        if tool.Function.Name == function || client.ToolName(function) == tool.Function.Name {
+            return tool.Function.Name, true
+        }
+    }
+    return "", false
+}
+
+func plannedInputs(reply *client.ChatResponse, request client.ChatRequest) map[string]any {
+    if inputs, ok := reply.Data["inputs"].(map[string]any); ok {
+        return inputs
+    }
+    inputs := map[string]any{}
+    for k, v := range request.Inputs {
+        if k != "history" {
+            inputs[k] = v
+        }
+    }
+    return inputs
+}
+
+func (g *Gateway) model() string {
+    if g.Model == "" {
+        return DefaultModel
+    }
+    return g.Model
+}
+
+func randomID() string {
+    b := make([]byte, 12)
+    rand.Read(b)
+    return hex.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload any) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(payload)
+}
+
+func writeError(w http.ResponseWriter, status int, kind, message string) {
+    writeJSON(w, status, map[string]any{"error": map[string]string{"type": kind, "message": message}})
+}
@@ -0,0 +1,118 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "sort"
+    "strings"
+)
+
+// OpenAPIDocument describes the catalog as an OpenAPI 3.0 document with one
+// POST /functions/{name}/invoke path per function, so API tooling can
+// consume the agent surface. The returned map marshals directly to JSON.
+func OpenAPIDocument(catalog *FunctionListResponse, title, version, serverURL string) map[string]any {
+    functions := append([]FunctionDescription(nil), catalog.Functions...)
+    sort.Slice(functions, func(i, j int) bool { return functions[i].Name < functions[j].Name })
+
+    paths := map[string]any{}
+    for _, fn := range functions {
+        operation := map[string]any{
+            "operationId": operationID(fn.Name),
+            "summary": firstLine(fn.Description),
+            "description": fn.Description,
+            "requestBody": map[string]any{
+                "required": true,
+                "content": map[string]any{
+                    "application/json": map[string]any{
+                        "schema": map[string]any{
+                            "type": "object",
+                            "properties": map[string]any{"inputs": objectSchema(fn.Parameters)},
+                        },
+                    },
+                },
+            },
+            "responses": map[string]any{
+                "200": map[string]any{
+                    "description": "Function result",
+                    "content": map[string]any{
+                        "application/json": map[string]any{
+                            "schema": map[string]any{"$ref": "#/components/schemas/ChatResponse"},
+                        },
+                    },
+                },
+                "default": map[string]any{"description": "Error response"},
+            },
+        }
+        if tags := metadataTags(fn.Metadata); len(tags) > 0 {
+            operation["tags"] = tags
+        }
+        if len(fn.Metadata) > 0 {
+            operation["x-echo-metadata"] = fn.Metadata
+        }
+        paths["/functions/"+fn.Name+"/invoke"] = map[string]any{"post": operation}
+    }
+
+    document := map[string]any{
+        "openapi": "3.0.3",
+        "info": map[string]any{"title": title, "version": version},
+        "paths": paths,
+        "components": map[string]any{
+            "schemas": map[string]any{
+                "ChatResponse": map[string]any{
+                    "type": "object",
+                    "required": []string{"function", "message", "data", "metadata"},
+                    "properties": map[string]any{
+                        "function": map[string]any{"type": "string"},
+                        "message": map[string]any{"type": "string"},
+                        "data": map[string]any{"type": "object", "additionalProperties": true},
+                        "metadata": map[string]any{"type": "object", "additionalProperties": true},
+                    },
+                },
+            },
+        },
+    }
+    if serverURL != "" {
+        document["servers"] = []map[string]any{{"url": serverURL}}
+    }
+    return document
+}
+
+// CatalogOpenAPI fetches the live catalog and renders it with
+// OpenAPIDocument, using the client's base URL as the server.
+func (c *Client) CatalogOpenAPI(ctx context.Context, title, version string) (map[string]any, error) {
+    catalog, err := c.ListFunctions(ctx)
+    if err != nil {
+        return nil, err
+    }
+    return OpenAPIDocument(catalog, title, version, c.baseURL), nil
+}
+
+func operationID(name string) string {
+    parts := strings.FieldsFunc(name, func(r rune) bool {
+        return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+    })
+    id := "invoke"
+    for _, part := range parts {
+        id += strings.ToUpper(part[:1]) + part[1:]
+    }
+    return id
+}
+
+func firstLine(s string) string {
+    line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+    return line
+}
+
+func metadataTags(metadata map[string]any) []string {
+    var tags []string
+    if category, ok := metadata["category"].(string); ok && category != "" {
+        tags = append(tags, category)
+    }
+    if list, ok := metadata["tags"].([]any); ok {
+        for _, tag := range list {
+            if s, ok := tag.(string); ok {
+                tags = append(tags, s)
+            }
+        }
+    }
+    return tags
+}
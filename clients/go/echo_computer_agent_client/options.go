@@ -0,0 +1,80 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "net/http"
+    "time"
+)
+
+// Option configures a client built by NewClientWithOptions. Each option
+// has a Set method counterpart for changing a client after construction.
+type Option func(*Client)
+
+// NewClientWithOptions is NewClient configured by opts, applied in order.
+func NewClientWithOptions(baseURL string, opts ...Option) *Client {
+    c := NewClient(baseURL, nil)
+    for _, opt := range opts {
+        opt(c)
+    }
+    return c
+}
+
+func WithHTTPClient(httpClient *http.Client) Option {
+    return func(c *Client) {
+        if httpClient != nil {
+            c.transport = httpClient
+        }
+    }
+}
+
+func WithTransport(transport Transport) Option {
+    return func(c *Client) { c.SetTransport(transport) }
+}
+
+// WithTimeout bounds each request and its response, so every retry gets
+// the full timeout. Streams and uploads, which run as long as their
+// content does, are not bounded; see StreamOptions.IdleTimeout.
+func WithTimeout(timeout time.Duration) Option {
+    return func(c *Client) { c.timeout = timeout }
+}
+
+func WithUserAgent(userAgent string) Option {
+    return func(c *Client) { c.SetDefaultHeader("User-Agent", userAgent) }
+}
+
+func WithHeader(key, value string) Option {
+    return func(c *Client) { c.SetDefaultHeader(key, value) }
+}
+
+func WithRetryPolicy(policy RetryPolicy) Option {
+    return func(c *Client) { c.SetRetryPolicy(policy) }
+}
+
+func WithDefaultExecute(execute bool) Option {
+    return func(c *Client) { c.SetDefaultExecute(execute) }
+}
+
+// WithBaseContext ties the client's calls to ctx: when it is done, calls
+// in flight are cancelled and new ones fail, e.g. on process shutdown.
+func WithBaseContext(ctx context.Context) Option {
+    return func(c *Client) { c.baseCtx = ctx }
+}
+
+// callContext bounds a single request by timeout, when positive, and by
+// the client's base context.
+func (c *Client) callContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+    var cancel context.CancelFunc
+    if timeout > 0 {
+        ctx, cancel = context.WithTimeout(ctx, timeout)
+    } else {
+        ctx, cancel = context.WithCancel(ctx)
+    }
+    if c.baseCtx == nil {
+        return ctx, cancel
+    }
+    stop := context.AfterFunc(c.baseCtx, cancel)
+    return ctx, func() {
+        stop()
+        cancel()
+    }
+}
@@ -0,0 +1,221 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strconv"
+    "time"
+)
+
+var ErrNoMorePages = errors.New("no more pages")
+
+// Cursor is an opaque position in a paginated listing. The empty cursor
+// is the start.
+type Cursor string
+
+// ListOptions selects where a listing starts and how many items a page
+// holds; a zero Limit leaves the page size to the agent.
+type ListOptions struct {
+    Limit int
+    Cursor Cursor
+}
+
+// Page is one page of a cursor-paginated listing. NextCursor is empty on
+// the last page.
+type Page[T any] struct {
+    Items []T
+    NextCursor Cursor
+    fetch func(ctx context.Context, cursor Cursor) ([]T, Cursor, error)
+}
+
+// NewPage loads the page at cursor from fetch, which returns a page's items
+// and the cursor of the page after it. It adapts any cursor-paginated
+// source to Page.
+func NewPage[T any](ctx context.Context, cursor Cursor, fetch func(ctx context.Context, cursor Cursor) ([]T, Cursor, error)) (*Page[T], error) {
+    items, next, err := fetch(ctx, cursor)
+    if err != nil {
+        return nil, err
+    }
+    return &Page[T]{Items: items, NextCursor: next, fetch: fetch}, nil
+}
+
+func (p *Page[T]) HasNext() bool {
+    return p.NextCursor != "" && p.fetch != nil
+}
+
+// Next loads the following page, failing with ErrNoMorePages after the
+// last.
+func (p *Page[T]) Next(ctx context.Context) (*Page[T], error) {
+    if !p.HasNext() {
+        return nil, ErrNoMorePages
+    }
+    return NewPage(ctx, p.NextCursor, p.fetch)
+}
+
+// All returns the items of this page and every page after it.
+func (p *Page[T]) All(ctx context.Context) ([]T, error) {
+    items := append([]T(nil), p.Items...)
+    for page := p; page.HasNext(); {
+        next, err := page.Next(ctx)
+        if err != nil {
+            return items, err
+        }
+        items = append(items, next.Items...)
+        page = next
+    }
+    return items, nil
+}
+
+// Each yields the items of this page and the pages after it, loading each
+// page as the previous one is used up. A failed load is yielded once as
+// the error with a zero item. On Go 1.23 and later it can be ranged over.
+func (p *Page[T]) Each(ctx context.Context) func(yield func(T, error) bool) {
+    return func(yield func(T, error) bool) {
+        it := p.Iterator()
+        for it.Next(ctx) {
+            if !yield(it.Item(), nil) {
+                return
+            }
+        }
+        if err := it.Err(); err != nil {
+            var zero T
+            yield(zero, err)
+        }
+    }
+}
+
+// Iterator steps through a listing item by item:
+//
+//  it := page.Iterator()
+//  for it.Next(ctx) {
+//      use(it.Item())
+//  }
+//  if err := it.Err(); err != nil { ... }
+type Iterator[T any] struct {
+    page *Page[T]
+    index int
+    item T
+    err error
+}
+
+func (p *Page[T]) Iterator() *Iterator[T] {
+    return &Iterator[T]{page: p, index: -1}
+}
+
+func (it *Iterator[T]) Next(ctx context.Context) bool {
+    if it.err != nil || it.page == nil {
+        return false
+    }
+    it.index++
+    for it.index >= len(it.page.Items) {
+        if !it.page.HasNext() {
+            it.page = nil
+            return false
+        }
+        next, err := it.page.Next(ctx)
+        if err != nil {
+            it.err = err
+            return false
+        }
+        it.page, it.index = next, 0
+    }
+    it.item = it.page.Items[it.index]
+    return true
+}
+
+func (it *Iterator[T]) Item() T {
+    return it.item
+}
+
+func (it *Iterator[T]) Err() error {
+    return it.err
+}
+
+// listPage fetches one page of an agent listing. The agent returns the
+// items under key (or "items") and the next cursor as "next_cursor";
+// agents that do not paginate return everything with no cursor.
+func listPage[T any](c *Client, path, key string, limit int) func(ctx context.Context, cursor Cursor) ([]T, Cursor, error) {
+    return func(ctx context.Context, cursor Cursor) ([]T, Cursor, error) {
+        query := url.Values{}
+        if limit > 0 {
+            query.Set("limit", strconv.Itoa(limit))
+        }
+        if cursor != "" {
+            query.Set("cursor", string(cursor))
+        }
+        target := path
+        if len(query) > 0 {
+            target += "?" + query.Encode()
+        }
+        var body map[string]json.RawMessage
+        if err := c.doJSON(ctx, http.MethodGet, target, nil, &body); err != nil {
+            return nil, "", err
+        }
+        raw, ok := body[key]
+        if !ok {
+            raw = body["items"]
+        }
+        var items []T
+        if len(raw) > 0 {
+            if err := json.Unmarshal(raw, &items); err != nil {
+                return nil, "", fmt.Errorf("decode %s: %w", path, err)
+            }
+        }
+        var next Cursor
+        if raw, ok := body["next_cursor"]; ok {
+            json.Unmarshal(raw, &next)
+        }
+        return items, next, nil
+    }
+}
+
+// ListFunctionPages pages through the catalog. ListFunctions returns it
+// whole.
+func (c *Client) ListFunctionPages(ctx context.Context, opts ListOptions) (*Page[FunctionDescription], error) {
+    fetch := listPage[FunctionDescription](c, "/functions", "functions", opts.Limit)
+    return NewPage(ctx, opts.Cursor, func(ctx context.Context, cursor Cursor) ([]FunctionDescription, Cursor, error) {
+        items, next, err := fetch(ctx, cursor)
+        if err == nil {
+            c.noteDeprecatedFunctions(&FunctionListResponse{Functions: items})
+        }
+        return items, next, err
+    })
+}
+
+// ConversationSummary describes a conversation the agent keeps.
+type ConversationSummary struct {
+    ID string `json:"id"`
+    Parent string `json:"parent,omitempty"`
+    Turns int `json:"turns"`
+    Created time.Time `json:"created,omitempty"`
+    Updated time.Time `json:"updated,omitempty"`
+}
+
+// ListConversations pages through the agent's conversations, newest
+// first.
+func (c *Client) ListConversations(ctx context.Context, opts ListOptions) (*Page[ConversationSummary], error) {
+    return NewPage(ctx, opts.Cursor, listPage[ConversationSummary](c, "/conversations", "conversations", opts.Limit))
+}
+
+// ListAuditEvents pages through the agent's own audit log.
+func (c *Client) ListAuditEvents(ctx context.Context, opts ListOptions) (*Page[AuditRecord], error) {
+    return NewPage(ctx, opts.Cursor, listPage[AuditRecord](c, "/audit/events", "events", opts.Limit))
+}
+
+// UsageRecord is one entry of the agent's usage history.
+type UsageRecord struct {
+    Time time.Time `json:"time"`
+    Function string `json:"function,omitempty"`
+    Actor string `json:"actor,omitempty"`
+    Counters map[string]float64 `json:"counters"`
+}
+
+// ListUsageRecords pages through the history behind the AgentUsage
+// totals.
+func (c *Client) ListUsageRecords(ctx context.Context, opts ListOptions) (*Page[UsageRecord], error) {
+    return NewPage(ctx, opts.Cursor, listPage[UsageRecord](c, "/usage/records", "records", opts.Limit))
+}
@@ -0,0 +1,259 @@
+// Package planapply runs agent actions Terraform-style: a declarative spec
+// is dry-run against the agent to build a plan, the plan is rendered for
+// review, and Apply executes it step by step with confirmation and
+// rollback.
+package planapply
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "sort"
+
+    client "echo_computer_agent_client"
+)
+
+var ErrDeclined = errors.New("planapply: step declined")
+
+// Action is one desired outcome, expressed as the chat request that
+// achieves it. Function, when set, pins the function the agent must route
+// to. Rollback is the request that undoes the action.
+type Action struct {
+    Name string `json:"name"`
+    Message string `json:"message"`
+    Inputs map[string]any `json:"inputs,omitempty"`
+    Function string `json:"function,omitempty"`
+    Rollback *Action `json:"rollback,omitempty"`
+}
+
+type Spec struct {
+    Actions []Action `json:"actions"`
+}
+
+func LoadSpec(name string) (*Spec, error) {
+    raw, err := os.ReadFile(name)
+    if err != nil {
+        return nil, err
+    }
+    var spec Spec
+    if err := json.Unmarshal(raw, &spec); err != nil {
+        return nil, fmt.Errorf("parse spec %s: %w", name, err)
+    }
+    for i, action := range spec.Actions {
+        if action.Name == "" || action.Message == "" {
+            return nil, fmt.Errorf("parse spec %s: action %d needs a name and message", name, i)
+        }
+    }
+    return &spec, nil
+}
+
+type ChangeKind string
+
+const (
+    ChangeNone ChangeKind = "no-op"
+    ChangeCreate ChangeKind = "create"
+    ChangeUpdate ChangeKind = "update"
+    ChangeDelete ChangeKind = "delete"
+)
+
+type FieldChange struct {
+    Field string `json:"field"`
+    From any `json:"from,omitempty"`
+    To any `json:"to,omitempty"`
+}
+
+// Step pairs an action with the agent's dry-run of it.
+type Step struct {
+    Action Action
+    Preview *client.ChatResponse
+    Kind ChangeKind
+    Changes []FieldChange
+}
+
+type Plan struct {
+    Steps []Step
+}
+
+// Build dry-runs every action. The agent describes pending changes in the
+// preview's Data: "change" (create/update/delete/no-op) or "changed": false
+// classify the step, and "changes" lists field-level differences.
+func Build(ctx context.Context, c *client.Client, spec *Spec) (*Plan, error) {
+    plan := &Plan{}
+    for _, action := range spec.Actions {
+        preview, err := c.Plan(ctx, request(action))
+        if err != nil {
+            return nil, fmt.Errorf("plan %s: %w", action.Name, err)
+        }
+        if action.Function != "" && preview.Function != action.Function {
+            return nil, fmt.Errorf("plan %s: agent routed to %s, spec requires %s", action.Name, preview.Function, action.Function)
+        }
+        step := Step{Action: action, Preview: preview, Kind: ChangeUpdate}
+        if kind, ok := preview.Data["change"].(string); ok && kind != "" {
+            step.Kind = ChangeKind(kind)
+        } else if changed, ok := preview.Data["changed"].(bool); ok && !changed {
+            step.Kind = ChangeNone
+        }
+        if raw, ok := preview.Data["changes"]; ok {
+            encoded, _ := json.Marshal(raw)
+            json.Unmarshal(encoded, &step.Changes)
+        }
+        plan.Steps = append(plan.Steps, step)
+    }
+    return plan, nil
+}
+
+// Pending returns the steps that would change something.
+func (p *Plan) Pending() []Step {
+    var steps []Step
+    for _, step := range p.Steps {
+        if step.Kind != ChangeNone {
+            steps = append(steps, step)
+        }
+    }
+    return steps
+}
+
+// Render writes the plan in a terraform-like layout.
+func (p *Plan) Render(w io.Writer) {
+    counts := map[ChangeKind]int{}
+    for _, step := range p.Steps {
+        counts[step.Kind]++
+        symbol := map[ChangeKind]string{ChangeCreate: "+", ChangeUpdate: "~", ChangeDelete: "-", ChangeNone: " "}[step.Kind]
+        if symbol == "" {
+            symbol = "?"
+        }
+        fmt.Fprintf(w, "  %s %s (%s via %s)\n", symbol, step.Action.Name, step.Kind, step.Preview.Function)
+        if step.Kind == ChangeNone {
+            continue
+        }
+        if step.Preview.Message != "" {
+            fmt.Fprintf(w, "      %s\n", step.Preview.Message)
+        }
+        changes := append([]FieldChange(nil), step.Changes...)
+        sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+        for _, change := range changes {
+            fmt.Fprintf(w, "      %s: %v -> %v\n", change.Field, display(change.From), display(change.To))
+        }
+    }
+    fmt.Fprintf(w, "\nPlan: %d to create, %d to change, %d to delete, %d unchanged.\n",
+        counts[ChangeCreate], counts[ChangeUpdate], counts[ChangeDelete], counts[ChangeNone])
+}
+
+type ApplyOptions struct {
+    // Confirm is asked before each pending step; nil applies without asking.
+    Confirm func(ctx context.Context, step Step) (bool, error)
+    // Rollback is called, newest first, for every applied step when a later
+    // step fails. Steps with a declarative Rollback action have it executed
+    // before the hook runs.
+    Rollback func(ctx context.Context, step Step, result *client.ChatResponse) error
+    OnApplied func(step Step, result *client.ChatResponse)
+}
+
+type StepResult struct {
+    Step Step
+    Result *client.ChatResponse
+    Skipped bool
+    Err error
+    RollbackErr error
+}
+
+type ApplyReport struct {
+    Results []StepResult
+    RolledBack bool
+}
+
+// Apply executes the plan's pending steps in order. Each step runs the
+// function its preview routed to, with the inputs the agent planned,
+// rather than being routed again, so what runs is what was reviewed. A
+// declined step stops the apply without rolling back what already ran; a
+// failed step rolls back every applied step in reverse order.
+func Apply(ctx context.Context, c *client.Client, plan *Plan, opts ApplyOptions) (*ApplyReport, error) {
+    report := &ApplyReport{}
+    for _, step := range plan.Pending() {
+        if opts.Confirm != nil {
+            ok, err := opts.Confirm(ctx, step)
+            if err != nil {
+                return report, err
+            }
+            if !ok {
+                report.Results = append(report.Results, StepResult{Step: step, Skipped: true})
+                return report, fmt.Errorf("%w: %s", ErrDeclined, step.Action.Name)
+            }
+        }
+        result, err := execute(ctx, c, step.Preview.Function, plannedInputs(step))
+        if err != nil {
+            report.Results = append(report.Results, StepResult{Step: step, Err: err})
+            rollback(ctx, c, report, opts)
+            return report, fmt.Errorf("apply %s: %w", step.Action.Name, err)
+        }
+        report.Results = append(report.Results, StepResult{Step: step, Result: result})
+        if opts.OnApplied != nil {
+            opts.OnApplied(step, result)
+        }
+    }
+    return report, nil
+}
+
+func rollback(ctx context.Context, c *client.Client, report *ApplyReport, opts ApplyOptions) {
+    report.RolledBack = true
+    for i := len(report.Results) - 1; i >= 0; i-- {
+        applied := &report.Results[i]
+        if applied.Result == nil {
+            continue
+        }
+        if undo := applied.Step.Action.Rollback; undo != nil {
+            var err error
+            if undo.Function != "" {
+                _, err = execute(ctx, c, undo.Function, undo.Inputs)
+            } else {
+                _, err = c.Chat(ctx, request(*undo).AutoExecute())
+            }
+            if err != nil {
+                applied.RollbackErr = err
+                continue
+            }
+        }
+        if opts.Rollback != nil {
+            applied.RollbackErr = opts.Rollback(ctx, applied.Step, applied.Result)
+        }
+    }
+}
+
+// execute invokes function directly, failing if the agent reports running
+// anything else.
+func execute(ctx context.Context, c *client.Client, function string, inputs map[string]any) (*client.ChatResponse, error) {
+    if function == "" {
+        return nil, errors.New("preview did not route to a function")
+    }
+    result, err := c.InvokeFunction(ctx, function, inputs)
+    if err != nil {
+        return nil, err
+    }
+    if result.Function != "" && result.Function != function {
+        return nil, fmt.Errorf("agent ran %s, plan approved %s", result.Function, function)
+    }
+    return result, nil
+}
+
+// plannedInputs are the inputs the preview says the agent would use, or
+// the action's own.
+func plannedInputs(step Step) map[string]any {
+    if inputs, ok := step.Preview.Data["inputs"].(map[string]any); ok {
+        return inputs
+    }
+    return step.Action.Inputs
+}
+
+func request(action Action) client.ChatRequest {
+    return client.ChatRequest{Message: action.Message, Inputs: action.Inputs}
+}
+
+func display(v any) any {
+    if v == nil {
+        return "(none)"
+    }
+    return v
+}
@@ -0,0 +1,193 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "path"
+)
+
+var (
+    ErrPolicyDenied = errors.New("execution denied by policy")
+    ErrConfirmationRequired = errors.New("execution requires confirmation")
+)
+
+type PolicyEffect string
+
+const (
+    PolicyAllow PolicyEffect = "allow"
+    PolicyDeny PolicyEffect = "deny"
+    PolicyRequireConfirmation PolicyEffect = "require_confirmation"
+)
+
+// PolicyRule matches when every populated selector matches. Inputs maps an
+// input field to a path.Match pattern applied to the formatted value;
+// Predicate is available for rules defined in Go.
+type PolicyRule struct {
+    Name string `json:"name,omitempty"`
+    Functions []string `json:"functions,omitempty"`
+    Roles []string `json:"roles,omitempty"`
+    Environments []string `json:"environments,omitempty"`
+    Inputs map[string]string `json:"inputs,omitempty"`
+    Effect PolicyEffect `json:"effect"`
+    Reason string `json:"reason,omitempty"`
+    Predicate func(PolicyRequest) bool `json:"-"`
+}
+
+// Policy is an ordered rule list; the first matching rule decides. Requests
+// no rule matches fall through to Default, which is allow when unset.
+type Policy struct {
+    Environment string `json:"environment,omitempty"`
+    Default PolicyEffect `json:"default,omitempty"`
+    Rules []PolicyRule `json:"rules"`
+}
+
+type PolicyRequest struct {
+    Function string
+    Inputs map[string]any
+    Roles []string
+    Environment string
+}
+
+type PolicyDecision struct {
+    Effect PolicyEffect
+    Rule string
+    Reason string
+}
+
+// Confirmer is asked to approve requests whose decision is
+// PolicyRequireConfirmation. Returning false denies the call.
+type Confirmer func(ctx context.Context, request PolicyRequest, decision PolicyDecision) (bool, error)
+
+func LoadPolicyFile(name string) (*Policy, error) {
+    raw, err := os.ReadFile(name)
+    if err != nil {
+        return nil, err
+    }
+    var policy Policy
+    if err := json.Unmarshal(raw, &policy); err != nil {
+        return nil, fmt.Errorf("parse policy %s: %w", name, err)
+    }
+    for i, rule := range policy.Rules {
+        switch rule.Effect {
+        case PolicyAllow, PolicyDeny, PolicyRequireConfirmation:
+        default:
+            return nil, fmt.Errorf("parse policy %s: rule %d has unknown effect %q", name, i, rule.Effect)
+        }
+    }
+    return &policy, nil
+}
+
+func (p *Policy) Evaluate(request PolicyRequest) PolicyDecision {
+    if request.Environment == "" {
+        request.Environment = p.Environment
+    }
+    for _, rule := range p.Rules {
+        if rule.matches(request) {
+            return PolicyDecision{Effect: rule.Effect, Rule: rule.Name, Reason: rule.Reason}
+        }
+    }
+    effect := p.Default
+    if effect == "" {
+        effect = PolicyAllow
+    }
+    return PolicyDecision{Effect: effect, Reason: "default"}
+}
+
+func (r PolicyRule) matches(request PolicyRequest) bool {
+    if len(r.Functions) > 0 && !matchAny(r.Functions, request.Function) {
+        return false
+    }
+    if len(r.Environments) > 0 && !matchAny(r.Environments, request.Environment) {
+        return false
+    }
+    if len(r.Roles) > 0 {
+        found := false
+        for _, role := range request.Roles {
+            if matchAny(r.Roles, role) {
+                found = true
+                break
+            }
+        }
+        if !found {
+            return false
+        }
+    }
+    for field, pattern := range r.Inputs {
+        value, ok := request.Inputs[field]
+        if !ok {
+            return false
+        }
+        if matched, err := path.Match(pattern, fmt.Sprint(value)); err != nil || !matched {
+            return false
+        }
+    }
+    if r.Predicate != nil && !r.Predicate(request) {
+        return false
+    }
+    return true
+}
+
+// SetPolicy installs a policy evaluated before every execute=true Chat call.
+// While a policy is attached, execution must be requested explicitly; see
+// SetDefaultExecute.
+// confirm may be nil, in which case require-confirmation decisions fail with
+// ErrConfirmationRequired.
+func (c *Client) SetPolicy(policy *Policy, confirm Confirmer) {
+    c.policy = policy
+    c.confirm = confirm
+}
+
+type rolesKey struct{}
+
+// WithRoles attaches the caller's roles to ctx for policy evaluation.
+func WithRoles(ctx context.Context, roles ...string) context.Context {
+    return context.WithValue(ctx, rolesKey{}, roles)
+}
+
+func rolesFromContext(ctx context.Context) []string {
+    roles, _ := ctx.Value(rolesKey{}).([]string)
+    if actor, ok := ActorFromContext(ctx); ok {
+        roles = append(append([]string{}, roles...), actor.Roles...)
+    }
+    return roles
+}
+
+func (c *Client) authorize(ctx context.Context, function string, request ChatRequest) error {
+    if err := c.guard.Check(function); err != nil {
+        return err
+    }
+    if err := c.checkBudget(ctx, function); err != nil {
+        return err
+    }
+    if c.policy == nil {
+        return nil
+    }
+    policyRequest := PolicyRequest{
+        Function: function,
+        Inputs: request.Inputs,
+        Roles: rolesFromContext(ctx),
+        Environment: c.policy.Environment,
+    }
+    decision := c.policy.Evaluate(policyRequest)
+    switch decision.Effect {
+    case PolicyAllow:
+        return nil
+    case PolicyRequireConfirmation:
+        if c.confirm == nil {
+            return fmt.Errorf("%w: %s (%s)", ErrConfirmationRequired, function, decision.Reason)
+        }
+        approved, err := c.confirm(ctx, policyRequest, decision)
+        if err != nil {
+            return err
+        }
+        if !approved {
+            return fmt.Errorf("%w: %s was not confirmed", ErrPolicyDenied, function)
+        }
+        return nil
+    default:
+        return fmt.Errorf("%w: %s (%s)", ErrPolicyDenied, function, decision.Reason)
+    }
+}
@@ -0,0 +1,91 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "strings"
+)
+
+// ResponseProcessor reshapes replies before callers see them, e.g. to
+// normalize Data field names across agent versions, add computed fields,
+// or strip internal metadata. It may change resp in place or return a new
+// one; an error fails the call. Processors run in the order added, after
+// the response filters, on every ChatResponse the client returns;
+// ExecuteFunction results, which are not chat replies, are only filtered.
+type ResponseProcessor interface {
+    ProcessResponse(ctx context.Context, resp *ChatResponse) (*ChatResponse, error)
+}
+
+type ResponseProcessorFunc func(ctx context.Context, resp *ChatResponse) (*ChatResponse, error)
+
+func (f ResponseProcessorFunc) ProcessResponse(ctx context.Context, resp *ChatResponse) (*ChatResponse, error) {
+    return f(ctx, resp)
+}
+
+func (c *Client) AddResponseProcessor(processors ...ResponseProcessor) {
+    c.responseProcessors = append(c.responseProcessors, processors...)
+}
+
+func WithResponseProcessor(processors ...ResponseProcessor) Option {
+    return func(c *Client) { c.AddResponseProcessor(processors...) }
+}
+
+func (c *Client) processResponse(ctx context.Context, resp *ChatResponse) error {
+    for _, processor := range c.responseProcessors {
+        processed, err := processor.ProcessResponse(ctx, resp)
+        if err != nil {
+            return err
+        }
+        if processed != nil && processed != resp {
+            *resp = *processed
+        }
+    }
+    return nil
+}
+
+// RenameDataFields moves top-level Data fields from old names to new ones,
+// e.g. {"svc_status": "status"} for an older agent. A field already
+// present under its new name is left as the agent sent it.
+func RenameDataFields(names map[string]string) ResponseProcessor {
+    return ResponseProcessorFunc(func(ctx context.Context, resp *ChatResponse) (*ChatResponse, error) {
+        for old, renamed := range names {
+            value, ok := resp.Data[old]
+            if !ok {
+                continue
+            }
+            if _, taken := resp.Data[renamed]; !taken {
+                resp.Data[renamed] = value
+            }
+            delete(resp.Data, old)
+        }
+        return resp, nil
+    })
+}
+
+// StripMetadata removes Metadata keys, and keys starting with a prefix
+// given with a trailing "*", such as "internal_*".
+func StripMetadata(keys ...string) ResponseProcessor {
+    return ResponseProcessorFunc(func(ctx context.Context, resp *ChatResponse) (*ChatResponse, error) {
+        for key := range resp.Metadata {
+            for _, pattern := range keys {
+                prefix, wildcard := strings.CutSuffix(pattern, "*")
+                if key == pattern || wildcard && strings.HasPrefix(key, prefix) {
+                    delete(resp.Metadata, key)
+                    break
+                }
+            }
+        }
+        return resp, nil
+    })
+}
+
+// ComputeData sets Data[field] to compute's result on every reply, e.g. a
+// display string derived from other fields.
+func ComputeData(field string, compute func(resp *ChatResponse) any) ResponseProcessor {
+    return ResponseProcessorFunc(func(ctx context.Context, resp *ChatResponse) (*ChatResponse, error) {
+        if resp.Data == nil {
+            resp.Data = map[string]any{}
+        }
+        resp.Data[field] = compute(resp)
+        return resp, nil
+    })
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package echo_computer_agent_client
+
+import "os/exec"
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func killProcessGroup(cmd *exec.Cmd) error {
+    return cmd.Process.Kill()
+}
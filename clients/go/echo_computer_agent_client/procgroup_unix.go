@@ -0,0 +1,18 @@
+//go:build unix
+
+package echo_computer_agent_client
+
+import (
+    "os/exec"
+    "syscall"
+)
+
+// setProcessGroup starts cmd in a group of its own, so killProcessGroup
+// reaches whatever it spawns.
+func setProcessGroup(cmd *exec.Cmd) {
+    cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func killProcessGroup(cmd *exec.Cmd) error {
+    return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
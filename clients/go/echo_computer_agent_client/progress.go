@@ -0,0 +1,64 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+)
+
+// Progress reports how far a long execution, such as a deployment, has
+// got. Percent runs from 0 to 100 and is negative when the agent cannot
+// estimate it. Logs holds the lines emitted since the previous report.
+type Progress struct {
+    Percent float64 `json:"percent"`
+    Stage string `json:"stage,omitempty"`
+    Logs []string `json:"logs,omitempty"`
+}
+
+func (*Progress) streamEvent() {}
+
+type progressKey struct{}
+
+// WithProgress asks WaitForJob and ChatStream calls made with ctx to send
+// the progress the agent reports to ch. Sends block until ch accepts them
+// or ctx is done, so ch should be buffered or drained concurrently; it is
+// never closed by the client.
+func WithProgress(ctx context.Context, ch chan<- Progress) context.Context {
+    return context.WithValue(ctx, progressKey{}, ch)
+}
+
+func reportProgress(ctx context.Context, progress Progress) {
+    ch, ok := ctx.Value(progressKey{}).(chan<- Progress)
+    if !ok || ch == nil {
+        return
+    }
+    select {
+    case ch <- progress:
+    case <-ctx.Done():
+    }
+}
+
+// progressTracker turns the cumulative progress a polled job reports into
+// reports of what changed, so log lines are delivered once.
+type progressTracker struct {
+    last *Progress
+    logs int
+}
+
+func (t *progressTracker) next(current *Progress) (Progress, bool) {
+    if current == nil {
+        return Progress{}, false
+    }
+    var fresh []string
+    if len(current.Logs) > t.logs {
+        fresh = current.Logs[t.logs:]
+    } else if len(current.Logs) < t.logs {
+        // The agent trimmed its log buffer; its lines can no longer be
+        // matched against those already sent.
+        fresh = current.Logs
+    }
+    changed := t.last == nil || current.Percent != t.last.Percent || current.Stage != t.last.Stage || len(fresh) > 0
+    t.last, t.logs = current, len(current.Logs)
+    if !changed {
+        return Progress{}, false
+    }
+    return Progress{Percent: current.Percent, Stage: current.Stage, Logs: append([]string(nil), fresh...)}, true
+}
@@ -0,0 +1,106 @@
+// Package progressbar renders the progress the agent reports for long
+// executions on a terminal.
+package progressbar
+
+import (
+    "fmt"
+    "io"
+    "os"
+    "strings"
+    "sync"
+
+    client "echo_computer_agent_client"
+)
+
+const width = 30
+
+// Bar draws progress as a single redrawn line, printing log lines above
+// it. Writers that are not terminals get a plain line per change instead,
+// so piped output stays readable.
+type Bar struct {
+    w io.Writer
+    tty bool
+
+    mu sync.Mutex
+    drawn bool
+    last client.Progress
+}
+
+func New(w io.Writer) *Bar {
+    return &Bar{w: w, tty: isTerminal(w)}
+}
+
+func isTerminal(w io.Writer) bool {
+    f, ok := w.(*os.File)
+    if !ok {
+        return false
+    }
+    info, err := f.Stat()
+    return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Run draws every report received on ch until it is closed, then finishes
+// the bar.
+func (b *Bar) Run(ch <-chan client.Progress) {
+    for progress := range ch {
+        b.Update(progress)
+    }
+    b.Finish()
+}
+
+func (b *Bar) Update(progress client.Progress) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    if b.tty {
+        b.clear()
+        for _, line := range progress.Logs {
+            fmt.Fprintln(b.w, line)
+        }
+        fmt.Fprint(b.w, render(progress))
+        b.drawn = true
+    } else {
+        for _, line := range progress.Logs {
+            fmt.Fprintln(b.w, line)
+        }
+        if progress.Percent != b.last.Percent || progress.Stage != b.last.Stage {
+            fmt.Fprintln(b.w, strings.TrimSpace(render(progress)))
+        }
+    }
+    b.last = progress
+}
+
+// Finish leaves the last drawn bar on its own line.
+func (b *Bar) Finish() {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    if b.drawn {
+        fmt.Fprintln(b.w)
+        b.drawn = false
+    }
+}
+
+func (b *Bar) clear() {
+    if b.drawn {
+        fmt.Fprint(b.w, "\r\033[K")
+    }
+}
+
+func render(progress client.Progress) string {
+    percent := progress.Percent
+    var bar, label string
+    if percent < 0 {
+        bar, label = strings.Repeat("-", width), "  ?%"
+    } else {
+        if percent > 100 {
+            percent = 100
+        }
+        filled := int(percent / 100 * width)
+        bar = strings.Repeat("#", filled) + strings.Repeat(" ", width-filled)
+        label = fmt.Sprintf("%3.0f%%", percent)
+    }
+    line := fmt.Sprintf("[%s] %s", bar, label)
+    if progress.Stage != "" {
+        line += " " + progress.Stage
+    }
+    return line
+}
@@ -0,0 +1,237 @@
+// Package prommetrics collects a client's request metrics and serves them
+// in the Prometheus text format:
+//
+//	agent := client.NewClient(baseURL, nil)
+//	metrics := prommetrics.New()
+//	agent.SetMetrics(metrics)
+//	http.Handle("/metrics", metrics)
+//
+// One Collector may be shared by several clients; set Labels to tell them
+// apart.
+package prommetrics
+
+import (
+    "bytes"
+    "io"
+    "net/http"
+    "sort"
+    "strconv"
+    "sync"
+    "time"
+
+    client "echo_computer_agent_client"
+    "echo_computer_agent_client/internal/promtext"
+)
+
+// DefaultBuckets are the latency histogram bounds, in seconds.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Collector implements client.Metrics, exposing:
+//
+//	echo_client_requests_total{method,endpoint,status}
+//	echo_client_request_errors_total{method,endpoint,status}
+//	echo_client_request_duration_seconds{method,endpoint}
+//	echo_client_requests_in_flight{method,endpoint}
+//	echo_client_tokens_total{function,type}
+//	echo_client_credits_total{function}
+//
+// status is the HTTP status, or "none" when no response arrived. type is
+// prompt, completion or total; function is "none" for replies that named
+// no function.
+type Collector struct {
+    // Labels are added to every sample, e.g. {"instance": "eu-1"}. Set
+    // them before the collector is used.
+    Labels map[string]string
+    // Buckets overrides DefaultBuckets; set it before the collector is used.
+    Buckets []float64
+
+    mu sync.Mutex
+    requests map[statusKey]uint64
+    errors map[statusKey]uint64
+    latency map[endpointKey]*histogram
+    inflight map[endpointKey]int
+    tokens map[tokenKey]float64
+    credits map[string]float64
+}
+
+type tokenKey struct {
+    function, kind string
+}
+
+type endpointKey struct {
+    method, endpoint string
+}
+
+type statusKey struct {
+    endpointKey
+    status string
+}
+
+type histogram struct {
+    counts []uint64
+    sum float64
+}
+
+func New() *Collector {
+    return &Collector{
+        requests: map[statusKey]uint64{},
+        errors: map[statusKey]uint64{},
+        latency: map[endpointKey]*histogram{},
+        inflight: map[endpointKey]int{},
+        tokens: map[tokenKey]float64{},
+        credits: map[string]float64{},
+    }
+}
+
+func (c *Collector) RequestStarted(method, endpoint string) {
+    c.mu.Lock()
+    c.inflight[endpointKey{method, endpoint}]++
+    c.mu.Unlock()
+}
+
+func (c *Collector) RequestFinished(method, endpoint string, status int, err error, latency time.Duration) {
+    key := endpointKey{method, endpoint}
+    code := "none"
+    if status > 0 {
+        code = strconv.Itoa(status)
+    }
+    bounds := c.buckets()
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.inflight[key]--
+    c.requests[statusKey{key, code}]++
+    if err != nil {
+        c.errors[statusKey{key, code}]++
+    }
+    h, ok := c.latency[key]
+    if !ok {
+        h = &histogram{counts: make([]uint64, len(bounds)+1)}
+        c.latency[key] = h
+    }
+    seconds := latency.Seconds()
+    i := sort.SearchFloat64s(bounds, seconds)
+    h.counts[i]++
+    h.sum += seconds
+}
+
+// RecordUsage implements client.UsageMetrics.
+func (c *Collector) RecordUsage(function string, usage client.Usage) {
+    if function == "" {
+        function = "none"
+    }
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.tokens[tokenKey{function, "prompt"}] += float64(usage.PromptTokens)
+    c.tokens[tokenKey{function, "completion"}] += float64(usage.CompletionTokens)
+    c.tokens[tokenKey{function, "total"}] += float64(usage.TotalTokens)
+    c.credits[function] += usage.Credits
+}
+
+func (c *Collector) buckets() []float64 {
+    if len(c.Buckets) > 0 {
+        return c.Buckets
+    }
+    return DefaultBuckets
+}
+
+// WriteTo writes the current metrics page to w.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+    var buf bytes.Buffer
+    m := promtext.NewWriter(&buf)
+    bounds := c.buckets()
+    c.mu.Lock()
+    for _, k := range sortedStatusKeys(c.requests) {
+        m.Sample("echo_client_requests_total", "counter", "Requests sent to the agent.", c.labels(k.endpointKey, k.status), float64(c.requests[k]))
+    }
+    for _, k := range sortedStatusKeys(c.errors) {
+        m.Sample("echo_client_request_errors_total", "counter", "Requests that failed or got an error status.", c.labels(k.endpointKey, k.status), float64(c.errors[k]))
+    }
+    for _, k := range sortedEndpointKeys(c.latency) {
+        h := c.latency[k]
+        m.Histogram("echo_client_request_duration_seconds", "Time from sending a request to its response headers.", c.labels(k, ""), bounds, h.counts, h.sum)
+    }
+    for _, k := range sortedEndpointKeys(c.inflight) {
+        m.Sample("echo_client_requests_in_flight", "gauge", "Requests awaiting a response.", c.labels(k, ""), float64(c.inflight[k]))
+    }
+    for _, k := range sortedTokenKeys(c.tokens) {
+        m.Sample("echo_client_tokens_total", "counter", "Tokens the agent reported using.", c.extraLabels(map[string]string{"function": k.function, "type": k.kind}), c.tokens[k])
+    }
+    for _, function := range sortedKeys(c.credits) {
+        m.Sample("echo_client_credits_total", "counter", "Credits the agent reported charging.", c.extraLabels(map[string]string{"function": function}), c.credits[function])
+    }
+    c.mu.Unlock()
+    return buf.WriteTo(w)
+}
+
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+    c.WriteTo(w)
+}
+
+func (c *Collector) labels(k endpointKey, status string) map[string]string {
+    labels := map[string]string{"method": k.method, "endpoint": k.endpoint}
+    if status != "" {
+        labels["status"] = status
+    }
+    return c.extraLabels(labels)
+}
+
+func (c *Collector) extraLabels(labels map[string]string) map[string]string {
+    for name, value := range c.Labels {
+        labels[name] = value
+    }
+    return labels
+}
+
+func sortedStatusKeys(m map[statusKey]uint64) []statusKey {
+    keys := make([]statusKey, 0, len(m))
+    for k := range m {
+        keys = append(keys, k)
+    }
+    sort.Slice(keys, func(i, j int) bool {
+        if keys[i].endpointKey != keys[j].endpointKey {
+            return lessEndpoint(keys[i].endpointKey, keys[j].endpointKey)
+        }
+        return keys[i].status < keys[j].status
+    })
+    return keys
+}
+
+func sortedEndpointKeys[V any](m map[endpointKey]V) []endpointKey {
+    keys := make([]endpointKey, 0, len(m))
+    for k := range m {
+        keys = append(keys, k)
+    }
+    sort.Slice(keys, func(i, j int) bool { return lessEndpoint(keys[i], keys[j]) })
+    return keys
+}
+
+func sortedTokenKeys(m map[tokenKey]float64) []tokenKey {
+    keys := make([]tokenKey, 0, len(m))
+    for k := range m {
+        keys = append(keys, k)
+    }
+    sort.Slice(keys, func(i, j int) bool {
+        if keys[i].function != keys[j].function {
+            return keys[i].function < keys[j].function
+        }
+        return keys[i].kind < keys[j].kind
+    })
+    return keys
+}
+
+func sortedKeys(m map[string]float64) []string {
+    keys := make([]string, 0, len(m))
+    for k := range m {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+    return keys
+}
+
+func lessEndpoint(a, b endpointKey) bool {
+    if a.endpoint != b.endpoint {
+        return a.endpoint < b.endpoint
+    }
+    return a.method < b.method
+}
@@ -0,0 +1,131 @@
+// Package proxy re-exposes a restricted slice of an agent on a local port.
+package proxy
+
+import (
+    "crypto/subtle"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "strings"
+
+    client "echo_computer_agent_client"
+)
+
+// Server serves /functions and /chat with the agent's wire format, but only
+// for functions the Guard and Policy allow outright; functions that would
+// need confirmation are not exposed because proxy callers cannot confirm.
+// Every request must carry "Authorization: Bearer <Token>"; a Server without
+// a Token refuses all requests unless Insecure is set.
+type Server struct {
+    Client *client.Client
+    Guard *client.FunctionGuard
+    Policy *client.Policy
+    Token string
+    Insecure bool
+}
+
+// ErrNoToken is returned by New for an empty token. Callers that really
+// mean to serve unauthenticated requests build the Server with Insecure.
+var ErrNoToken = errors.New("proxy: no token configured")
+
+func New(c *client.Client, guard *client.FunctionGuard, policy *client.Policy, token string) (*Server, error) {
+    if token == "" {
+        return nil, ErrNoToken
+    }
+    return &Server{Client: c, Guard: guard, Policy: policy, Token: token}, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    if !s.authorized(r) {
+        w.Header().Set("WWW-Authenticate", `Bearer realm="echo-agent-proxy"`)
+        writeError(w, http.StatusUnauthorized, "unauthorized")
+        return
+    }
+    switch {
+    case r.URL.Path == "/functions" && r.Method == http.MethodGet:
+        s.listFunctions(w, r)
+    case r.URL.Path == "/chat" && r.Method == http.MethodPost:
+        s.chat(w, r)
+    default:
+        writeError(w, http.StatusNotFound, "not found")
+    }
+}
+
+func (s *Server) listFunctions(w http.ResponseWriter, r *http.Request) {
+    catalog, err := s.Client.ListFunctions(r.Context())
+    if err != nil {
+        writeError(w, http.StatusBadGateway, err.Error())
+        return
+    }
+    allowed := client.FunctionListResponse{Functions: []client.FunctionDescription{}}
+    for _, fn := range catalog.Functions {
+        if s.allows(fn.Name, nil) {
+            allowed.Functions = append(allowed.Functions, fn)
+        }
+    }
+    writeJSON(w, http.StatusOK, allowed)
+}
+
+func (s *Server) chat(w http.ResponseWriter, r *http.Request) {
+    var request client.ChatRequest
+    if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&request); err != nil {
+        writeError(w, http.StatusBadRequest, "invalid chat request")
+        return
+    }
+    // Proxy callers are untrusted: inputs naming secrets are dropped, not
+    // resolved into the function call.
+    request.Inputs = s.Client.StripSecretRefs(request.Inputs)
+    plan, err := s.Client.Plan(r.Context(), request)
+    if err != nil {
+        writeError(w, http.StatusBadGateway, err.Error())
+        return
+    }
+    if !s.allows(plan.Function, request.Inputs) {
+        writeError(w, http.StatusForbidden, "function "+plan.Function+" is not exposed by this proxy")
+        return
+    }
+    if request.Execute == nil || !*request.Execute {
+        writeJSON(w, http.StatusOK, plan)
+        return
+    }
+    // Executing the checked function directly, rather than chatting again,
+    // keeps the agent from routing elsewhere once the plan is approved.
+    resp, err := s.Client.InvokeFunction(r.Context(), plan.Function, request.Inputs)
+    if err != nil {
+        writeError(w, http.StatusBadGateway, err.Error())
+        return
+    }
+    writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) allows(function string, inputs map[string]any) bool {
+    if function == "" {
+        return false
+    }
+    if err := s.Guard.Check(function); err != nil {
+        return false
+    }
+    if s.Policy == nil {
+        return true
+    }
+    decision := s.Policy.Evaluate(client.PolicyRequest{Function: function, Inputs: inputs})
+    return decision.Effect == client.PolicyAllow
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+    if s.Token == "" {
+        return s.Insecure
+    }
+    token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+    return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) == 1
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload any) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(payload)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+    writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,145 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "strconv"
+    "sync"
+    "time"
+)
+
+// ErrRateLimitExhausted is returned by a fail-fast client whose request
+// budget, its own or the agent's, is used up.
+var ErrRateLimitExhausted = errors.New("request rate budget exhausted")
+
+// RateLimitState is the agent's view of the client's budget from the
+// X-RateLimit-* headers of its latest response, alongside the client's
+// own limit.
+type RateLimitState struct {
+    Known bool `json:"known"`
+    Limit int `json:"limit,omitempty"`
+    Remaining int `json:"remaining,omitempty"`
+    Reset time.Time `json:"reset,omitempty"`
+    Updated time.Time `json:"updated,omitempty"`
+    ClientRate float64 `json:"client_rate,omitempty"`
+    ClientBurst int `json:"client_burst,omitempty"`
+}
+
+// rateLimitState is the client's token bucket and the agent's last
+// reported budget.
+type rateLimitState struct {
+    sync.Mutex
+    bucket *tokenBucket
+    burst int
+    failFast bool
+    agent RateLimitState
+}
+
+// SetRateLimit caps the client at rps requests a second with bursts of up
+// to burst. Every request counts, retries included. A non-positive rps
+// removes the limit.
+func (c *Client) SetRateLimit(rps float64, burst int) {
+    c.rateLimit.Lock()
+    defer c.rateLimit.Unlock()
+    if rps <= 0 {
+        c.rateLimit.bucket, c.rateLimit.burst = nil, 0
+        return
+    }
+    c.rateLimit.bucket, c.rateLimit.burst = newTokenBucket(rps, burst), burst
+}
+
+// SetRateLimitFailFast makes requests over budget fail with
+// ErrRateLimitExhausted instead of waiting for it to refill. The budget
+// is the client's rate limit and, once the agent reports X-RateLimit-
+// Remaining of zero, the agent's until its X-RateLimit-Reset.
+func (c *Client) SetRateLimitFailFast(failFast bool) {
+    c.rateLimit.Lock()
+    c.rateLimit.failFast = failFast
+    c.rateLimit.Unlock()
+}
+
+func WithRateLimit(rps float64, burst int) Option {
+    return func(c *Client) { c.SetRateLimit(rps, burst) }
+}
+
+func WithRateLimitFailFast() Option {
+    return func(c *Client) { c.SetRateLimitFailFast(true) }
+}
+
+// RateLimitState returns the agent's last reported budget and the
+// client's own limit.
+func (c *Client) RateLimitState() RateLimitState {
+    c.rateLimit.Lock()
+    defer c.rateLimit.Unlock()
+    state := c.rateLimit.agent
+    if c.rateLimit.bucket != nil {
+        state.ClientRate, state.ClientBurst = c.rateLimit.bucket.rate, c.rateLimit.burst
+    }
+    return state
+}
+
+// awaitRateLimit holds a request until the budget allows it, or fails it
+// when the client fails fast.
+func (c *Client) awaitRateLimit(ctx context.Context) error {
+    c.rateLimit.Lock()
+    bucket, failFast := c.rateLimit.bucket, c.rateLimit.failFast
+    var agentWait time.Duration
+    if agent := c.rateLimit.agent; agent.Known && agent.Remaining <= 0 {
+        agentWait = time.Until(agent.Reset)
+    }
+    c.rateLimit.Unlock()
+    if agentWait > 0 {
+        if failFast {
+            return fmt.Errorf("%w: agent budget resets in %s", ErrRateLimitExhausted, agentWait.Round(time.Second))
+        }
+        if err := sleepCtx(ctx, agentWait); err != nil {
+            return err
+        }
+    }
+    if bucket == nil {
+        return nil
+    }
+    if !failFast {
+        return bucket.wait(ctx)
+    }
+    if ok, wait := bucket.take(); !ok {
+        return fmt.Errorf("%w: next request allowed in %s", ErrRateLimitExhausted, wait.Round(time.Millisecond))
+    }
+    return nil
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+    timer := time.NewTimer(d)
+    defer timer.Stop()
+    select {
+    case <-ctx.Done():
+        return ctx.Err()
+    case <-timer.C:
+        return nil
+    }
+}
+
+// noteRateLimit records the agent's X-RateLimit-Limit, -Remaining and
+// -Reset headers. Reset is read as seconds from now, or as a Unix time
+// when it is too large to be a delay.
+func (c *Client) noteRateLimit(header http.Header) {
+    remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+    if err != nil {
+        return
+    }
+    now := time.Now()
+    state := RateLimitState{Known: true, Remaining: remaining, Updated: now}
+    state.Limit, _ = strconv.Atoi(header.Get("X-RateLimit-Limit"))
+    if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+        if reset > 1e9 {
+            state.Reset = time.Unix(reset, 0)
+        } else {
+            state.Reset = now.Add(time.Duration(reset) * time.Second)
+        }
+    }
+    c.rateLimit.Lock()
+    c.rateLimit.agent = state
+    c.rateLimit.Unlock()
+}
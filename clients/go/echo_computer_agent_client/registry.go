@@ -0,0 +1,179 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "strings"
+    "sync"
+    "time"
+)
+
+// FunctionRegistry is a local copy of the agent's catalog for applications
+// that look functions up on every request. It lists the catalog at most
+// once per TTL, and refreshes conditionally, so an agent that serves ETag
+// or Last-Modified answers an unchanged catalog with a 304 rather than
+// sending it again.
+type FunctionRegistry struct {
+    client *Client
+    ttl time.Duration
+    mu sync.Mutex
+    functions Functions
+    validators validators
+    fetched time.Time
+}
+
+// NewFunctionRegistry caches c's catalog for ttl; zero means CatalogTTL.
+func NewFunctionRegistry(c *Client, ttl time.Duration) *FunctionRegistry {
+    if ttl <= 0 {
+        ttl = CatalogTTL
+    }
+    return &FunctionRegistry{client: c, ttl: ttl}
+}
+
+// Functions returns the catalog, refreshing it first once it is older
+// than the TTL.
+func (r *FunctionRegistry) Functions(ctx context.Context) (Functions, error) {
+    r.mu.Lock()
+    functions, fresh := r.functions, r.functions != nil && time.Since(r.fetched) < r.ttl
+    r.mu.Unlock()
+    if fresh {
+        return functions, nil
+    }
+    if _, err := r.Refresh(ctx); err != nil {
+        return nil, err
+    }
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    return r.functions, nil
+}
+
+// Refresh lists the catalog now, whatever its age, and reports what
+// changed since the last listing.
+func (r *FunctionRegistry) Refresh(ctx context.Context) (CatalogDiff, error) {
+    r.mu.Lock()
+    since := r.validators
+    if r.functions == nil {
+        since = validators{}
+    }
+    r.mu.Unlock()
+    list, received, unchanged, err := r.client.listFunctionsSince(ctx, since)
+    if err != nil {
+        return CatalogDiff{}, err
+    }
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    r.fetched, r.validators = time.Now(), received
+    if unchanged {
+        return CatalogDiff{}, nil
+    }
+    next := NewFunctions(list.Functions)
+    diff := DiffCatalogs(r.functions, next)
+    r.functions = next
+    return diff, nil
+}
+
+func (r *FunctionRegistry) Lookup(ctx context.Context, name string) (FunctionDescription, bool, error) {
+    functions, err := r.Functions(ctx)
+    if err != nil {
+        return FunctionDescription{}, false, err
+    }
+    fn, ok := functions.ByName(name)
+    return fn, ok, nil
+}
+
+// WithTags returns the functions tagged with every one of tags.
+func (r *FunctionRegistry) WithTags(ctx context.Context, tags ...string) (Functions, error) {
+    functions, err := r.Functions(ctx)
+    if err != nil {
+        return nil, err
+    }
+    var matched Functions
+    for _, fn := range functions {
+        have := map[string]bool{}
+        for _, tag := range Tags(fn) {
+            have[strings.ToLower(tag)] = true
+        }
+        all := true
+        for _, tag := range tags {
+            if !have[strings.ToLower(tag)] {
+                all = false
+                break
+            }
+        }
+        if all {
+            matched = append(matched, fn)
+        }
+    }
+    return matched, nil
+}
+
+type FunctionEventType string
+
+const (
+    FunctionAdded FunctionEventType = "added"
+    FunctionRemoved FunctionEventType = "removed"
+    FunctionChanged FunctionEventType = "changed"
+)
+
+// FunctionEvent is one change Watch saw. Previous is set for changed
+// functions.
+type FunctionEvent struct {
+    Type FunctionEventType
+    Function FunctionDescription
+    Previous *FunctionDescription
+}
+
+// Watch refreshes the registry every interval, the TTL when zero, and
+// sends an event for each function added, removed or changed. The channel
+// is closed once ctx is done. Failed refreshes are retried on the next
+// tick; a slow receiver delays the next refresh rather than losing events.
+func (r *FunctionRegistry) Watch(ctx context.Context, interval time.Duration) <-chan FunctionEvent {
+    if interval <= 0 {
+        interval = r.ttl
+    }
+    events := make(chan FunctionEvent, 16)
+    go func() {
+        defer close(events)
+        send := func(event FunctionEvent) bool {
+            select {
+            case events <- event:
+                return true
+            case <-ctx.Done():
+                return false
+            }
+        }
+        // The first listing is the baseline, not a series of additions.
+        if _, err := r.Functions(ctx); err != nil && ctx.Err() != nil {
+            return
+        }
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+        for {
+            select {
+            case <-ctx.Done():
+                return
+            case <-ticker.C:
+            }
+            diff, err := r.Refresh(ctx)
+            if err != nil {
+                continue
+            }
+            for _, fn := range diff.Added {
+                if !send(FunctionEvent{Type: FunctionAdded, Function: fn}) {
+                    return
+                }
+            }
+            for _, fn := range diff.Removed {
+                if !send(FunctionEvent{Type: FunctionRemoved, Function: fn}) {
+                    return
+                }
+            }
+            for _, change := range diff.Changed {
+                previous := change.Old
+                if !send(FunctionEvent{Type: FunctionChanged, Function: change.New, Previous: &previous}) {
+                    return
+                }
+            }
+        }
+    }()
+    return events
+}
@@ -0,0 +1,428 @@
+// Package relay turns inbound third-party webhooks into agent calls. Each
+// relay verifies the sender, renders the payload into a function
+// invocation or chat request through "{source.path}" templates, and can
+// post the result back to the sender's API.
+package relay
+
+import (
+    "bytes"
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "log"
+    "net/http"
+    "os"
+    "strings"
+    "sync"
+    "time"
+
+    client "echo_computer_agent_client"
+    "echo_computer_agent_client/internal/reftemplate"
+    "echo_computer_agent_client/webhooks"
+)
+
+// Verification schemes.
+const (
+    VerifyNone = ""
+    VerifyGitHub = "github"
+    VerifyStripe = "stripe"
+    VerifyEcho = "echo"
+)
+
+var ErrInvalidSignature = errors.New("relay: invalid signature")
+
+// seenTTL is how long a delivery ID is remembered, so a sender's retries of
+// a delivery that was already handled are not run again.
+const seenTTL = 24 * time.Hour
+
+// Relay describes one inbound endpoint, served at /hooks/{name}.
+//
+// Inputs, Message, When keys, and Reply fields are templates over the
+// sources body, header, and query; Reply additionally sees response (the
+// agent's ChatResponse as JSON, e.g. {response.data.summary}) and env.
+// Events filters on the sender's event type: the X-GitHub-Event header for
+// GitHub, the payload's "type" field otherwise. When requires each
+// rendered key to equal its value; deliveries that do not match are
+// acknowledged and skipped. Relays that execute, by naming a Function or
+// setting Execute, must set Verify.
+type Relay struct {
+    Verify string `json:"verify,omitempty"`
+    Secret string `json:"secret,omitempty"`
+    SecretEnv string `json:"secret_env,omitempty"`
+    Events []string `json:"events,omitempty"`
+    When map[string]string `json:"when,omitempty"`
+    Function string `json:"function,omitempty"`
+    Message string `json:"message,omitempty"`
+    Execute bool `json:"execute,omitempty"`
+    Inputs map[string]string `json:"inputs,omitempty"`
+    Reply *Reply `json:"reply,omitempty"`
+    Async bool `json:"async,omitempty"`
+}
+
+// Reply posts the agent's result somewhere, typically a comment or status
+// API on the service that sent the webhook.
+type Reply struct {
+    Method string `json:"method,omitempty"`
+    URL string `json:"url"`
+    Headers map[string]string `json:"headers,omitempty"`
+    Body map[string]string `json:"body,omitempty"`
+}
+
+type Config struct {
+    Relays map[string]Relay `json:"relays"`
+}
+
+func LoadConfig(path string) (*Config, error) {
+    raw, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    var config Config
+    if err := json.Unmarshal(raw, &config); err != nil {
+        return nil, fmt.Errorf("relay: %s: %w", path, err)
+    }
+    for name, relay := range config.Relays {
+        if (relay.Function == "") == (relay.Message == "") {
+            return nil, fmt.Errorf("relay: %s: exactly one of function and message is required", name)
+        }
+        switch relay.Verify {
+        case VerifyNone:
+            // Anyone who can reach the endpoint could run the function.
+            if relay.Function != "" || relay.Execute {
+                return nil, fmt.Errorf("relay: %s: an executing relay must verify its sender", name)
+            }
+        case VerifyGitHub, VerifyStripe, VerifyEcho:
+            if relay.Secret == "" && relay.SecretEnv == "" {
+                return nil, fmt.Errorf("relay: %s: verify %s needs a secret or secret_env", name, relay.Verify)
+            }
+        default:
+            return nil, fmt.Errorf("relay: %s: unknown verification scheme %q", name, relay.Verify)
+        }
+    }
+    return &config, nil
+}
+
+// Server serves every configured relay under /hooks/.
+type Server struct {
+    Client *client.Client
+    Config *Config
+    HTTPClient *http.Client
+    MaxBodyBytes int64
+    Tolerance time.Duration
+    mu sync.RWMutex
+    async sync.WaitGroup
+    seenMu sync.Mutex
+    seen map[string]time.Time
+}
+
+func NewServer(c *client.Client, config *Config) *Server {
+    return &Server{Client: c, Config: config, HTTPClient: &http.Client{Timeout: 30 * time.Second}, MaxBodyBytes: 1 << 20, Tolerance: 5 * time.Minute}
+}
+
+// SetConfig replaces the relays while the server is running, e.g. on
+// reload. Deliveries already accepted finish under the old config.
+func (s *Server) SetConfig(config *Config) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.Config = config
+}
+
+func (s *Server) config() *Config {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    return s.Config
+}
+
+// Drain waits for async deliveries to finish, or for ctx to be done.
+// Synchronous ones are waited for by http.Server.Shutdown.
+func (s *Server) Drain(ctx context.Context) error {
+    done := make(chan struct{})
+    go func() {
+        s.async.Wait()
+        close(done)
+    }()
+    select {
+    case <-done:
+        return nil
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    name, ok := strings.CutPrefix(r.URL.Path, "/hooks/")
+    relay, found := s.config().Relays[name]
+    if !ok || !found {
+        writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown relay"})
+        return
+    }
+    if r.Method != http.MethodPost {
+        writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "POST required"})
+        return
+    }
+    raw, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.MaxBodyBytes))
+    if err != nil {
+        writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": "body too large"})
+        return
+    }
+    if err := s.verify(relay, r, raw); err != nil {
+        writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+        return
+    }
+    var body any
+    if len(bytes.TrimSpace(raw)) > 0 {
+        if err := json.Unmarshal(raw, &body); err != nil {
+            writeJSON(w, http.StatusBadRequest, map[string]string{"error": "body must be JSON"})
+            return
+        }
+    }
+    sources := map[string]reftemplate.Source{
+        "body": reftemplate.Map(body),
+        "query": func(key string) (any, bool) { v := r.URL.Query(); return v.Get(key), v.Has(key) },
+        "header": func(key string) (any, bool) { v := r.Header.Get(key); return v, v != "" },
+    }
+    if reason, matched := match(relay, r, body, sources); !matched {
+        writeJSON(w, http.StatusAccepted, map[string]string{"skipped": reason})
+        return
+    }
+    delivery := deliveryID(relay, r, body)
+    if delivery != "" {
+        delivery = name + ":" + delivery
+        if !s.firstDelivery(delivery) {
+            writeJSON(w, http.StatusAccepted, map[string]string{"skipped": "duplicate delivery"})
+            return
+        }
+    }
+
+    if relay.Async {
+        s.async.Add(1)
+        go func() {
+            defer s.async.Done()
+            if _, err := s.dispatch(context.WithoutCancel(r.Context()), name, relay, sources); err != nil {
+                log.Printf("relay %s: %v", name, err)
+            }
+        }()
+        writeJSON(w, http.StatusAccepted, map[string]string{"status": "accepted"})
+        return
+    }
+    resp, err := s.dispatch(r.Context(), name, relay, sources)
+    if err != nil {
+        // The sender retries failed deliveries under the same ID.
+        s.forget(delivery)
+        writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+        return
+    }
+    writeJSON(w, http.StatusOK, resp)
+}
+
+// dispatch runs the agent call and, when configured, the reply.
+func (s *Server) dispatch(ctx context.Context, name string, relay Relay, sources map[string]reftemplate.Source) (*client.ChatResponse, error) {
+    inputs := make(map[string]any, len(relay.Inputs))
+    for key, template := range relay.Inputs {
+        value, err := reftemplate.Render(template, sources)
+        if err != nil {
+            return nil, fmt.Errorf("input %s: %w", key, err)
+        }
+        inputs[key] = value
+    }
+    // Webhook payloads come from outside: a field holding a secret ref must
+    // not be resolved, or the secret could be posted back through Reply.
+    inputs = s.Client.StripSecretRefs(inputs)
+    var resp *client.ChatResponse
+    var err error
+    if relay.Function != "" {
+        resp, err = s.Client.InvokeFunction(ctx, relay.Function, inputs)
+    } else {
+        var message any
+        if message, err = reftemplate.Render(relay.Message, sources); err != nil {
+            return nil, fmt.Errorf("message: %w", err)
+        }
+        resp, err = s.Client.Chat(ctx, client.ChatRequest{Message: fmt.Sprint(message), Inputs: inputs, Execute: &relay.Execute})
+    }
+    if err != nil {
+        return nil, err
+    }
+    if relay.Reply != nil {
+        if err := s.reply(ctx, relay.Reply, resp, sources); err != nil {
+            return resp, fmt.Errorf("reply: %w", err)
+        }
+    }
+    return resp, nil
+}
+
+func (s *Server) reply(ctx context.Context, reply *Reply, resp *client.ChatResponse, inbound map[string]reftemplate.Source) error {
+    var response any
+    raw, _ := json.Marshal(resp)
+    json.Unmarshal(raw, &response)
+    sources := map[string]reftemplate.Source{
+        "response": reftemplate.Map(response),
+        "env": func(key string) (any, bool) { return os.LookupEnv(key) },
+    }
+    for name, source := range inbound {
+        sources[name] = source
+    }
+    renderString := func(template string) (string, error) {
+        value, err := reftemplate.Render(template, sources)
+        return fmt.Sprint(value), err
+    }
+
+    target, err := renderString(reply.URL)
+    if err != nil {
+        return err
+    }
+    payload := make(map[string]any, len(reply.Body))
+    for key, template := range reply.Body {
+        if payload[key], err = reftemplate.Render(template, sources); err != nil {
+            return fmt.Errorf("body %s: %w", key, err)
+        }
+    }
+    encoded, err := json.Marshal(payload)
+    if err != nil {
+        return err
+    }
+    method := reply.Method
+    if method == "" {
+        method = http.MethodPost
+    }
+    req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(encoded))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    for key, template := range reply.Headers {
+        value, err := renderString(template)
+        if err != nil {
+            return fmt.Errorf("header %s: %w", key, err)
+        }
+        req.Header.Set(key, value)
+    }
+    httpResp, err := s.HTTPClient.Do(req)
+    if err != nil {
+        return err
+    }
+    defer httpResp.Body.Close()
+    io.Copy(io.Discard, httpResp.Body)
+    if httpResp.StatusCode >= 400 {
+        return fmt.Errorf("%s %s failed with status %d", method, target, httpResp.StatusCode)
+    }
+    return nil
+}
+
+func (s *Server) verify(relay Relay, r *http.Request, body []byte) error {
+    secret := relay.Secret
+    if relay.SecretEnv != "" {
+        secret = os.Getenv(relay.SecretEnv)
+    }
+    switch relay.Verify {
+    case VerifyNone:
+        return nil
+    case VerifyGitHub:
+        signature, ok := strings.CutPrefix(r.Header.Get("X-Hub-Signature-256"), "sha256=")
+        if !ok || secret == "" {
+            return ErrInvalidSignature
+        }
+        mac := hmac.New(sha256.New, []byte(secret))
+        mac.Write(body)
+        if !hmac.Equal([]byte(signature), []byte(hex.EncodeToString(mac.Sum(nil)))) {
+            return ErrInvalidSignature
+        }
+        return nil
+    case VerifyStripe, VerifyEcho:
+        // Stripe signs "t.body" with the same t=/v1= header layout as the
+        // agent's own webhooks.
+        header := r.Header.Get("Stripe-Signature")
+        if relay.Verify == VerifyEcho {
+            header = r.Header.Get(webhooks.HeaderSignature)
+        }
+        if secret == "" {
+            return ErrInvalidSignature
+        }
+        if err := webhooks.Verify([]byte(secret), header, body, time.Now(), s.Tolerance); err != nil {
+            return ErrInvalidSignature
+        }
+        return nil
+    }
+    return fmt.Errorf("relay: unknown verification scheme %q", relay.Verify)
+}
+
+// deliveryID is the sender's ID for a delivery, which it keeps across
+// retries, or "" when the sender has none.
+func deliveryID(relay Relay, r *http.Request, body any) string {
+    switch relay.Verify {
+    case VerifyGitHub:
+        return r.Header.Get("X-GitHub-Delivery")
+    case VerifyEcho:
+        return r.Header.Get(webhooks.HeaderDelivery)
+    case VerifyStripe:
+        value, _ := reftemplate.Lookup(body, "id")
+        id, _ := value.(string)
+        return id
+    }
+    return ""
+}
+
+func (s *Server) firstDelivery(key string) bool {
+    now := time.Now()
+    s.seenMu.Lock()
+    defer s.seenMu.Unlock()
+    if s.seen == nil {
+        s.seen = map[string]time.Time{}
+    }
+    if _, ok := s.seen[key]; ok {
+        return false
+    }
+    for k, at := range s.seen {
+        if now.Sub(at) > seenTTL {
+            delete(s.seen, k)
+        }
+    }
+    s.seen[key] = now
+    return true
+}
+
+func (s *Server) forget(key string) {
+    if key == "" {
+        return
+    }
+    s.seenMu.Lock()
+    defer s.seenMu.Unlock()
+    delete(s.seen, key)
+}
+
+func match(relay Relay, r *http.Request, body any, sources map[string]reftemplate.Source) (string, bool) {
+    if len(relay.Events) > 0 {
+        event := r.Header.Get("X-GitHub-Event")
+        if relay.Verify != VerifyGitHub {
+            value, _ := reftemplate.Lookup(body, "type")
+            event, _ = value.(string)
+        }
+        found := false
+        for _, want := range relay.Events {
+            if want == event {
+                found = true
+                break
+            }
+        }
+        if !found {
+            return "event " + event + " not relayed", false
+        }
+    }
+    for template, want := range relay.When {
+        value, err := reftemplate.Render(template, sources)
+        if err != nil || fmt.Sprint(value) != want {
+            return template + " does not match", false
+        }
+    }
+    return "", true
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload any) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(payload)
+}
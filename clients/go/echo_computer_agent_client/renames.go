@@ -0,0 +1,143 @@
+package echo_computer_agent_client
+
+import (
+    "encoding/json"
+    "io"
+    "strconv"
+    "strings"
+)
+
+// fieldRename records a wire field the agent renamed in version since.
+// The client's types keep the old name.
+type fieldRename struct {
+    scope string
+    old string
+    new string
+    since string
+}
+
+const (
+    scopeChat = "chat"
+    scopeFunction = "function"
+)
+
+var fieldRenames = []fieldRename{
+    {scope: scopeChat, old: "function", new: "function_name", since: APIVersion2},
+    {scope: scopeFunction, old: "parameters", new: "input_schema", since: APIVersion2},
+}
+
+// scopedObjects finds the objects of each scope in a request or response
+// body for path.
+func scopedObjects(path string, body map[string]any) map[string][]map[string]any {
+    path, _, _ = strings.Cut(path, "?")
+    objects := map[string][]map[string]any{}
+    switch {
+    case path == "/chat" || path == "/chat/stream" || strings.HasSuffix(path, "/invoke") || strings.HasSuffix(path, "/execute"):
+        objects[scopeChat] = append(objects[scopeChat], body)
+    case strings.HasSuffix(path, "/batch"):
+        results, _ := body["results"].([]any)
+        for _, result := range results {
+            if object, ok := result.(map[string]any); ok {
+                objects[scopeChat] = append(objects[scopeChat], object)
+            }
+        }
+    case strings.HasPrefix(path, "/jobs"):
+        if result, ok := body["result"].(map[string]any); ok {
+            objects[scopeChat] = append(objects[scopeChat], result)
+        }
+    case path == "/functions":
+        if _, ok := body["name"]; ok {
+            objects[scopeFunction] = append(objects[scopeFunction], body)
+        }
+        functions, _ := body["functions"].([]any)
+        for _, fn := range functions {
+            if object, ok := fn.(map[string]any); ok {
+                objects[scopeFunction] = append(objects[scopeFunction], object)
+            }
+        }
+    }
+    return objects
+}
+
+// normalize maps renamed fields back to the client's names. Both spellings
+// are accepted whatever the version so mixed fleets keep working during a
+// rolling upgrade; the version only decides which spelling wins when a
+// body carries both.
+func normalize(version, path string, body map[string]any) {
+    objects := scopedObjects(path, body)
+    for _, rename := range fieldRenames {
+        preferNew := versionAtLeast(version, rename.since)
+        for _, object := range objects[rename.scope] {
+            value, hasNew := object[rename.new]
+            if !hasNew {
+                continue
+            }
+            if _, hasOld := object[rename.old]; preferNew || !hasOld {
+                object[rename.old] = value
+            }
+            delete(object, rename.new)
+        }
+    }
+}
+
+// denormalize rewrites a request body for an agent known to speak version.
+func denormalize(version, path string, body map[string]any) bool {
+    changed := false
+    objects := scopedObjects(path, body)
+    for _, rename := range fieldRenames {
+        if !versionAtLeast(version, rename.since) {
+            continue
+        }
+        for _, object := range objects[rename.scope] {
+            if value, ok := object[rename.old]; ok {
+                object[rename.new] = value
+                delete(object, rename.old)
+                changed = true
+            }
+        }
+    }
+    return changed
+}
+
+func versionAtLeast(version, since string) bool {
+    v, err := strconv.Atoi(version)
+    if err != nil {
+        return false
+    }
+    s, err := strconv.Atoi(since)
+    return err == nil && v >= s
+}
+
+// encodeVersioned marshals in, renaming fields only when an earlier
+// response showed the agent speaks a version that expects the new names;
+// the requested version alone proves nothing about an older agent.
+func (c *Client) encodeVersioned(path string, in any) ([]byte, error) {
+    encoded, err := json.Marshal(in)
+    version := c.NegotiatedAPIVersion()
+    if err != nil || !versionAtLeast(version, APIVersion2) {
+        return encoded, err
+    }
+    var body map[string]any
+    if json.Unmarshal(encoded, &body) != nil || !denormalize(version, path, body) {
+        return encoded, nil
+    }
+    return json.Marshal(body)
+}
+
+// decodeVersioned decodes r into out after normalizing renamed fields.
+func decodeVersioned(r io.Reader, version, path string, out any) error {
+    raw, err := io.ReadAll(r)
+    if err != nil {
+        return err
+    }
+    var body map[string]any
+    if json.Unmarshal(raw, &body) != nil || len(scopedObjects(path, body)) == 0 {
+        return json.Unmarshal(raw, out)
+    }
+    normalize(version, path, body)
+    raw, err = json.Marshal(body)
+    if err != nil {
+        return err
+    }
+    return json.Unmarshal(raw, out)
+}
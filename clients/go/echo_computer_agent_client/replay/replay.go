@@ -0,0 +1,221 @@
+// Package replay records chat sessions as scripts and re-executes them
+// against an agent, turning exploratory sessions into regression checks.
+package replay
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+
+    client "echo_computer_agent_client"
+    "echo_computer_agent_client/internal/yamlite"
+)
+
+// Script is a recorded session. Step messages and string inputs may
+// reference Vars as "{vars.name}", so one script can be replayed against
+// other environments by overriding them.
+type Script struct {
+    Name string `json:"name,omitempty"`
+    Vars map[string]any `json:"vars,omitempty"`
+    Steps []Step `json:"steps"`
+}
+
+// Step is one request of a script and the function it is expected to be
+// routed to; ExpectFunction is not checked when empty.
+type Step struct {
+    Message string `json:"message"`
+    Inputs map[string]any `json:"inputs,omitempty"`
+    Execute *bool `json:"execute,omitempty"`
+    ExpectFunction string `json:"expect_function,omitempty"`
+}
+
+// Load reads a script from a YAML or JSON file.
+func Load(path string) (*Script, error) {
+    raw, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    var script Script
+    if err := yamlite.Unmarshal(raw, &script); err != nil {
+        return nil, fmt.Errorf("%s: %w", path, err)
+    }
+    for i, step := range script.Steps {
+        if step.Message == "" {
+            return nil, fmt.Errorf("%s: step %d: message is required", path, i+1)
+        }
+    }
+    return &script, nil
+}
+
+// Save writes s as YAML.
+func (s *Script) Save(path string) error {
+    encoded, err := yamlite.Marshal(s)
+    if err != nil {
+        return err
+    }
+    return os.WriteFile(path, encoded, 0o644)
+}
+
+// Recorder builds a script from the requests of a session. Values listed
+// in Placeholders (literal text to variable name), such as a hostname or
+// an environment, are replaced by references to the variable wherever they
+// appear in messages and string inputs, and their recorded value becomes
+// the variable's default.
+type Recorder struct {
+    Placeholders map[string]string
+
+    mu sync.Mutex
+    script Script
+}
+
+func NewRecorder(name string) *Recorder {
+    return &Recorder{script: Script{Name: name}}
+}
+
+// Record appends request as a step expecting the function resp was routed
+// to. Failed calls are recorded without an expectation.
+func (r *Recorder) Record(request client.ChatRequest, resp *client.ChatResponse) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    step := Step{Message: r.parameterize(request.Message), Execute: request.Execute}
+    if len(request.Inputs) > 0 {
+        step.Inputs = make(map[string]any, len(request.Inputs))
+        for key, value := range request.Inputs {
+            if text, ok := value.(string); ok {
+                value = r.parameterize(text)
+            }
+            step.Inputs[key] = value
+        }
+    }
+    if resp != nil {
+        step.ExpectFunction = resp.Function
+    }
+    r.script.Steps = append(r.script.Steps, step)
+}
+
+// Chat sends request with c and records it.
+func (r *Recorder) Chat(ctx context.Context, c *client.Client, request client.ChatRequest) (*client.ChatResponse, error) {
+    resp, err := c.Chat(ctx, request)
+    r.Record(request, resp)
+    return resp, err
+}
+
+// parameterize replaces placeholder values in text, longest first so a
+// value containing another is replaced whole.
+func (r *Recorder) parameterize(text string) string {
+    values := make([]string, 0, len(r.Placeholders))
+    for value := range r.Placeholders {
+        if value != "" {
+            values = append(values, value)
+        }
+    }
+    sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+    for _, value := range values {
+        if !strings.Contains(text, value) {
+            continue
+        }
+        name := r.Placeholders[value]
+        text = strings.ReplaceAll(text, value, "{vars."+name+"}")
+        if r.script.Vars == nil {
+            r.script.Vars = map[string]any{}
+        }
+        r.script.Vars[name] = value
+    }
+    return text
+}
+
+// Script returns a copy of the script recorded so far.
+func (r *Recorder) Script() *Script {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    script := Script{Name: r.script.Name, Steps: append([]Step(nil), r.script.Steps...)}
+    if r.script.Vars != nil {
+        script.Vars = make(map[string]any, len(r.script.Vars))
+        for name, value := range r.script.Vars {
+            script.Vars[name] = value
+        }
+    }
+    return &script
+}
+
+// Options controls a replay. Vars override the script's defaults. DryRun
+// sends every step as a dry run, checking routing without executing.
+type Options struct {
+    Vars map[string]any
+    DryRun bool
+    // Environment, when set, makes the replay strict: it refuses to run
+    // unless the live agent matches the snapshot the script was recorded
+    // against; see client.VerifyEnvironment.
+    Environment *client.EnvironmentSnapshot
+    // OnStep, when set, is called after each step.
+    OnStep func(StepResult)
+}
+
+type StepResult struct {
+    Index int `json:"index"`
+    Message string `json:"message"`
+    Expected string `json:"expected,omitempty"`
+    Function string `json:"function,omitempty"`
+    Error string `json:"error,omitempty"`
+}
+
+// Passed reports whether the step succeeded and was routed as expected.
+func (r StepResult) Passed() bool {
+    return r.Error == "" && (r.Expected == "" || r.Expected == r.Function)
+}
+
+type Result struct {
+    Steps []StepResult `json:"steps"`
+}
+
+func (r *Result) Passed() bool {
+    for _, step := range r.Steps {
+        if !step.Passed() {
+            return false
+        }
+    }
+    return true
+}
+
+// Replay runs the script's steps in order with c. A step that fails or is
+// routed elsewhere is reported in the result and does not stop the replay;
+// only a step that cannot be rendered, or ctx ending, does.
+func Replay(ctx context.Context, c *client.Client, script *Script, opts Options) (*Result, error) {
+    if opts.Environment != nil {
+        if err := c.VerifyEnvironment(ctx, opts.Environment); err != nil {
+            return nil, err
+        }
+    }
+    templates := client.NewTemplateRegistry()
+    result := &Result{}
+    for i, step := range script.Steps {
+        name := strconv.Itoa(i + 1)
+        templates.Register(name, client.RequestTemplate{Message: step.Message, Inputs: step.Inputs, Defaults: script.Vars, Execute: step.Execute})
+        request, err := templates.Render(name, opts.Vars)
+        if err != nil {
+            return result, fmt.Errorf("step %d: %w", i+1, err)
+        }
+        if opts.DryRun {
+            request = request.DryRun()
+        }
+        outcome := StepResult{Index: i + 1, Message: request.Message, Expected: step.ExpectFunction}
+        resp, err := c.Chat(ctx, request)
+        if err != nil {
+            if ctx.Err() != nil {
+                return result, ctx.Err()
+            }
+            outcome.Error = err.Error()
+        } else {
+            outcome.Function = resp.Function
+        }
+        result.Steps = append(result.Steps, outcome)
+        if opts.OnStep != nil {
+            opts.OnStep(outcome)
+        }
+    }
+    return result, nil
+}
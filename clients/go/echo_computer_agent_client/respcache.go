@@ -0,0 +1,234 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "time"
+)
+
+// ResponseCache stores responses by function and a key derived from the
+// call. Invalidate("") empties the whole cache.
+type ResponseCache interface {
+    Get(function, key string) (*ChatResponse, bool)
+    Set(function, key string, resp *ChatResponse, ttl time.Duration)
+    Invalidate(function string)
+}
+
+// dryRunFunction files dry-run Chat responses, whose function is only
+// known after the call.
+const dryRunFunction = "/chat"
+
+// SetResponseCache caches dry-run Chat and Plan responses, and
+// InvokeFunction results for functions whose metadata marks them
+// "idempotent". Entries live for the function's "cache_ttl_seconds"
+// metadata, or ttl. Keys cover the function, message, normalized inputs,
+// actor, and language, so callers never share each other's responses.
+// Invoked functions are still authorized and audited on a hit.
+func (c *Client) SetResponseCache(cache ResponseCache, ttl time.Duration) {
+    c.responseCache, c.responseTTL = cache, ttl
+}
+
+// InvalidateResponses drops cached responses for function, or every cached
+// response when function is empty.
+func (c *Client) InvalidateResponses(function string) {
+    if c.responseCache != nil {
+        c.responseCache.Invalidate(function)
+    }
+}
+
+// cacheable reports whether calls to function may be served from the
+// cache, and for how long.
+func (c *Client) cacheable(ctx context.Context, function string) (time.Duration, bool) {
+    if c.responseCache == nil {
+        return 0, false
+    }
+    if function == dryRunFunction {
+        return c.responseTTL, true
+    }
+    functions, err := c.Functions(ctx)
+    if err != nil {
+        return 0, false
+    }
+    fn, ok := functions.ByName(function)
+    if idempotent, _ := fn.Metadata["idempotent"].(bool); !ok || !idempotent {
+        return 0, false
+    }
+    if seconds, ok := number(fn.Metadata["cache_ttl_seconds"]); ok {
+        return time.Duration(seconds * float64(time.Second)), seconds > 0
+    }
+    return c.responseTTL, true
+}
+
+func responseKey(ctx context.Context, message string, inputs map[string]any) string {
+    // encoding/json sorts map keys, which normalizes the inputs.
+    encoded, _ := json.Marshal(inputs)
+    language, _ := LanguageFromContext(ctx)
+    h := sha256.New()
+    parts := []string{message, string(encoded), language}
+    if actor, ok := ActorFromContext(ctx); ok {
+        parts = append(parts, actor.ID, actor.Tenant, strings.Join(actor.Roles, ","))
+    }
+    for _, part := range parts {
+        h.Write([]byte(part))
+        h.Write([]byte{0})
+    }
+    return hex.EncodeToString(h.Sum(nil))
+}
+
+// cachedCall serves function's response for key from the cache, or runs
+// fetch and stores a successful result.
+func (c *Client) cachedCall(ctx context.Context, function, key string, fetch func() (*ChatResponse, error)) (*ChatResponse, error) {
+    ttl, ok := c.cacheable(ctx, function)
+    if !ok {
+        return fetch()
+    }
+    resp, hit := c.responseCache.Get(function, key)
+    c.noteCacheLookup(hit)
+    if hit {
+        return resp, nil
+    }
+    resp, err := fetch()
+    if err == nil && ttl > 0 {
+        c.responseCache.Set(function, key, resp, ttl)
+    }
+    return resp, err
+}
+
+type cachedResponse struct {
+    Response json.RawMessage `json:"response"`
+    Expires time.Time `json:"expires"`
+}
+
+// MemoryCache is an in-process ResponseCache. Responses are stored
+// encoded, so callers may modify what they get back.
+type MemoryCache struct {
+    mu sync.Mutex
+    entries map[string]map[string]cachedResponse
+}
+
+func NewMemoryCache() *MemoryCache {
+    return &MemoryCache{entries: map[string]map[string]cachedResponse{}}
+}
+
+func (m *MemoryCache) Get(function, key string) (*ChatResponse, bool) {
+    m.mu.Lock()
+    entry, ok := m.entries[function][key]
+    if ok && time.Now().After(entry.Expires) {
+        delete(m.entries[function], key)
+        ok = false
+    }
+    m.mu.Unlock()
+    if !ok {
+        return nil, false
+    }
+    var resp ChatResponse
+    if json.Unmarshal(entry.Response, &resp) != nil {
+        return nil, false
+    }
+    return &resp, true
+}
+
+func (m *MemoryCache) Set(function, key string, resp *ChatResponse, ttl time.Duration) {
+    encoded, err := json.Marshal(resp)
+    if err != nil {
+        return
+    }
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    if m.entries[function] == nil {
+        m.entries[function] = map[string]cachedResponse{}
+    }
+    m.entries[function][key] = cachedResponse{Response: encoded, Expires: time.Now().Add(ttl)}
+}
+
+func (m *MemoryCache) Invalidate(function string) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    if function == "" {
+        m.entries = map[string]map[string]cachedResponse{}
+        return
+    }
+    delete(m.entries, function)
+}
+
+// DiskCache is a ResponseCache under Dir, one JSON file per entry grouped
+// in a directory per function, so it survives restarts and can be shared
+// by processes on one host. Expired files are removed when read.
+type DiskCache struct {
+    Dir string
+}
+
+func NewDiskCache(dir string) (*DiskCache, error) {
+    if err := os.MkdirAll(dir, 0o700); err != nil {
+        return nil, err
+    }
+    return &DiskCache{Dir: dir}, nil
+}
+
+func (d *DiskCache) functionDir(function string) string {
+    sum := sha256.Sum256([]byte(function))
+    return filepath.Join(d.Dir, hex.EncodeToString(sum[:8]))
+}
+
+func (d *DiskCache) Get(function, key string) (*ChatResponse, bool) {
+    path := filepath.Join(d.functionDir(function), key+".json")
+    raw, err := os.ReadFile(path)
+    if err != nil {
+        return nil, false
+    }
+    var entry cachedResponse
+    if json.Unmarshal(raw, &entry) != nil || time.Now().After(entry.Expires) {
+        os.Remove(path)
+        return nil, false
+    }
+    var resp ChatResponse
+    if json.Unmarshal(entry.Response, &resp) != nil {
+        return nil, false
+    }
+    return &resp, true
+}
+
+// Set writes through a temporary file so concurrent readers never see a
+// partial entry. Write errors are ignored; the cache is best effort.
+func (d *DiskCache) Set(function, key string, resp *ChatResponse, ttl time.Duration) {
+    encoded, err := json.Marshal(resp)
+    if err != nil {
+        return
+    }
+    raw, err := json.Marshal(cachedResponse{Response: encoded, Expires: time.Now().Add(ttl)})
+    if err != nil {
+        return
+    }
+    dir := d.functionDir(function)
+    if os.MkdirAll(dir, 0o700) != nil {
+        return
+    }
+    tmp, err := os.CreateTemp(dir, "tmp-*")
+    if err != nil {
+        return
+    }
+    _, err = tmp.Write(raw)
+    if closeErr := tmp.Close(); err == nil {
+        err = closeErr
+    }
+    if err != nil || os.Rename(tmp.Name(), filepath.Join(dir, key+".json")) != nil {
+        os.Remove(tmp.Name())
+    }
+}
+
+func (d *DiskCache) Invalidate(function string) {
+    if function == "" {
+        entries, _ := os.ReadDir(d.Dir)
+        for _, entry := range entries {
+            os.RemoveAll(filepath.Join(d.Dir, entry.Name()))
+        }
+        return
+    }
+    os.RemoveAll(d.functionDir(function))
+}
@@ -0,0 +1,130 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "errors"
+    "io"
+    "math/rand"
+    "net"
+    "net/http"
+    "syscall"
+    "time"
+)
+
+// RetryPolicy retries idempotent calls that failed transiently: 5xx
+// responses, 429s the throttle wait does not absorb, and dropped
+// connections. The delay before attempt n+1 is InitialBackoff doubled n-1
+// times, capped at MaxBackoff, with up to Jitter of it (0 to 1) removed at
+// random so clients do not retry in lockstep. A Retry-After on the failed
+// response is honored when it asks for longer.
+type RetryPolicy struct {
+    MaxAttempts int `json:"max_attempts"`
+    InitialBackoff time.Duration `json:"initial_backoff_ns"`
+    MaxBackoff time.Duration `json:"max_backoff_ns"`
+    Jitter float64 `json:"jitter"`
+}
+
+// DefaultRetryPolicy is a reasonable policy for interactive callers.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, InitialBackoff: 200 * time.Millisecond, MaxBackoff: 5 * time.Second, Jitter: 0.5}
+
+// SetRetryPolicy enables retries. Calls are attempted once until it is set.
+//
+// Only calls that are safe to repeat are retried: GETs such as
+// ListFunctions, dry-run Chat and Plan calls, and executing Chat calls
+// whose resolved function the catalog marks "idempotent". Other executing
+// calls may already have run when the failure arrived and are never
+// repeated.
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+    c.retry = policy
+}
+
+type idempotentKey struct{}
+
+// withIdempotent marks calls made with ctx as safe to repeat.
+func withIdempotent(ctx context.Context) context.Context {
+    return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+func (c *Client) retryable(ctx context.Context, method string) bool {
+    if c.retry.MaxAttempts <= 1 {
+        return false
+    }
+    switch method {
+    case http.MethodGet, http.MethodHead, http.MethodOptions:
+        return true
+    }
+    marked, _ := ctx.Value(idempotentKey{}).(bool)
+    return marked
+}
+
+// idempotentFunction reports whether the catalog marks function safe to
+// execute more than once.
+func (c *Client) idempotentFunction(ctx context.Context, function string) bool {
+    if function == "" || c.retry.MaxAttempts <= 1 {
+        return false
+    }
+    functions, err := c.Functions(ctx)
+    if err != nil {
+        return false
+    }
+    fn, ok := functions.ByName(function)
+    idempotent, _ := fn.Metadata["idempotent"].(bool)
+    return ok && idempotent
+}
+
+func transient(err error) bool {
+    var apiErr *APIError
+    if errors.As(err, &apiErr) {
+        return apiErr.Status >= 500 && apiErr.Status != http.StatusNotImplemented || apiErr.Status == http.StatusTooManyRequests
+    }
+    if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+        return false
+    }
+    var changed *HostKeyChangedError
+    if errors.As(err, &changed) {
+        return false
+    }
+    if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+        return true
+    }
+    var netErr net.Error
+    return errors.As(err, &netErr)
+}
+
+// backoff sleeps before the attempt after attempt, failing when ctx ends
+// first or its deadline leaves no room for another attempt.
+func (c *Client) backoff(ctx context.Context, attempt int, cause error) error {
+    p := c.retry
+    initial, max := p.InitialBackoff, p.MaxBackoff
+    if initial <= 0 {
+        initial = DefaultRetryPolicy.InitialBackoff
+    }
+    if max <= 0 {
+        max = DefaultRetryPolicy.MaxBackoff
+    }
+    delay := initial
+    for i := 1; i < attempt && delay < max; i++ {
+        delay *= 2
+    }
+    if delay > max {
+        delay = max
+    }
+    if p.Jitter > 0 {
+        delay -= time.Duration(rand.Float64() * min(p.Jitter, 1) * float64(delay))
+    }
+    var apiErr *APIError
+    if errors.As(cause, &apiErr) && apiErr.retryAfter > delay {
+        delay = apiErr.retryAfter
+    }
+    if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+        return cause
+    }
+    timer := time.NewTimer(delay)
+    defer timer.Stop()
+    select {
+    case <-ctx.Done():
+        return cause
+    case <-timer.C:
+        return nil
+    }
+}
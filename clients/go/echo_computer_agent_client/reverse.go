@@ -0,0 +1,197 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "crypto/tls"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "sort"
+    "strings"
+    "time"
+
+    "echo_computer_agent_client/internal/wsconn"
+)
+
+// ToolCall is a request pushed by the agent for a function the client
+// executes locally.
+type ToolCall struct {
+    ID string `json:"id"`
+    Function string `json:"function"`
+    Inputs map[string]any `json:"inputs,omitempty"`
+}
+
+type ToolHandler func(ctx context.Context, call ToolCall) (map[string]any, error)
+
+// ReverseEvent is a non-tool message pushed over a reverse connection.
+type ReverseEvent struct {
+    Type string `json:"type"`
+    Data json.RawMessage `json:"data,omitempty"`
+}
+
+type ReverseOptions struct {
+    Tools map[string]ToolHandler
+    OnEvent func(ReverseEvent)
+    MinReconnectDelay time.Duration
+    MaxReconnectDelay time.Duration
+    Heartbeat Heartbeat
+}
+
+type reverseMessage struct {
+    Type string `json:"type"`
+    ID string `json:"id,omitempty"`
+    Function string `json:"function,omitempty"`
+    Inputs map[string]any `json:"inputs,omitempty"`
+    Output map[string]any `json:"output,omitempty"`
+    Error string `json:"error,omitempty"`
+    Tools []string `json:"tools,omitempty"`
+    Stream string `json:"stream,omitempty"`
+    Chunk string `json:"chunk,omitempty"`
+    Data json.RawMessage `json:"data,omitempty"`
+}
+
+type toolOutputKey struct{}
+
+type toolOutput func(stream string, chunk []byte) error
+
+// EmitToolOutput streams an incremental chunk of output (stream is usually
+// "stdout" or "stderr") for the tool call being handled with ctx. It is a
+// no-op outside a reverse-connection tool call.
+func EmitToolOutput(ctx context.Context, stream string, chunk []byte) error {
+    emit, ok := ctx.Value(toolOutputKey{}).(toolOutput)
+    if !ok {
+        return nil
+    }
+    return emit(stream, chunk)
+}
+
+func withToolOutput(ctx context.Context, conn *wsconn.Conn, id string) context.Context {
+    return context.WithValue(ctx, toolOutputKey{}, toolOutput(func(stream string, chunk []byte) error {
+        return writeReverse(conn, reverseMessage{Type: "tool_output", ID: id, Stream: stream, Chunk: string(chunk)})
+    }))
+}
+
+// ServeReverse dials out to the agent's /connect WebSocket and serves
+// tool calls pushed through it, so a client behind NAT can host local
+// functions without opening inbound ports. The connection is re-established
+// with exponential backoff until ctx is cancelled, including when the
+// heartbeat finds it silently dead.
+func (c *Client) ServeReverse(ctx context.Context, opts ReverseOptions) error {
+    minDelay := opts.MinReconnectDelay
+    if minDelay <= 0 {
+        minDelay = time.Second
+    }
+    maxDelay := opts.MaxReconnectDelay
+    if maxDelay <= 0 {
+        maxDelay = time.Minute
+    }
+    delay := minDelay
+    for {
+        connected, _ := c.serveReverseOnce(ctx, opts)
+        if ctx.Err() != nil {
+            return ctx.Err()
+        }
+        if connected {
+            delay = minDelay
+        }
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-time.After(delay):
+        }
+        delay *= 2
+        if delay > maxDelay {
+            delay = maxDelay
+        }
+    }
+}
+
+func (c *Client) serveReverseOnce(ctx context.Context, opts ReverseOptions) (bool, error) {
+    conn, err := c.dialWebSocket(ctx, "/connect")
+    if err != nil {
+        return false, err
+    }
+    defer conn.Close(wsconn.CloseNormal, "")
+    connCtx, cancel := context.WithCancel(ctx)
+    defer cancel()
+    go func() {
+        <-connCtx.Done()
+        conn.Close(wsconn.CloseGoingAway, "client shutting down")
+    }()
+
+    names := make([]string, 0, len(opts.Tools))
+    for name := range opts.Tools {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    if err := writeReverse(conn, reverseMessage{Type: "hello", Tools: names}); err != nil {
+        return false, err
+    }
+    touch := keepAlive(connCtx, conn, opts.Heartbeat)
+    for {
+        _, raw, err := conn.ReadMessage()
+        if err != nil {
+            return true, err
+        }
+        touch()
+        var message reverseMessage
+        if err := json.Unmarshal(raw, &message); err != nil {
+            continue
+        }
+        if message.Type != "tool_call" {
+            if opts.OnEvent != nil {
+                opts.OnEvent(ReverseEvent{Type: message.Type, Data: message.Data})
+            }
+            continue
+        }
+        call := ToolCall{ID: message.ID, Function: message.Function, Inputs: message.Inputs}
+        go func() {
+            result := reverseMessage{Type: "tool_result", ID: call.ID}
+            handler, ok := opts.Tools[call.Function]
+            if !ok {
+                result.Error = fmt.Sprintf("function %s is not served by this client", call.Function)
+            } else if output, err := handler(withToolOutput(connCtx, conn, call.ID), call); err != nil {
+                result.Error = err.Error()
+            } else {
+                result.Output = output
+            }
+            writeReverse(conn, result)
+        }()
+    }
+}
+
+func writeReverse(conn *wsconn.Conn, message reverseMessage) error {
+    encoded, err := json.Marshal(message)
+    if err != nil {
+        return err
+    }
+    return conn.WriteMessage(wsconn.OpText, encoded)
+}
+
+func (c *Client) dialWebSocket(ctx context.Context, path string) (*wsconn.Conn, error) {
+    target := c.baseURL + path
+    if strings.HasPrefix(target, "https://") {
+        target = "wss://" + strings.TrimPrefix(target, "https://")
+    } else if strings.HasPrefix(target, "http://") {
+        target = "ws://" + strings.TrimPrefix(target, "http://")
+    }
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+    if err != nil {
+        return nil, err
+    }
+    c.decorate(ctx, req)
+    if err := c.credentials(req); err != nil {
+        return nil, err
+    }
+    conn, _, err := wsconn.Dial(ctx, target, req.Header, c.tlsConfig())
+    return conn, err
+}
+
+func (c *Client) tlsConfig() *tls.Config {
+    if httpClient, ok := c.transport.(*http.Client); ok {
+        if transport, ok := httpClient.Transport.(*http.Transport); ok {
+            return transport.TLSClientConfig
+        }
+    }
+    return nil
+}
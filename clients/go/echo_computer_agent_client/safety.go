@@ -0,0 +1,181 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "regexp"
+)
+
+var ErrContentBlocked = errors.New("content blocked by safety filter")
+
+// RequestFilter runs before a chat request is sent and may reject or rewrite
+// it. ResponseFilter runs on every decoded reply and may redact it in place.
+type RequestFilter interface {
+    FilterRequest(ctx context.Context, request *ChatRequest) error
+}
+
+type ResponseFilter interface {
+    FilterResponse(ctx context.Context, resp *ChatResponse) error
+}
+
+// Classifier is a hook for external moderation models. It returns the labels
+// that apply to text, e.g. "violence" or "pii".
+type Classifier interface {
+    Classify(ctx context.Context, text string) ([]string, error)
+}
+
+func (c *Client) AddRequestFilter(filter RequestFilter) {
+    c.requestFilters = append(c.requestFilters, filter)
+}
+
+func (c *Client) AddResponseFilter(filter ResponseFilter) {
+    c.responseFilters = append(c.responseFilters, filter)
+}
+
+// SetSafetyFilter registers filter as both a request and a response filter.
+func (c *Client) SetSafetyFilter(filter *SafetyFilter) {
+    c.AddRequestFilter(filter)
+    c.AddResponseFilter(filter)
+}
+
+func (c *Client) filterRequest(ctx context.Context, request *ChatRequest) error {
+    for _, filter := range c.requestFilters {
+        if err := filter.FilterRequest(ctx, request); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// filterResponse meters the reply's usage, then runs the response filters
+// and the processors.
+func (c *Client) filterResponse(ctx context.Context, resp *ChatResponse) error {
+    c.meterUsage(ctx, resp)
+    if err := c.runResponseFilters(ctx, resp); err != nil {
+        return err
+    }
+    return c.processResponse(ctx, resp)
+}
+
+func (c *Client) runResponseFilters(ctx context.Context, resp *ChatResponse) error {
+    for _, filter := range c.responseFilters {
+        if err := filter.FilterResponse(ctx, resp); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// SafetyFilter blocks prompts matching BlockPrompts and redacts matches of
+// Redact from the reply Message and every string inside Data. Labels from
+// Classifier that appear in BlockLabels block prompts; any other labels on a
+// reply are reported under Metadata["safety_flags"].
+type SafetyFilter struct {
+    BlockPrompts []*regexp.Regexp
+    Redact []*regexp.Regexp
+    Replacement string
+    Classifier Classifier
+    BlockLabels []string
+}
+
+// DefaultSafetyFilter blocks common prompt-injection phrasing and
+// destructive shell idioms, and redacts private keys, cloud access keys, and
+// bearer tokens from replies.
+func DefaultSafetyFilter() *SafetyFilter {
+    return &SafetyFilter{
+        BlockPrompts: []*regexp.Regexp{
+            regexp.MustCompile(`(?i)ignore (all )?(previous|prior) instructions`),
+            regexp.MustCompile(`(?i)disregard (the )?system prompt`),
+            regexp.MustCompile(`rm\s+-rf\s+/(\s|$)`),
+        },
+        Redact: []*regexp.Regexp{
+            regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+            regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`),
+            regexp.MustCompile(`(?i)\bbearer\s+[a-z0-9._~+/-]{16,}=*`),
+        },
+        Replacement: "[REDACTED]",
+    }
+}
+
+func (f *SafetyFilter) FilterRequest(ctx context.Context, request *ChatRequest) error {
+    for _, pattern := range f.BlockPrompts {
+        if pattern.MatchString(request.Message) {
+            return fmt.Errorf("%w: prompt matches %q", ErrContentBlocked, pattern.String())
+        }
+    }
+    if f.Classifier == nil {
+        return nil
+    }
+    labels, err := f.Classifier.Classify(ctx, request.Message)
+    if err != nil {
+        return err
+    }
+    for _, label := range labels {
+        for _, blocked := range f.BlockLabels {
+            if label == blocked {
+                return fmt.Errorf("%w: prompt classified as %s", ErrContentBlocked, label)
+            }
+        }
+    }
+    return nil
+}
+
+func (f *SafetyFilter) FilterResponse(ctx context.Context, resp *ChatResponse) error {
+    var flags []string
+    redacted := false
+    resp.Message = f.redact(resp.Message, &redacted)
+    for key, value := range resp.Data {
+        resp.Data[key] = f.redactValue(value, &redacted)
+    }
+    if redacted {
+        flags = append(flags, "redacted")
+    }
+    if f.Classifier != nil {
+        labels, err := f.Classifier.Classify(ctx, resp.Message)
+        if err != nil {
+            return err
+        }
+        flags = append(flags, labels...)
+    }
+    if len(flags) > 0 {
+        if resp.Metadata == nil {
+            resp.Metadata = map[string]any{}
+        }
+        resp.Metadata["safety_flags"] = flags
+    }
+    return nil
+}
+
+func (f *SafetyFilter) redact(text string, redacted *bool) string {
+    replacement := f.Replacement
+    if replacement == "" {
+        replacement = "[REDACTED]"
+    }
+    for _, pattern := range f.Redact {
+        if pattern.MatchString(text) {
+            *redacted = true
+            text = pattern.ReplaceAllLiteralString(text, replacement)
+        }
+    }
+    return text
+}
+
+func (f *SafetyFilter) redactValue(value any, redacted *bool) any {
+    switch v := value.(type) {
+    case string:
+        return f.redact(v, redacted)
+    case map[string]any:
+        for key, item := range v {
+            v[key] = f.redactValue(item, redacted)
+        }
+        return v
+    case []any:
+        for i, item := range v {
+            v[i] = f.redactValue(item, redacted)
+        }
+        return v
+    default:
+        return value
+    }
+}
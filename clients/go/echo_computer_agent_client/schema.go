@@ -0,0 +1,318 @@
+package echo_computer_agent_client
+
+import (
+    "encoding/json"
+    "fmt"
+    "math"
+    "reflect"
+    "regexp"
+    "runtime"
+    "sort"
+    "strings"
+    "sync"
+    "unicode/utf8"
+)
+
+// FieldError is one way inputs fail a function's parameter schema. Field
+// is a path such as "targets[2].region", empty for the inputs as a whole.
+type FieldError struct {
+    Field string `json:"field"`
+    Message string `json:"message"`
+}
+
+func (e FieldError) String() string {
+    if e.Field == "" {
+        return e.Message
+    }
+    return e.Field + ": " + e.Message
+}
+
+// ValidationResult reports how the input set at Index fared.
+type ValidationResult struct {
+    Index int `json:"index"`
+    Errors []FieldError `json:"errors,omitempty"`
+}
+
+func (r ValidationResult) Valid() bool { return len(r.Errors) == 0 }
+
+// ValidateInputs checks each input set against fnName's parameter schema,
+// in parallel, and reports on all of them in order. It is meant for
+// checking a batch before spending agent quota on it; the catalog is
+// whatever f holds, e.g. from Client.Functions.
+//
+// The schema support covers what function parameters use: type, required,
+// properties, additionalProperties, items, enum, const, numeric and length
+// bounds, pattern, and allOf, anyOf and oneOf. Other keywords are ignored.
+func (f Functions) ValidateInputs(fnName string, inputs []map[string]any) []ValidationResult {
+    results := make([]ValidationResult, len(inputs))
+    fn, ok := f.ByName(fnName)
+    if !ok {
+        for i := range results {
+            results[i] = ValidationResult{Index: i, Errors: []FieldError{{Message: fmt.Sprintf("unknown function %q", fnName)}}}
+        }
+        return results
+    }
+    schema, err := viaJSON(objectSchema(fn.Parameters))
+    if err != nil {
+        for i := range results {
+            results[i] = ValidationResult{Index: i, Errors: []FieldError{{Message: "parameter schema: " + err.Error()}}}
+        }
+        return results
+    }
+    work := make(chan int)
+    var wg sync.WaitGroup
+    for w := 0; w < min(runtime.GOMAXPROCS(0), len(inputs)); w++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for i := range work {
+                results[i] = ValidationResult{Index: i, Errors: validateInputs(schema.(map[string]any), inputs[i])}
+            }
+        }()
+    }
+    for i := range inputs {
+        work <- i
+    }
+    close(work)
+    wg.Wait()
+    return results
+}
+
+// viaJSON returns v as it decodes from JSON, so Go ints, typed slices and
+// structs compare like the values the agent will see.
+func viaJSON(v any) (any, error) {
+    encoded, err := json.Marshal(v)
+    if err != nil {
+        return nil, err
+    }
+    var decoded any
+    err = json.Unmarshal(encoded, &decoded)
+    return decoded, err
+}
+
+// validateInputs checks inputs against a schema already through viaJSON.
+func validateInputs(schema map[string]any, inputs map[string]any) []FieldError {
+    var value any = map[string]any{}
+    if inputs != nil {
+        var err error
+        if value, err = viaJSON(inputs); err != nil {
+            return []FieldError{{Message: err.Error()}}
+        }
+    }
+    var errs []FieldError
+    checkSchema(schema, value, "", &errs)
+    return errs
+}
+
+func checkSchema(schema map[string]any, value any, path string, errs *[]FieldError) {
+    fail := func(format string, args ...any) {
+        *errs = append(*errs, FieldError{Field: path, Message: fmt.Sprintf(format, args...)})
+    }
+    if types := schemaTypes(schema["type"]); len(types) > 0 && !matchesType(types, value) {
+        fail("must be %s, not %s", strings.Join(types, " or "), jsonType(value))
+        return
+    }
+    if enum, ok := schema["enum"].([]any); ok && !containsValue(enum, value) {
+        fail("must be one of %s", formatValues(enum))
+    }
+    if want, ok := schema["const"]; ok && !reflect.DeepEqual(want, value) {
+        fail("must be %s", formatValues([]any{want}))
+    }
+    switch v := value.(type) {
+    case float64:
+        if limit, ok := bound(schema["minimum"]); ok && v < limit {
+            fail("must be at least %v", limit)
+        }
+        if limit, ok := bound(schema["maximum"]); ok && v > limit {
+            fail("must be at most %v", limit)
+        }
+        if limit, ok := bound(schema["exclusiveMinimum"]); ok && v <= limit {
+            fail("must be greater than %v", limit)
+        }
+        if limit, ok := bound(schema["exclusiveMaximum"]); ok && v >= limit {
+            fail("must be less than %v", limit)
+        }
+    case string:
+        length := float64(utf8.RuneCountInString(v))
+        if limit, ok := bound(schema["minLength"]); ok && length < limit {
+            fail("must be at least %v characters", limit)
+        }
+        if limit, ok := bound(schema["maxLength"]); ok && length > limit {
+            fail("must be at most %v characters", limit)
+        }
+        if pattern, ok := schema["pattern"].(string); ok {
+            if re, err := compilePattern(pattern); err == nil && !re.MatchString(v) {
+                fail("must match %s", pattern)
+            }
+        }
+    case []any:
+        if limit, ok := bound(schema["minItems"]); ok && float64(len(v)) < limit {
+            fail("must have at least %v items", limit)
+        }
+        if limit, ok := bound(schema["maxItems"]); ok && float64(len(v)) > limit {
+            fail("must have at most %v items", limit)
+        }
+        if items, ok := schema["items"].(map[string]any); ok {
+            for i, item := range v {
+                checkSchema(items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+            }
+        }
+    case map[string]any:
+        checkObject(schema, v, path, errs)
+    }
+    if all, ok := schema["allOf"].([]any); ok {
+        for _, sub := range all {
+            if sub, ok := sub.(map[string]any); ok {
+                checkSchema(sub, value, path, errs)
+            }
+        }
+    }
+    if anyOf, ok := schema["anyOf"].([]any); ok && countMatches(anyOf, value) == 0 {
+        fail("must match at least one allowed schema")
+    }
+    if oneOf, ok := schema["oneOf"].([]any); ok {
+        if n := countMatches(oneOf, value); n != 1 {
+            fail("must match exactly one allowed schema, matched %d", n)
+        }
+    }
+}
+
+func checkObject(schema map[string]any, object map[string]any, path string, errs *[]FieldError) {
+    properties, _ := schema["properties"].(map[string]any)
+    if required, ok := schema["required"].([]any); ok {
+        for _, name := range required {
+            if name, ok := name.(string); ok {
+                if _, present := object[name]; !present {
+                    *errs = append(*errs, FieldError{Field: joinField(path, name), Message: "is required"})
+                }
+            }
+        }
+    }
+    names := make([]string, 0, len(object))
+    for name := range object {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    for _, name := range names {
+        field := joinField(path, name)
+        if sub, ok := properties[name].(map[string]any); ok {
+            checkSchema(sub, object[name], field, errs)
+            continue
+        }
+        switch extra := schema["additionalProperties"].(type) {
+        case bool:
+            if !extra {
+                *errs = append(*errs, FieldError{Field: field, Message: "is not a known parameter"})
+            }
+        case map[string]any:
+            checkSchema(extra, object[name], field, errs)
+        }
+    }
+}
+
+func countMatches(schemas []any, value any) int {
+    n := 0
+    for _, sub := range schemas {
+        if sub, ok := sub.(map[string]any); ok {
+            var errs []FieldError
+            checkSchema(sub, value, "", &errs)
+            if len(errs) == 0 {
+                n++
+            }
+        }
+    }
+    return n
+}
+
+func joinField(path, name string) string {
+    if path == "" {
+        return name
+    }
+    return path + "." + name
+}
+
+func schemaTypes(raw any) []string {
+    switch t := raw.(type) {
+    case string:
+        return []string{t}
+    case []any:
+        var types []string
+        for _, v := range t {
+            if s, ok := v.(string); ok {
+                types = append(types, s)
+            }
+        }
+        return types
+    }
+    return nil
+}
+
+func matchesType(types []string, value any) bool {
+    actual := jsonType(value)
+    for _, t := range types {
+        if t == actual || t == "number" && actual == "integer" {
+            return true
+        }
+    }
+    return false
+}
+
+func jsonType(value any) string {
+    switch v := value.(type) {
+    case nil:
+        return "null"
+    case bool:
+        return "boolean"
+    case float64:
+        if v == math.Trunc(v) && !math.IsInf(v, 0) {
+            return "integer"
+        }
+        return "number"
+    case string:
+        return "string"
+    case []any:
+        return "array"
+    case map[string]any:
+        return "object"
+    }
+    return fmt.Sprintf("%T", value)
+}
+
+// bound reads a numeric schema keyword; absent or mistyped ones are
+// ignored.
+func bound(raw any) (float64, bool) {
+    n, ok := raw.(float64)
+    return n, ok
+}
+
+func containsValue(values []any, value any) bool {
+    for _, v := range values {
+        if reflect.DeepEqual(v, value) {
+            return true
+        }
+    }
+    return false
+}
+
+func formatValues(values []any) string {
+    parts := make([]string, len(values))
+    for i, v := range values {
+        encoded, _ := json.Marshal(v)
+        parts[i] = string(encoded)
+    }
+    return strings.Join(parts, ", ")
+}
+
+var patterns sync.Map
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+    if re, ok := patterns.Load(pattern); ok {
+        return re.(*regexp.Regexp), nil
+    }
+    re, err := regexp.Compile(pattern)
+    if err != nil {
+        return nil, err
+    }
+    patterns.Store(pattern, re)
+    return re, nil
+}
@@ -0,0 +1,247 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "fmt"
+    "math"
+    "net/http"
+    "sort"
+    "strings"
+    "sync"
+    "unicode"
+)
+
+type embedRequest struct {
+    Inputs []string `json:"inputs"`
+}
+
+type embedResponse struct {
+    Embeddings [][]float64 `json:"embeddings"`
+}
+
+// Embed asks the agent's /embed endpoint for an embedding of each text,
+// in order.
+func (c *Client) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+    var resp embedResponse
+    if err := c.doJSON(withIdempotent(ctx), http.MethodPost, "/embed", embedRequest{Inputs: texts}, &resp); err != nil {
+        return nil, err
+    }
+    if len(resp.Embeddings) != len(texts) {
+        return nil, fmt.Errorf("embed: agent returned %d embeddings for %d texts", len(resp.Embeddings), len(texts))
+    }
+    return resp.Embeddings, nil
+}
+
+// FunctionMatch is a SearchFunctions result. Score, from 0 to 1, ranks
+// the matches; Fuzzy and Semantic are its parts, Semantic being zero
+// without embeddings.
+type FunctionMatch struct {
+    Function FunctionDescription `json:"function"`
+    Score float64 `json:"score"`
+    Fuzzy float64 `json:"fuzzy"`
+    Semantic float64 `json:"semantic,omitempty"`
+}
+
+// SearchOption adjusts one SearchFunctions call.
+type SearchOption func(*searchSettings)
+
+type searchSettings struct {
+    semantic bool
+    limit int
+    threshold float64
+}
+
+// WithSemanticSearch blends in similarity between the query's embedding
+// and each function's, from the agent's Embed endpoint. Agents that do
+// not offer it are searched fuzzily alone.
+func WithSemanticSearch() SearchOption {
+    return func(s *searchSettings) { s.semantic = true }
+}
+
+// WithSearchLimit returns at most n matches.
+func WithSearchLimit(n int) SearchOption {
+    return func(s *searchSettings) { s.limit = n }
+}
+
+// WithMinScore drops matches scoring below score; the default is 0.3.
+func WithMinScore(score float64) SearchOption {
+    return func(s *searchSettings) { s.threshold = score }
+}
+
+// SearchFunctions ranks the cached catalog against query, tolerating
+// typos and partial words, e.g. "restrat servce" finds service.restart.
+// Unlike Functions.Search, which needs every word to appear, each query
+// word scores by its closest word in a function's name, tags or
+// description, with name matches weighing most. With
+// WithSemanticSearch, matches by meaning rank too, so "bring the web
+// server back" can find service.restart.
+func (c *Client) SearchFunctions(ctx context.Context, query string, opts ...SearchOption) ([]FunctionMatch, error) {
+    settings := searchSettings{threshold: 0.3}
+    for _, opt := range opts {
+        opt(&settings)
+    }
+    functions, err := c.Functions(ctx)
+    if err != nil {
+        return nil, err
+    }
+    words := searchWords(query)
+    var semantic []float64
+    if settings.semantic {
+        if semantic, err = c.semanticScores(ctx, query, functions); err != nil {
+            return nil, err
+        }
+    }
+    var matches []FunctionMatch
+    for i, fn := range functions {
+        match := FunctionMatch{Function: fn, Fuzzy: fuzzyScore(words, fn)}
+        match.Score = match.Fuzzy
+        if semantic != nil {
+            match.Semantic = semantic[i]
+            match.Score = 0.4*match.Fuzzy + 0.6*match.Semantic
+        }
+        if match.Score >= settings.threshold {
+            matches = append(matches, match)
+        }
+    }
+    sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+    if settings.limit > 0 && len(matches) > settings.limit {
+        matches = matches[:settings.limit]
+    }
+    return matches, nil
+}
+
+// embeddingCache holds catalog embeddings by the text embedded, so a
+// catalog is embedded once rather than on every search.
+type embeddingCache struct {
+    sync.Mutex
+    vectors map[string][]float64
+}
+
+// semanticScores returns each function's similarity to query, or nil when
+// the agent cannot embed.
+func (c *Client) semanticScores(ctx context.Context, query string, functions Functions) ([]float64, error) {
+    if caps, err := c.Capabilities(ctx); err == nil && caps.lacks(FeatureEmbeddings) {
+        return nil, nil
+    }
+    texts := make([]string, len(functions))
+    missing := []string{query}
+    c.embeddings.Lock()
+    for i, fn := range functions {
+        texts[i] = strings.TrimSpace(fn.Name + "\n" + fn.Description + "\n" + strings.Join(Tags(fn), " "))
+        if _, ok := c.embeddings.vectors[texts[i]]; !ok {
+            missing = append(missing, texts[i])
+        }
+    }
+    c.embeddings.Unlock()
+    vectors, err := c.Embed(ctx, missing)
+    if err != nil {
+        if IsNotFound(err) {
+            return nil, nil
+        }
+        return nil, err
+    }
+    c.embeddings.Lock()
+    defer c.embeddings.Unlock()
+    if c.embeddings.vectors == nil {
+        c.embeddings.vectors = map[string][]float64{}
+    }
+    for i, text := range missing[1:] {
+        c.embeddings.vectors[text] = vectors[i+1]
+    }
+    scores := make([]float64, len(functions))
+    for i, text := range texts {
+        // Cosine similarity in [-1, 1]; unrelated texts sit near 0.
+        scores[i] = math.Max(0, cosine(vectors[0], c.embeddings.vectors[text]))
+    }
+    return scores, nil
+}
+
+func cosine(a, b []float64) float64 {
+    var dot, na, nb float64
+    for i := 0; i < len(a) && i < len(b); i++ {
+        dot += a[i] * b[i]
+        na += a[i] * a[i]
+        nb += b[i] * b[i]
+    }
+    if na == 0 || nb == 0 {
+        return 0
+    }
+    return dot / math.Sqrt(na*nb)
+}
+
+func searchWords(text string) []string {
+    return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+}
+
+// fuzzyScore averages, over the query words, each one's best similarity
+// to a word of fn: full weight in its name, less in its tags and less
+// again in its description.
+func fuzzyScore(query []string, fn FunctionDescription) float64 {
+    if len(query) == 0 {
+        return 0
+    }
+    fields := []struct {
+        words []string
+        weight float64
+    }{
+        {searchWords(fn.Name), 1},
+        {searchWords(strings.Join(Tags(fn), " ")), 0.8},
+        {searchWords(fn.Description), 0.6},
+    }
+    total := 0.0
+    for _, q := range query {
+        best := 0.0
+        for _, field := range fields {
+            for _, w := range field.words {
+                best = math.Max(best, field.weight*wordSimilarity(q, w))
+            }
+        }
+        total += best
+    }
+    return total / float64(len(query))
+}
+
+// wordSimilarity is 1 for equal words, high for a prefix such as "conf"
+// of "config", and otherwise falls with edit distance, so "restrat" is
+// still close to "restart".
+func wordSimilarity(q, w string) float64 {
+    if q == w {
+        return 1
+    }
+    if len(q) >= 3 && strings.HasPrefix(w, q) {
+        return 0.9
+    }
+    a, b := []rune(q), []rune(w)
+    longest := max(len(a), len(b))
+    similarity := 1 - float64(editDistance(a, b))/float64(longest)
+    if similarity < 0.5 {
+        return 0
+    }
+    return similarity
+}
+
+// editDistance is the Damerau-Levenshtein distance counting adjacent
+// transpositions as one edit.
+func editDistance(a, b []rune) int {
+    d := make([][]int, len(a)+1)
+    for i := range d {
+        d[i] = make([]int, len(b)+1)
+        d[i][0] = i
+    }
+    for j := range d[0] {
+        d[0][j] = j
+    }
+    for i := 1; i <= len(a); i++ {
+        for j := 1; j <= len(b); j++ {
+            cost := 1
+            if a[i-1] == b[j-1] {
+                cost = 0
+            }
+            d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+            if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+                d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+            }
+        }
+    }
+    return d[len(a)][len(b)]
+}
@@ -0,0 +1,245 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "os"
+    "os/exec"
+    "strings"
+)
+
+// SecretRef is a parsed symbolic reference such as "vault://kv/app#api_key".
+type SecretRef struct {
+    Scheme string
+    Path string
+    Field string
+}
+
+func (r SecretRef) String() string {
+    ref := r.Scheme + "://" + r.Path
+    if r.Field != "" {
+        ref += "#" + r.Field
+    }
+    return ref
+}
+
+type SecretResolver interface {
+    ResolveSecret(ctx context.Context, ref SecretRef) (string, error)
+}
+
+type SecretResolverFunc func(ctx context.Context, ref SecretRef) (string, error)
+
+func (f SecretResolverFunc) ResolveSecret(ctx context.Context, ref SecretRef) (string, error) {
+    return f(ctx, ref)
+}
+
+// SetSecretResolver registers resolver for references using scheme. On
+// calls made with a WithTrustedInputs context, input strings of the form
+// "<scheme>://path#field" are replaced with the resolved secret on the wire
+// copy of each request only; the caller's Inputs and anything recorded from
+// them (audit records, transcripts) keep the reference.
+func (c *Client) SetSecretResolver(scheme string, resolver SecretResolver) {
+    if c.secrets == nil {
+        c.secrets = map[string]SecretResolver{}
+    }
+    c.secrets[scheme] = resolver
+}
+
+type trustedInputsKey struct{}
+
+// WithTrustedInputs marks calls made with ctx as carrying Inputs the caller
+// wrote itself, so their secret references are resolved. Gateways that
+// forward remote input must not set it.
+func WithTrustedInputs(ctx context.Context) context.Context {
+    return context.WithValue(ctx, trustedInputsKey{}, true)
+}
+
+func trustedInputs(ctx context.Context) bool {
+    trusted, _ := ctx.Value(trustedInputsKey{}).(bool)
+    return trusted
+}
+
+// StripSecretRefs returns a copy of inputs without the strings that
+// reference a registered secret scheme, dropping such map entries and list
+// items, for gateways to apply to input they forward.
+func (c *Client) StripSecretRefs(inputs map[string]any) map[string]any {
+    if len(c.secrets) == 0 || inputs == nil {
+        return inputs
+    }
+    stripped, _ := c.stripValue(inputs)
+    return stripped.(map[string]any)
+}
+
+func (c *Client) stripValue(value any) (any, bool) {
+    switch v := value.(type) {
+    case string:
+        if ref, ok := ParseSecretRef(v); ok && c.secrets[ref.Scheme] != nil {
+            return nil, false
+        }
+        return v, true
+    case map[string]any:
+        out := make(map[string]any, len(v))
+        for key, item := range v {
+            if kept, ok := c.stripValue(item); ok {
+                out[key] = kept
+            }
+        }
+        return out, true
+    case []any:
+        out := make([]any, 0, len(v))
+        for _, item := range v {
+            if kept, ok := c.stripValue(item); ok {
+                out = append(out, kept)
+            }
+        }
+        return out, true
+    default:
+        return value, true
+    }
+}
+
+func ParseSecretRef(value string) (SecretRef, bool) {
+    scheme, rest, ok := strings.Cut(value, "://")
+    if !ok || scheme == "" || rest == "" {
+        return SecretRef{}, false
+    }
+    refPath, field, _ := strings.Cut(rest, "#")
+    return SecretRef{Scheme: scheme, Path: refPath, Field: field}, true
+}
+
+func (c *Client) resolveSecrets(ctx context.Context, inputs map[string]any) (map[string]any, error) {
+    if len(c.secrets) == 0 || inputs == nil || !trustedInputs(ctx) {
+        return inputs, nil
+    }
+    resolved, err := c.resolveValue(ctx, inputs)
+    if err != nil {
+        return nil, err
+    }
+    return resolved.(map[string]any), nil
+}
+
+func (c *Client) resolveValue(ctx context.Context, value any) (any, error) {
+    switch v := value.(type) {
+    case string:
+        ref, ok := ParseSecretRef(v)
+        if !ok {
+            return v, nil
+        }
+        resolver, ok := c.secrets[ref.Scheme]
+        if !ok {
+            return v, nil
+        }
+        secret, err := resolver.ResolveSecret(ctx, ref)
+        if err != nil {
+            return nil, fmt.Errorf("resolve secret %s: %w", ref, err)
+        }
+        c.noteSecret(secret)
+        return secret, nil
+    case map[string]any:
+        out := make(map[string]any, len(v))
+        for key, item := range v {
+            resolved, err := c.resolveValue(ctx, item)
+            if err != nil {
+                return nil, err
+            }
+            out[key] = resolved
+        }
+        return out, nil
+    case []any:
+        out := make([]any, len(v))
+        for i, item := range v {
+            resolved, err := c.resolveValue(ctx, item)
+            if err != nil {
+                return nil, err
+            }
+            out[i] = resolved
+        }
+        return out, nil
+    default:
+        return value, nil
+    }
+}
+
+// EnvSecretResolver resolves "env://NAME" from the process environment.
+type EnvSecretResolver struct{}
+
+func (EnvSecretResolver) ResolveSecret(ctx context.Context, ref SecretRef) (string, error) {
+    value, ok := os.LookupEnv(ref.Path)
+    if !ok {
+        return "", fmt.Errorf("environment variable %s is not set", ref.Path)
+    }
+    return value, nil
+}
+
+// VaultSecretResolver reads fields from a HashiCorp Vault KV secret, for
+// example "vault://secret/data/echo#api_key". Both KV v1 and v2 response
+// shapes are understood.
+type VaultSecretResolver struct {
+    Address string
+    Token string
+    HTTPClient *http.Client
+}
+
+func (v VaultSecretResolver) ResolveSecret(ctx context.Context, ref SecretRef) (string, error) {
+    if ref.Field == "" {
+        return "", fmt.Errorf("vault reference %s has no #field", ref)
+    }
+    address := v.Address
+    if address == "" {
+        address = os.Getenv("VAULT_ADDR")
+    }
+    token := v.Token
+    if token == "" {
+        token = os.Getenv("VAULT_TOKEN")
+    }
+    httpClient := v.HTTPClient
+    if httpClient == nil {
+        httpClient = http.DefaultClient
+    }
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(address, "/")+"/v1/"+strings.TrimLeft(ref.Path, "/"), nil)
+    if err != nil {
+        return "", err
+    }
+    req.Header.Set("X-Vault-Token", token)
+    resp, err := httpClient.Do(req)
+    if err != nil {
+        return "", err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode >= 400 {
+        return "", fmt.Errorf("vault request failed with status %d", resp.StatusCode)
+    }
+    var payload struct {
+        Data map[string]any `json:"data"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+        return "", err
+    }
+    fields := payload.Data
+    if nested, ok := fields["data"].(map[string]any); ok {
+        fields = nested
+    }
+    value, ok := fields[ref.Field]
+    if !ok {
+        return "", fmt.Errorf("vault secret %s has no field %s", ref.Path, ref.Field)
+    }
+    return fmt.Sprint(value), nil
+}
+
+// KeychainSecretResolver reads generic passwords from the macOS keychain via
+// the security tool: "keychain://service#account".
+type KeychainSecretResolver struct{}
+
+func (KeychainSecretResolver) ResolveSecret(ctx context.Context, ref SecretRef) (string, error) {
+    args := []string{"find-generic-password", "-w", "-s", ref.Path}
+    if ref.Field != "" {
+        args = append(args, "-a", ref.Field)
+    }
+    out, err := exec.CommandContext(ctx, "security", args...).Output()
+    if err != nil {
+        return "", fmt.Errorf("keychain lookup %s: %w", ref, err)
+    }
+    return strings.TrimRight(string(out), "\n"), nil
+}
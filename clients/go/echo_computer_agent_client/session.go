@@ -0,0 +1,284 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "strconv"
+    "sync"
+    "time"
+
+    "echo_computer_agent_client/internal/wsconn"
+)
+
+var ErrSessionClosed = errors.New("session closed")
+
+// SessionMessage is a message pushed by the agent over a Session. ID
+// names the request it answers, and is empty for server-initiated pushes.
+// Type "response" carries Response; "function_result" carries a result the
+// agent pushes on its own, e.g. from a job it finished, in Function and
+// Response; stream event kinds carry Event; "error" carries Error.
+type SessionMessage struct {
+    Type string
+    ID string
+    Function string
+    Response *ChatResponse
+    Event StreamEvent
+    Error string
+    Raw json.RawMessage
+}
+
+type sessionFrame struct {
+    Type string `json:"type"`
+    ID string `json:"id,omitempty"`
+    Function string `json:"function,omitempty"`
+    Message string `json:"message,omitempty"`
+    Inputs map[string]any `json:"inputs,omitempty"`
+    Execute *bool `json:"execute,omitempty"`
+    Response *ChatResponse `json:"response,omitempty"`
+    Error string `json:"error,omitempty"`
+}
+
+// Session is a WebSocket connection to the agent's /session endpoint
+// carrying interleaved requests and pushes. Replies to Chat are routed to
+// their caller; everything else, including stream events for in-flight
+// requests, arrives on Messages.
+type Session struct {
+    client *Client
+    conn *wsconn.Conn
+    ctx context.Context
+    cancel context.CancelFunc
+    messages chan SessionMessage
+    done chan struct{}
+
+    mu sync.Mutex
+    nextID int
+    pending map[string]chan SessionMessage
+    inflight sync.WaitGroup
+    closing bool
+    err error
+}
+
+// OpenSession dials the agent's session WebSocket. hb configures its
+// heartbeat; a session found dead reports the failure through Err and is
+// not reconnected, since in-flight requests cannot be resumed.
+func (c *Client) OpenSession(ctx context.Context, hb Heartbeat) (*Session, error) {
+    conn, err := c.dialWebSocket(ctx, "/session")
+    if err != nil {
+        return nil, err
+    }
+    sessionCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+    s := &Session{
+        client: c,
+        conn: conn,
+        ctx: sessionCtx,
+        cancel: cancel,
+        messages: make(chan SessionMessage, 64),
+        done: make(chan struct{}),
+        pending: map[string]chan SessionMessage{},
+    }
+    touch := keepAlive(sessionCtx, conn, hb)
+    go s.read(touch)
+    return s, nil
+}
+
+// Messages delivers pushes and stream events. It is closed when the
+// session ends. Consumers must drain it; a full channel holds up replies.
+func (s *Session) Messages() <-chan SessionMessage {
+    return s.messages
+}
+
+// Done is closed when the session has ended; Err then says why.
+func (s *Session) Done() <-chan struct{} {
+    return s.done
+}
+
+func (s *Session) Err() error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.err
+}
+
+func (s *Session) read(touch func()) {
+    var err error
+    defer func() {
+        s.mu.Lock()
+        if s.closing {
+            err = ErrSessionClosed
+        }
+        s.err = err
+        for id, waiter := range s.pending {
+            close(waiter)
+            delete(s.pending, id)
+        }
+        s.mu.Unlock()
+        s.cancel()
+        close(s.messages)
+        close(s.done)
+    }()
+    for {
+        var raw []byte
+        if _, raw, err = s.conn.ReadMessage(); err != nil {
+            return
+        }
+        touch()
+        var frame sessionFrame
+        if json.Unmarshal(raw, &frame) != nil {
+            continue
+        }
+        message := SessionMessage{Type: frame.Type, ID: frame.ID, Function: frame.Function, Response: frame.Response, Error: frame.Error, Raw: raw}
+        if event, err := ParseStreamEvent(ServerSentEvent{Event: frame.Type, Data: raw}); err == nil {
+            message.Event = event
+        }
+        if frame.Type == "response" || frame.Type == "error" {
+            s.mu.Lock()
+            waiter, ok := s.pending[frame.ID]
+            delete(s.pending, frame.ID)
+            s.mu.Unlock()
+            if ok {
+                waiter <- message
+                continue
+            }
+        }
+        select {
+        case s.messages <- message:
+        case <-s.ctx.Done():
+            // Close gave up on the consumer.
+            return
+        }
+    }
+}
+
+// Send writes request without waiting for its reply, returning the ID its
+// reply and events will carry. Executing requests are not checked against
+// the client's guard or policy; use Chat for that.
+func (s *Session) Send(request ChatRequest) (string, error) {
+    return s.send(request, nil)
+}
+
+func (s *Session) send(request ChatRequest, waiter chan SessionMessage) (string, error) {
+    s.mu.Lock()
+    if s.closing || s.err != nil || isClosed(s.done) {
+        s.mu.Unlock()
+        return "", ErrSessionClosed
+    }
+    s.nextID++
+    id := strconv.Itoa(s.nextID)
+    if waiter != nil {
+        s.pending[id] = waiter
+    }
+    s.mu.Unlock()
+
+    encoded, err := json.Marshal(sessionFrame{Type: "chat", ID: id, Message: request.Message, Inputs: request.Inputs, Execute: request.Execute})
+    if err == nil {
+        err = s.conn.WriteMessage(wsconn.OpText, encoded)
+    }
+    if err != nil {
+        s.mu.Lock()
+        delete(s.pending, id)
+        s.mu.Unlock()
+        return "", err
+    }
+    return id, nil
+}
+
+// Chat sends request and waits for its reply. Executing requests are
+// planned, authorized, and audited like Client.Chat.
+func (s *Session) Chat(ctx context.Context, request ChatRequest) (*ChatResponse, error) {
+    c := s.client
+    s.mu.Lock()
+    if s.closing {
+        s.mu.Unlock()
+        return nil, ErrSessionClosed
+    }
+    s.inflight.Add(1)
+    s.mu.Unlock()
+    defer s.inflight.Done()
+    request = c.withDefaultExecute(request)
+    var function string
+    if executes(request) {
+        var err error
+        if function, err = c.preflight(ctx, request); err != nil {
+            return nil, err
+        }
+    }
+    wire := request
+    if err := c.prepare(ctx, &wire); err != nil {
+        return nil, err
+    }
+    waiter := make(chan SessionMessage, 1)
+    start := time.Now()
+    id, err := s.send(wire, waiter)
+    var resp *ChatResponse
+    if err == nil {
+        resp, err = s.await(ctx, id, waiter)
+    }
+    if err == nil {
+        err = c.filterResponse(ctx, resp)
+    }
+    if executes(request) {
+        if err == nil {
+            c.observeLatency(resp.Function, time.Since(start))
+        }
+        c.audit(ctx, function, request, resp, err)
+    }
+    if err != nil {
+        return nil, err
+    }
+    return resp, nil
+}
+
+func (s *Session) await(ctx context.Context, id string, waiter chan SessionMessage) (*ChatResponse, error) {
+    select {
+    case <-ctx.Done():
+        s.mu.Lock()
+        delete(s.pending, id)
+        s.mu.Unlock()
+        return nil, ctx.Err()
+    case message, ok := <-waiter:
+        if !ok {
+            if err := s.Err(); err != nil {
+                return nil, err
+            }
+            return nil, ErrSessionClosed
+        }
+        if message.Type == "error" {
+            return nil, &ErrorEvent{Message: message.Error}
+        }
+        if message.Response == nil {
+            return &ChatResponse{}, nil
+        }
+        return message.Response, nil
+    }
+}
+
+// Close stops new sends, waits for in-flight Chat calls until ctx is done,
+// then closes the connection with a normal close frame.
+func (s *Session) Close(ctx context.Context) error {
+    s.mu.Lock()
+    s.closing = true
+    s.mu.Unlock()
+    drained := make(chan struct{})
+    go func() {
+        s.inflight.Wait()
+        close(drained)
+    }()
+    select {
+    case <-drained:
+    case <-ctx.Done():
+    case <-s.done:
+    }
+    s.cancel()
+    err := s.conn.Close(wsconn.CloseNormal, "")
+    <-s.done
+    return err
+}
+
+func isClosed(ch chan struct{}) bool {
+    select {
+    case <-ch:
+        return true
+    default:
+        return false
+    }
+}
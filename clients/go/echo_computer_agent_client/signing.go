@@ -0,0 +1,199 @@
+package echo_computer_agent_client
+
+import (
+    "bytes"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+const (
+    HeaderSignature = "X-Echo-Signature"
+
+    // DefaultSignatureTolerance is how far a signed request's timestamp
+    // may be from the agent's clock before VerifyRequest rejects it.
+    DefaultSignatureTolerance = 5 * time.Minute
+)
+
+// clockDrift is how far the agent's Date may be from the local clock
+// before a rejected signed request is put down to skew. Date has a
+// resolution of a second, and is stamped some latency after the request.
+const clockDrift = 5 * time.Second
+
+var ErrInvalidRequestSignature = errors.New("invalid request signature")
+
+// Signer signs the message SigningMessage builds for a request, returning
+// the hex-encoded signature. HMACSigner covers shared-key deployments;
+// implement Signer to keep the key in a KMS or HSM instead.
+type Signer interface {
+    Sign(message []byte) (string, error)
+}
+
+type SignerFunc func(message []byte) (string, error)
+
+func (f SignerFunc) Sign(message []byte) (string, error) { return f(message) }
+
+// HMACSigner signs with HMAC-SHA256 under key.
+func HMACSigner(key []byte) Signer {
+    return SignerFunc(func(message []byte) (string, error) {
+        return hmacHex(key, message), nil
+    })
+}
+
+func hmacHex(key, message []byte) string {
+    mac := hmac.New(sha256.New, key)
+    mac.Write(message)
+    return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SigningMessage is what a request's signature covers:
+// "<unix seconds>.<METHOD>.<path and query>.<body>".
+func SigningMessage(method, path string, t time.Time, body []byte) []byte {
+    var b bytes.Buffer
+    b.WriteString(strconv.FormatInt(t.Unix(), 10))
+    b.WriteByte('.')
+    b.WriteString(strings.ToUpper(method))
+    b.WriteByte('.')
+    b.WriteString(path)
+    b.WriteByte('.')
+    b.Write(body)
+    return b.Bytes()
+}
+
+// signingState is the client's signer and its estimate of how far the
+// agent's clock is from the local one.
+type signingState struct {
+    sync.Mutex
+    signer Signer
+    offset time.Duration
+}
+
+// SetRequestSigner signs every request with signer, sending
+// "t=<unix seconds>,v1=<signature>" in X-Echo-Signature, the same form
+// as webhook deliveries. Bodies are buffered to be signed. When the agent
+// rejects a request with a 401 and its Date header shows the clocks have
+// drifted apart, the client adopts the agent's clock for timestamps and
+// sends the request once more.
+func (c *Client) SetRequestSigner(signer Signer) {
+    c.signing.Lock()
+    c.signing.signer, c.signing.offset = signer, 0
+    c.signing.Unlock()
+}
+
+func WithRequestSigner(signer Signer) Option {
+    return func(c *Client) { c.SetRequestSigner(signer) }
+}
+
+// sign sets req's signature header, if the client signs requests.
+func (c *Client) sign(req *http.Request) error {
+    c.signing.Lock()
+    signer, offset := c.signing.signer, c.signing.offset
+    c.signing.Unlock()
+    if signer == nil {
+        return nil
+    }
+    body, err := replayableBody(req)
+    if err != nil {
+        return &AuthError{Err: fmt.Errorf("read body to sign: %w", err)}
+    }
+    t := time.Now().Add(offset)
+    signature, err := signer.Sign(SigningMessage(req.Method, req.URL.RequestURI(), t, body))
+    if err != nil {
+        return &AuthError{Err: fmt.Errorf("sign request: %w", err)}
+    }
+    req.Header.Set(HeaderSignature, "t="+strconv.FormatInt(t.Unix(), 10)+",v1="+signature)
+    return nil
+}
+
+// replayableBody returns req's body, buffering it so it can still be sent
+// and resent.
+func replayableBody(req *http.Request) ([]byte, error) {
+    if req.Body == nil || req.Body == http.NoBody {
+        return nil, nil
+    }
+    if req.GetBody == nil {
+        raw, err := io.ReadAll(req.Body)
+        req.Body.Close()
+        if err != nil {
+            return nil, err
+        }
+        req.Body = io.NopCloser(bytes.NewReader(raw))
+        req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(raw)), nil }
+        req.ContentLength = int64(len(raw))
+        return raw, nil
+    }
+    body, err := req.GetBody()
+    if err != nil {
+        return nil, err
+    }
+    defer body.Close()
+    return io.ReadAll(body)
+}
+
+// skewed reports whether a signed request was rejected because the
+// clocks disagree, correcting the client's offset if so.
+func (c *Client) skewed(req *http.Request, resp *http.Response) bool {
+    if resp.StatusCode != http.StatusUnauthorized || req.Header.Get(HeaderSignature) == "" {
+        return false
+    }
+    agent, err := http.ParseTime(resp.Header.Get("Date"))
+    if err != nil {
+        return false
+    }
+    c.signing.Lock()
+    defer c.signing.Unlock()
+    offset := time.Until(agent)
+    if drift := offset - c.signing.offset; drift > -clockDrift && drift < clockDrift {
+        return false
+    }
+    c.signing.offset = offset
+    return true
+}
+
+// VerifyRequest checks r's X-Echo-Signature with HMAC key, for agents and
+// proxies that accept signed calls. Timestamps further than tolerance
+// from now are rejected, so captured requests cannot be replayed later.
+// The body is read and restored for the handler.
+func VerifyRequest(r *http.Request, key []byte, now time.Time, tolerance time.Duration) error {
+    var ts string
+    var signatures []string
+    for _, part := range strings.Split(r.Header.Get(HeaderSignature), ",") {
+        name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+        if !ok {
+            continue
+        }
+        switch name {
+        case "t":
+            ts = value
+        case "v1":
+            signatures = append(signatures, value)
+        }
+    }
+    unix, err := strconv.ParseInt(ts, 10, 64)
+    if err != nil || len(signatures) == 0 {
+        return ErrInvalidRequestSignature
+    }
+    t := time.Unix(unix, 0)
+    if skew := now.Sub(t); tolerance > 0 && (skew > tolerance || skew < -tolerance) {
+        return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidRequestSignature)
+    }
+    body, err := replayableBody(r)
+    if err != nil {
+        return err
+    }
+    expected := hmacHex(key, SigningMessage(r.Method, r.URL.RequestURI(), t, body))
+    for _, signature := range signatures {
+        if hmac.Equal([]byte(signature), []byte(expected)) {
+            return nil
+        }
+    }
+    return ErrInvalidRequestSignature
+}
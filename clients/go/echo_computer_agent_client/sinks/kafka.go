@@ -0,0 +1,40 @@
+package sinks
+
+import "context"
+
+// KafkaProducer is the minimal producer surface the Kafka sink needs. Wrap
+// the producer of whichever Kafka library the application already uses
+// (franz-go, sarama, segmentio/kafka-go) to satisfy it.
+type KafkaProducer interface {
+    Produce(ctx context.Context, topic string, key, value []byte, headers map[string]string) error
+    Close() error
+}
+
+type KafkaSink struct {
+    Producer KafkaProducer
+    Topic string
+    Serializer Serializer
+}
+
+func NewKafkaSink(producer KafkaProducer, topic string, serializer Serializer) *KafkaSink {
+    if serializer == nil {
+        serializer = JSON{}
+    }
+    return &KafkaSink{Producer: producer, Topic: topic, Serializer: serializer}
+}
+
+func (s *KafkaSink) Publish(ctx context.Context, record Record) error {
+    value, err := s.Serializer.Marshal(record.Value)
+    if err != nil {
+        return err
+    }
+    headers := map[string]string{"content-type": s.Serializer.ContentType()}
+    for k, v := range record.Headers {
+        headers[k] = v
+    }
+    return s.Producer.Produce(ctx, s.Topic, []byte(record.Key), value, headers)
+}
+
+func (s *KafkaSink) Close() error {
+    return s.Producer.Close()
+}
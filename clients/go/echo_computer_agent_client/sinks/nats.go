@@ -0,0 +1,187 @@
+package sinks
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net"
+    "strings"
+    "sync"
+    "time"
+    "unicode"
+)
+
+var ErrInvalidSubject = errors.New("nats: invalid subject")
+
+// NATSPublisher matches (*nats.Conn).Publish from nats.go, so an existing
+// connection can be passed straight in. DialNATS provides a dependency-free
+// publisher for simple deployments.
+type NATSPublisher interface {
+    Publish(subject string, data []byte) error
+}
+
+// NATSSink publishes to Subject; a "{key}" placeholder in Subject is
+// replaced with the record key, e.g. "echo.results.{key}". Slashes in the
+// key become token separators, and whitespace, control characters and
+// wildcards become "_".
+type NATSSink struct {
+    Publisher NATSPublisher
+    Subject string
+    Serializer Serializer
+}
+
+func NewNATSSink(publisher NATSPublisher, subject string, serializer Serializer) *NATSSink {
+    if serializer == nil {
+        serializer = JSON{}
+    }
+    return &NATSSink{Publisher: publisher, Subject: subject, Serializer: serializer}
+}
+
+func (s *NATSSink) Publish(ctx context.Context, record Record) error {
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+    value, err := s.Serializer.Marshal(record.Value)
+    if err != nil {
+        return err
+    }
+    subject := strings.ReplaceAll(s.Subject, "{key}", subjectTokens(record.Key))
+    if !validSubject(subject) {
+        return fmt.Errorf("%w: %q", ErrInvalidSubject, subject)
+    }
+    return s.Publisher.Publish(subject, value)
+}
+
+func subjectTokens(key string) string {
+    return strings.Map(func(r rune) rune {
+        switch {
+        case r == '/':
+            return '.'
+        case r == '*' || r == '>' || unicode.IsSpace(r) || unicode.IsControl(r):
+            return '_'
+        }
+        return r
+    }, key)
+}
+
+// validSubject reports whether subject is a publishable NATS subject: dot
+// separated, non-empty tokens with no whitespace, control characters or
+// wildcards.
+func validSubject(subject string) bool {
+    for _, token := range strings.Split(subject, ".") {
+        if token == "" || strings.ContainsFunc(token, func(r rune) bool {
+            return r == '*' || r == '>' || unicode.IsSpace(r) || unicode.IsControl(r)
+        }) {
+            return false
+        }
+    }
+    return true
+}
+
+func (s *NATSSink) Close() error {
+    if closer, ok := s.Publisher.(interface{ Close() error }); ok {
+        return closer.Close()
+    }
+    return nil
+}
+
+// NATSConn is a publish-only NATS protocol connection. The server reports
+// failures asynchronously with -ERR; the next Publish returns them, and
+// every Publish fails once the connection is lost.
+type NATSConn struct {
+    mu sync.Mutex
+    conn net.Conn
+    w *bufio.Writer
+    err error
+    closed error
+}
+
+// DialNATS connects to a NATS server at addr ("host:4222"). Credentials,
+// when needed, are passed as user and pass.
+func DialNATS(ctx context.Context, addr, user, pass string) (*NATSConn, error) {
+    var dialer net.Dialer
+    conn, err := dialer.DialContext(ctx, "tcp", addr)
+    if err != nil {
+        return nil, err
+    }
+    conn.SetDeadline(time.Now().Add(10 * time.Second))
+    r := bufio.NewReader(conn)
+    info, err := r.ReadString('\n')
+    if err != nil || !strings.HasPrefix(info, "INFO ") {
+        conn.Close()
+        return nil, fmt.Errorf("nats: unexpected greeting %q", strings.TrimSpace(info))
+    }
+    options := map[string]any{"verbose": false, "pedantic": false, "name": "echo-agent-sink", "lang": "go", "version": "1.0.0"}
+    if user != "" {
+        options["user"] = user
+        options["pass"] = pass
+    }
+    connect, _ := json.Marshal(options)
+    w := bufio.NewWriter(conn)
+    fmt.Fprintf(w, "CONNECT %s\r\nPING\r\n", connect)
+    if err := w.Flush(); err != nil {
+        conn.Close()
+        return nil, err
+    }
+    reply, err := r.ReadString('\n')
+    if err != nil || strings.TrimSpace(reply) != "PONG" {
+        conn.Close()
+        return nil, fmt.Errorf("nats: connect rejected: %s", strings.TrimSpace(reply))
+    }
+    conn.SetDeadline(time.Time{})
+    nc := &NATSConn{conn: conn, w: w}
+    // Answer server keepalive pings so the connection is not dropped, and
+    // keep the server's errors for Publish.
+    go func() {
+        for {
+            line, err := r.ReadString('\n')
+            if err != nil {
+                nc.mu.Lock()
+                nc.closed = fmt.Errorf("nats: connection lost: %w", err)
+                nc.mu.Unlock()
+                return
+            }
+            line = strings.TrimSpace(line)
+            switch {
+            case line == "PING":
+                nc.mu.Lock()
+                nc.w.WriteString("PONG\r\n")
+                nc.w.Flush()
+                nc.mu.Unlock()
+            case strings.HasPrefix(line, "-ERR"):
+                nc.mu.Lock()
+                nc.err = fmt.Errorf("nats: server error: %s", strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")), "'"))
+                nc.mu.Unlock()
+            }
+        }
+    }()
+    return nc, nil
+}
+
+func (c *NATSConn) Publish(subject string, data []byte) error {
+    if !validSubject(subject) {
+        return fmt.Errorf("%w: %q", ErrInvalidSubject, subject)
+    }
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    if c.closed != nil {
+        return c.closed
+    }
+    if err := c.err; err != nil {
+        c.err = nil
+        return err
+    }
+    fmt.Fprintf(c.w, "PUB %s %d\r\n", subject, len(data))
+    c.w.Write(data)
+    c.w.WriteString("\r\n")
+    return c.w.Flush()
+}
+
+func (c *NATSConn) Close() error {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.w.Flush()
+    return c.conn.Close()
+}
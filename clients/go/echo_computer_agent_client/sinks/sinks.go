@@ -0,0 +1,77 @@
+// Package sinks publishes agent output (bulk results, webhook events) to
+// streaming platforms such as Kafka and NATS.
+package sinks
+
+import (
+    "context"
+    "encoding/json"
+    "strconv"
+
+    client "echo_computer_agent_client"
+    "echo_computer_agent_client/webhooks"
+)
+
+type Record struct {
+    Key string
+    Value any
+    Headers map[string]string
+}
+
+type Sink interface {
+    Publish(ctx context.Context, record Record) error
+    Close() error
+}
+
+type Serializer interface {
+    ContentType() string
+    Marshal(v any) ([]byte, error)
+}
+
+// JSON serialises record values as compact JSON.
+type JSON struct{}
+
+func (JSON) ContentType() string { return "application/json" }
+
+func (JSON) Marshal(v any) ([]byte, error) { return json.Marshal(v) }
+
+// SerializerFunc adapts a plain function, e.g. a protobuf or Avro encoder.
+type SerializerFunc struct {
+    Type string
+    Fn func(v any) ([]byte, error)
+}
+
+func (s SerializerFunc) ContentType() string { return s.Type }
+
+func (s SerializerFunc) Marshal(v any) ([]byte, error) { return s.Fn(v) }
+
+// PublishBulk publishes every BulkInvoke result keyed by "<function>/<index>".
+// It stops at the first publish error.
+func PublishBulk(ctx context.Context, sink Sink, results []client.BulkResult) error {
+    for _, result := range results {
+        record := Record{
+            Key: result.Function + "/" + strconv.Itoa(result.Index),
+            Value: result,
+            Headers: map[string]string{"echo-function": result.Function},
+        }
+        if result.Err != nil {
+            record.Headers["echo-error"] = "true"
+        }
+        if err := sink.Publish(ctx, record); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// EventHandler forwards webhook events to sink, suitable as the callback of
+// webhooks.NewHandler. A publish failure makes the webhook fail, so the
+// agent redelivers the event.
+func EventHandler(sink Sink) func(webhooks.Event) error {
+    return func(event webhooks.Event) error {
+        return sink.Publish(context.Background(), Record{
+            Key: event.ID,
+            Value: event,
+            Headers: map[string]string{"echo-event-type": event.Type},
+        })
+    }
+}
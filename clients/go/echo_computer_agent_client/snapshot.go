@@ -0,0 +1,235 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "reflect"
+    "sort"
+    "strings"
+    "time"
+)
+
+var (
+    ErrSnapshotSignature = errors.New("environment snapshot signature does not match")
+    ErrEnvironmentChanged = errors.New("agent environment differs from snapshot")
+)
+
+// EnvironmentSnapshot pins down the agent a run was recorded against: its
+// version, API version, features, and full function catalog. Digest covers
+// those, not the time or base URL, so a snapshot from staging matches an
+// identical production agent. Signature is an HMAC-SHA256 of the digest,
+// present once Sign is called.
+type EnvironmentSnapshot struct {
+    Taken time.Time `json:"taken"`
+    BaseURL string `json:"base_url"`
+    ServerVersion string `json:"server_version,omitempty"`
+    APIVersion string `json:"api_version,omitempty"`
+    Features []string `json:"features"`
+    Functions []FunctionDescription `json:"functions"`
+    Digest string `json:"digest"`
+    Signature string `json:"signature,omitempty"`
+}
+
+// EnvironmentChangedError lists how a live agent differs from a snapshot.
+// It matches ErrEnvironmentChanged.
+type EnvironmentChangedError struct {
+    Differences []string
+}
+
+func (e *EnvironmentChangedError) Error() string {
+    return fmt.Sprintf("%v:\n  %s", ErrEnvironmentChanged, strings.Join(e.Differences, "\n  "))
+}
+
+func (e *EnvironmentChangedError) Unwrap() error { return ErrEnvironmentChanged }
+
+// SnapshotEnvironment captures the agent as it is now. The catalog is
+// listed afresh rather than taken from the Functions cache.
+func (c *Client) SnapshotEnvironment(ctx context.Context) (*EnvironmentSnapshot, error) {
+    list, err := c.ListFunctions(ctx)
+    if err != nil {
+        return nil, err
+    }
+    caps, err := c.Capabilities(ctx)
+    if err != nil {
+        return nil, err
+    }
+    s := &EnvironmentSnapshot{
+        Taken: time.Now().UTC(),
+        BaseURL: c.baseURL,
+        ServerVersion: caps.Version,
+        APIVersion: c.NegotiatedAPIVersion(),
+        Features: append([]string{}, caps.Features...),
+        Functions: NewFunctions(list.Functions),
+    }
+    // /health reports the build when capabilities do not; agents without it
+    // are snapshotted without a version.
+    if health, err := c.Health(ctx); err == nil && health.Version != "" {
+        s.ServerVersion = health.Version
+    }
+    sort.Strings(s.Features)
+    if s.Digest, err = s.digest(); err != nil {
+        return nil, err
+    }
+    return s, nil
+}
+
+func (s *EnvironmentSnapshot) digest() (string, error) {
+    encoded, err := json.Marshal(struct {
+        ServerVersion string `json:"server_version"`
+        APIVersion string `json:"api_version"`
+        Features []string `json:"features"`
+        Functions []FunctionDescription `json:"functions"`
+    }{s.ServerVersion, s.APIVersion, s.Features, s.Functions})
+    if err != nil {
+        return "", err
+    }
+    sum := sha256.Sum256(encoded)
+    return hex.EncodeToString(sum[:]), nil
+}
+
+func (s *EnvironmentSnapshot) Sign(key []byte) {
+    s.Signature = snapshotMAC(key, s.Digest)
+}
+
+// Verify checks that the snapshot is unaltered: its digest matches its
+// content and, with a key, its signature matches the digest.
+func (s *EnvironmentSnapshot) Verify(key []byte) error {
+    digest, err := s.digest()
+    if err != nil {
+        return err
+    }
+    if digest != s.Digest {
+        return fmt.Errorf("%w: content does not match digest", ErrSnapshotSignature)
+    }
+    if key != nil && !hmac.Equal([]byte(s.Signature), []byte(snapshotMAC(key, digest))) {
+        return ErrSnapshotSignature
+    }
+    return nil
+}
+
+func snapshotMAC(key []byte, digest string) string {
+    mac := hmac.New(sha256.New, key)
+    mac.Write([]byte(digest))
+    return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *EnvironmentSnapshot) Save(path string) error {
+    encoded, err := json.MarshalIndent(s, "", "  ")
+    if err != nil {
+        return err
+    }
+    return os.WriteFile(path, append(encoded, '\n'), 0o644)
+}
+
+// LoadSnapshot reads a snapshot saved by Save and verifies it with key;
+// see Verify.
+func LoadSnapshot(path string, key []byte) (*EnvironmentSnapshot, error) {
+    raw, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    var s EnvironmentSnapshot
+    if err := json.Unmarshal(raw, &s); err != nil {
+        return nil, fmt.Errorf("parse snapshot %s: %w", path, err)
+    }
+    if err := s.Verify(key); err != nil {
+        return nil, fmt.Errorf("snapshot %s: %w", path, err)
+    }
+    return &s, nil
+}
+
+// Diff describes how other differs from s, one line per difference.
+func (s *EnvironmentSnapshot) Diff(other *EnvironmentSnapshot) []string {
+    var diffs []string
+    if s.ServerVersion != other.ServerVersion {
+        diffs = append(diffs, fmt.Sprintf("server version %q, snapshot has %q", other.ServerVersion, s.ServerVersion))
+    }
+    if s.APIVersion != other.APIVersion {
+        diffs = append(diffs, fmt.Sprintf("API version %q, snapshot has %q", other.APIVersion, s.APIVersion))
+    }
+    added, removed := setDiff(s.Features, other.Features)
+    for _, f := range added {
+        diffs = append(diffs, "feature "+f+" added")
+    }
+    for _, f := range removed {
+        diffs = append(diffs, "feature "+f+" removed")
+    }
+    was, now := Functions(s.Functions), Functions(other.Functions)
+    added, removed = setDiff(was.Names(), now.Names())
+    for _, name := range added {
+        diffs = append(diffs, "function "+name+" added")
+    }
+    for _, name := range removed {
+        diffs = append(diffs, "function "+name+" removed")
+    }
+    for _, fn := range was {
+        live, ok := now.ByName(fn.Name)
+        if !ok {
+            continue
+        }
+        switch {
+        case !jsonEqual(fn.Parameters, live.Parameters):
+            diffs = append(diffs, "function "+fn.Name+" parameters changed")
+        case fn.Description != live.Description:
+            diffs = append(diffs, "function "+fn.Name+" description changed")
+        case !jsonEqual(fn.Metadata, live.Metadata):
+            diffs = append(diffs, "function "+fn.Name+" metadata changed")
+        }
+    }
+    return diffs
+}
+
+// VerifyEnvironment snapshots the live agent and fails with an
+// *EnvironmentChangedError unless it matches want, for runs that must not
+// proceed against a different agent than they were recorded with.
+func (c *Client) VerifyEnvironment(ctx context.Context, want *EnvironmentSnapshot) error {
+    live, err := c.SnapshotEnvironment(ctx)
+    if err != nil {
+        return err
+    }
+    if live.Digest == want.Digest {
+        return nil
+    }
+    diffs := want.Diff(live)
+    if len(diffs) == 0 {
+        diffs = []string{"digest " + live.Digest + ", snapshot has " + want.Digest}
+    }
+    return &EnvironmentChangedError{Differences: diffs}
+}
+
+// setDiff returns the sorted members of b missing from a, and of a
+// missing from b.
+func setDiff(a, b []string) (added, removed []string) {
+    inA, inB := map[string]bool{}, map[string]bool{}
+    for _, v := range a {
+        inA[v] = true
+    }
+    for _, v := range b {
+        inB[v] = true
+        if !inA[v] {
+            added = append(added, v)
+        }
+    }
+    for _, v := range a {
+        if !inB[v] {
+            removed = append(removed, v)
+        }
+    }
+    sort.Strings(added)
+    sort.Strings(removed)
+    return added, removed
+}
+
+// jsonEqual compares values as their JSON, so a decoded snapshot compares
+// equal to the live catalog it was taken from.
+func jsonEqual(a, b any) bool {
+    x, errA := viaJSON(a)
+    y, errB := viaJSON(b)
+    return errA == nil && errB == nil && reflect.DeepEqual(x, y)
+}
@@ -0,0 +1,163 @@
+package echo_computer_agent_client
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "io"
+    "net/http"
+    "time"
+
+    "echo_computer_agent_client/internal/sse"
+)
+
+// ErrStreamDone may be returned by a stream handler to end the stream
+// normally.
+var ErrStreamDone = errors.New("stream done")
+
+type ServerSentEvent struct {
+    ID string
+    Event string
+    Data json.RawMessage
+}
+
+// StreamOptions tunes StreamEvents. A stream that stays silent, including
+// ": keepalive" comments, for IdleTimeout is treated as dead; dropped
+// streams are resumed by reconnecting with Last-Event-ID, waiting the
+// server's retry hint or MinReconnectDelay (doubling up to
+// MaxReconnectDelay). MaxReconnects < 0 disables reconnection.
+type StreamOptions struct {
+    IdleTimeout time.Duration
+    MinReconnectDelay time.Duration
+    MaxReconnectDelay time.Duration
+    MaxReconnects int
+    LastEventID string
+}
+
+// StreamEvents consumes a text/event-stream endpoint, calling handle for
+// each event until handle returns an error (ErrStreamDone ends the stream
+// cleanly) or ctx is done. body, when non-nil, is re-sent as JSON on every
+// reconnection so the server can resume from Last-Event-ID.
+func (c *Client) StreamEvents(ctx context.Context, method, path string, body any, opts StreamOptions, handle func(ServerSentEvent) error) error {
+    if opts.IdleTimeout <= 0 {
+        opts.IdleTimeout = time.Minute
+    }
+    if opts.MinReconnectDelay <= 0 {
+        opts.MinReconnectDelay = time.Second
+    }
+    if opts.MaxReconnectDelay <= 0 {
+        opts.MaxReconnectDelay = 30 * time.Second
+    }
+    var encoded []byte
+    if body != nil {
+        var err error
+        if encoded, err = c.encodeVersioned(path, body); err != nil {
+            return err
+        }
+    }
+    ctx, cancel := c.callContext(ctx, 0)
+    defer cancel()
+    lastID := opts.LastEventID
+    delay := opts.MinReconnectDelay
+    for attempt := 0; ; attempt++ {
+        retry, progressed, err := c.streamOnce(ctx, method, path, encoded, &lastID, opts.IdleTimeout, handle)
+        if errors.Is(err, ErrStreamDone) {
+            return nil
+        }
+        if ctx.Err() != nil {
+            return ctx.Err()
+        }
+        var apiErr *APIError
+        if errors.As(err, &apiErr) && apiErr.Status < 500 && apiErr.Status != http.StatusTooManyRequests {
+            return err
+        }
+        var handler *handlerError
+        if errors.As(err, &handler) {
+            return handler.err
+        }
+        if opts.MaxReconnects < 0 || (opts.MaxReconnects > 0 && attempt >= opts.MaxReconnects) {
+            if err == nil {
+                err = io.ErrUnexpectedEOF
+            }
+            return err
+        }
+        if progressed {
+            delay, attempt = opts.MinReconnectDelay, 0
+        }
+        wait := delay
+        if retry > 0 {
+            wait = retry
+        }
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-time.After(wait):
+        }
+        if delay *= 2; delay > opts.MaxReconnectDelay {
+            delay = opts.MaxReconnectDelay
+        }
+    }
+}
+
+// handlerError separates a handler's own failure from a dropped stream.
+type handlerError struct {
+    err error
+}
+
+func (e *handlerError) Error() string { return e.err.Error() }
+func (e *handlerError) Unwrap() error { return e.err }
+
+func (c *Client) streamOnce(ctx context.Context, method, path string, body []byte, lastID *string, idle time.Duration, handle func(ServerSentEvent) error) (time.Duration, bool, error) {
+    streamCtx, cancel := context.WithCancel(ctx)
+    defer cancel()
+    var reader io.Reader
+    if body != nil {
+        reader = bytes.NewReader(body)
+    }
+    req, err := http.NewRequestWithContext(streamCtx, method, c.baseURL+path, reader)
+    if err != nil {
+        return 0, false, err
+    }
+    if body != nil {
+        req.Header.Set("Content-Type", "application/json")
+    }
+    c.decorate(ctx, req)
+    req.Header.Set("Accept", "text/event-stream")
+    req.Header.Set("Cache-Control", "no-cache")
+    if *lastID != "" {
+        req.Header.Set("Last-Event-ID", *lastID)
+    }
+    resp, err := c.do(req)
+    if err != nil {
+        return 0, false, err
+    }
+    defer resp.Body.Close()
+    c.observeVersion(resp)
+    c.observeDeprecation(path, resp.Header)
+    if resp.StatusCode >= 400 {
+        return 0, false, c.apiError(req, resp)
+    }
+
+    // The idle timer cancels the request, which unblocks the body read.
+    timer := time.AfterFunc(idle, cancel)
+    defer timer.Stop()
+    events := sse.NewReader(resp.Body)
+    events.LastID = *lastID
+    events.OnActivity = func() { timer.Reset(idle) }
+    progressed := false
+    for {
+        event, err := events.Next()
+        *lastID = events.LastID
+        if err != nil {
+            if err == io.EOF {
+                err = nil
+            }
+            return events.Retry, progressed, err
+        }
+        progressed = true
+        if err := handle(ServerSentEvent{ID: event.ID, Event: event.Type, Data: event.Data}); err != nil {
+            return events.Retry, progressed, &handlerError{err}
+        }
+    }
+}
@@ -0,0 +1,181 @@
+package echo_computer_agent_client
+
+import (
+    "encoding/json"
+    "fmt"
+)
+
+// StreamEvent is one decoded event of a chat stream: a TextDelta,
+// FunctionSelected, ToolCallRequested, UsageReport, Progress, Done, or
+// ErrorEvent.
+// The interface is sealed; handle events with a type switch, a
+// StreamHandlers, or VisitStreamEvent for a compile-time exhaustive match.
+type StreamEvent interface {
+    streamEvent()
+}
+
+// TextDelta is the next fragment of the agent's reply.
+type TextDelta struct {
+    Text string `json:"text"`
+}
+
+// FunctionSelected reports the function the agent routed the request to.
+type FunctionSelected struct {
+    Function string `json:"function"`
+    Inputs map[string]any `json:"inputs,omitempty"`
+}
+
+// ToolCallRequested asks the caller to run a tool and report back.
+type ToolCallRequested struct {
+    ID string `json:"id"`
+    Name string `json:"name"`
+    Arguments map[string]any `json:"arguments,omitempty"`
+}
+
+type UsageReport struct {
+    PromptTokens int `json:"prompt_tokens"`
+    CompletionTokens int `json:"completion_tokens"`
+    TotalTokens int `json:"total_tokens"`
+}
+
+// Done ends the stream with the assembled response, when the agent sends
+// one.
+type Done struct {
+    Response *ChatResponse
+}
+
+// ErrorEvent is an error the agent reported in-band. It implements error.
+type ErrorEvent struct {
+    Code string `json:"code,omitempty"`
+    Message string `json:"message"`
+}
+
+func (e *ErrorEvent) Error() string {
+    if e.Code == "" {
+        return "stream error: " + e.Message
+    }
+    return fmt.Sprintf("stream error %s: %s", e.Code, e.Message)
+}
+
+func (*TextDelta) streamEvent() {}
+func (*FunctionSelected) streamEvent() {}
+func (*ToolCallRequested) streamEvent() {}
+func (*UsageReport) streamEvent() {}
+func (*Done) streamEvent() {}
+func (*ErrorEvent) streamEvent() {}
+
+// ParseStreamEvent decodes a server-sent event. The kind comes from the
+// SSE event name, or from a "type" field in the data for unnamed events.
+// Kinds this client does not know yield a nil event and no error, so newer
+// agents can add events without breaking older consumers.
+func ParseStreamEvent(frame ServerSentEvent) (StreamEvent, error) {
+    kind := frame.Event
+    if kind == "" || kind == "message" {
+        var envelope struct {
+            Type string `json:"type"`
+        }
+        json.Unmarshal(frame.Data, &envelope)
+        kind = envelope.Type
+    }
+    var event StreamEvent
+    switch kind {
+    case "text_delta", "delta":
+        event = &TextDelta{}
+    case "function_selected":
+        event = &FunctionSelected{}
+    case "tool_call":
+        event = &ToolCallRequested{}
+    case "usage":
+        event = &UsageReport{}
+    case "progress":
+        event = &Progress{}
+    case "done":
+        done := &Done{}
+        if len(frame.Data) > 0 && string(frame.Data) != "null" && string(frame.Data) != "[DONE]" {
+            done.Response = &ChatResponse{}
+            if err := json.Unmarshal(frame.Data, done.Response); err != nil {
+                return nil, fmt.Errorf("decode done event: %w", err)
+            }
+        }
+        return done, nil
+    case "error":
+        event = &ErrorEvent{}
+    default:
+        return nil, nil
+    }
+    if len(frame.Data) > 0 {
+        if err := json.Unmarshal(frame.Data, event); err != nil {
+            return nil, fmt.Errorf("decode %s event: %w", kind, err)
+        }
+    }
+    if usage, ok := event.(*UsageReport); ok && usage.TotalTokens == 0 {
+        usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+    }
+    return event, nil
+}
+
+// StreamEventVisitor has one method per event kind, so implementations
+// fail to compile when a kind is added.
+type StreamEventVisitor interface {
+    TextDelta(*TextDelta) error
+    FunctionSelected(*FunctionSelected) error
+    ToolCallRequested(*ToolCallRequested) error
+    UsageReport(*UsageReport) error
+    Progress(*Progress) error
+    Done(*Done) error
+    ErrorEvent(*ErrorEvent) error
+}
+
+func VisitStreamEvent(event StreamEvent, v StreamEventVisitor) error {
+    switch e := event.(type) {
+    case *TextDelta:
+        return v.TextDelta(e)
+    case *FunctionSelected:
+        return v.FunctionSelected(e)
+    case *ToolCallRequested:
+        return v.ToolCallRequested(e)
+    case *UsageReport:
+        return v.UsageReport(e)
+    case *Progress:
+        return v.Progress(e)
+    case *Done:
+        return v.Done(e)
+    case *ErrorEvent:
+        return v.ErrorEvent(e)
+    }
+    return nil
+}
+
+// StreamHandlers is a StreamEventVisitor built from optional funcs. Kinds
+// without a handler are ignored, except ErrorEvent, which is returned as
+// the error when OnError is nil.
+type StreamHandlers struct {
+    OnText func(*TextDelta) error
+    OnFunctionSelected func(*FunctionSelected) error
+    OnToolCall func(*ToolCallRequested) error
+    OnUsage func(*UsageReport) error
+    OnProgress func(*Progress) error
+    OnDone func(*Done) error
+    OnError func(*ErrorEvent) error
+}
+
+func (h StreamHandlers) TextDelta(e *TextDelta) error { return handleEvent(h.OnText, e) }
+func (h StreamHandlers) FunctionSelected(e *FunctionSelected) error { return handleEvent(h.OnFunctionSelected, e) }
+func (h StreamHandlers) ToolCallRequested(e *ToolCallRequested) error { return handleEvent(h.OnToolCall, e) }
+func (h StreamHandlers) UsageReport(e *UsageReport) error { return handleEvent(h.OnUsage, e) }
+func (h StreamHandlers) Progress(e *Progress) error { return handleEvent(h.OnProgress, e) }
+func (h StreamHandlers) Done(e *Done) error { return handleEvent(h.OnDone, e) }
+
+func (h StreamHandlers) ErrorEvent(e *ErrorEvent) error {
+    if h.OnError == nil {
+        return e
+    }
+    return h.OnError(e)
+}
+
+func handleEvent[E any](handler func(E) error, event E) error {
+    if handler == nil {
+        return nil
+    }
+    return handler(event)
+}
@@ -0,0 +1,149 @@
+package echo_computer_agent_client
+
+import (
+    "bytes"
+    "context"
+    "io"
+    "net/http"
+    "regexp"
+    "strings"
+)
+
+type RenderMode int
+
+const (
+    // RenderPlain writes the text as the agent sent it.
+    RenderPlain RenderMode = iota
+    // RenderANSI renders the reply's markdown with terminal escapes:
+    // headings, bold, inline code, fenced code blocks and bullets. Text is
+    // written a line at a time, as markup can span chunks.
+    RenderANSI
+)
+
+type StreamToOptions struct {
+    Render RenderMode
+    // NoFlush leaves w's buffering alone; by default writers with a Flush
+    // method, such as http.ResponseWriter and bufio.Writer, are flushed
+    // after every write so the reply shows as it streams.
+    NoFlush bool
+    // OnChunk, when set, sees every chunk after its text is written.
+    OnChunk func(ChatChunk)
+}
+
+// ChatStreamTo streams request's reply into w, returning the assembled
+// response like ChatStream. A failed write stops the stream and is
+// returned.
+func (c *Client) ChatStreamTo(ctx context.Context, request ChatRequest, w io.Writer, opts StreamToOptions) (*ChatResponse, error) {
+    out := &streamWriter{w: w, flush: !opts.NoFlush, ansi: opts.Render == RenderANSI}
+    resp, err := c.ChatStream(ctx, request, func(chunk ChatChunk) error {
+        if chunk.Text != "" {
+            if err := out.write(chunk.Text); err != nil {
+                return err
+            }
+        }
+        if opts.OnChunk != nil {
+            opts.OnChunk(chunk)
+        }
+        return nil
+    })
+    if closeErr := out.close(); err == nil {
+        err = closeErr
+    }
+    return resp, err
+}
+
+type streamWriter struct {
+    w io.Writer
+    flush bool
+    ansi bool
+    line bytes.Buffer
+    fenced bool
+}
+
+func (s *streamWriter) write(text string) error {
+    if !s.ansi {
+        return s.emit(text)
+    }
+    s.line.WriteString(text)
+    var rendered strings.Builder
+    for {
+        line, rest, ok := strings.Cut(s.line.String(), "\n")
+        if !ok {
+            break
+        }
+        if line, ok := s.render(line); ok {
+            rendered.WriteString(line + "\n")
+        }
+        s.line.Reset()
+        s.line.WriteString(rest)
+    }
+    if rendered.Len() == 0 {
+        return nil
+    }
+    return s.emit(rendered.String())
+}
+
+// close writes a final line the reply did not end.
+func (s *streamWriter) close() error {
+    if s.line.Len() == 0 {
+        return nil
+    }
+    line, ok := s.render(s.line.String())
+    s.line.Reset()
+    if !ok {
+        return nil
+    }
+    return s.emit(line + "\n")
+}
+
+func (s *streamWriter) emit(text string) error {
+    if _, err := io.WriteString(s.w, text); err != nil {
+        return err
+    }
+    if !s.flush {
+        return nil
+    }
+    switch f := s.w.(type) {
+    case http.Flusher:
+        f.Flush()
+    case interface{ Flush() error }:
+        return f.Flush()
+    }
+    return nil
+}
+
+const (
+    ansiReset = "\033[0m"
+    ansiBold = "\033[1m"
+    ansiHeading = "\033[1;4m"
+    ansiCode = "\033[36m"
+)
+
+var (
+    markdownBold = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+    markdownCode = regexp.MustCompile("`([^`]+)`")
+)
+
+// render renders one line of markdown, reporting false for lines that
+// only mark up others, such as code fences.
+func (s *streamWriter) render(line string) (string, bool) {
+    trimmed := strings.TrimSpace(line)
+    if strings.HasPrefix(trimmed, "```") {
+        s.fenced = !s.fenced
+        return "", false
+    }
+    if s.fenced {
+        return ansiCode + "    " + line + ansiReset, true
+    }
+    if heading := strings.TrimLeft(trimmed, "#"); heading != trimmed && strings.HasPrefix(heading, " ") {
+        return ansiHeading + strings.TrimSpace(heading) + ansiReset, true
+    }
+    indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+    if rest, ok := strings.CutPrefix(trimmed, "- "); ok {
+        line = indent + "• " + rest
+    } else if rest, ok := strings.CutPrefix(trimmed, "* "); ok {
+        line = indent + "• " + rest
+    }
+    line = markdownCode.ReplaceAllString(line, ansiCode+"$1"+ansiReset)
+    return markdownBold.ReplaceAllString(line, ansiBold+"$1"+ansiReset), true
+}
@@ -0,0 +1,105 @@
+// Package systemd implements the sd_notify protocol for daemons built on
+// the client, tying READY and WATCHDOG notifications to agent health so a
+// unit with WatchdogSec= is restarted when the agent stays unreachable.
+package systemd
+
+import (
+    "context"
+    "errors"
+    "net"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+
+    client "echo_computer_agent_client"
+)
+
+// ErrNoSocket is returned by Notify when the process was not started with
+// Type=notify (NOTIFY_SOCKET is unset).
+var ErrNoSocket = errors.New("systemd: NOTIFY_SOCKET not set")
+
+// Notify sends one or more KEY=VALUE assignments to the service manager.
+func Notify(state ...string) error {
+    socket := os.Getenv("NOTIFY_SOCKET")
+    if socket == "" {
+        return ErrNoSocket
+    }
+    if strings.HasPrefix(socket, "@") {
+        socket = "\x00" + socket[1:]
+    }
+    conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+    if err != nil {
+        return err
+    }
+    defer conn.Close()
+    _, err = conn.Write([]byte(strings.Join(state, "\n")))
+    return err
+}
+
+// WatchdogInterval reports the interval configured with WatchdogSec=, or
+// zero when the watchdog is disabled for this process.
+func WatchdogInterval() time.Duration {
+    usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+    if err != nil || usec <= 0 {
+        return 0
+    }
+    if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+        return 0
+    }
+    return time.Duration(usec) * time.Microsecond
+}
+
+// Supervise reports READY=1 once the prober first sees a healthy agent and
+// then pets the watchdog at half its interval, but only while the agent is
+// healthy; a sustained outage therefore lets systemd restart the unit.
+// STATUS= follows the prober. It sends STOPPING=1 when ctx is done and is a
+// no-op outside systemd.
+func Supervise(ctx context.Context, prober *client.HealthProber) {
+    if os.Getenv("NOTIFY_SOCKET") == "" {
+        return
+    }
+    tick := WatchdogInterval() / 2
+    if tick <= 0 {
+        tick = prober.Interval
+    }
+    if tick <= 0 {
+        tick = 10 * time.Second
+    }
+    ticker := time.NewTicker(tick)
+    defer ticker.Stop()
+
+    ready := false
+    lastStatus := ""
+    for {
+        status := prober.Status()
+        text := "agent healthy"
+        if !status.Healthy {
+            text = "agent unavailable"
+            if status.LastError != "" {
+                text += ": " + status.LastError
+            }
+        }
+        var state []string
+        if text != lastStatus {
+            state = append(state, "STATUS="+text)
+            lastStatus = text
+        }
+        if status.Healthy {
+            if !ready {
+                state = append(state, "READY=1")
+                ready = true
+            }
+            state = append(state, "WATCHDOG=1")
+        }
+        if len(state) > 0 {
+            Notify(state...)
+        }
+        select {
+        case <-ctx.Done():
+            Notify("STOPPING=1")
+            return
+        case <-ticker.C:
+        }
+    }
+}
@@ -0,0 +1,137 @@
+package echo_computer_agent_client
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "sort"
+    "sync"
+
+    "echo_computer_agent_client/internal/reftemplate"
+    "echo_computer_agent_client/internal/yamlite"
+)
+
+var ErrUnknownTemplate = errors.New("unknown request template")
+
+// RequestTemplate is a named ChatRequest preset. Message and string
+// inputs may reference variables as "{vars.name}"; an input that is
+// exactly one reference keeps the variable's type. Defaults fill in
+// variables the caller leaves out. Execute, when set, fixes whether the
+// request executes.
+type RequestTemplate struct {
+    Description string `json:"description,omitempty"`
+    Message string `json:"message"`
+    Inputs map[string]any `json:"inputs,omitempty"`
+    Defaults map[string]any `json:"defaults,omitempty"`
+    Execute *bool `json:"execute,omitempty"`
+}
+
+// TemplateRegistry holds request templates by name.
+type TemplateRegistry struct {
+    mu sync.RWMutex
+    templates map[string]RequestTemplate
+}
+
+func NewTemplateRegistry() *TemplateRegistry {
+    return &TemplateRegistry{templates: map[string]RequestTemplate{}}
+}
+
+func (r *TemplateRegistry) Register(name string, template RequestTemplate) {
+    r.mu.Lock()
+    r.templates[name] = template
+    r.mu.Unlock()
+}
+
+// LoadTemplates reads a YAML (or JSON) file of the form
+//
+//  templates:
+//    deploy:
+//      message: "deploy {vars.service} to {vars.env}"
+//      inputs: {service: "{vars.service}"}
+//      defaults: {env: staging}
+//      execute: true
+//
+// registering each template, replacing any of the same name.
+func (r *TemplateRegistry) LoadTemplates(path string) error {
+    raw, err := os.ReadFile(path)
+    if err != nil {
+        return err
+    }
+    var file struct {
+        Templates map[string]RequestTemplate `json:"templates"`
+    }
+    if err := yamlite.Unmarshal(raw, &file); err != nil {
+        return fmt.Errorf("%s: %w", path, err)
+    }
+    for name, template := range file.Templates {
+        if template.Message == "" {
+            return fmt.Errorf("%s: template %s: message is required", path, name)
+        }
+        r.Register(name, template)
+    }
+    return nil
+}
+
+func (r *TemplateRegistry) Names() []string {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+    names := make([]string, 0, len(r.templates))
+    for name := range r.templates {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
+
+// Render builds the request for template name with vars.
+func (r *TemplateRegistry) Render(name string, vars map[string]any) (ChatRequest, error) {
+    r.mu.RLock()
+    template, ok := r.templates[name]
+    r.mu.RUnlock()
+    if !ok {
+        return ChatRequest{}, fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+    }
+    merged := make(map[string]any, len(template.Defaults)+len(vars))
+    for key, value := range template.Defaults {
+        merged[key] = value
+    }
+    for key, value := range vars {
+        merged[key] = value
+    }
+    sources := map[string]reftemplate.Source{"vars": reftemplate.Map(merged)}
+
+    message, err := reftemplate.Render(template.Message, sources)
+    if err != nil {
+        return ChatRequest{}, fmt.Errorf("template %s: message: %w", name, err)
+    }
+    request := ChatRequest{Message: fmt.Sprint(message)}
+    if len(template.Inputs) > 0 {
+        request.Inputs = make(map[string]any, len(template.Inputs))
+        for key, value := range template.Inputs {
+            if text, ok := value.(string); ok {
+                if value, err = reftemplate.Render(text, sources); err != nil {
+                    return ChatRequest{}, fmt.Errorf("template %s: input %s: %w", name, key, err)
+                }
+            }
+            request.Inputs[key] = value
+        }
+    }
+    if template.Execute != nil {
+        execute := *template.Execute
+        request.Execute = &execute
+    }
+    return request, nil
+}
+
+// SetTemplates installs the registry FromTemplate renders from.
+func (c *Client) SetTemplates(templates *TemplateRegistry) {
+    c.templates = templates
+}
+
+// FromTemplate renders the named request template with vars.
+func (c *Client) FromTemplate(name string, vars map[string]any) (ChatRequest, error) {
+    if c.templates == nil {
+        return ChatRequest{}, fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+    }
+    return c.templates.Render(name, vars)
+}
@@ -0,0 +1,212 @@
+package echo_computer_agent_client
+
+import (
+    "container/list"
+    "context"
+    "math"
+    "net/http"
+    "sync"
+    "time"
+)
+
+// TenantConfig describes how a tenant's client differs from the others.
+// Token is sent as a bearer Authorization header. Rate is the sustained
+// requests per second allowed to the tenant, with bursts up to Burst;
+// zero is unlimited.
+type TenantConfig struct {
+    Token string
+    Headers map[string]string
+    Guard *FunctionGuard
+    Policy *Policy
+    Confirm Confirmer
+    Budget *Budget
+    AuditSink AuditSink
+    Rate float64
+    Burst int
+}
+
+// ClientManager hands out one Client per tenant, built on first use from
+// Configure. Every tenant's client shares the manager's HTTP transport, so
+// connections to the agent are pooled across tenants. At most MaxTenants
+// clients are kept; the least recently used is evicted beyond that and
+// rebuilt if the tenant returns.
+type ClientManager struct {
+    MaxTenants int
+    Configure func(ctx context.Context, tenant string) (TenantConfig, error)
+
+    baseURL string
+    httpClient *http.Client
+
+    mu sync.Mutex
+    tenants map[string]*list.Element
+    order *list.List
+}
+
+type tenantClient struct {
+    tenant string
+    client *Client
+}
+
+func NewClientManager(baseURL string, httpClient *http.Client, configure func(ctx context.Context, tenant string) (TenantConfig, error)) *ClientManager {
+    if httpClient == nil {
+        httpClient = http.DefaultClient
+    }
+    return &ClientManager{
+        MaxTenants: 1000,
+        Configure: configure,
+        baseURL: baseURL,
+        httpClient: httpClient,
+        tenants: map[string]*list.Element{},
+        order: list.New(),
+    }
+}
+
+// Client returns tenant's client, configuring it if needed.
+func (m *ClientManager) Client(ctx context.Context, tenant string) (*Client, error) {
+    m.mu.Lock()
+    if element, ok := m.tenants[tenant]; ok {
+        m.order.MoveToFront(element)
+        m.mu.Unlock()
+        return element.Value.(*tenantClient).client, nil
+    }
+    m.mu.Unlock()
+
+    config, err := m.Configure(ctx, tenant)
+    if err != nil {
+        return nil, err
+    }
+    client := m.build(tenant, config)
+
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    // Another caller may have configured the tenant meanwhile; keep theirs.
+    if element, ok := m.tenants[tenant]; ok {
+        m.order.MoveToFront(element)
+        return element.Value.(*tenantClient).client, nil
+    }
+    m.tenants[tenant] = m.order.PushFront(&tenantClient{tenant: tenant, client: client})
+    for m.MaxTenants > 0 && m.order.Len() > m.MaxTenants {
+        oldest := m.order.Back()
+        m.order.Remove(oldest)
+        delete(m.tenants, oldest.Value.(*tenantClient).tenant)
+    }
+    return client, nil
+}
+
+// Forget drops tenant's client, e.g. after its credentials rotate.
+func (m *ClientManager) Forget(tenant string) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    if element, ok := m.tenants[tenant]; ok {
+        m.order.Remove(element)
+        delete(m.tenants, tenant)
+    }
+}
+
+// Tenants returns the tenants with live clients, most recently used first.
+func (m *ClientManager) Tenants() []string {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    tenants := make([]string, 0, m.order.Len())
+    for element := m.order.Front(); element != nil; element = element.Next() {
+        tenants = append(tenants, element.Value.(*tenantClient).tenant)
+    }
+    return tenants
+}
+
+func (m *ClientManager) build(tenant string, config TenantConfig) *Client {
+    httpClient := m.httpClient
+    if config.Rate > 0 {
+        limited := *m.httpClient
+        limited.Transport = &rateLimitedTransport{base: m.httpClient.Transport, bucket: newTokenBucket(config.Rate, config.Burst)}
+        httpClient = &limited
+    }
+    c := NewClient(m.baseURL, httpClient)
+    c.SetDefaultHeader(HeaderTenant, tenant)
+    if config.Token != "" {
+        c.SetDefaultHeader("Authorization", "Bearer "+config.Token)
+    }
+    for key, value := range config.Headers {
+        c.SetDefaultHeader(key, value)
+    }
+    c.SetFunctionGuard(config.Guard)
+    c.SetPolicy(config.Policy, config.Confirm)
+    c.SetBudget(config.Budget)
+    c.SetAuditSink(config.AuditSink)
+    return c
+}
+
+// rateLimitedTransport delays requests to stay within its bucket.
+type rateLimitedTransport struct {
+    base http.RoundTripper
+    bucket *tokenBucket
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+    if err := t.bucket.wait(req.Context()); err != nil {
+        return nil, err
+    }
+    base := t.base
+    if base == nil {
+        base = http.DefaultTransport
+    }
+    return base.RoundTrip(req)
+}
+
+type tokenBucket struct {
+    mu sync.Mutex
+    rate float64
+    burst float64
+    tokens float64
+    updated time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+    capacity := math.Max(1, float64(burst))
+    return &tokenBucket{rate: rate, burst: capacity, tokens: capacity, updated: time.Now()}
+}
+
+// reserve takes a token, returning how long the caller must wait before
+// using it.
+func (b *tokenBucket) reserve() time.Duration {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    now := time.Now()
+    b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.updated).Seconds()*b.rate)
+    b.updated = now
+    b.tokens--
+    if b.tokens >= 0 {
+        return 0
+    }
+    return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// take takes a token if one is available now, or reports how long until
+// one will be.
+func (b *tokenBucket) take() (bool, time.Duration) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    now := time.Now()
+    b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.updated).Seconds()*b.rate)
+    b.updated = now
+    if b.tokens < 1 {
+        return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+    }
+    b.tokens--
+    return true, 0
+}
+
+func (b *tokenBucket) wait(ctx context.Context) error {
+    delay := b.reserve()
+    if delay <= 0 {
+        return nil
+    }
+    timer := time.NewTimer(delay)
+    defer timer.Stop()
+    select {
+    case <-ctx.Done():
+        return ctx.Err()
+    case <-timer.C:
+        return nil
+    }
+}
@@ -0,0 +1,98 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "strconv"
+    "sync"
+    "time"
+)
+
+// ErrRateLimited is returned when the agent answered 429 and waiting out
+// its Retry-After would exceed the client's throttle wait or the call's
+// deadline.
+var ErrRateLimited = errors.New("rate limited by agent")
+
+// DefaultThrottleWait bounds how long one call waits out 429 responses.
+const DefaultThrottleWait = time.Minute
+
+// throttleState is the Retry-After horizon shared by every call, so once
+// the agent throttles one request the others queue behind it instead of
+// piling more 429s onto the agent.
+type throttleState struct {
+    sync.Mutex
+    until time.Time
+    maxWait *time.Duration
+}
+
+// SetThrottleWait bounds how long a call may be parked waiting for the
+// agent's Retry-After horizon before failing with ErrRateLimited. Zero
+// disables waiting, so 429 responses fail immediately.
+func (c *Client) SetThrottleWait(max time.Duration) {
+    c.throttle.Lock()
+    c.throttle.maxWait = &max
+    c.throttle.Unlock()
+}
+
+func (c *Client) throttleWait() time.Duration {
+    if c.throttle.maxWait == nil {
+        return DefaultThrottleWait
+    }
+    return *c.throttle.maxWait
+}
+
+// noteThrottled records a 429's Retry-After (one second when absent).
+func (c *Client) noteThrottled(header http.Header) {
+    delay := retryAfter(header.Get("Retry-After"), time.Now())
+    if delay <= 0 {
+        delay = time.Second
+    }
+    c.throttle.Lock()
+    if until := time.Now().Add(delay); until.After(c.throttle.until) {
+        c.throttle.until = until
+    }
+    c.throttle.Unlock()
+}
+
+// awaitThrottle parks the caller until the Retry-After horizon. waited is
+// the time this call has already spent parked; calls that would exceed the
+// throttle wait or ctx's deadline fail with ErrRateLimited instead.
+func (c *Client) awaitThrottle(ctx context.Context, waited time.Duration) (time.Duration, error) {
+    c.throttle.Lock()
+    delay := time.Until(c.throttle.until)
+    limit := c.throttleWait()
+    c.throttle.Unlock()
+    if delay <= 0 {
+        return 0, nil
+    }
+    if waited+delay > limit {
+        return 0, fmt.Errorf("%w: retry after %s", ErrRateLimited, delay.Round(time.Second))
+    }
+    if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+        return 0, fmt.Errorf("%w: retry after %s is past the deadline", ErrRateLimited, delay.Round(time.Second))
+    }
+    timer := time.NewTimer(delay)
+    defer timer.Stop()
+    select {
+    case <-ctx.Done():
+        return 0, ctx.Err()
+    case <-timer.C:
+        return delay, nil
+    }
+}
+
+// retryAfter parses Retry-After as delay-seconds or an HTTP date.
+func retryAfter(value string, now time.Time) time.Duration {
+    if value == "" {
+        return 0
+    }
+    if seconds, err := strconv.Atoi(value); err == nil {
+        return time.Duration(seconds) * time.Second
+    }
+    if t, err := http.ParseTime(value); err == nil {
+        return t.Sub(now)
+    }
+    return 0
+}
@@ -0,0 +1,113 @@
+package echo_computer_agent_client
+
+import "strings"
+
+// OpenAITool is an OpenAI function-calling tool definition.
+type OpenAITool struct {
+    Type string `json:"type"`
+    Function OpenAIFunction `json:"function"`
+}
+
+type OpenAIFunction struct {
+    Name string `json:"name"`
+    Description string `json:"description,omitempty"`
+    Parameters map[string]any `json:"parameters"`
+}
+
+// AnthropicTool is an Anthropic Messages API tool definition.
+type AnthropicTool struct {
+    Name string `json:"name"`
+    Description string `json:"description,omitempty"`
+    InputSchema map[string]any `json:"input_schema"`
+}
+
+// ToolName maps a function name onto the character set LLM providers accept
+// for tool names (letters, digits, '_' and '-', at most 64 characters).
+func ToolName(name string) string {
+    var b strings.Builder
+    for _, r := range name {
+        switch {
+        case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+            b.WriteRune(r)
+        default:
+            b.WriteByte('_')
+        }
+    }
+    out := b.String()
+    if len(out) > 64 {
+        out = out[:64]
+    }
+    return out
+}
+
+func (f FunctionDescription) OpenAITool() OpenAITool {
+    return OpenAITool{
+        Type: "function",
+        Function: OpenAIFunction{Name: ToolName(f.Name), Description: f.Description, Parameters: objectSchema(f.Parameters)},
+    }
+}
+
+func (f FunctionDescription) AnthropicTool() AnthropicTool {
+    return AnthropicTool{Name: ToolName(f.Name), Description: f.Description, InputSchema: objectSchema(f.Parameters)}
+}
+
+func FunctionFromOpenAITool(tool OpenAITool) FunctionDescription {
+    return FunctionDescription{
+        Name: tool.Function.Name,
+        Description: tool.Function.Description,
+        Parameters: tool.Function.Parameters,
+        Metadata: map[string]any{"source": "openai"},
+    }
+}
+
+func FunctionFromAnthropicTool(tool AnthropicTool) FunctionDescription {
+    return FunctionDescription{
+        Name: tool.Name,
+        Description: tool.Description,
+        Parameters: tool.InputSchema,
+        Metadata: map[string]any{"source": "anthropic"},
+    }
+}
+
+func (r *FunctionListResponse) OpenAITools() []OpenAITool {
+    tools := make([]OpenAITool, 0, len(r.Functions))
+    for _, fn := range r.Functions {
+        tools = append(tools, fn.OpenAITool())
+    }
+    return tools
+}
+
+func (r *FunctionListResponse) AnthropicTools() []AnthropicTool {
+    tools := make([]AnthropicTool, 0, len(r.Functions))
+    for _, fn := range r.Functions {
+        tools = append(tools, fn.AnthropicTool())
+    }
+    return tools
+}
+
+// FunctionForTool finds the catalog entry a provider tool name came from,
+// undoing ToolName's sanitising.
+func (r *FunctionListResponse) FunctionForTool(toolName string) (FunctionDescription, bool) {
+    for _, fn := range r.Functions {
+        if fn.Name == toolName || ToolName(fn.Name) == toolName {
+            return fn, true
+        }
+    }
+    return FunctionDescription{}, false
+}
+
+// objectSchema copies parameters, defaulting to an empty object schema as
+// both providers require.
+func objectSchema(parameters map[string]any) map[string]any {
+    schema := map[string]any{}
+    for k, v := range parameters {
+        schema[k] = v
+    }
+    if _, ok := schema["type"]; !ok {
+        schema["type"] = "object"
+    }
+    if _, ok := schema["properties"]; !ok {
+        schema["properties"] = map[string]any{}
+    }
+    return schema
+}
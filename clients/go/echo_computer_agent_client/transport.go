@@ -0,0 +1,121 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "net"
+    "net/http"
+    "sync"
+)
+
+// Transport carries the client's requests to the agent. Everything above
+// it (versioning, policy, auditing, throttling, caching) works on the
+// request and response, so it behaves the same whatever the transport.
+// *http.Client is the default implementation; HandlerTransport and
+// UnixSocketTransport cover in-process and local-socket agents, and a
+// recording or replaying transport can wrap any of them.
+//
+// Reverse connections and WebSocket sessions dial the base URL directly
+// and are not routed through the transport.
+type Transport interface {
+    Do(req *http.Request) (*http.Response, error)
+}
+
+// SetTransport replaces the client's transport. The base URL is still
+// used to build request URLs, so transports that ignore the host may be
+// given any, e.g. NewClient("http://agent", nil).
+func (c *Client) SetTransport(transport Transport) {
+    c.transport = transport
+}
+
+// UnixSocketTransport speaks HTTP to an agent on a Unix domain socket.
+func UnixSocketTransport(socketPath string) *http.Client {
+    return &http.Client{Transport: &http.Transport{
+        DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+            var dialer net.Dialer
+            return dialer.DialContext(ctx, "unix", socketPath)
+        },
+    }}
+}
+
+// HandlerTransport serves requests from an in-process handler, such as a
+// fake agent in tests or an agent embedded in the same binary. Response
+// bodies stream as the handler writes them, so event streams work too.
+func HandlerTransport(handler http.Handler) Transport {
+    return handlerTransport{handler}
+}
+
+type handlerTransport struct {
+    handler http.Handler
+}
+
+func (t handlerTransport) Do(req *http.Request) (*http.Response, error) {
+    inbound := req.Clone(req.Context())
+    inbound.RequestURI = req.URL.RequestURI()
+    inbound.RemoteAddr = "in-process"
+    if inbound.Body == nil {
+        inbound.Body = http.NoBody
+    }
+    body, pipe := io.Pipe()
+    w := &pipeResponseWriter{header: http.Header{}, pipe: pipe, ready: make(chan struct{})}
+    go func() {
+        defer func() {
+            if recovered := recover(); recovered != nil {
+                w.WriteHeader(http.StatusInternalServerError)
+                pipe.CloseWithError(fmt.Errorf("handler panic: %v", recovered))
+                return
+            }
+            w.WriteHeader(http.StatusOK)
+            pipe.Close()
+        }()
+        t.handler.ServeHTTP(w, inbound)
+    }()
+    select {
+    case <-w.ready:
+    case <-req.Context().Done():
+        body.CloseWithError(req.Context().Err())
+        return nil, req.Context().Err()
+    }
+    return &http.Response{
+        Status: fmt.Sprintf("%d %s", w.status, http.StatusText(w.status)),
+        StatusCode: w.status,
+        Proto: "HTTP/1.1",
+        ProtoMajor: 1,
+        ProtoMinor: 1,
+        Header: w.sent,
+        Body: body,
+        ContentLength: -1,
+        Request: req,
+    }, nil
+}
+
+// pipeResponseWriter hands the handler's output to the caller through a
+// pipe, releasing the response once headers are written.
+type pipeResponseWriter struct {
+    header http.Header
+    pipe *io.PipeWriter
+    once sync.Once
+    ready chan struct{}
+    status int
+    sent http.Header
+}
+
+func (w *pipeResponseWriter) Header() http.Header {
+    return w.header
+}
+
+func (w *pipeResponseWriter) WriteHeader(status int) {
+    w.once.Do(func() {
+        w.status, w.sent = status, w.header.Clone()
+        close(w.ready)
+    })
+}
+
+func (w *pipeResponseWriter) Write(p []byte) (int, error) {
+    w.WriteHeader(http.StatusOK)
+    return w.pipe.Write(p)
+}
+
+// Flush is a no-op: writes reach the reader as soon as it reads them.
+func (w *pipeResponseWriter) Flush() {}
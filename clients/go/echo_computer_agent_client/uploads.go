@@ -0,0 +1,430 @@
+package echo_computer_agent_client
+
+import (
+    "bytes"
+    "compress/gzip"
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// FeatureChunkedUploads is advertised by agents that accept uploads in
+// chunks under /uploads.
+const FeatureChunkedUploads = "chunked_uploads"
+
+const (
+    DefaultChunkSize = 8 << 20
+    DefaultUploadParallelism = 4
+    // HeaderChunkChecksum carries the hex SHA-256 of a chunk as sent,
+    // before any Content-Encoding, for the agent to verify.
+    HeaderChunkChecksum = "X-Chunk-SHA256"
+)
+
+// Compressor encodes upload chunks. Encoding names it in Content-Encoding.
+type Compressor interface {
+    Encoding() string
+    Compress(chunk []byte) ([]byte, error)
+}
+
+// GzipCompressor compresses chunks with gzip at level, or the default
+// level when zero.
+type GzipCompressor struct {
+    Level int
+}
+
+func (GzipCompressor) Encoding() string { return "gzip" }
+
+func (g GzipCompressor) Compress(chunk []byte) ([]byte, error) {
+    level := g.Level
+    if level == 0 {
+        level = gzip.DefaultCompression
+    }
+    var buf bytes.Buffer
+    w, err := gzip.NewWriterLevel(&buf, level)
+    if err != nil {
+        return nil, err
+    }
+    if _, err := w.Write(chunk); err != nil {
+        return nil, err
+    }
+    if err := w.Close(); err != nil {
+        return nil, err
+    }
+    return buf.Bytes(), nil
+}
+
+// ChunkedUploadOptions configure UploadChunked. Zero fields take the
+// defaults: DefaultChunkSize, DefaultUploadParallelism, and three
+// attempts per chunk, backing off as the client's RetryPolicy does.
+//
+// With Manifest set, progress is saved to that file after every chunk
+// and an interrupted upload of the same content resumes from it; the file
+// is removed once the upload completes. Compressor, when set, encodes
+// each chunk that it makes smaller, except for content types that are
+// already compressed, such as images, video, and archives.
+type ChunkedUploadOptions struct {
+    ChunkSize int64
+    Parallelism int
+    Attempts int
+    Manifest string
+    Compressor Compressor
+    OnProgress func(sent, total int64)
+}
+
+// UploadManifest is the on-disk record of a chunked upload in progress.
+type UploadManifest struct {
+    UploadID string `json:"upload_id"`
+    Name string `json:"name"`
+    ContentType string `json:"content_type,omitempty"`
+    Size int64 `json:"size"`
+    ChunkSize int64 `json:"chunk_size"`
+    ModTime time.Time `json:"mod_time,omitempty"`
+    Checksums []string `json:"checksums"`
+    Done []bool `json:"done"`
+}
+
+type uploadSession struct {
+    ID string `json:"id"`
+    ChunkSize int64 `json:"chunk_size,omitempty"`
+    Received []int `json:"received,omitempty"`
+}
+
+type createUpload struct {
+    Name string `json:"name"`
+    ContentType string `json:"content_type,omitempty"`
+    Size int64 `json:"size"`
+    ChunkSize int64 `json:"chunk_size"`
+    Chunks int `json:"chunks"`
+}
+
+type completeUpload struct {
+    Checksums []string `json:"checksums"`
+}
+
+// UploadFileChunked uploads the file at path with UploadChunked, recording
+// its modification time in the manifest so a changed file is not resumed.
+func (c *Client) UploadFileChunked(ctx context.Context, path, contentType string, opts ChunkedUploadOptions) (*FileRef, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+    info, err := f.Stat()
+    if err != nil {
+        return nil, err
+    }
+    return c.uploadChunked(ctx, filepath.Base(path), contentType, f, info.Size(), info.ModTime(), opts)
+}
+
+// UploadChunked uploads size bytes of r in chunks, several at a time, so
+// a dropped connection costs one chunk rather than the whole file. Each
+// chunk is sent with its checksum and resent on transient failures.
+// Agents known not to support FeatureChunkedUploads get a single
+// UploadFile instead.
+func (c *Client) UploadChunked(ctx context.Context, name, contentType string, r io.ReaderAt, size int64, opts ChunkedUploadOptions) (*FileRef, error) {
+    return c.uploadChunked(ctx, name, contentType, r, size, time.Time{}, opts)
+}
+
+func (c *Client) uploadChunked(ctx context.Context, name, contentType string, r io.ReaderAt, size int64, modTime time.Time, opts ChunkedUploadOptions) (*FileRef, error) {
+    if caps, err := c.Capabilities(ctx); err == nil && caps.lacks(FeatureChunkedUploads) {
+        return c.UploadFile(ctx, name, contentType, io.NewSectionReader(r, 0, size))
+    }
+    if opts.ChunkSize <= 0 {
+        opts.ChunkSize = DefaultChunkSize
+    }
+    if opts.Parallelism <= 0 {
+        opts.Parallelism = DefaultUploadParallelism
+    }
+    if opts.Attempts <= 0 {
+        opts.Attempts = 3
+    }
+    if contentType == "" {
+        contentType = "application/octet-stream"
+    }
+    if alreadyCompressed(contentType) {
+        opts.Compressor = nil
+    }
+
+    m, err := c.openUpload(ctx, name, contentType, r, size, modTime, opts)
+    if err != nil {
+        return nil, err
+    }
+    u := &chunkedUpload{client: c, manifest: m, r: r, opts: opts}
+    if err := u.run(ctx); err != nil {
+        return nil, err
+    }
+    var ref FileRef
+    if err := c.doJSON(ctx, http.MethodPost, "/uploads/"+url.PathEscape(m.UploadID)+"/complete", completeUpload{Checksums: m.Checksums}, &ref); err != nil {
+        return nil, err
+    }
+    if opts.Manifest != "" {
+        os.Remove(opts.Manifest)
+    }
+    return &ref, nil
+}
+
+// openUpload resumes the upload in opts.Manifest when it matches the
+// content and the agent still holds it, and starts a new one otherwise,
+// replacing the manifest.
+func (c *Client) openUpload(ctx context.Context, name, contentType string, r io.ReaderAt, size int64, modTime time.Time, opts ChunkedUploadOptions) (*UploadManifest, error) {
+    if m, err := loadManifest(opts.Manifest); err == nil && m.matches(name, size, opts.ChunkSize, modTime) {
+        session, err := c.uploadStatus(ctx, m.UploadID)
+        if err == nil {
+            received := make([]bool, len(m.Done))
+            for _, i := range session.Received {
+                if i >= 0 && i < len(received) {
+                    received[i] = true
+                }
+            }
+            // Only chunks both sides agree on are skipped.
+            for i := range m.Done {
+                m.Done[i] = m.Done[i] && received[i]
+            }
+            return m, nil
+        }
+        if !IsNotFound(err) {
+            return nil, err
+        }
+    }
+    chunks := int((size + opts.ChunkSize - 1) / opts.ChunkSize)
+    m := &UploadManifest{
+        Name: name,
+        ContentType: contentType,
+        Size: size,
+        ChunkSize: opts.ChunkSize,
+        ModTime: modTime,
+        Checksums: make([]string, chunks),
+        Done: make([]bool, chunks),
+    }
+    var session uploadSession
+    if err := c.doJSON(ctx, http.MethodPost, "/uploads", createUpload{Name: name, ContentType: contentType, Size: size, ChunkSize: opts.ChunkSize, Chunks: chunks}, &session); err != nil {
+        return nil, err
+    }
+    if session.ChunkSize != 0 && session.ChunkSize != opts.ChunkSize {
+        return nil, fmt.Errorf("agent requires %d-byte chunks, not %d", session.ChunkSize, opts.ChunkSize)
+    }
+    m.UploadID = session.ID
+    if opts.Manifest != "" {
+        if err := m.save(opts.Manifest); err != nil {
+            return nil, err
+        }
+    }
+    return m, nil
+}
+
+func (c *Client) uploadStatus(ctx context.Context, id string) (*uploadSession, error) {
+    var session uploadSession
+    if err := c.doJSON(ctx, http.MethodGet, "/uploads/"+url.PathEscape(id), nil, &session); err != nil {
+        return nil, err
+    }
+    return &session, nil
+}
+
+type chunkedUpload struct {
+    client *Client
+    manifest *UploadManifest
+    r io.ReaderAt
+    opts ChunkedUploadOptions
+
+    mu sync.Mutex
+    sent int64
+}
+
+// run sends the chunks not yet done, Parallelism at a time. The first
+// failure cancels the rest; chunks already sent stay done.
+func (u *chunkedUpload) run(ctx context.Context) error {
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+    for i, done := range u.manifest.Done {
+        if done {
+            u.sent += u.length(i)
+        }
+    }
+    pending := make(chan int)
+    go func() {
+        defer close(pending)
+        for i, done := range u.manifest.Done {
+            if done {
+                continue
+            }
+            select {
+            case pending <- i:
+            case <-ctx.Done():
+                return
+            }
+        }
+    }()
+    var wg sync.WaitGroup
+    var once sync.Once
+    var failure error
+    for w := 0; w < u.opts.Parallelism; w++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for i := range pending {
+                if err := u.sendChunk(ctx, i); err != nil {
+                    once.Do(func() {
+                        failure = fmt.Errorf("upload chunk %d: %w", i, err)
+                        cancel()
+                    })
+                    return
+                }
+            }
+        }()
+    }
+    wg.Wait()
+    return failure
+}
+
+func (u *chunkedUpload) length(i int) int64 {
+    return min(u.manifest.ChunkSize, u.manifest.Size-int64(i)*u.manifest.ChunkSize)
+}
+
+func (u *chunkedUpload) sendChunk(ctx context.Context, i int) error {
+    chunk := make([]byte, u.length(i))
+    if _, err := u.r.ReadAt(chunk, int64(i)*u.manifest.ChunkSize); err != nil && err != io.EOF {
+        return err
+    }
+    sum := sha256.Sum256(chunk)
+    checksum := hex.EncodeToString(sum[:])
+    body, encoding := chunk, ""
+    if u.opts.Compressor != nil {
+        compressed, err := u.opts.Compressor.Compress(chunk)
+        if err != nil {
+            return err
+        }
+        if len(compressed) < len(chunk) {
+            body, encoding = compressed, u.opts.Compressor.Encoding()
+        }
+    }
+    c := u.client
+    path := "/uploads/" + url.PathEscape(u.manifest.UploadID) + "/chunks/" + strconv.Itoa(i)
+    for attempt := 1; ; attempt++ {
+        err := c.putChunk(ctx, path, body, encoding, checksum)
+        if err == nil {
+            break
+        }
+        if attempt >= u.opts.Attempts || !transient(err) && !checksumRejected(err) {
+            return err
+        }
+        if err := c.backoff(ctx, attempt, err); err != nil {
+            return err
+        }
+    }
+
+    u.mu.Lock()
+    defer u.mu.Unlock()
+    u.manifest.Checksums[i], u.manifest.Done[i] = checksum, true
+    u.sent += int64(len(chunk))
+    if u.opts.OnProgress != nil {
+        u.opts.OnProgress(u.sent, u.manifest.Size)
+    }
+    if u.opts.Manifest != "" {
+        return u.manifest.save(u.opts.Manifest)
+    }
+    return nil
+}
+
+func (c *Client) putChunk(ctx context.Context, path string, body []byte, encoding, checksum string) error {
+    ctx, cancel := c.callContext(ctx, c.timeout)
+    defer cancel()
+    req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.baseURL+path, bytes.NewReader(body))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/octet-stream")
+    req.Header.Set(HeaderChunkChecksum, checksum)
+    if encoding != "" {
+        req.Header.Set("Content-Encoding", encoding)
+    }
+    c.decorate(ctx, req)
+    resp, err := c.do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    c.observeDeprecation(path, resp.Header)
+    if resp.StatusCode == http.StatusTooManyRequests {
+        c.noteThrottled(resp.Header)
+    }
+    if resp.StatusCode >= 400 {
+        return c.apiError(req, resp)
+    }
+    io.Copy(io.Discard, resp.Body)
+    return nil
+}
+
+// checksumRejected reports the agent refusing a chunk that arrived
+// corrupted, which is worth sending again.
+func checksumRejected(err error) bool {
+    var apiErr *APIError
+    return errors.As(err, &apiErr) && (apiErr.Status == http.StatusUnprocessableEntity || apiErr.Status == http.StatusBadRequest && strings.Contains(strings.ToLower(apiErr.Message), "checksum"))
+}
+
+// alreadyCompressed reports content types compression would not shrink.
+func alreadyCompressed(contentType string) bool {
+    contentType, _, _ = strings.Cut(strings.ToLower(contentType), ";")
+    if strings.HasPrefix(contentType, "image/") && contentType != "image/svg+xml" || strings.HasPrefix(contentType, "video/") || strings.HasPrefix(contentType, "audio/") {
+        return true
+    }
+    switch contentType {
+    case "application/zip", "application/gzip", "application/x-gzip", "application/zstd", "application/x-xz", "application/x-bzip2", "application/x-7z-compressed":
+        return true
+    }
+    return false
+}
+
+func (m *UploadManifest) matches(name string, size, chunkSize int64, modTime time.Time) bool {
+    return m.UploadID != "" && m.Name == name && m.Size == size && m.ChunkSize == chunkSize && m.ModTime.Equal(modTime) && len(m.Done) == len(m.Checksums)
+}
+
+func loadManifest(path string) (*UploadManifest, error) {
+    if path == "" {
+        return nil, os.ErrNotExist
+    }
+    raw, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    var m UploadManifest
+    if err := json.Unmarshal(raw, &m); err != nil {
+        return nil, fmt.Errorf("%s: %w", path, err)
+    }
+    return &m, nil
+}
+
+// save writes the manifest through a temporary file, so an interruption
+// never leaves half of one behind.
+func (m *UploadManifest) save(path string) error {
+    raw, err := json.Marshal(m)
+    if err != nil {
+        return err
+    }
+    tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+    if err != nil {
+        return err
+    }
+    _, err = tmp.Write(raw)
+    if closeErr := tmp.Close(); err == nil {
+        err = closeErr
+    }
+    if err == nil {
+        err = os.Rename(tmp.Name(), path)
+    }
+    if err != nil {
+        os.Remove(tmp.Name())
+    }
+    return err
+}
@@ -0,0 +1,120 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "sync"
+)
+
+// Usage totals what the agent reported spending on a set of calls, from
+// the token and credit counts in reply metadata; see ResponseMeta.
+// Calls counts every reply, including those that reported nothing.
+type Usage struct {
+    Calls int `json:"calls"`
+    PromptTokens int `json:"prompt_tokens"`
+    CompletionTokens int `json:"completion_tokens"`
+    TotalTokens int `json:"total_tokens"`
+    Credits float64 `json:"credits"`
+}
+
+func (u *Usage) add(other Usage) {
+    u.Calls += other.Calls
+    u.PromptTokens += other.PromptTokens
+    u.CompletionTokens += other.CompletionTokens
+    u.TotalTokens += other.TotalTokens
+    u.Credits += other.Credits
+}
+
+// UsageSummary is a client's usage since it was created or last reset,
+// broken down by the function each reply came from and by the
+// Conversation that made the call.
+type UsageSummary struct {
+    Total Usage `json:"total"`
+    Functions map[string]Usage `json:"functions"`
+    Conversations map[string]Usage `json:"conversations"`
+}
+
+// UsageMetrics is implemented by Metrics that also record usage; the
+// client reports each reply's usage to them as it arrives.
+type UsageMetrics interface {
+    RecordUsage(function string, usage Usage)
+}
+
+type usageState struct {
+    sync.Mutex
+    total Usage
+    functions map[string]Usage
+    conversations map[string]Usage
+}
+
+type conversationKey struct{}
+
+func withConversation(ctx context.Context, id string) context.Context {
+    return context.WithValue(ctx, conversationKey{}, id)
+}
+
+// Usage returns the client's usage totals. Every reply off the wire is
+// counted, dry runs and preflight plans included, before response filters
+// can reject it; replies served from the response cache are not.
+func (c *Client) Usage() UsageSummary {
+    c.usage.Lock()
+    defer c.usage.Unlock()
+    summary := UsageSummary{
+        Total: c.usage.total,
+        Functions: make(map[string]Usage, len(c.usage.functions)),
+        Conversations: make(map[string]Usage, len(c.usage.conversations)),
+    }
+    for name, u := range c.usage.functions {
+        summary.Functions[name] = u
+    }
+    for id, u := range c.usage.conversations {
+        summary.Conversations[id] = u
+    }
+    return summary
+}
+
+func (c *Client) ResetUsage() {
+    c.usage.Lock()
+    c.usage.total, c.usage.functions, c.usage.conversations = Usage{}, nil, nil
+    c.usage.Unlock()
+}
+
+// Usage returns what the conversation's calls have used so far.
+func (v *Conversation) Usage() Usage {
+    return v.client.Usage().Conversations[v.ID()]
+}
+
+func (c *Client) meterUsage(ctx context.Context, resp *ChatResponse) {
+    meta := resp.Meta()
+    u := Usage{Calls: 1, PromptTokens: meta.PromptTokens, CompletionTokens: meta.CompletionTokens, TotalTokens: meta.TotalTokens, Credits: meta.Credits}
+    c.usage.Lock()
+    c.usage.total.add(u)
+    if c.usage.functions == nil {
+        c.usage.functions, c.usage.conversations = map[string]Usage{}, map[string]Usage{}
+    }
+    if resp.Function != "" {
+        function := c.usage.functions[resp.Function]
+        function.add(u)
+        c.usage.functions[resp.Function] = function
+    }
+    if id, ok := ctx.Value(conversationKey{}).(string); ok {
+        conversation := c.usage.conversations[id]
+        conversation.add(u)
+        c.usage.conversations[id] = conversation
+    }
+    c.usage.Unlock()
+    if metrics, ok := c.metrics.(UsageMetrics); ok {
+        metrics.RecordUsage(resp.Function, u)
+    }
+}
+
+// Usage returns each live tenant's usage totals. A tenant's totals start
+// over if its client is evicted and rebuilt.
+func (m *ClientManager) Usage() map[string]Usage {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    usage := make(map[string]Usage, len(m.tenants))
+    for tenant, element := range m.tenants {
+        usage[tenant] = element.Value.(*tenantClient).client.Usage().Total
+    }
+    return usage
+}
@@ -0,0 +1,75 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "errors"
+    "strings"
+)
+
+// ErrInvalidInputs is matched by a *ValidationError.
+var ErrInvalidInputs = errors.New("inputs do not match the function's parameters")
+
+// ValidationError lists the ways a call's inputs fail its function's
+// parameter schema, found before the call was sent.
+type ValidationError struct {
+    Function string
+    Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+    fields := make([]string, len(e.Errors))
+    for i, fieldErr := range e.Errors {
+        fields[i] = fieldErr.String()
+    }
+    return e.Function + ": " + ErrInvalidInputs.Error() + ": " + strings.Join(fields, "; ")
+}
+
+func (e *ValidationError) Unwrap() error { return ErrInvalidInputs }
+
+// SetInputValidation makes the client check inputs against the cached
+// catalog's parameter schema, see Functions.ValidateInputs, before
+// executing a call, failing with a *ValidationError instead of sending
+// it. InvokeFunction, ExecuteFunction and BulkInvoke are checked by name.
+// Executing chat requests that carry inputs are checked against the
+// function the agent plans to route to, without requiring top-level
+// fields, as the agent may take those from the message. Functions missing
+// from the cached catalog are not checked.
+func (c *Client) SetInputValidation(enabled bool) {
+    c.validateInputs = enabled
+}
+
+func WithInputValidation() Option {
+    return func(c *Client) { c.SetInputValidation(true) }
+}
+
+// checkInputs validates inputs for function when the client validates.
+// fromMessage drops missing top-level fields, which a chat message can
+// supply.
+func (c *Client) checkInputs(ctx context.Context, function string, inputs map[string]any, fromMessage bool) error {
+    if !c.validateInputs || function == "" || fromMessage && len(inputs) == 0 {
+        return nil
+    }
+    functions, err := c.Functions(ctx)
+    if err != nil {
+        return err
+    }
+    fn, ok := functions.ByName(function)
+    if !ok {
+        return nil
+    }
+    schema, err := viaJSON(objectSchema(fn.Parameters))
+    if err != nil {
+        return nil
+    }
+    var failures []FieldError
+    for _, fieldErr := range validateInputs(schema.(map[string]any), inputs) {
+        if fromMessage && fieldErr.Message == "is required" && !strings.ContainsAny(fieldErr.Field, ".[") {
+            continue
+        }
+        failures = append(failures, fieldErr)
+    }
+    if len(failures) > 0 {
+        return &ValidationError{Function: function, Errors: failures}
+    }
+    return nil
+}
@@ -0,0 +1,370 @@
+// Package vcr records a client's exchanges with a live agent to cassette
+// files and replays them, so integration tests run without the agent and
+// get the same answers every time:
+//
+//	recorder, err := vcr.New("testdata/restart.yaml", vcr.Options{Mode: vcr.ModeReplayOrRecord})
+//	if err != nil {
+//	    t.Fatal(err)
+//	}
+//	defer recorder.Stop()
+//	agent := client.NewClient(baseURL, recorder.HTTPClient())
+//
+// Cassettes are YAML, or JSON when the file name ends in ".json".
+package vcr
+
+import (
+    "bytes"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "os"
+    "reflect"
+    "strings"
+    "sync"
+    "time"
+    "unicode/utf8"
+
+    "echo_computer_agent_client/internal/yamlite"
+)
+
+// ErrNoInteraction is returned for a request a replaying recorder has no
+// recorded interaction for.
+var ErrNoInteraction = errors.New("vcr: no recorded interaction matches request")
+
+// redactedValue replaces the values of redacted headers.
+const redactedValue = "[REDACTED]"
+
+// DefaultRedactHeaders are the headers whose values are never written to
+// a cassette.
+var DefaultRedactHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "X-Echo-Signature"}
+
+type Mode int
+
+const (
+    // ModeReplay serves requests from the cassette only; requests it has
+    // no interaction for fail with ErrNoInteraction.
+    ModeReplay Mode = iota
+    // ModeRecord sends every request to the agent and records a new
+    // cassette, replacing the old one.
+    ModeRecord
+    // ModeReplayOrRecord replays what the cassette has and records the
+    // rest, adding to it.
+    ModeReplayOrRecord
+)
+
+// Cassette is a recorded sequence of exchanges.
+type Cassette struct {
+    Interactions []Interaction `json:"interactions"`
+}
+
+type Interaction struct {
+    Request Request `json:"request"`
+    Response Response `json:"response"`
+}
+
+// Request is a recorded request. URI is the path and query, so cassettes
+// replay against an agent on any host.
+type Request struct {
+    Method string `json:"method"`
+    URI string `json:"uri"`
+    Header http.Header `json:"header,omitempty"`
+    Body string `json:"body,omitempty"`
+    Encoding string `json:"encoding,omitempty"`
+}
+
+type Response struct {
+    Status int `json:"status"`
+    Header http.Header `json:"header,omitempty"`
+    Body string `json:"body,omitempty"`
+    Encoding string `json:"encoding,omitempty"`
+}
+
+// Load reads a cassette saved by Save.
+func Load(path string) (*Cassette, error) {
+    raw, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    var cassette Cassette
+    if strings.HasSuffix(path, ".json") {
+        err = json.Unmarshal(raw, &cassette)
+    } else {
+        err = yamlite.Unmarshal(raw, &cassette)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", path, err)
+    }
+    return &cassette, nil
+}
+
+func (c *Cassette) Save(path string) error {
+    var encoded []byte
+    var err error
+    if strings.HasSuffix(path, ".json") {
+        encoded, err = json.MarshalIndent(c, "", "  ")
+        encoded = append(encoded, '\n')
+    } else {
+        encoded, err = yamlite.Marshal(c)
+    }
+    if err != nil {
+        return err
+    }
+    return os.WriteFile(path, encoded, 0o644)
+}
+
+// Matcher reports whether a live request, with its body read into body,
+// matches a recorded one.
+type Matcher func(r *http.Request, body []byte, recorded Request) bool
+
+// DefaultMatchers match on method, path and query, and body.
+var DefaultMatchers = []Matcher{MatchMethod, MatchURI, MatchBody}
+
+func MatchMethod(r *http.Request, body []byte, recorded Request) bool {
+    return r.Method == recorded.Method
+}
+
+func MatchURI(r *http.Request, body []byte, recorded Request) bool {
+    return r.URL.RequestURI() == recorded.URI
+}
+
+// MatchBody compares JSON bodies by value, so key order and spacing do
+// not matter, and other bodies byte for byte.
+func MatchBody(r *http.Request, body []byte, recorded Request) bool {
+    want, err := recorded.body()
+    if err != nil {
+        return false
+    }
+    var x, y any
+    if json.Unmarshal(body, &x) == nil && json.Unmarshal(want, &y) == nil {
+        return reflect.DeepEqual(x, y)
+    }
+    return bytes.Equal(body, want)
+}
+
+// MatchHeader matches requests whose values of the named headers equal
+// the recorded ones. Redacted headers never match.
+func MatchHeader(names ...string) Matcher {
+    return func(r *http.Request, body []byte, recorded Request) bool {
+        for _, name := range names {
+            if !reflect.DeepEqual(r.Header.Values(name), recorded.Header.Values(name)) {
+                return false
+            }
+        }
+        return true
+    }
+}
+
+// Options configure a Recorder. Zero fields take the defaults:
+// DefaultRedactHeaders, DefaultMatchers, and http.DefaultTransport.
+type Options struct {
+    Mode Mode
+    RedactHeaders []string
+    Matchers []Matcher
+    Upstream http.RoundTripper
+    // Repeat lets a replayed interaction answer again once every
+    // interaction matching a request has been used; otherwise each is
+    // used once, in recorded order.
+    Repeat bool
+}
+
+// Recorder is an http.RoundTripper that records to, or replays from, one
+// cassette. It is safe for concurrent use, though concurrent requests are
+// recorded in the order they complete.
+type Recorder struct {
+    path string
+    opts Options
+
+    mu sync.Mutex
+    cassette *Cassette
+    used []bool
+    recorded bool
+}
+
+// New opens the cassette at path. A missing cassette is an error when
+// replaying and starts empty otherwise.
+func New(path string, opts Options) (*Recorder, error) {
+    if opts.RedactHeaders == nil {
+        opts.RedactHeaders = DefaultRedactHeaders
+    }
+    if opts.Matchers == nil {
+        opts.Matchers = DefaultMatchers
+    }
+    if opts.Upstream == nil {
+        opts.Upstream = http.DefaultTransport
+    }
+    r := &Recorder{path: path, opts: opts, cassette: &Cassette{}}
+    if opts.Mode != ModeRecord {
+        cassette, err := Load(path)
+        switch {
+        case err == nil:
+            r.cassette = cassette
+        case !errors.Is(err, os.ErrNotExist) || opts.Mode == ModeReplay:
+            return nil, err
+        }
+    }
+    r.used = make([]bool, len(r.cassette.Interactions))
+    return r, nil
+}
+
+// HTTPClient returns an *http.Client that sends its requests through r.
+func (r *Recorder) HTTPClient() *http.Client {
+    return &http.Client{Transport: r}
+}
+
+// Cassette returns a copy of the interactions recorded and loaded so far.
+func (r *Recorder) Cassette() Cassette {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    return Cassette{Interactions: append([]Interaction(nil), r.cassette.Interactions...)}
+}
+
+// Stop saves the cassette if anything was recorded.
+func (r *Recorder) Stop() error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    if !r.recorded {
+        return nil
+    }
+    r.recorded = false
+    return r.cassette.Save(r.path)
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+    var body []byte
+    if req.Body != nil {
+        var err error
+        body, err = io.ReadAll(req.Body)
+        req.Body.Close()
+        if err != nil {
+            return nil, err
+        }
+    }
+    if r.opts.Mode != ModeRecord {
+        if resp, ok := r.replay(req, body); ok {
+            return resp, nil
+        }
+        if r.opts.Mode == ModeReplay {
+            return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, req.Method, req.URL.RequestURI())
+        }
+    }
+    return r.record(req, body)
+}
+
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, bool) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    last := -1
+    for i, interaction := range r.cassette.Interactions {
+        if !r.matches(req, body, interaction.Request) {
+            continue
+        }
+        if !r.used[i] {
+            r.used[i] = true
+            return interaction.Response.toHTTP(req), true
+        }
+        last = i
+    }
+    if r.opts.Repeat && last >= 0 {
+        return r.cassette.Interactions[last].Response.toHTTP(req), true
+    }
+    return nil, false
+}
+
+func (r *Recorder) matches(req *http.Request, body []byte, recorded Request) bool {
+    for _, match := range r.opts.Matchers {
+        if !match(req, body, recorded) {
+            return false
+        }
+    }
+    return true
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+    upstream := req.Clone(req.Context())
+    if body != nil {
+        upstream.Body = io.NopCloser(bytes.NewReader(body))
+        upstream.ContentLength = int64(len(body))
+    }
+    resp, err := r.opts.Upstream.RoundTrip(upstream)
+    if err != nil {
+        return nil, err
+    }
+    respBody, err := io.ReadAll(resp.Body)
+    resp.Body.Close()
+    if err != nil {
+        return nil, err
+    }
+    resp.Body = io.NopCloser(bytes.NewReader(respBody))
+    interaction := Interaction{
+        Request: Request{Method: req.Method, URI: req.URL.RequestURI(), Header: r.redact(req.Header)},
+        Response: Response{Status: resp.StatusCode, Header: r.redact(resp.Header)},
+    }
+    interaction.Request.Body, interaction.Request.Encoding = encodeBody(body)
+    interaction.Response.Body, interaction.Response.Encoding = encodeBody(respBody)
+    r.mu.Lock()
+    r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+    r.used = append(r.used, true)
+    r.recorded = true
+    r.mu.Unlock()
+    return resp, nil
+}
+
+func (r *Recorder) redact(header http.Header) http.Header {
+    if len(header) == 0 {
+        return nil
+    }
+    redacted := header.Clone()
+    for _, name := range r.opts.RedactHeaders {
+        if values := redacted.Values(name); len(values) > 0 {
+            redacted.Set(name, redactedValue)
+        }
+    }
+    return redacted
+}
+
+// encodeBody keeps text bodies readable in the cassette and base64-encodes
+// the rest.
+func encodeBody(body []byte) (string, string) {
+    if utf8.Valid(body) {
+        return string(body), ""
+    }
+    return base64.StdEncoding.EncodeToString(body), "base64"
+}
+
+func decodeBody(body, encoding string) ([]byte, error) {
+    if encoding == "base64" {
+        return base64.StdEncoding.DecodeString(body)
+    }
+    return []byte(body), nil
+}
+
+func (r Request) body() ([]byte, error) {
+    return decodeBody(r.Body, r.Encoding)
+}
+
+func (r Response) toHTTP(req *http.Request) *http.Response {
+    // A hand-edited body that is not valid base64 replays as empty.
+    body, _ := decodeBody(r.Body, r.Encoding)
+    header := r.Header.Clone()
+    if header == nil {
+        header = http.Header{}
+    }
+    // A recorded Date would read as clock skew to the client.
+    if header.Get("Date") != "" {
+        header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+    }
+    return &http.Response{
+        Status: fmt.Sprintf("%d %s", r.Status, http.StatusText(r.Status)),
+        StatusCode: r.Status,
+        Proto: "HTTP/1.1",
+        ProtoMajor: 1,
+        ProtoMinor: 1,
+        Header: header,
+        Body: io.NopCloser(bytes.NewReader(body)),
+        ContentLength: int64(len(body)),
+        Request: req,
+    }
+}
@@ -0,0 +1,69 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "net/http"
+)
+
+const HeaderAPIVersion = "X-Echo-API-Version"
+
+// API versions the client can speak. Agents that predate negotiation do
+// not answer with a version header and are treated as APIVersion1.
+const (
+    APIVersion1 = "1"
+    APIVersion2 = "2"
+    LatestAPIVersion = APIVersion2
+)
+
+type apiVersionKey struct{}
+
+// WithAPIVersion pins the API version requested by calls made with ctx,
+// overriding the client's setting.
+func WithAPIVersion(ctx context.Context, version string) context.Context {
+    return context.WithValue(ctx, apiVersionKey{}, version)
+}
+
+// SetAPIVersion pins the version the client requests; by default it asks
+// for LatestAPIVersion and adapts to whatever the agent answers with.
+func (c *Client) SetAPIVersion(version string) {
+    c.apiVersion = version
+}
+
+// NegotiatedAPIVersion reports the version the agent answered the most
+// recent request with, or "" before the first response.
+func (c *Client) NegotiatedAPIVersion() string {
+    c.versionMu.Lock()
+    defer c.versionMu.Unlock()
+    return c.negotiatedVersion
+}
+
+func (c *Client) requestedAPIVersion(ctx context.Context) string {
+    if version, ok := ctx.Value(apiVersionKey{}).(string); ok && version != "" {
+        return version
+    }
+    if c.apiVersion != "" {
+        return c.apiVersion
+    }
+    return LatestAPIVersion
+}
+
+func (c *Client) setVersionHeaders(ctx context.Context, req *http.Request) {
+    version := c.requestedAPIVersion(ctx)
+    req.Header.Set(HeaderAPIVersion, version)
+    if req.Header.Get("Accept") == "" {
+        req.Header.Set("Accept", "application/json; version="+version)
+    }
+}
+
+// observeVersion records and returns the version a response was served
+// with.
+func (c *Client) observeVersion(resp *http.Response) string {
+    version := resp.Header.Get(HeaderAPIVersion)
+    if version == "" {
+        version = APIVersion1
+    }
+    c.versionMu.Lock()
+    c.negotiatedVersion = version
+    c.versionMu.Unlock()
+    return version
+}
@@ -0,0 +1,112 @@
+package echo_computer_agent_client
+
+import (
+    "context"
+    "net/http"
+    "reflect"
+    "time"
+)
+
+// FeatureCatalogEvents is advertised by agents that push catalog changes
+// on GET /functions/events.
+const FeatureCatalogEvents = "catalog_events"
+
+// FunctionChange pairs the old and new description of a function whose
+// description, parameters, or metadata changed.
+type FunctionChange struct {
+    Old FunctionDescription
+    New FunctionDescription
+}
+
+// SchemaChanged reports whether the function's parameters changed, as
+// opposed to only its description or metadata.
+func (f FunctionChange) SchemaChanged() bool {
+    return !reflect.DeepEqual(f.Old.Parameters, f.New.Parameters)
+}
+
+type CatalogDiff struct {
+    Added []FunctionDescription
+    Removed []FunctionDescription
+    Changed []FunctionChange
+}
+
+func (d CatalogDiff) Empty() bool {
+    return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffCatalogs compares two catalogs by function name.
+func DiffCatalogs(before, after Functions) CatalogDiff {
+    var diff CatalogDiff
+    for _, fn := range after {
+        previous, ok := before.ByName(fn.Name)
+        switch {
+        case !ok:
+            diff.Added = append(diff.Added, fn)
+        case previous.Description != fn.Description || !reflect.DeepEqual(previous.Parameters, fn.Parameters) || !reflect.DeepEqual(previous.Metadata, fn.Metadata):
+            diff.Changed = append(diff.Changed, FunctionChange{Old: previous, New: fn})
+        }
+    }
+    for _, fn := range before {
+        if _, ok := after.ByName(fn.Name); !ok {
+            diff.Removed = append(diff.Removed, fn)
+        }
+    }
+    return diff
+}
+
+// WatchFunctions keeps the cached catalog fresh until ctx is done, calling
+// handle with every non-empty change. Agents advertising
+// FeatureCatalogEvents are followed over their event stream, with any
+// event triggering a re-list; otherwise, or if the stream fails, the
+// catalog is polled every CatalogTTL. Listing errors are retried on the
+// next tick; WatchFunctions only returns ctx's error, or the first
+// listing's error.
+func (c *Client) WatchFunctions(ctx context.Context, handle func(diff CatalogDiff)) error {
+    current, err := c.Functions(ctx)
+    if err != nil {
+        return err
+    }
+    refresh := func() {
+        list, err := c.ListFunctions(ctx)
+        if err != nil {
+            return
+        }
+        next := NewFunctions(list.Functions)
+        c.catalog.Lock()
+        c.catalog.functions, c.catalog.fetched = next, time.Now()
+        c.catalog.Unlock()
+        if diff := DiffCatalogs(current, next); !diff.Empty() {
+            current = next
+            for _, change := range diff.Changed {
+                c.InvalidateResponses(change.New.Name)
+            }
+            for _, fn := range diff.Removed {
+                c.InvalidateResponses(fn.Name)
+            }
+            handle(diff)
+        }
+    }
+
+    if c.Supports(ctx, FeatureCatalogEvents) {
+        c.StreamEvents(ctx, http.MethodGet, "/functions/events", nil, StreamOptions{IdleTimeout: 2 * CatalogTTL}, func(ServerSentEvent) error {
+            refresh()
+            return nil
+        })
+        if ctx.Err() != nil {
+            return ctx.Err()
+        }
+        // Events may have been missed while the stream was failing.
+        refresh()
+    }
+
+    ticker := time.NewTicker(CatalogTTL)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-ticker.C:
+            refresh()
+        }
+    }
+}
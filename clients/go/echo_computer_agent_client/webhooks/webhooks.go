@@ -0,0 +1,243 @@
+// Package webhooks receives and verifies webhook deliveries from the Echo
+// Computer Agent.
+package webhooks
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    client "echo_computer_agent_client"
+)
+
+const (
+    HeaderSignature = "X-Echo-Signature"
+    HeaderDelivery = "X-Echo-Delivery"
+
+    EventJobCompleted = "job.completed"
+    EventFunctionRegistered = "function.registered"
+)
+
+var ErrInvalidSignature = errors.New("webhooks: invalid signature")
+
+// Event is one delivery. Payload holds the typed body for known event
+// types (*JobCompleted, *FunctionRegistered) and is nil otherwise; Data
+// always carries the raw JSON.
+type Event struct {
+    ID string `json:"id"`
+    Type string `json:"type"`
+    Created time.Time `json:"created"`
+    Data json.RawMessage `json:"data"`
+    Payload any `json:"-"`
+}
+
+type JobCompleted struct {
+    JobID string `json:"job_id"`
+    Function string `json:"function"`
+    Status string `json:"status"`
+    Result map[string]any `json:"result,omitempty"`
+    Error string `json:"error,omitempty"`
+}
+
+type FunctionRegistered struct {
+    Function client.FunctionDescription `json:"function"`
+}
+
+// Handler verifies, deduplicates, and dispatches deliveries. Responses
+// follow the agent's retry contract: 2xx acknowledges (including repeats of
+// an already-handled delivery), 4xx rejects permanently, and 5xx — returned
+// when the callback fails or a copy of the delivery is still being handled —
+// asks the agent to redeliver later.
+type Handler struct {
+    Secret []byte
+    Tolerance time.Duration
+    DedupWindow time.Duration
+    MaxBodyBytes int64
+    OnEvent func(Event) error
+    Now func() time.Time
+
+    mu sync.Mutex
+    seen map[string]time.Time
+    inFlight map[string]bool
+}
+
+func NewHandler(secret string, onEvent func(Event) error) *Handler {
+    return &Handler{
+        Secret: []byte(secret),
+        Tolerance: 5 * time.Minute,
+        DedupWindow: 24 * time.Hour,
+        MaxBodyBytes: 1 << 20,
+        OnEvent: onEvent,
+        Now: time.Now,
+        seen: map[string]time.Time{},
+        inFlight: map[string]bool{},
+    }
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodPost {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    body, err := io.ReadAll(io.LimitReader(r.Body, h.MaxBodyBytes+1))
+    if err != nil {
+        http.Error(w, "read body", http.StatusBadRequest)
+        return
+    }
+    if int64(len(body)) > h.MaxBodyBytes {
+        http.Error(w, "body too large", http.StatusRequestEntityTooLarge)
+        return
+    }
+    if err := Verify(h.Secret, r.Header.Get(HeaderSignature), body, h.Now(), h.Tolerance); err != nil {
+        http.Error(w, err.Error(), http.StatusUnauthorized)
+        return
+    }
+    event, err := ParseEvent(body)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    delivery := r.Header.Get(HeaderDelivery)
+    if delivery == "" {
+        delivery = event.ID
+    }
+
+    switch h.claim(delivery) {
+    case claimDuplicate:
+        w.WriteHeader(http.StatusOK)
+        return
+    case claimBusy:
+        http.Error(w, "delivery in progress", http.StatusServiceUnavailable)
+        return
+    }
+    if err := h.OnEvent(event); err != nil {
+        h.release(delivery, false)
+        http.Error(w, "handler failed", http.StatusInternalServerError)
+        return
+    }
+    h.release(delivery, true)
+    w.WriteHeader(http.StatusNoContent)
+}
+
+type claimResult int
+
+const (
+    claimNew claimResult = iota
+    claimDuplicate
+    claimBusy
+)
+
+func (h *Handler) claim(delivery string) claimResult {
+    if delivery == "" {
+        return claimNew
+    }
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    now := h.Now()
+    for id, at := range h.seen {
+        if now.Sub(at) > h.DedupWindow {
+            delete(h.seen, id)
+        }
+    }
+    if _, ok := h.seen[delivery]; ok {
+        return claimDuplicate
+    }
+    if h.inFlight[delivery] {
+        return claimBusy
+    }
+    h.inFlight[delivery] = true
+    return claimNew
+}
+
+func (h *Handler) release(delivery string, handled bool) {
+    if delivery == "" {
+        return
+    }
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    delete(h.inFlight, delivery)
+    if handled {
+        h.seen[delivery] = h.Now()
+    }
+}
+
+func ParseEvent(body []byte) (Event, error) {
+    var event Event
+    if err := json.Unmarshal(body, &event); err != nil {
+        return Event{}, fmt.Errorf("webhooks: decode event: %w", err)
+    }
+    var payload any
+    switch event.Type {
+    case EventJobCompleted:
+        payload = &JobCompleted{}
+    case EventFunctionRegistered:
+        payload = &FunctionRegistered{}
+    default:
+        return event, nil
+    }
+    if err := json.Unmarshal(event.Data, payload); err != nil {
+        return Event{}, fmt.Errorf("webhooks: decode %s payload: %w", event.Type, err)
+    }
+    event.Payload = payload
+    return event, nil
+}
+
+// Sign returns the signature header value for body sent at timestamp t:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">".
+func Sign(secret []byte, t time.Time, body []byte) string {
+    ts := strconv.FormatInt(t.Unix(), 10)
+    return "t=" + ts + ",v1=" + digest(secret, ts, body)
+}
+
+// Verify checks header against body, rejecting timestamps further than
+// tolerance from now to stop replays of captured deliveries.
+func Verify(secret []byte, header string, body []byte, now time.Time, tolerance time.Duration) error {
+    var ts string
+    var signatures []string
+    for _, part := range strings.Split(header, ",") {
+        key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+        if !ok {
+            continue
+        }
+        switch key {
+        case "t":
+            ts = value
+        case "v1":
+            signatures = append(signatures, value)
+        }
+    }
+    if ts == "" || len(signatures) == 0 {
+        return ErrInvalidSignature
+    }
+    unix, err := strconv.ParseInt(ts, 10, 64)
+    if err != nil {
+        return ErrInvalidSignature
+    }
+    if skew := now.Sub(time.Unix(unix, 0)); tolerance > 0 && (skew > tolerance || skew < -tolerance) {
+        return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+    }
+    expected := digest(secret, ts, body)
+    for _, signature := range signatures {
+        if hmac.Equal([]byte(signature), []byte(expected)) {
+            return nil
+        }
+    }
+    return ErrInvalidSignature
+}
+
+func digest(secret []byte, ts string, body []byte) string {
+    mac := hmac.New(sha256.New, secret)
+    mac.Write([]byte(ts))
+    mac.Write([]byte("."))
+    mac.Write(body)
+    return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,353 @@
+// Package workflow runs declarative multi-step workflows against the agent.
+// Each step calls a function by name or sends a chat message, and may use
+// earlier steps' outputs in its inputs, run only when a condition holds,
+// and retry on failure.
+package workflow
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "os"
+    "strings"
+    "time"
+
+    client "echo_computer_agent_client"
+    "echo_computer_agent_client/internal/reftemplate"
+    "echo_computer_agent_client/internal/yamlite"
+)
+
+// Workflow is a workflow file. Step messages, inputs and conditions may
+// reference "{vars.name}" and earlier steps' outputs as
+// "{steps.<id>.<path>}"; see StepResult.Output for what a step exposes.
+type Workflow struct {
+    Name string `json:"name,omitempty"`
+    Vars map[string]any `json:"vars,omitempty"`
+    Steps []Step `json:"steps"`
+}
+
+// Step runs Function with Inputs through ExecuteFunction when Function is
+// set, and otherwise sends Message as an executing chat request.
+//
+// When, if set, is a condition the step runs under: a single reference
+// that must be truthy, or two operands compared with == or !=, such as
+// "{steps.status.output.state} != running". Retries is how many more
+// attempts a failing step gets, RetryDelay ("2s") the pause between them.
+// A step that still fails stops the workflow unless ContinueOnError is set.
+type Step struct {
+    ID string `json:"id"`
+    Function string `json:"function,omitempty"`
+    Message string `json:"message,omitempty"`
+    Inputs map[string]any `json:"inputs,omitempty"`
+    When string `json:"when,omitempty"`
+    Retries int `json:"retries,omitempty"`
+    RetryDelay string `json:"retry_delay,omitempty"`
+    ContinueOnError bool `json:"continue_on_error,omitempty"`
+}
+
+// Load reads a workflow from a YAML or JSON file.
+func Load(path string) (*Workflow, error) {
+    raw, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    var wf Workflow
+    if err := yamlite.Unmarshal(raw, &wf); err != nil {
+        return nil, fmt.Errorf("%s: %w", path, err)
+    }
+    if err := wf.Validate(); err != nil {
+        return nil, fmt.Errorf("%s: %w", path, err)
+    }
+    return &wf, nil
+}
+
+// Validate checks that every step has a unique ID, exactly one of
+// Function and Message, and a valid retry delay.
+func (w *Workflow) Validate() error {
+    seen := map[string]bool{}
+    for i, step := range w.Steps {
+        switch {
+        case step.ID == "":
+            return fmt.Errorf("step %d: id is required", i+1)
+        case seen[step.ID]:
+            return fmt.Errorf("step %s: duplicate id", step.ID)
+        case (step.Function == "") == (step.Message == ""):
+            return fmt.Errorf("step %s: set one of function and message", step.ID)
+        case step.Retries < 0:
+            return fmt.Errorf("step %s: retries cannot be negative", step.ID)
+        }
+        if step.RetryDelay != "" {
+            if _, err := time.ParseDuration(step.RetryDelay); err != nil {
+                return fmt.Errorf("step %s: retry_delay: %w", step.ID, err)
+            }
+        }
+        seen[step.ID] = true
+    }
+    return nil
+}
+
+type Options struct {
+    // Vars override the workflow's vars.
+    Vars map[string]any
+    // DryRun plans chat steps instead of executing them. Function steps,
+    // which have no dry run, are skipped.
+    DryRun bool
+    // OnStep, when set, is called after each step.
+    OnStep func(StepResult)
+}
+
+// StepResult is one step's outcome. Output is what later steps reference
+// under "steps.<id>": function steps expose "function", "status" and
+// "output"; chat steps "function", "message", "data" and "metadata".
+type StepResult struct {
+    ID string `json:"id"`
+    Function string `json:"function,omitempty"`
+    Skipped bool `json:"skipped,omitempty"`
+    Attempts int `json:"attempts,omitempty"`
+    Output map[string]any `json:"output,omitempty"`
+    Error string `json:"error,omitempty"`
+}
+
+type Result struct {
+    Steps []StepResult `json:"steps"`
+}
+
+// Passed reports whether no step failed.
+func (r *Result) Passed() bool {
+    for _, step := range r.Steps {
+        if step.Error != "" {
+            return false
+        }
+    }
+    return true
+}
+
+// StepError is a step that failed after its retries, stopping the
+// workflow.
+type StepError struct {
+    Step string
+    Err error
+}
+
+func (e *StepError) Error() string { return "step " + e.Step + ": " + e.Err.Error() }
+func (e *StepError) Unwrap() error { return e.Err }
+
+// Run executes the workflow's steps in order with c. It stops at the
+// first step that fails without ContinueOnError, returning the result so
+// far and a *StepError; steps that cannot be rendered or whose condition
+// cannot be evaluated stop it too.
+func Run(ctx context.Context, c *client.Client, wf *Workflow, opts Options) (*Result, error) {
+    if err := wf.Validate(); err != nil {
+        return nil, err
+    }
+    vars := map[string]any{}
+    for key, value := range wf.Vars {
+        vars[key] = value
+    }
+    for key, value := range opts.Vars {
+        vars[key] = value
+    }
+    outputs := map[string]any{}
+    sources := map[string]reftemplate.Source{"vars": reftemplate.Map(vars), "steps": reftemplate.Map(outputs)}
+    result := &Result{}
+    for _, step := range wf.Steps {
+        outcome, err := runStep(ctx, c, step, sources, opts)
+        if err != nil {
+            return result, &StepError{Step: step.ID, Err: err}
+        }
+        if outcome.Output != nil {
+            outputs[step.ID] = outcome.Output
+        }
+        result.Steps = append(result.Steps, outcome)
+        if opts.OnStep != nil {
+            opts.OnStep(outcome)
+        }
+        if outcome.Error != "" && !step.ContinueOnError {
+            return result, &StepError{Step: step.ID, Err: errors.New(outcome.Error)}
+        }
+    }
+    return result, nil
+}
+
+// runStep runs one step, returning an error only when the step cannot be
+// run at all; the step's own failure is in the result.
+func runStep(ctx context.Context, c *client.Client, step Step, sources map[string]reftemplate.Source, opts Options) (StepResult, error) {
+    outcome := StepResult{ID: step.ID, Function: step.Function}
+    if step.When != "" {
+        ok, err := evaluate(step.When, sources)
+        if err != nil {
+            return outcome, fmt.Errorf("when: %w", err)
+        }
+        if !ok {
+            outcome.Skipped = true
+            return outcome, nil
+        }
+    }
+    if step.Function != "" && opts.DryRun {
+        outcome.Skipped = true
+        return outcome, nil
+    }
+    inputs, err := renderInputs(step.Inputs, sources)
+    if err != nil {
+        return outcome, err
+    }
+    message := ""
+    if step.Message != "" {
+        rendered, err := reftemplate.Render(step.Message, sources)
+        if err != nil {
+            return outcome, fmt.Errorf("message: %w", err)
+        }
+        message = fmt.Sprint(rendered)
+    }
+    delay, _ := time.ParseDuration(step.RetryDelay)
+    for attempt := 0; attempt <= step.Retries; attempt++ {
+        if attempt > 0 && delay > 0 {
+            timer := time.NewTimer(delay)
+            select {
+            case <-ctx.Done():
+                timer.Stop()
+                return outcome, ctx.Err()
+            case <-timer.C:
+            }
+        }
+        outcome.Attempts = attempt + 1
+        outcome.Output, err = call(ctx, c, step, message, inputs, opts)
+        if err == nil {
+            outcome.Error = ""
+            outcome.Function, _ = outcome.Output["function"].(string)
+            return outcome, nil
+        }
+        if ctx.Err() != nil {
+            return outcome, ctx.Err()
+        }
+        outcome.Error = err.Error()
+    }
+    return outcome, nil
+}
+
+func call(ctx context.Context, c *client.Client, step Step, message string, inputs map[string]any, opts Options) (map[string]any, error) {
+    if step.Function != "" {
+        result, err := c.ExecuteFunction(ctx, step.Function, inputs)
+        if err != nil {
+            return nil, err
+        }
+        if result.Error != "" {
+            return nil, errors.New(result.Error)
+        }
+        return map[string]any{"function": result.Function, "status": result.Status, "output": jsonMap(result.Output)}, nil
+    }
+    request := client.ChatRequest{Message: message, Inputs: inputs}.AutoExecute()
+    if opts.DryRun {
+        request = request.DryRun()
+    }
+    resp, err := c.Chat(ctx, request)
+    if err != nil {
+        return nil, err
+    }
+    return map[string]any{"function": resp.Function, "message": resp.Message, "data": jsonMap(resp.Data), "metadata": jsonMap(resp.Metadata)}, nil
+}
+
+// jsonMap lets reftemplate.Lookup walk a nil map as an empty one.
+func jsonMap(m map[string]any) map[string]any {
+    if m == nil {
+        return map[string]any{}
+    }
+    return m
+}
+
+// renderInputs renders string inputs at any depth.
+func renderInputs(inputs map[string]any, sources map[string]reftemplate.Source) (map[string]any, error) {
+    if len(inputs) == 0 {
+        return nil, nil
+    }
+    rendered, err := renderValue(inputs, sources, "")
+    if err != nil {
+        return nil, err
+    }
+    return rendered.(map[string]any), nil
+}
+
+func renderValue(value any, sources map[string]reftemplate.Source, path string) (any, error) {
+    switch value := value.(type) {
+    case string:
+        rendered, err := reftemplate.Render(value, sources)
+        if err != nil {
+            return nil, fmt.Errorf("input %s: %w", path, err)
+        }
+        return rendered, nil
+    case map[string]any:
+        out := make(map[string]any, len(value))
+        for key, item := range value {
+            field := key
+            if path != "" {
+                field = path + "." + key
+            }
+            rendered, err := renderValue(item, sources, field)
+            if err != nil {
+                return nil, err
+            }
+            out[key] = rendered
+        }
+        return out, nil
+    case []any:
+        out := make([]any, len(value))
+        for i, item := range value {
+            rendered, err := renderValue(item, sources, fmt.Sprintf("%s[%d]", path, i))
+            if err != nil {
+                return nil, err
+            }
+            out[i] = rendered
+        }
+        return out, nil
+    }
+    return value, nil
+}
+
+// evaluate decides a When condition. References to skipped or missing
+// steps compare as empty rather than failing, so a condition can test
+// whether an earlier step ran.
+func evaluate(condition string, sources map[string]reftemplate.Source) (bool, error) {
+    for _, op := range []string{"!=", "=="} {
+        left, right, ok := strings.Cut(condition, op)
+        if !ok {
+            continue
+        }
+        a, err := operand(left, sources)
+        if err != nil {
+            return false, err
+        }
+        b, err := operand(right, sources)
+        if err != nil {
+            return false, err
+        }
+        return (a == b) == (op == "=="), nil
+    }
+    value, err := operand(condition, sources)
+    if err != nil {
+        return false, err
+    }
+    switch strings.ToLower(value) {
+    case "", "false", "0", "no", "null", "<nil>":
+        return false, nil
+    }
+    return true, nil
+}
+
+func operand(text string, sources map[string]reftemplate.Source) (string, error) {
+    text = strings.Trim(strings.TrimSpace(text), `"'`)
+    lenient := map[string]reftemplate.Source{}
+    for name, source := range sources {
+        source := source
+        lenient[name] = func(key string) (any, bool) {
+            if value, ok := source(key); ok {
+                return value, true
+            }
+            return "", true
+        }
+    }
+    value, err := reftemplate.Render(text, lenient)
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprint(value), nil
+}
@@ -391,10 +391,54 @@ def generate_typescript_client(spec: OpenAPISpec, output_dir: Path) -> None:
 # Go client generation
 
 
+def go_struct_block(name: str, schema: Mapping[str, Any]) -> list[str]:
+    properties = schema.get("properties", {})
+    required = set(schema.get("required", []))
+    lines = [f"type {to_pascal(to_snake(name))} struct {{"]
+    for prop, definition in properties.items():
+        go_field_name = to_pascal(to_snake(prop))
+        optional = prop not in required
+        go_type = go_type_for(prop, definition, optional)
+        tag = f'`json:"{prop}"`'
+        if optional:
+            tag = f'`json:"{prop},omitempty"`'
+        lines.append(f"    {go_field_name} {go_type} {tag}")
+    lines.append("}")
+    return lines
+
+
+def update_go_types(spec: OpenAPISpec, client_path: Path) -> None:
+    """Rewrite only the schema structs in a hand-maintained client.go.
+
+    Each struct is replaced where it stands; structs new to the spec are
+    inserted before the Client type. Everything else in the package is left
+    alone.
+    """
+
+    source = client_path.read_text(encoding="utf-8")
+    for name, schema in spec.schemas.items():
+        block = "\n".join(go_struct_block(name, schema))
+        marker = f"type {to_pascal(to_snake(name))} struct {{"
+        start = source.find(marker)
+        if start >= 0:
+            end = source.index("\n}", start) + 2
+            source = source[:start] + block + source[end:]
+            continue
+        anchor = source.find("type Client struct {")
+        if anchor < 0:
+            raise SystemExit(f"{client_path}: no Client type to insert {name} before")
+        source = source[:anchor] + block + "\n\n" + source[anchor:]
+    client_path.write_text(source, encoding="utf-8")
+
+
 def generate_go_client(spec: OpenAPISpec, output_dir: Path) -> None:
-    if output_dir.exists():
-        shutil.rmtree(output_dir)
-    (output_dir / "cmd" / "smoke").mkdir(parents=True)
+    # The Go client has grown well past the template, so an existing package
+    # only has its schema types refreshed.
+    client_path = output_dir / "client.go"
+    if client_path.exists():
+        update_go_types(spec, client_path)
+        return
+    (output_dir / "cmd" / "smoke").mkdir(parents=True, exist_ok=True)
 
     go_mod = """module echo_computer_agent_client\n\ngo 1.21\n"""
     (output_dir / "go.mod").write_text(go_mod, encoding="utf-8")
@@ -414,18 +458,7 @@ def generate_go_client(spec: OpenAPISpec, output_dir: Path) -> None:
     ]
 
     for name, schema in spec.schemas.items():
-        properties = schema.get("properties", {})
-        required = set(schema.get("required", []))
-        struct_lines.append(f"type {to_pascal(to_snake(name))} struct {{")
-        for prop, definition in properties.items():
-            go_field_name = to_pascal(to_snake(prop))
-            optional = prop not in required
-            go_type = go_type_for(prop, definition, optional)
-            tag = f'`json:"{prop}"`'
-            if optional:
-                tag = f'`json:"{prop},omitempty"`'
-            struct_lines.append(f"    {go_field_name} {go_type} {tag}")
-        struct_lines.append("}")
+        struct_lines.extend(go_struct_block(name, schema))
         struct_lines.append("")
 
     client_code = """type Client struct {\n    baseURL string\n    httpClient *http.Client\n    defaultHeaders map[string]string\n}\n\nfunc NewClient(baseURL string, httpClient *http.Client) *Client {\n    trimmed := strings.TrimRight(baseURL, "/")\n    if httpClient == nil {\n        httpClient = http.DefaultClient\n    }\n    return &Client{\n        baseURL: trimmed,\n        httpClient: httpClient,\n        defaultHeaders: map[string]string{},\n    }\n}\n\nfunc (c *Client) SetDefaultHeader(key, value string) {\n    c.defaultHeaders[key] = value\n}\n\nfunc (c *Client) ListFunctions(ctx context.Context) (*FunctionListResponse, error) {\n    req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/functions", nil)\n    if err != nil {\n        return nil, err\n    }\n    for k, v := range c.defaultHeaders {\n        req.Header.Set(k, v)\n    }\n    resp, err := c.httpClient.Do(req)\n    if err != nil {\n        return nil, err\n    }\n    defer resp.Body.Close()\n    if resp.StatusCode >= 400 {\n        return nil, fmt.Errorf("request failed with status %d", resp.StatusCode)\n    }\n    var payload FunctionListResponse\n    if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {\n        return nil, err\n    }\n    return &payload, nil\n}\n\nfunc (c *Client) Chat(ctx context.Context, request ChatRequest) (*ChatResponse, error) {\n    body, err := json.Marshal(request)\n    if err != nil {\n        return nil, err\n    }\n    req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat", bytes.NewReader(body))\n    if err != nil {\n        return nil, err\n    }\n    req.Header.Set("Content-Type", "application/json")\n    for k, v := range c.defaultHeaders {\n        req.Header.Set(k, v)\n    }\n    resp, err := c.httpClient.Do(req)\n    if err != nil {\n        return nil, err\n    }\n    defer resp.Body.Close()\n    if resp.StatusCode >= 400 {\n        return nil, fmt.Errorf("request failed with status %d", resp.StatusCode)\n    }\n    var payload ChatResponse\n    if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {\n        return nil, err\n    }\n    return &payload, nil\n}\n"""